@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// archiveActions are the words runArchive accepts after "archive".
+var archiveActions = []string{"export", "import"}
+
+// runArchive exports a job, job.json and every artefact, as one tar.gz file, or imports
+// such a file into a running server under a new job ID.
+func runArchive(args []string) error {
+	fs := newFlagSet("archive")
+	serverURL := fs.String("server", "", "export from or import into this running audi server")
+	token := fs.String("token", os.Getenv("AUDI_TOKEN"), "with --server, the server password sent as a Bearer token (default $AUDI_TOKEN)")
+	dataDir := fs.String("data", defaultDataDir(), "export: without --server, the server data directory to read the job from (default $AUDI_DATA_DIR or data)")
+	outPath := fs.String("out", "", "export: write the archive here (default <job id>.tar.gz)")
+	output := outputFlag(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	if len(positional) == 0 {
+		return errors.New("archive: want export or import")
+	}
+
+	ctx := context.Background()
+	switch action := positional[0]; action {
+	case "export":
+		if len(positional) != 2 {
+			return errors.New("archive: export takes one job ID")
+		}
+		jobID := positional[1]
+		dst := *outPath
+		if dst == "" {
+			dst = jobID + ".tar.gz"
+		}
+		if *serverURL != "" {
+			err = newRemoteClient(*serverURL, *token).save(ctx, "/api/v1/jobs/"+url.PathEscape(jobID)+"/export", dst)
+		} else {
+			err = exportLocalJob(storage.JobDir(filepath.Join(*dataDir, "jobs"), jobID), dst)
+		}
+		if err != nil {
+			return fmt.Errorf("archive: export: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "job %s written to %s\n", jobID, dst)
+		return nil
+	case "import":
+		if len(positional) != 2 {
+			return errors.New("archive: import takes one file")
+		}
+		if *serverURL == "" {
+			return errors.New("archive: import needs --server, which indexes and seals the job")
+		}
+		job, raw, err := newRemoteClient(*serverURL, *token).importJob(ctx, positional[1])
+		if err != nil {
+			return fmt.Errorf("archive: import: %w", err)
+		}
+		if format == outputJSON {
+			return printRawJSON(raw)
+		}
+		fmt.Printf("job %s imported as %s (%s)\n", job.ImportedFrom, job.ID, job.Status)
+		return nil
+	default:
+		return fmt.Errorf("archive: unknown action %q (want %s)", action, strings.Join(archiveActions, " or "))
+	}
+}
+
+// exportLocalJob archives a finished job of a local data directory into dst, through a
+// temporary file so a failed export leaves nothing behind.
+func exportLocalJob(jobDir, dst string) error {
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		return err
+	}
+	if !job.IsDone() {
+		return fmt.Errorf("job %s is still %s", job.ID, job.Status)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".archive-*")
+	if err != nil {
+		return err
+	}
+	if err := storage.ExportJob(tmp, jobDir); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// importJob posts an archive to a server's /api/v1/jobs:import and returns the new job
+// with its raw JSON.
+func (c *remoteClient) importJob(ctx context.Context, archivePath string) (*chunker.Job, []byte, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/v1/jobs:import", f)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	if info, err := f.Stat(); err == nil {
+		req.ContentLength = info.Size()
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, nil, responseError(resp)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var job chunker.Job
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return nil, nil, err
+	}
+	return &job, raw, nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// batchSummaryFile is written into --out once every file was processed.
+const batchSummaryFile = "batch.json"
+
+// batchFile is one input's outcome in the batch summary.
+type batchFile struct {
+	// Input is relative to the batch root, OutDir relative to --out.
+	Input           string  `json:"input"`
+	OutDir          string  `json:"outDir"`
+	Status          string  `json:"status"`
+	Chunks          int     `json:"chunks"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	ElapsedSeconds  float64 `json:"elapsedSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// batchSummary is the combined manifest of a batch run.
+type batchSummary struct {
+	Root       string      `json:"root"`
+	Patterns   []string    `json:"patterns"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt time.Time   `json:"finishedAt"`
+	Succeeded  int         `json:"succeeded"`
+	Failed     int         `json:"failed"`
+	Files      []batchFile `json:"files"`
+}
+
+// runBatch chunks every matching file under a directory with one set of options,
+// several at a time, and fails when any file did.
+func runBatch(args []string) error {
+	fs := newFlagSet("batch")
+	globFlag := fs.String("glob", "*", "comma-separated file name patterns to process, e.g. \"*.mp4,*.mkv\"")
+	parallel := fs.Int("parallel", 1, "number of files processed at the same time")
+	outDir := fs.String("out", "", "output directory, mirroring the input tree (default <dir>-chunks)")
+	processing := addChunkFlags(fs)
+	metaFlag := addMetadataFlag(fs)
+	output := outputFlag(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("batch: exactly one directory is required")
+	}
+	root := positional[0]
+	if *parallel < 1 {
+		return errors.New("batch: --parallel must be at least 1")
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+	patterns, err := parseGlobs(*globFlag)
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+
+	opts, filterPreset, setNames, err := processing.options()
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+	metadata, err := metaFlag.metadata()
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+	proc, err := processing.processor(fs, &opts, setNames)
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+	defer closeProcessor(proc)
+
+	dir := *outDir
+	if dir == "" {
+		dir = filepath.Clean(root) + "-chunks"
+	}
+	inputs, err := findBatchInputs(root, dir, patterns)
+	if err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("batch: no files under %s match %s", root, strings.Join(patterns, ", "))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	summary := batchSummary{
+		Root:      root,
+		Patterns:  patterns,
+		StartedAt: time.Now(),
+		Files:     make([]batchFile, len(inputs)),
+	}
+	var (
+		wg      sync.WaitGroup
+		printMu sync.Mutex
+		next    = make(chan int)
+	)
+	jobDirs := batchJobDirs(inputs)
+	for range min(*parallel, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result := chunkBatchFile(ctx, proc, root, dir, inputs[i], jobDirs[i], metadata, opts, filterPreset)
+				summary.Files[i] = result
+				if format == outputTable {
+					printMu.Lock()
+					printBatchFile(result, dir)
+					printMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range inputs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	summary.FinishedAt = time.Now()
+	for _, file := range summary.Files {
+		if file.Status == string(chunker.JobStatusCompleted) {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	summaryPath := filepath.Join(dir, batchSummaryFile)
+	if err := writeBatchSummary(summaryPath, summary); err != nil {
+		return fmt.Errorf("batch: %w", err)
+	}
+
+	if format == outputJSON {
+		if err := printJSON(summary); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("%d succeeded, %d failed; summary in %s\n", summary.Succeeded, summary.Failed, summaryPath)
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("batch: %d of %d files failed", summary.Failed, len(inputs))
+	}
+	return nil
+}
+
+// parseGlobs splits and validates the --glob patterns.
+func parseGlobs(value string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid --glob pattern %q", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return nil, errors.New("--glob needs at least one pattern")
+	}
+	return patterns, nil
+}
+
+// findBatchInputs walks root for regular files whose name matches a pattern, skipping
+// hidden entries and the output directory, and returns their paths relative to root in
+// lexical order.
+func findBatchInputs(root, outDir string, patterns []string) ([]string, error) {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absOut {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, d.Name()); ok {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				inputs = append(inputs, rel)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", root, err)
+	}
+	sort.Strings(inputs)
+	return inputs, nil
+}
+
+// batchJobDirs names each input's output directory after its relative path without the
+// extension, keeping the extension where that would make two inputs share a directory,
+// as with talk.mp4 and talk.mkv.
+func batchJobDirs(inputs []string) []string {
+	seen := make(map[string]int, len(inputs))
+	for _, rel := range inputs {
+		seen[strings.TrimSuffix(rel, filepath.Ext(rel))]++
+	}
+	dirs := make([]string, len(inputs))
+	for i, rel := range inputs {
+		dirs[i] = strings.TrimSuffix(rel, filepath.Ext(rel))
+		if seen[dirs[i]] > 1 {
+			dirs[i] = rel
+		}
+	}
+	return dirs
+}
+
+// chunkBatchFile processes one input into jobDir under outDir.
+func chunkBatchFile(ctx context.Context, proc *chunker.Processor, root, outDir, rel, jobDir string, metadata map[string]string, opts chunker.Options, filters chunker.FilterPreset) batchFile {
+	result := batchFile{
+		Input:  filepath.ToSlash(rel),
+		OutDir: filepath.ToSlash(jobDir),
+		Status: string(chunker.JobStatusFailed),
+	}
+	if err := ctx.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	started := time.Now()
+	job, err := chunkLocal(ctx, proc, filepath.Join(root, rel), filepath.Join(outDir, jobDir), "", metadata, nil, opts, filters)
+	result.ElapsedSeconds = time.Since(started).Seconds()
+	if job != nil {
+		result.Status = string(job.Status)
+		result.Chunks = len(job.Chunks)
+		for _, chunk := range job.Chunks {
+			result.DurationSeconds += chunk.DurationSeconds
+		}
+	}
+	if err != nil {
+		result.Status = string(chunker.JobStatusFailed)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func printBatchFile(file batchFile, outDir string) {
+	if file.Error != "" {
+		fmt.Printf("FAIL  %s: %s\n", file.Input, file.Error)
+		return
+	}
+	fmt.Printf("ok    %s  %d chunks, %.1fs in %.1fs  -> %s\n", file.Input, file.Chunks, file.DurationSeconds, file.ElapsedSeconds, filepath.Join(outDir, filepath.FromSlash(file.OutDir)))
+}
+
+// writeBatchSummary saves the summary as indented JSON.
+func writeBatchSummary(path string, summary batchSummary) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing batch summary: %w", err)
+	}
+	return nil
+}
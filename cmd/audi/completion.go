@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// completeCommand is the hidden subcommand the completion scripts call with the words
+// typed so far; it prints the candidates for the last one, one per line. Printing none
+// makes the shells fall back to file names.
+const completeCommand = "__complete"
+
+// completionTimeout bounds the server query behind job ID completion, so a slow or
+// unreachable server never hangs the shell.
+const completionTimeout = 3 * time.Second
+
+const bashCompletion = `# bash completion for audi; load with: source <(audi completion bash)
+_audi() {
+    local IFS=$'\n'
+    COMPREPLY=($("$1" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _audi audi
+`
+
+const zshCompletion = `#compdef audi
+# zsh completion for audi; load with: source <(audi completion zsh)
+_audi() {
+    local -a candidates
+    candidates=("${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -z "${candidates[1]}" ]]; then
+        _files
+    else
+        compadd -a candidates
+    fi
+}
+compdef _audi audi
+`
+
+const fishCompletion = `# fish completion for audi; load with: audi completion fish | source
+function __audi_complete
+    set -l words (commandline -opc)
+    set -l current (commandline -ct)
+    test -n "$current"; or set current ''
+    set -e words[1]
+    audi __complete $words $current 2>/dev/null
+end
+complete -c audi -a '(__audi_complete)'
+`
+
+// runCompletion prints the completion script for a shell.
+func runCompletion(args []string) error {
+	fs := newFlagSet("completion")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch fs.Arg(0) {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	case "":
+		return errors.New("completion: name a shell: bash, zsh, or fish")
+	default:
+		return fmt.Errorf("completion: unsupported shell %q (want bash, zsh, or fish)", fs.Arg(0))
+	}
+	return nil
+}
+
+// runComplete prints the completion candidates for the words after "audi".
+func runComplete(words []string) error {
+	for _, candidate := range complete(words) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// complete returns the subcommands, flags, flag values, or job IDs that fit the last
+// word, which may be empty.
+func complete(words []string) []string {
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+	}
+	if len(words) <= 1 {
+		return withPrefix(commandNames, current)
+	}
+
+	fs := commandFlags(words[0])
+	if fs == nil {
+		return nil
+	}
+	if name, value, ok := strings.Cut(current, "="); ok && strings.HasPrefix(name, "-") {
+		var matches []string
+		for _, candidate := range withPrefix(flagValues(words[0], strings.TrimLeft(name, "-"), words), value) {
+			matches = append(matches, name+"="+candidate)
+		}
+		return matches
+	}
+	if strings.HasPrefix(current, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) {
+			names = append(names, "--"+f.Name)
+		})
+		return withPrefix(names, current)
+	}
+
+	// The previous word names the flag whose value is being typed. Bash splits
+	// "--flag=value" into three words, leaving "=" in between.
+	prev := ""
+	if len(words) >= 2 {
+		prev = words[len(words)-2]
+	}
+	if prev == "=" && len(words) >= 3 {
+		prev = words[len(words)-3]
+	}
+	if strings.HasPrefix(prev, "-") {
+		if f := fs.Lookup(strings.TrimLeft(prev, "-")); f != nil && !isBoolFlag(f) {
+			return withPrefix(flagValues(words[0], f.Name, words), current)
+		}
+	}
+	if words[0] == "jobs" {
+		return withPrefix(completeJobIDs(words), current)
+	}
+	if words[0] == "config" && len(words) == 2 {
+		return withPrefix(configActions, current)
+	}
+	if words[0] == "archive" && len(words) == 2 {
+		return withPrefix(archiveActions, current)
+	}
+	if words[0] == "archive" && len(words) == 3 && words[1] == "export" {
+		return withPrefix(completeJobIDs(words), current)
+	}
+	return nil
+}
+
+// commandFlags returns a subcommand's flag set by running it with -h, which parses
+// nothing else and returns before doing any work.
+func commandFlags(name string) *flag.FlagSet {
+	run := command(name)
+	if run == nil || name == completeCommand {
+		return nil
+	}
+	out := flagOutput
+	flagOutput = io.Discard
+	defer func() { flagOutput = out }()
+	lastFlagSet = nil
+	_ = run([]string{"-h"})
+	return lastFlagSet
+}
+
+// flagValues lists the accepted values of enumerated flags and the job IDs for --job;
+// free-form and path flags have none.
+func flagValues(cmd, name string, words []string) []string {
+	switch name {
+	case "output":
+		return outputFormats
+	case "remainder":
+		return []string{string(chunker.RemainderKeep), string(chunker.RemainderMerge), string(chunker.RemainderPad)}
+	case "filters":
+		values := make([]string, 0, len(chunker.FilterPresets))
+		for _, preset := range chunker.FilterPresets {
+			values = append(values, string(preset))
+		}
+		return values
+	case "track":
+		return []string{string(chunker.TrackMix), string(chunker.TrackSeparate)}
+	case "merged":
+		return []string{string(chunker.MergedMP3), string(chunker.MergedOpus)}
+	case "subtitles":
+		return []string{string(chunker.SubtitlesSRT), string(chunker.SubtitlesMP4)}
+	case "redact":
+		return []string{string(chunker.RedactText), string(chunker.RedactAudio)}
+	case "download":
+		return append(append([]string(nil), remoteArtefacts...), "all")
+	case "job":
+		if cmd == "verify" {
+			return completeJobIDs(words)
+		}
+	}
+	return nil
+}
+
+// completeJobIDs lists job IDs from the --server or --data given earlier on the line,
+// falling back to the default data directory. Errors yield no candidates.
+func completeJobIDs(words []string) []string {
+	serverURL := flagArg(words, "server")
+	dataDir := flagArg(words, "data")
+	if dataDir == "" {
+		dataDir = defaultDataDir()
+	}
+	token := flagArg(words, "token")
+	if token == "" {
+		token = os.Getenv("AUDI_TOKEN")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	jobs, err := loadJobs(ctx, serverURL, token, dataDir, "")
+	if err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+// flagArg returns the value of a flag among the typed words, in any of the forms the
+// flag package accepts.
+func flagArg(words []string, name string) string {
+	for i, word := range words {
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if !strings.HasPrefix(word, "-") || flagName != name {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(words) && words[i+1] == "=" && i+2 < len(words) {
+			return words[i+2]
+		}
+		if i+1 < len(words) {
+			return words[i+1]
+		}
+	}
+	return ""
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func withPrefix(candidates []string, prefix string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
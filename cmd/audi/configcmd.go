@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/storage"
+)
+
+// configActions are the words runConfig accepts after "config".
+var configActions = []string{"export", "import"}
+
+// runConfig exports a server's configuration and preset library as one file, or imports
+// such a file into a running server.
+func runConfig(args []string) error {
+	fs := newFlagSet("config")
+	serverURL := fs.String("server", "", "export from or import into this running audi server")
+	token := fs.String("token", os.Getenv("AUDI_TOKEN"), "with --server, the server password sent as a Bearer token (default $AUDI_TOKEN)")
+	configPath := fs.String("config", "", "export: without --server, the server's YAML config file; environment overrides apply as for the server")
+	secrets := fs.Bool("secrets", false, "export: keep passwords and header values instead of replacing them with "+config.RedactedSecret)
+	outPath := fs.String("out", "", "export: write the file here instead of stdout")
+	dryRun := fs.Bool("dry-run", false, "import: report what would change without changing it")
+	prune := fs.Bool("prune", false, "import: also delete the server's API-managed presets missing from the file")
+	output := outputFlag(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if len(positional) == 0 {
+		return errors.New("config: want export or import")
+	}
+
+	ctx := context.Background()
+	switch action := positional[0]; action {
+	case "export":
+		if len(positional) > 1 {
+			return errors.New("config: export takes no arguments")
+		}
+		var data []byte
+		if *serverURL != "" {
+			data, err = newRemoteClient(*serverURL, *token).exportConfig(ctx, *secrets)
+		} else {
+			data, err = exportLocalConfig(*configPath, *secrets)
+		}
+		if err != nil {
+			return fmt.Errorf("config: export: %w", err)
+		}
+		if *outPath == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(*outPath, data, 0o600); err != nil {
+			return fmt.Errorf("config: export: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "configuration written to %s\n", *outPath)
+		return nil
+	case "import":
+		if len(positional) != 2 {
+			return errors.New("config: import takes one file")
+		}
+		if *serverURL == "" {
+			return errors.New("config: import needs --server; to use the file locally, start the server with -config")
+		}
+		data, err := os.ReadFile(positional[1])
+		if err != nil {
+			return fmt.Errorf("config: import: %w", err)
+		}
+		raw, err := newRemoteClient(*serverURL, *token).importConfig(ctx, data, *dryRun, *prune)
+		if err != nil {
+			return fmt.Errorf("config: import: %w", err)
+		}
+		if format == outputJSON {
+			return printRawJSON(raw)
+		}
+		return printImportResult(raw)
+	default:
+		return fmt.Errorf("config: unknown action %q (want %s)", action, strings.Join(configActions, " or "))
+	}
+}
+
+// exportLocalConfig renders the config file at path, with the environment applied, and
+// the presets saved in its data directory, as the server would export them.
+func exportLocalConfig(path string, secrets bool) ([]byte, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	fixed := make([]storage.Preset, 0, len(cfg.Presets))
+	for _, p := range cfg.Presets {
+		options := make(map[string][]string, len(p.Options))
+		for field, values := range p.Options {
+			options[field] = values
+		}
+		fixed = append(fixed, storage.Preset{Name: p.Name, Description: p.Description, Options: options})
+	}
+	presets, err := storage.OpenPresets(filepath.Join(cfg.DataDir, "presets.json"), fixed)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Presets = nil
+	for _, p := range presets.List() {
+		options := make(config.FormValues, len(p.Options))
+		for field, values := range p.Options {
+			options[field] = values
+		}
+		cfg.Presets = append(cfg.Presets, config.PresetConfig{Name: p.Name, Description: p.Description, Options: options})
+	}
+
+	comment := []string{"audi configuration exported " + time.Now().UTC().Format(time.RFC3339) + " from " + displayPath(path)}
+	if !secrets {
+		var redacted []string
+		if cfg, redacted = cfg.Redact(); len(redacted) > 0 {
+			comment = append(comment, "", "Secrets to fill in before starting a server with it:")
+			for _, p := range redacted {
+				comment = append(comment, "  "+p)
+			}
+		}
+	}
+	return cfg.Marshal(comment)
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "the defaults"
+	}
+	return path
+}
+
+// exportConfig fetches a server's GET /api/v1/config:export.
+func (c *remoteClient) exportConfig(ctx context.Context, secrets bool) ([]byte, error) {
+	route := "/api/v1/config:export"
+	if secrets {
+		route += "?secrets=include"
+	}
+	resp, err := c.get(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// importConfig posts a config file to a server's /api/v1/config:import and returns the
+// JSON answer.
+func (c *remoteClient) importConfig(ctx context.Context, data []byte, dryRun, prune bool) ([]byte, error) {
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	if prune {
+		query.Set("prune", "true")
+	}
+	route := "/api/v1/config:import"
+	if len(query) > 0 {
+		route += "?" + query.Encode()
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, route, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/yaml")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// printImportResult summarises an import answer, one line per kind of change.
+func printImportResult(raw []byte) error {
+	var result struct {
+		DryRun          bool     `json:"dryRun"`
+		Created         []string `json:"created"`
+		Updated         []string `json:"updated"`
+		Unchanged       []string `json:"unchanged"`
+		Deleted         []string `json:"deleted"`
+		RestartRequired []string `json:"restartRequired"`
+		Skipped         []struct {
+			Name  string `json:"name"`
+			Error string `json:"error"`
+		} `json:"skipped"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return err
+	}
+	if result.DryRun {
+		fmt.Println("dry run: nothing was changed")
+	}
+	for _, line := range []struct {
+		label string
+		names []string
+	}{
+		{"presets created", result.Created},
+		{"presets updated", result.Updated},
+		{"presets unchanged", result.Unchanged},
+		{"presets deleted", result.Deleted},
+	} {
+		if len(line.names) > 0 {
+			fmt.Printf("%s: %s\n", line.label, strings.Join(line.names, ", "))
+		}
+	}
+	for _, skip := range result.Skipped {
+		fmt.Printf("preset %s skipped: %s\n", skip.Name, skip.Error)
+	}
+	if len(result.RestartRequired) > 0 {
+		fmt.Printf("settings that differ and need the file installed and a restart: %s\n", strings.Join(result.RestartRequired, ", "))
+	} else {
+		fmt.Println("all other settings match the running server")
+	}
+	return nil
+}
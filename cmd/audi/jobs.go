@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// defaultDataDir mirrors the server's data_dir default and AUDI_DATA_DIR override, so
+// "audi jobs" run next to a server sees its jobs without flags.
+func defaultDataDir() string {
+	if dir := os.Getenv("AUDI_DATA_DIR"); dir != "" {
+		return dir
+	}
+	return "data"
+}
+
+// runJobs lists the jobs of a server or a local data directory, or shows one job.
+func runJobs(args []string) error {
+	fs := newFlagSet("jobs")
+	serverURL := fs.String("server", "", "list the jobs of a running audi server instead of a local data directory")
+	token := fs.String("token", os.Getenv("AUDI_TOKEN"), "with --server, the server password sent as a Bearer token (default $AUDI_TOKEN)")
+	dataDir := fs.String("data", defaultDataDir(), "server data directory to read jobs from (default $AUDI_DATA_DIR or data)")
+	output := outputFlag(fs)
+	quiet := fs.Bool("quiet", false, "print job IDs only, one per line")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return errors.New("jobs: at most one job ID is allowed")
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("jobs: %w", err)
+	}
+
+	var jobID string
+	if len(positional) == 1 {
+		jobID = positional[0]
+	}
+	jobs, err := loadJobs(context.Background(), *serverURL, *token, *dataDir, jobID)
+	if err != nil {
+		return fmt.Errorf("jobs: %w", err)
+	}
+
+	switch {
+	case *quiet:
+		for _, job := range jobs {
+			fmt.Println(job.ID)
+		}
+	case format == outputJSON && jobID != "":
+		return printJSON(jobs[0])
+	case format == outputJSON:
+		if jobs == nil {
+			jobs = []*chunker.Job{}
+		}
+		return printJSON(jobs)
+	default:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTATUS\tCHUNKS\tCREATED\tNAME")
+		for _, job := range jobs {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", job.ID, job.Status, len(job.Chunks), job.CreatedAt.Local().Format("2006-01-02 15:04"), job.DisplayName())
+		}
+		return tw.Flush()
+	}
+	return nil
+}
+
+// loadJobs fetches one job, or all of them newest first, from the server when serverURL
+// is set and from dataDir otherwise.
+func loadJobs(ctx context.Context, serverURL, token, dataDir, jobID string) ([]*chunker.Job, error) {
+	if serverURL != "" {
+		client := newRemoteClient(serverURL, token)
+		if jobID == "" {
+			return client.jobs(ctx)
+		}
+		job, _, err := client.job(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		return []*chunker.Job{job}, nil
+	}
+
+	jobsDir := filepath.Join(dataDir, "jobs")
+	if jobID == "" {
+		return storage.ListJobs(jobsDir)
+	}
+	job, err := storage.LoadJob(storage.JobDir(jobsDir, jobID))
+	if err != nil {
+		return nil, err
+	}
+	return []*chunker.Job{job}, nil
+}
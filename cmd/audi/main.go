@@ -0,0 +1,490 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+const usage = `usage: audi <command> [flags]
+
+Commands:
+  chunk       split a local video/audio file into chunks (offline or via --server)
+  probe       list the audio tracks of a file
+  batch       chunk every matching file in a directory tree, several at a time
+  verify      re-hash a job directory against its manifest.json
+  jobs        list the jobs of a server or data directory, or show one
+  config      export a server's configuration and presets, or import them into another
+  archive     export a job with all its files as one archive, or import one into a server
+  completion  print a bash, zsh, or fish completion script
+
+Run "audi <command> -h" for command flags.
+`
+
+// commandNames lists the public subcommands, in the order completion offers them.
+var commandNames = []string{"chunk", "batch", "probe", "verify", "jobs", "config", "archive", "completion"}
+
+// command returns the function running a subcommand, or nil for an unknown name.
+func command(name string) func([]string) error {
+	switch name {
+	case "chunk":
+		return runChunk
+	case "batch":
+		return runBatch
+	case "probe":
+		return runProbe
+	case "verify":
+		return runVerify
+	case "jobs":
+		return runJobs
+	case "config":
+		return runConfig
+	case "archive":
+		return runArchive
+	case "completion":
+		return runCompletion
+	case completeCommand:
+		return runComplete
+	}
+	return nil
+}
+
+// main dispatches to the requested subcommand.
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+		return
+	}
+	run := command(os.Args[1])
+	if run == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "audi: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runChunk processes a file locally, or uploads it when --server is given.
+func runChunk(args []string) error {
+	fs := newFlagSet("chunk")
+	input := fs.String("input", "", "path to the video or audio file to chunk")
+	outDir := fs.String("out", "", "output directory (default <input name>-chunks)")
+	processing := addChunkFlags(fs)
+	recordingStart := fs.String("recording-start", "", "absolute recording start time (RFC 3339), stored in job.json")
+	titleFlag := fs.String("title", "", "friendly job title shown instead of the file name")
+	metaFlag := addMetadataFlag(fs)
+	serverURL := fs.String("server", "", "upload to a running audi server instead of processing locally")
+	force := fs.Bool("force", false, "with --server, process even if the server already has an identical job")
+	preset := fs.String("preset", "", "with --server, use this server preset; only processing flags given explicitly override it")
+	token := fs.String("token", os.Getenv("AUDI_TOKEN"), "with --server, the server password sent as a Bearer token (default $AUDI_TOKEN)")
+	wait := fs.Bool("wait", false, "with --server, wait until the job finishes and exit non-zero if it fails")
+	downloadFlag := fs.String("download", "", "with --server, wait and save these artefacts into --out: "+strings.Join(remoteArtefacts, ", ")+", or all")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *input == "" && fs.NArg() > 0 {
+		*input = fs.Arg(0)
+	}
+	if *input == "" {
+		return errors.New("chunk: --input is required")
+	}
+
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("chunk: %w", err)
+	}
+
+	opts, filterPreset, setNames, err := processing.options()
+	if err != nil {
+		return fmt.Errorf("chunk: %w", err)
+	}
+
+	title, err := chunker.NormalizeTitle(*titleFlag)
+	if err != nil {
+		return fmt.Errorf("chunk: %w", err)
+	}
+	metadata, err := metaFlag.metadata()
+	if err != nil {
+		return fmt.Errorf("chunk: %w", err)
+	}
+
+	dir := *outDir
+	if dir == "" {
+		base := filepath.Base(*input)
+		dir = strings.TrimSuffix(base, filepath.Ext(base)) + "-chunks"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *serverURL != "" {
+		downloads, err := parseArtefacts(*downloadFlag)
+		if err != nil {
+			return fmt.Errorf("chunk: %w", err)
+		}
+		explicit := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+		client := newRemoteClient(*serverURL, *token)
+		return chunkRemote(ctx, client, *input, title, metadata, setNames, opts, filterPreset, *force, *preset, explicit, *wait, downloads, dir, format)
+	}
+	if *preset != "" {
+		return errors.New("chunk: --preset requires --server")
+	}
+
+	proc, err := processing.processor(fs, &opts, setNames)
+	if err != nil {
+		return fmt.Errorf("chunk: %w", err)
+	}
+	defer closeProcessor(proc)
+
+	var start *time.Time
+	if *recordingStart != "" {
+		t, err := time.Parse(time.RFC3339, *recordingStart)
+		if err != nil {
+			return fmt.Errorf("chunk: invalid --recording-start: %w", err)
+		}
+		start = &t
+	}
+
+	job, procErr := chunkLocal(ctx, proc, *input, dir, title, metadata, start, opts, filterPreset)
+	if job == nil {
+		return procErr
+	}
+	if format == outputJSON {
+		if err := printJSON(job); err != nil {
+			return err
+		}
+		return procErr
+	}
+	if procErr != nil {
+		return procErr
+	}
+
+	for _, chunk := range job.Chunks {
+		file := chunk.AudioFile
+		if file == "" {
+			file = chunk.TranscriptFile
+		}
+		fmt.Printf("%3d  %8.2fs  %8.2fs  %s\n", chunk.Index, chunk.StartSeconds, chunk.DurationSeconds, filepath.Join(dir, filepath.FromSlash(file)))
+	}
+	if job.MergedAudioFile != "" {
+		fmt.Printf("merged audio: %s\n", filepath.Join(dir, filepath.FromSlash(job.MergedAudioFile)))
+	}
+	if len(job.Chunks) > 0 && job.Chunks[0].VideoFile != "" {
+		fmt.Printf("video clips: %s\n", filepath.Join(dir, "video"))
+	}
+	for _, rel := range []string{job.SubtitlesFile, job.SubtitledVideoFile} {
+		if rel != "" {
+			fmt.Printf("subtitles: %s\n", filepath.Join(dir, filepath.FromSlash(rel)))
+		}
+	}
+	if job.ChaptersFile != "" {
+		fmt.Printf("chapters: %d in %s\n", len(job.Chapters), filepath.Join(dir, filepath.FromSlash(job.ChaptersFile)))
+	}
+	if job.AnalyzeSentiment {
+		sum, counts := 0.0, make(map[string]int)
+		for _, chunk := range job.Chunks {
+			if chunk.Sentiment != nil {
+				sum += chunk.Sentiment.Score
+				counts[chunk.Sentiment.Label]++
+			}
+		}
+		if rated := counts[chunker.SentimentPositive] + counts[chunker.SentimentNeutral] + counts[chunker.SentimentNegative]; rated > 0 {
+			fmt.Printf("sentiment: %+.2f on average (%d positive, %d neutral, %d negative)\n", sum/float64(rated),
+				counts[chunker.SentimentPositive], counts[chunker.SentimentNeutral], counts[chunker.SentimentNegative])
+		}
+	}
+	if job.DetectPII {
+		findings := 0
+		for _, chunk := range job.Chunks {
+			findings += len(chunk.PII)
+		}
+		fmt.Printf("pii: %d findings, masked transcripts in %s\n", findings, filepath.Join(dir, "transcripts"))
+	}
+	if s := job.Summary; s != nil {
+		if s.Error != "" {
+			fmt.Printf("summary failed: %s\n", s.Error)
+		} else {
+			fmt.Printf("summary: %d chapters and %d action items in %s\n", len(s.Chapters), len(s.ActionItems), filepath.Join(dir, "job.json"))
+		}
+	}
+	if job.FailedChunks > 0 {
+		fmt.Printf("transcription failed for %d chunks; see transcriptError in %s\n", job.FailedChunks, filepath.Join(dir, "job.json"))
+	}
+	fmt.Printf("%d chunks written to %s\n", len(job.Chunks), dir)
+	return nil
+}
+
+// chunkLocal runs the processor directly and writes a job.json next to the artefacts.
+// It returns the saved job, also when processing failed, unless the job could not be
+// written at all.
+func chunkLocal(ctx context.Context, proc *chunker.Processor, inputPath, outDir, title string, metadata map[string]string, recordingStart *time.Time, opts chunker.Options, filters chunker.FilterPreset) (*chunker.Job, error) {
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolving input path: %w", err)
+	}
+
+	// The server hashes uploads as it stores them; here the input stays where it is.
+	inputSHA256, err := storage.HashFile(absInput)
+	if err != nil {
+		return nil, fmt.Errorf("hashing input: %w", err)
+	}
+
+	job := &chunker.Job{
+		ID:                     filepath.Base(outDir),
+		Title:                  title,
+		Metadata:               metadata,
+		OriginalFileName:       filepath.Base(inputPath),
+		OriginalVideoPath:      absInput,
+		OriginalSHA256:         inputSHA256,
+		CreatedAt:              time.Now(),
+		ChunkDurationSeconds:   opts.ChunkDurationSeconds,
+		ChunkCount:             opts.ChunkCount,
+		TranscriptionRequested: opts.Transcribe,
+		TranscriptOnly:         opts.TranscriptOnly,
+		WordTimestamps:         opts.WordTimestamps,
+		MergedFormat:           string(opts.MergedFormat),
+		VideoClips:             opts.VideoClips,
+		Subtitles:              string(opts.Subtitles),
+		Summarize:              opts.Summarize,
+		ExtractKeywords:        opts.ExtractKeywords,
+		AnalyzeSentiment:       opts.AnalyzeSentiment,
+		DetectChapters:         opts.DetectChapters,
+		Redact:                 string(opts.Redact),
+		DetectPII:              opts.DetectPII,
+		CPUOnly:                opts.CPUOnly,
+		WhisperArgs:            opts.WhisperArgs,
+		SkipSilence:            opts.SkipSilence,
+		RemainderPolicy:        string(opts.Remainder),
+		FadeMillis:             opts.FadeMillis,
+		PaddingMillis:          opts.PaddingMillis,
+		FilterPreset:           string(filters),
+		TrackMode:              string(opts.TrackMode),
+		AudioTrack:             opts.AudioTrack,
+		Status:                 chunker.JobStatusProcessing,
+	}
+	if opts.Transcribe && opts.QualityGate.Enabled() {
+		gate := opts.QualityGate
+		job.QualityGate = &gate
+	}
+	if opts.Transcribe && opts.Escalation.Enabled() {
+		escalation := opts.Escalation
+		job.Escalation = &escalation
+	}
+	for _, set := range opts.TranscriptSets {
+		job.TranscriptSets = append(job.TranscriptSets, set.Name)
+	}
+	if recordingStart != nil {
+		job.RecordingStart = recordingStart
+		job.RecordingStartSource = chunker.RecordingStartUser
+	}
+	job.ResetStages()
+	jobLog, err := storage.OpenJobLog(outDir, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer jobLog.Close()
+	job.LogFile = storage.JobLogFile
+	ctx = chunker.WithOutputLog(ctx, chunker.NewOutputLog(jobLog))
+	if err := storage.CreateJob(outDir, job); err != nil {
+		return nil, err
+	}
+
+	opts.Hooks = append(opts.Hooks, chunker.StageRecorder{Job: job})
+	result, procErr := proc.Process(ctx, outDir, absInput, opts)
+
+	completed := time.Now()
+	job.CompletedAt = &completed
+	job.EndStages(procErr, completed)
+	job.Chunks = result.Chunks
+	job.CountFailedChunks()
+	job.AudioStreams = result.AudioStreams
+	job.Commands = result.Commands
+	job.CPUFallback = result.CPUFallback
+	job.MergedAudioFile = result.MergedAudioFile
+	job.SubtitlesFile = result.SubtitlesFile
+	job.SubtitledVideoFile = result.SubtitledVideoFile
+	job.Summary = result.Summary
+	job.Chapters = result.Chapters
+	job.ChaptersFile = result.ChaptersFile
+	job.ChapterMetadataFile = result.ChapterMetadataFile
+	if procErr != nil {
+		job.Status = chunker.JobStatusFailed
+		job.ErrorMessage = procErr.Error()
+	} else {
+		job.Status = chunker.JobStatusCompleted
+		if opts.ChunkCount > 0 && result.SegmentSeconds > 0 {
+			job.ChunkDurationSeconds = int(math.Round(result.SegmentSeconds))
+		}
+	}
+	if result.InputSeconds > 0 {
+		job.InputDurationSeconds = result.InputSeconds
+	}
+	if job.RecordingStart == nil && result.MediaCreationTime != nil {
+		job.RecordingStart = result.MediaCreationTime
+		job.RecordingStartSource = chunker.RecordingStartMetadata
+	}
+	if procErr == nil {
+		if _, err := storage.WriteManifest(outDir, job); err != nil {
+			return job, fmt.Errorf("writing manifest: %w", err)
+		}
+	}
+	if _, err := storage.WriteReport(outDir, job); err != nil {
+		return job, fmt.Errorf("writing integrity report: %w", err)
+	}
+	if usage, err := storage.MeasureJob(outDir); err == nil {
+		job.DiskUsage = usage
+	}
+	if err := storage.SaveJob(outDir, job); err != nil {
+		return nil, err
+	}
+	return job, procErr
+}
+
+// parseDurationSeconds accepts plain seconds ("300") or Go durations ("5m", "1h30m").
+func parseDurationSeconds(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if sec, err := strconv.Atoi(value); err == nil {
+		if sec <= 0 {
+			return 0, fmt.Errorf("duration must be positive, got %q", value)
+		}
+		return sec, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	sec := int(d / time.Second)
+	if sec <= 0 {
+		return 0, fmt.Errorf("duration must be at least one second, got %q", value)
+	}
+	return sec, nil
+}
+
+// runProbe prints the audio streams ffmpeg finds in a file, numbered as --track expects.
+func runProbe(args []string) error {
+	fs := newFlagSet("probe")
+	input := fs.String("input", "", "path to the video or audio file to inspect")
+	configPath := fs.String("config", "", "path to a YAML config file for ffmpeg settings")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return errors.New("probe: --input is required")
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("probe: %w", err)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	proc := chunker.New(chunker.WithFFmpeg(cfg.FFmpegBin))
+
+	streams, err := proc.ProbeAudioStreams(context.Background(), *input)
+	if err != nil {
+		return fmt.Errorf("probe: %w", err)
+	}
+	if format == outputJSON {
+		if streams == nil {
+			streams = []chunker.AudioStream{}
+		}
+		return printJSON(streams)
+	}
+	for _, stream := range streams {
+		parts := []string{fmt.Sprintf("track %d:", stream.Index+1)}
+		if stream.Title != "" {
+			parts = append(parts, stream.Title)
+		}
+		if stream.Language != "" {
+			parts = append(parts, "["+stream.Language+"]")
+		}
+		fmt.Println(strings.Join(append(parts, stream.Details), " "))
+	}
+	return nil
+}
+
+// runVerify checks a job directory (server or CLI output) for corrupt, missing, or
+// unexpected files and exits non-zero when anything differs from the manifest.
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	dir := fs.String("dir", "", "job directory containing manifest.json")
+	jobID := fs.String("job", "", "verify this job of the server data directory instead of --dir")
+	dataDir := fs.String("data", defaultDataDir(), "with --job, the server data directory (default $AUDI_DATA_DIR or data)")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" && fs.NArg() > 0 {
+		*dir = fs.Arg(0)
+	}
+	if *jobID != "" {
+		if *dir != "" {
+			return errors.New("verify: pass either --dir or --job")
+		}
+		*dir = storage.JobDir(filepath.Join(*dataDir, "jobs"), *jobID)
+	}
+	if *dir == "" {
+		return errors.New("verify: --dir or --job is required")
+	}
+	format, err := parseOutputFormat(*output)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+
+	report, err := storage.VerifyJob(*dir)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	var mismatch error
+	if !report.OK() {
+		mismatch = fmt.Errorf("verify: %d corrupt, %d missing, %d unexpected", len(report.Corrupt), len(report.Missing), len(report.Unexpected))
+	}
+	if format == outputJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+		return mismatch
+	}
+	for _, p := range report.Corrupt {
+		fmt.Printf("corrupt     %s\n", p)
+	}
+	for _, p := range report.Missing {
+		fmt.Printf("missing     %s\n", p)
+	}
+	for _, p := range report.Unexpected {
+		fmt.Printf("unexpected  %s\n", p)
+	}
+	if mismatch != nil {
+		return mismatch
+	}
+	fmt.Printf("ok: %d files match the manifest\n", report.Checked)
+	return nil
+}
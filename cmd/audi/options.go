@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/setup"
+	"audi/pkg/chunker"
+)
+
+// chunkFlags are the processing flags shared by chunk and batch: how to cut, shape, and
+// transcribe the audio, independent of which file it comes from.
+type chunkFlags struct {
+	duration       *string
+	count          *int
+	transcribe     *bool
+	skipSilence    *bool
+	words          *bool
+	transcriptOnly *bool
+	escalateBelow  *float64
+	escalateArgs   *string
+	whisperArgs    *string
+	sets           *string
+	minLoudness    *float64
+	minChunk       *float64
+	maxClipping    *float64
+	noBase64       *bool
+	remainder      *string
+	fade           *time.Duration
+	filters        *string
+	track          *string
+	merged         *string
+	video          *bool
+	subtitles      *string
+	summarize      *bool
+	keywords       *bool
+	sentiment      *bool
+	chapters       *bool
+	redact         *string
+	pii            *bool
+	pad            *time.Duration
+	ffmpegTimeout  *time.Duration
+	whisperTimeout *time.Duration
+	nice           *int
+	cpus           *string
+	cpuOnly        *bool
+	cacheDir       *string
+	configPath     *string
+}
+
+// addChunkFlags registers the processing flags on fs.
+func addChunkFlags(fs *flag.FlagSet) *chunkFlags {
+	return &chunkFlags{
+		duration:       fs.String("duration", "300", "chunk length (seconds or Go duration such as 5m)"),
+		count:          fs.Int("count", 0, "split into exactly this many equal chunks (at most 10000) instead of by --duration"),
+		transcribe:     fs.Bool("transcribe", false, "transcribe each chunk via WHISPER_BIN"),
+		skipSilence:    fs.Bool("skip-silence", false, "detect silent chunks and leave them out of transcription"),
+		words:          fs.Bool("words", false, "with --transcribe, store word-level timings as transcripts/<chunk>.words.json"),
+		transcriptOnly: fs.Bool("transcript-only", false, "with --transcribe, cut chunks into a temporary directory and keep only transcripts and timings"),
+		escalateBelow:  fs.Float64("escalate-below", 0, "re-transcribe chunks whose confidence (0-1) is below this with --escalate-args (overrides whisper.escalation in --config)"),
+		escalateArgs:   fs.String("escalate-args", "", "whisper arguments appended for low-confidence chunks, e.g. \"-m ggml-large-v3.bin\""),
+		whisperArgs:    fs.String("whisper-args", "", "extra whisper arguments for this job from the per-job allowlist, e.g. \"-l de -bs 5\" (stored in job.json)"),
+		sets:           fs.String("transcript-sets", "", "comma-separated extra transcript sets from whisper.sets in the config (or the server's)"),
+		minLoudness:    fs.Float64("min-loudness-db", 0, "skip transcribing chunks quieter than this RMS level in dBFS, e.g. -45 (overrides quality_gate in --config)"),
+		minChunk:       fs.Float64("min-chunk-seconds", 0, "skip transcribing chunks shorter than this (overrides quality_gate in --config)"),
+		maxClipping:    fs.Float64("max-clipping-percent", 0, "skip transcribing chunks with more clipped samples than this percentage (overrides quality_gate in --config)"),
+		noBase64:       fs.Bool("no-base64", false, "disable generation of base64 dumps"),
+		remainder:      fs.String("remainder", "keep", "final short chunk handling: keep, merge, or pad"),
+		fade:           fs.Duration("fade", 0, "fade-in/out length applied to each chunk (e.g. 50ms)"),
+		filters:        fs.String("filters", "none", "audio filter preset: none, highpass, denoise, normalize, or voice"),
+		track:          fs.String("track", "", "audio track to chunk: a number from 1, mix, or separate (default first track)"),
+		merged:         fs.String("merged", "", "also write the full audio as one loudness-normalised listening file: mp3 or opus"),
+		video:          fs.Bool("video", false, "also copy the video into clips under video/, cut at the keyframes nearest the chunk boundaries"),
+		subtitles:      fs.String("subtitles", "", "with --transcribe, also write subtitles for the input: srt for transcripts/subtitles.srt, mp4 to add video/subtitled.mp4 with them as a soft track"),
+		summarize:      fs.Bool("summarize", false, "with --transcribe, also ask the LLM from summarization in --config (or the server's) for a summary, chapters, and action items"),
+		keywords:       fs.Bool("keywords", false, "with --transcribe, store each chunk's keywords and named entities, found locally or by the keyword service in --config (or the server's)"),
+		sentiment:      fs.Bool("sentiment", false, "with --transcribe, store each chunk's sentiment and emotions, rated locally or by the sentiment service in --config (or the server's)"),
+		chapters:       fs.Bool("chapters", false, "with --transcribe, write transcripts/chapters.txt (YouTube description lines) and transcripts/chapters.ffmeta, from the summary's chapters or detected from topic changes and pauses"),
+		redact:         fs.String("redact", "", "with --transcribe, also write copies with the words and patterns of redaction in --config (or the server's) removed: text for transcripts, audio for bleeped chunk audio as well (needs --words)"),
+		pii:            fs.Bool("pii", false, "with --transcribe, flag emails, phone numbers, and card numbers in each chunk's transcript and write a masked copy beside it"),
+		pad:            fs.Duration("pad", 0, "silence added before and after each chunk (e.g. 250ms)"),
+		ffmpegTimeout:  fs.Duration("ffmpeg-timeout", 0, "stop any ffmpeg run taking longer than this, e.g. 30m (overrides commands.ffmpeg_timeout in --config)"),
+		whisperTimeout: fs.Duration("whisper-timeout", 0, "fail a chunk whose whisper run takes longer than this, e.g. 10m (overrides commands.whisper_timeout in --config)"),
+		nice:           fs.Int("nice", 0, "run ffmpeg and whisper at this niceness, 1-19 (Linux, macOS, and the BSDs; overrides commands.nice in --config)"),
+		cpus:           fs.String("cpus", "", "pin ffmpeg and whisper to these CPUs, e.g. 0-3,6 (Linux; overrides commands.cpus in --config)"),
+		cpuOnly:        fs.Bool("cpu-only", false, "run whisper and video decoding on the CPU even when acceleration in --config enables a GPU"),
+		cacheDir:       fs.String("cache-dir", "", "reuse transcripts stored in this directory for identical chunks"),
+		configPath:     fs.String("config", "", "path to a YAML config file for ffmpeg/whisper settings"),
+	}
+}
+
+// options validates the flags that need no config file and returns the options, the
+// filter preset, and the requested transcript set names. That is all an upload needs;
+// local runs complete the options with processor.
+func (f *chunkFlags) options() (chunker.Options, chunker.FilterPreset, []string, error) {
+	if *f.transcriptOnly && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--transcript-only requires --transcribe")
+	}
+	seconds, err := parseDurationSeconds(*f.duration)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	if *f.count < 0 || *f.count > chunker.MaxChunkCount {
+		return chunker.Options{}, "", nil, fmt.Errorf("--count must be between 1 and %d", chunker.MaxChunkCount)
+	}
+	remainder, err := chunker.ParseRemainderPolicy(*f.remainder)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	filterPreset, err := chunker.ParseFilterPreset(*f.filters)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	trackMode, audioTrack, err := chunker.ParseTrackSelection(*f.track)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	mergedFormat, err := chunker.ParseMergedFormat(*f.merged)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	subtitles, err := chunker.ParseSubtitleMode(*f.subtitles)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	if subtitles != chunker.SubtitlesNone && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--subtitles requires --transcribe")
+	}
+	if *f.summarize && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--summarize requires --transcribe")
+	}
+	if *f.keywords && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--keywords requires --transcribe")
+	}
+	if *f.sentiment && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--sentiment requires --transcribe")
+	}
+	if *f.chapters && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--chapters requires --transcribe")
+	}
+	if *f.pii && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--pii requires --transcribe")
+	}
+	redact, err := chunker.ParseRedactionMode(*f.redact)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	switch {
+	case redact != chunker.RedactNone && !*f.transcribe:
+		return chunker.Options{}, "", nil, errors.New("--redact requires --transcribe")
+	case redact == chunker.RedactAudio && !*f.words:
+		return chunker.Options{}, "", nil, errors.New("--redact=audio requires --words")
+	case redact == chunker.RedactAudio && *f.transcriptOnly:
+		return chunker.Options{}, "", nil, errors.New("--redact=audio needs the chunk audio, which --transcript-only drops")
+	}
+	whisperArgs, err := chunker.ParseWhisperArgs(*f.whisperArgs)
+	if err != nil {
+		return chunker.Options{}, "", nil, err
+	}
+	if len(whisperArgs) > 0 && !*f.transcribe {
+		return chunker.Options{}, "", nil, errors.New("--whisper-args requires --transcribe")
+	}
+
+	var setNames []string
+	if *f.sets != "" {
+		setNames = strings.Split(*f.sets, ",")
+	}
+	return chunker.Options{
+		ChunkDurationSeconds: seconds,
+		ChunkCount:           *f.count,
+		MakeBase64:           !*f.noBase64,
+		Transcribe:           *f.transcribe,
+		TranscriptOnly:       *f.transcriptOnly,
+		WordTimestamps:       *f.words,
+		SkipSilence:          *f.skipSilence,
+		Remainder:            remainder,
+		FadeMillis:           int(f.fade.Milliseconds()),
+		PaddingMillis:        int(f.pad.Milliseconds()),
+		AudioFilters:         filterPreset.Chain(),
+		TrackMode:            trackMode,
+		AudioTrack:           audioTrack,
+		MergedFormat:         mergedFormat,
+		VideoClips:           *f.video,
+		Subtitles:            subtitles,
+		Summarize:            *f.summarize,
+		ExtractKeywords:      *f.keywords,
+		AnalyzeSentiment:     *f.sentiment,
+		DetectChapters:       *f.chapters,
+		Redact:               redact,
+		DetectPII:            *f.pii,
+		WhisperArgs:          whisperArgs,
+		CPUOnly:              *f.cpuOnly,
+	}, filterPreset, setNames, nil
+}
+
+// metadataFlag collects repeated --meta key=value flags.
+type metadataFlag []string
+
+func (m *metadataFlag) String() string { return strings.Join(*m, ",") }
+
+func (m *metadataFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// addMetadataFlag registers --meta on fs.
+func addMetadataFlag(fs *flag.FlagSet) *metadataFlag {
+	var m metadataFlag
+	fs.Var(&m, "meta", "key=value metadata stored on the job and copied into its manifest, e.g. caseID=4711 (repeatable)")
+	return &m
+}
+
+// metadata parses the collected flags.
+func (m *metadataFlag) metadata() (map[string]string, error) {
+	return chunker.ParseMetadataPairs(*m)
+}
+
+// processor loads the config file and builds the processor for a local run, adding the
+// quality gate, escalation policy, and transcript sets to opts. Flags set on fs
+// override the config. A transcribing run starts the configured whisper server, which
+// the caller stops with closeProcessor.
+func (f *chunkFlags) processor(fs *flag.FlagSet, opts *chunker.Options, setNames []string) (*chunker.Processor, error) {
+	cfg, err := config.Load(*f.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	gate := chunker.QualityGate{
+		MinLoudnessDB:      cfg.QualityGate.MinLoudnessDB,
+		MinDurationSeconds: cfg.QualityGate.MinChunkSeconds,
+		MaxClippingPercent: cfg.QualityGate.MaxClippingPercent,
+	}
+	escalation := chunker.EscalationPolicy{
+		MinConfidence: cfg.Whisper.Escalation.MinConfidence,
+		Args:          cfg.Whisper.Escalation.Args,
+	}
+	priority := setup.CommandPriority(cfg.Commands)
+	opts.FFmpegTimeout = cfg.Commands.FFmpegTimeout
+	opts.WhisperTimeoutPerChunk = cfg.Commands.WhisperTimeout
+	var cpusErr error
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "min-loudness-db":
+			gate.MinLoudnessDB = *f.minLoudness
+		case "min-chunk-seconds":
+			gate.MinDurationSeconds = *f.minChunk
+		case "max-clipping-percent":
+			gate.MaxClippingPercent = *f.maxClipping
+		case "escalate-below":
+			escalation.MinConfidence = *f.escalateBelow
+		case "escalate-args":
+			escalation.Args = strings.Fields(*f.escalateArgs)
+		case "ffmpeg-timeout":
+			opts.FFmpegTimeout = *f.ffmpegTimeout
+		case "whisper-timeout":
+			opts.WhisperTimeoutPerChunk = *f.whisperTimeout
+		case "nice":
+			priority.Nice = *f.nice
+		case "cpus":
+			priority.CPUs, cpusErr = chunker.ParseCPUList(*f.cpus)
+		}
+	})
+	if err := gate.Validate(); err != nil {
+		return nil, err
+	}
+	if cpusErr != nil {
+		return nil, fmt.Errorf("--cpus: %w", cpusErr)
+	}
+	if err := priority.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.FFmpegTimeout < 0 || opts.WhisperTimeoutPerChunk < 0 {
+		return nil, errors.New("--ffmpeg-timeout and --whisper-timeout must not be negative")
+	}
+	if err := escalation.Validate(); err != nil {
+		return nil, err
+	}
+
+	configured := make([]chunker.TranscriptSet, 0, len(cfg.Whisper.Sets))
+	for _, set := range cfg.Whisper.Sets {
+		configured = append(configured, chunker.TranscriptSet{Name: set.Name, Args: set.Args})
+	}
+	if err := chunker.ValidateTranscriptSets(configured); err != nil {
+		return nil, fmt.Errorf("whisper.sets: %w", err)
+	}
+	sets, err := chunker.SelectTranscriptSets(configured, setNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(sets) > 0 && !opts.Transcribe {
+		return nil, errors.New("--transcript-sets requires --transcribe")
+	}
+
+	opts.QualityGate = gate
+	opts.Escalation = escalation
+	opts.TranscriptSets = sets
+	if opts.Transcribe {
+		if err := fitChunkLimit(cfg.Whisper, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	tools, err := setup.CheckTools(cfg, stderrf)
+	if err != nil {
+		return nil, err
+	}
+	proc := chunker.New(
+		chunker.WithFFmpeg(cfg.FFmpegBin),
+		chunker.WithWhisper(cfg.Whisper.Bin, cfg.Whisper.Args...),
+		chunker.WithPools(cfg.EncodeWorkers, cfg.TranscribeWorkers),
+		chunker.WithCommandPriority(priority),
+		chunker.WithAcceleration(setup.Acceleration(cfg.FFmpegBin, cfg.Acceleration, stderrf)),
+		chunker.WithTools(tools...),
+	)
+	opts.TranscribeConcurrency = cfg.ChunkConcurrency()
+	if *f.cacheDir != "" {
+		proc.Cache = chunker.NewDirCache(*f.cacheDir, int64(cfg.Storage.TranscriptCacheMax))
+	}
+	if opts.Transcribe && proc.WhisperBin == "" {
+		return nil, errors.New("--transcribe requires WHISPER_BIN to be set")
+	}
+	if opts.Summarize {
+		if proc.Summarizer = setup.Summarizer(cfg.Summarization); proc.Summarizer == nil {
+			return nil, errors.New("--summarize requires summarization.url in --config or AUDI_SUMMARY_URL")
+		}
+	}
+	if opts.ExtractKeywords {
+		proc.Keywords = setup.KeywordExtractor(cfg.Keywords)
+	}
+	if opts.AnalyzeSentiment {
+		proc.Sentiment = setup.SentimentAnalyzer(cfg.Sentiment)
+	}
+	if opts.Redact != chunker.RedactNone {
+		if proc.Redactor, err = setup.Redactor(cfg.Redaction); err != nil {
+			return nil, err
+		}
+		if proc.Redactor == nil {
+			return nil, errors.New("--redact requires redaction.words or redaction.patterns in --config")
+		}
+	}
+	if opts.Transcribe {
+		proc.Resident = setup.ResidentWhisper(cfg.Whisper.Server, nil)
+	}
+	if proc.Resident != nil {
+		if err := proc.Resident.Start(); err != nil {
+			return nil, err
+		}
+		if err := proc.Resident.WaitReady(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "%v; transcribing with %s until it is\n", err, proc.WhisperBin)
+		}
+	}
+	return proc, nil
+}
+
+// fitChunkLimit applies whisper's chunk limit and those of the selected transcript sets
+// to opts, shortening a chunk duration that would exceed it.
+func fitChunkLimit(cfg config.WhisperConfig, opts *chunker.Options) error {
+	limitOf := func(c config.ChunkLimitConfig) chunker.ChunkLimit {
+		return chunker.ChunkLimit{MaxSeconds: c.MaxChunkDuration.Seconds(), MaxBytes: int64(c.MaxChunkSize)}
+	}
+	limit := limitOf(cfg.ChunkLimit)
+	for _, set := range cfg.Sets {
+		for _, picked := range opts.TranscriptSets {
+			if picked.Name == set.Name {
+				limit = limit.Tighter(limitOf(set.ChunkLimit))
+			}
+		}
+	}
+	opts.MaxChunkSeconds = limit.Seconds()
+	if opts.ChunkCount > 0 {
+		return nil
+	}
+	fit, err := chunker.FitChunkDuration(opts.ChunkDurationSeconds, opts.PaddingMillis, opts.MaxChunkSeconds)
+	if err != nil {
+		return err
+	}
+	if fit < opts.ChunkDurationSeconds {
+		fmt.Fprintf(os.Stderr, "chunk duration lowered from %ds to %ds to fit whisper's chunk limit\n", opts.ChunkDurationSeconds, fit)
+		opts.ChunkDurationSeconds = fit
+	}
+	return nil
+}
+
+// closeProcessor stops the whisper server processor started, if any.
+func closeProcessor(proc *chunker.Processor) {
+	if proc.Resident != nil {
+		proc.Resident.Close()
+	}
+}
+
+// stderrf prints a note about the run, such as acceleration falling back to the CPU, on
+// stderr.
+func stderrf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
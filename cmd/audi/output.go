@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// outputFormat selects how a command prints its result: aligned text for people or
+// JSON for scripts. Progress and warnings always go to stderr, so stdout stays
+// parseable in JSON mode.
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+)
+
+var outputFormats = []string{string(outputTable), string(outputJSON)}
+
+// flagOutput receives flag usage and errors; completion silences it while it inspects
+// a command's flags.
+var flagOutput io.Writer = os.Stderr
+
+// lastFlagSet is the flag set most recently created by newFlagSet, which lets
+// completion list a command's flags without declaring them twice.
+var lastFlagSet *flag.FlagSet
+
+// newFlagSet creates the flag set of a subcommand.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(flagOutput)
+	lastFlagSet = fs
+	return fs
+}
+
+// parseInterspersed parses args allowing flags after positional arguments, as in
+// "audi batch ./recordings --parallel 4", and returns the positional ones.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// outputFlag registers the --output flag shared by every command with a result.
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", string(outputTable), "result format: table or json")
+}
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch format := outputFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case outputTable, outputJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want table or json)", value)
+}
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printRawJSON re-indents JSON received from a server and writes it to stdout.
+func printRawJSON(data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(os.Stdout)
+	return err
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+const remotePollInterval = 2 * time.Second
+
+// Artefact kinds --download accepts. The job metadata is always saved as job.json.
+var remoteArtefacts = []string{"audio", "transcripts", "transcript", "base64", "merged", "video", "subtitles", "chapters", "redacted", "masked"}
+
+// remoteClient talks to a running server. With a token, every request carries it as a
+// Bearer token, which the server accepts in place of basic auth credentials.
+type remoteClient struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func newRemoteClient(serverURL, token string) *remoteClient {
+	return &remoteClient{
+		base:  strings.TrimRight(serverURL, "/"),
+		token: token,
+		client: &http.Client{
+			// The upload answers with a redirect to the job page; its Location is the result.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (c *remoteClient) newRequest(ctx context.Context, method, route string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+route, body)
+	if err != nil {
+		return nil, err
+	}
+	// Errors then come back as JSON instead of an HTML page.
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// get fetches route and fails on any status but 200.
+func (c *remoteClient) get(ctx context.Context, route string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, route, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// responseError turns an error response into a Go error, preferring the server's JSON message.
+func responseError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apiErr struct {
+		Error     string `json:"error"`
+		RequestID string `json:"requestId"`
+	}
+	if json.Unmarshal(msg, &apiErr) == nil && apiErr.Error != "" {
+		return fmt.Errorf("%s: %s (request %s)", resp.Status, apiErr.Error, apiErr.RequestID)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// upload posts the file to a server's /upload endpoint and returns the job ID. With a
+// preset, only the flags named in explicit are sent, so the preset supplies the rest.
+func (c *remoteClient) upload(ctx context.Context, inputPath, title string, metadata map[string]string, transcriptSets []string, opts chunker.Options, filters chunker.FilterPreset, force bool, preset string, explicit map[string]bool) (string, error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// writeForm writes the whole form, the file last, so the file is streamed from disk
+	// rather than held in memory.
+	writeForm := func(writer *multipart.Writer, content io.Reader) error {
+		send := func(flag string) bool { return preset == "" || explicit[flag] }
+		if preset != "" {
+			_ = writer.WriteField("preset", preset)
+		}
+		if title != "" {
+			_ = writer.WriteField("title", title)
+		}
+		for _, key := range slices.Sorted(maps.Keys(metadata)) {
+			_ = writer.WriteField("metadata", key+"="+metadata[key])
+		}
+		if send("duration") {
+			_ = writer.WriteField("chunk_value", strconv.Itoa(opts.ChunkDurationSeconds))
+			_ = writer.WriteField("chunk_unit", "seconds")
+		}
+		if opts.ChunkCount > 0 {
+			_ = writer.WriteField("chunk_mode", "count")
+			_ = writer.WriteField("chunk_count", strconv.Itoa(opts.ChunkCount))
+		}
+		if send("remainder") {
+			_ = writer.WriteField("remainder", string(opts.Remainder))
+		}
+		if send("fade") {
+			_ = writer.WriteField("fade_ms", strconv.Itoa(opts.FadeMillis))
+		}
+		if send("pad") {
+			_ = writer.WriteField("pad_ms", strconv.Itoa(opts.PaddingMillis))
+		}
+		if send("filters") {
+			_ = writer.WriteField("filters", string(filters))
+		}
+		switch {
+		case opts.TrackMode == chunker.TrackMix || opts.TrackMode == chunker.TrackSeparate:
+			_ = writer.WriteField("audio_track", string(opts.TrackMode))
+		case opts.AudioTrack > 0:
+			_ = writer.WriteField("audio_track", strconv.Itoa(opts.AudioTrack+1))
+		}
+		if opts.Transcribe {
+			_ = writer.WriteField("transcribe", "on")
+		}
+		if opts.SkipSilence {
+			_ = writer.WriteField("skip_silence", "on")
+		}
+		if opts.TranscriptOnly {
+			_ = writer.WriteField("transcript_only", "on")
+		}
+		if opts.WordTimestamps {
+			_ = writer.WriteField("word_timestamps", "on")
+		}
+		if opts.MergedFormat != chunker.MergedNone {
+			_ = writer.WriteField("merged_audio", string(opts.MergedFormat))
+		}
+		if opts.VideoClips {
+			_ = writer.WriteField("video_clips", "on")
+		}
+		if opts.Subtitles != chunker.SubtitlesNone {
+			_ = writer.WriteField("subtitles", string(opts.Subtitles))
+		}
+		if opts.Summarize {
+			_ = writer.WriteField("summarize", "on")
+		}
+		if opts.ExtractKeywords {
+			_ = writer.WriteField("keywords", "on")
+		}
+		if opts.AnalyzeSentiment {
+			_ = writer.WriteField("sentiment", "on")
+		}
+		if opts.DetectChapters {
+			_ = writer.WriteField("chapters", "on")
+		}
+		if opts.Redact != chunker.RedactNone {
+			_ = writer.WriteField("redact", string(opts.Redact))
+		}
+		if opts.DetectPII {
+			_ = writer.WriteField("pii", "on")
+		}
+		if opts.CPUOnly {
+			_ = writer.WriteField("cpu_only", "on")
+		}
+		if len(opts.WhisperArgs) > 0 {
+			_ = writer.WriteField("whisper_args", strings.Join(opts.WhisperArgs, " "))
+		}
+		for _, name := range transcriptSets {
+			_ = writer.WriteField("transcript_set", strings.TrimSpace(name))
+		}
+		if force {
+			_ = writer.WriteField("force", "on")
+		}
+		part, err := writer.CreateFormFile("video", filepath.Base(inputPath))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, content); err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+		return writer.Close()
+	}
+
+	// Writing the form without the file first gives its length, so the request can
+	// carry a Content-Length the server checks the quota against.
+	var length countingWriter
+	sizer := multipart.NewWriter(&length)
+	if err := writeForm(sizer, strings.NewReader("")); err != nil {
+		return "", err
+	}
+
+	body, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	if err := writer.SetBoundary(sizer.Boundary()); err != nil {
+		return "", err
+	}
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		pipe.CloseWithError(writeForm(writer, file))
+	}()
+	defer func() {
+		body.Close()
+		<-written
+	}()
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/upload", body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if info.Mode().IsRegular() {
+		req.ContentLength = int64(length) + info.Size()
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("uploading: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSeeOther {
+		return "", fmt.Errorf("upload failed: %w", responseError(resp))
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("upload answered with a bad location: %w", err)
+	}
+	jobID := path.Base(location.Path)
+	if dup := resp.Header.Get("X-Duplicate-Of"); dup != "" {
+		fmt.Fprintf(os.Stderr, "identical upload already processed as job %s; pass --force to process again\n", dup)
+		jobID = dup
+	}
+	return jobID, nil
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// chunkRemote uploads the file and, when asked, waits for the job and downloads its
+// artefacts. Downloading implies waiting. It prints the job URL, or in JSON mode the
+// job once it finished, or its ID and URL when not waiting.
+func chunkRemote(ctx context.Context, c *remoteClient, inputPath, title string, metadata map[string]string, transcriptSets []string, opts chunker.Options, filters chunker.FilterPreset, force bool, preset string, explicit map[string]bool, wait bool, downloads map[string]bool, outDir string, format outputFormat) error {
+	jobID, err := c.upload(ctx, inputPath, title, metadata, transcriptSets, opts, filters, force, preset, explicit)
+	if err != nil {
+		return err
+	}
+	jobURL := c.base + "/jobs/" + jobID
+	if format == outputTable {
+		fmt.Println(jobURL)
+	}
+	if !wait && len(downloads) == 0 {
+		if format == outputJSON {
+			return printJSON(map[string]string{"id": jobID, "url": jobURL})
+		}
+		return nil
+	}
+	job, raw, err := c.wait(ctx, jobID)
+	if format == outputJSON && raw != nil {
+		if err := printRawJSON(raw); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(downloads) == 0 {
+		return nil
+	}
+	return c.download(ctx, job, raw, downloads, outDir)
+}
+
+// jobs lists every job on the server, newest first.
+func (c *remoteClient) jobs(ctx context.Context) ([]*chunker.Job, error) {
+	resp, err := c.get(ctx, "/api/jobs")
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+	defer resp.Body.Close()
+	var jobs []*chunker.Job
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("decoding job list: %w", err)
+	}
+	return jobs, nil
+}
+
+// job fetches a job's metadata along with the raw JSON, which is saved unchanged.
+func (c *remoteClient) job(ctx context.Context, jobID string) (*chunker.Job, []byte, error) {
+	resp, err := c.get(ctx, "/api/v1/jobs/"+url.PathEscape(jobID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading job %s: %w", jobID, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading job %s: %w", jobID, err)
+	}
+	var job chunker.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, nil, fmt.Errorf("decoding job %s: %w", jobID, err)
+	}
+	return &job, data, nil
+}
+
+// wait polls the job until it finishes, reporting progress on stderr, and fails when the
+// job does.
+func (c *remoteClient) wait(ctx context.Context, jobID string) (*chunker.Job, []byte, error) {
+	var lastLine string
+	for {
+		job, raw, err := c.job(ctx, jobID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if job.IsDone() {
+			if job.Status == chunker.JobStatusFailed {
+				return job, raw, fmt.Errorf("job %s failed: %s", jobID, job.ErrorMessage)
+			}
+			return job, raw, nil
+		}
+		line := string(job.Status)
+		if job.Progress != nil {
+			p := job.Progress
+			line = fmt.Sprintf("%s: %s, %.0f%%", job.Status, p.Stage, p.Percent)
+			if p.Speed > 0 {
+				line += fmt.Sprintf(" at %.1fx", p.Speed)
+			}
+			if p.RemainingSeconds > 0 {
+				line += fmt.Sprintf(", about %s left", time.Duration(p.RemainingSeconds*float64(time.Second)).Round(time.Second))
+			}
+		}
+		if line != lastLine {
+			fmt.Fprintln(os.Stderr, line)
+			lastLine = line
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(remotePollInterval):
+		}
+	}
+}
+
+// parseArtefacts validates the --download list; "all" selects every kind.
+func parseArtefacts(value string) (map[string]bool, error) {
+	kinds := make(map[string]bool)
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		switch {
+		case kind == "":
+		case kind == "all":
+			for _, k := range remoteArtefacts {
+				kinds[k] = true
+			}
+		case slices.Contains(remoteArtefacts, kind):
+			kinds[kind] = true
+		default:
+			return nil, fmt.Errorf("unknown artefact %q (want %s, or all)", kind, strings.Join(remoteArtefacts, ", "))
+		}
+	}
+	return kinds, nil
+}
+
+// download saves the finished job's chosen artefacts into outDir, laid out like a job
+// directory so the copy reads like local CLI output.
+func (c *remoteClient) download(ctx context.Context, job *chunker.Job, raw []byte, kinds map[string]bool, outDir string) error {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "job.json"), raw, 0o644); err != nil {
+		return fmt.Errorf("writing job.json: %w", err)
+	}
+
+	// Purged jobs only have their transcripts left.
+	hasAudio := job.ArtefactsPurgedAt == nil
+	files := make(map[string]string)
+	fileRoute := func(rel string) string {
+		return "/files/jobs/" + url.PathEscape(job.ID) + "/" + rel
+	}
+	for _, chunk := range job.Chunks {
+		if kinds["audio"] && hasAudio && chunk.AudioFile != "" {
+			files[chunk.AudioFile] = fileRoute(chunk.AudioFile)
+		}
+		if kinds["base64"] && hasAudio && chunk.AudioFile != "" {
+			name := strings.TrimSuffix(path.Base(chunk.AudioFile), path.Ext(chunk.AudioFile)) + ".b64.txt"
+			files["base64/"+name] = fmt.Sprintf("/jobs/%s/chunks/%d/base64", url.PathEscape(job.ID), chunk.Index)
+		}
+		if kinds["video"] && hasAudio && chunk.VideoFile != "" {
+			files[chunk.VideoFile] = fileRoute(chunk.VideoFile)
+		}
+		if kinds["redacted"] {
+			if chunk.RedactedTranscriptFile != "" {
+				files[chunk.RedactedTranscriptFile] = fileRoute(chunk.RedactedTranscriptFile)
+			}
+			if hasAudio && chunk.RedactedAudioFile != "" {
+				files[chunk.RedactedAudioFile] = fileRoute(chunk.RedactedAudioFile)
+			}
+		}
+		if kinds["masked"] && chunk.MaskedTranscriptFile != "" {
+			files[chunk.MaskedTranscriptFile] = fileRoute(chunk.MaskedTranscriptFile)
+		}
+		if kinds["transcripts"] {
+			for _, rel := range []string{chunk.TranscriptFile, chunk.WordsFile} {
+				if rel != "" {
+					files[rel] = fileRoute(rel)
+				}
+			}
+			for _, t := range chunk.Transcripts {
+				if t.File != "" {
+					files[t.File] = fileRoute(t.File)
+				}
+			}
+		}
+	}
+	if kinds["merged"] && hasAudio && job.MergedAudioFile != "" {
+		files[job.MergedAudioFile] = fileRoute(job.MergedAudioFile)
+	}
+	if kinds["subtitles"] {
+		if job.SubtitlesFile != "" {
+			files[job.SubtitlesFile] = fileRoute(job.SubtitlesFile)
+		}
+		if hasAudio && job.SubtitledVideoFile != "" {
+			files[job.SubtitledVideoFile] = fileRoute(job.SubtitledVideoFile)
+		}
+	}
+	if kinds["chapters"] {
+		for _, rel := range []string{job.ChaptersFile, job.ChapterMetadataFile} {
+			if rel != "" {
+				files[rel] = fileRoute(rel)
+			}
+		}
+	}
+	if kinds["transcript"] && job.TranscriptionRequested {
+		files["transcript.txt"] = "/jobs/" + url.PathEscape(job.ID) + "/transcript.txt"
+	}
+
+	for rel, route := range files {
+		if err := c.save(ctx, route, filepath.Join(outDir, filepath.FromSlash(rel))); err != nil {
+			return fmt.Errorf("downloading %s: %w", rel, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d files saved to %s\n", len(files)+1, outDir)
+	return nil
+}
+
+// save streams route into dst through a temporary file, so an interrupted download never
+// leaves a truncated artefact behind.
+func (c *remoteClient) save(ctx context.Context, route, dst string) error {
+	resp, err := c.get(ctx, route)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".download-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
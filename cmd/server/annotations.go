@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// maxAnnotationBody caps PATCH bodies; tags and a note never need more.
+const maxAnnotationBody = 64 << 10
+
+// jobPatch is the body of PATCH /api/v1/jobs/{id}. Omitted fields are left untouched;
+// an empty list or string clears them.
+type jobPatch struct {
+	Title *string   `json:"title"`
+	Tags  *[]string `json:"tags"`
+	Note  *string   `json:"note"`
+}
+
+// normalize validates the patch in place so a bad value is rejected before the job is touched.
+func (p *jobPatch) normalize() error {
+	if p.Title != nil {
+		title, err := chunker.NormalizeTitle(*p.Title)
+		if err != nil {
+			return err
+		}
+		p.Title = &title
+	}
+	if p.Tags != nil {
+		tags, err := chunker.NormalizeTags(*p.Tags)
+		if err != nil {
+			return err
+		}
+		p.Tags = &tags
+	}
+	if p.Note != nil {
+		note, err := chunker.NormalizeNote(*p.Note)
+		if err != nil {
+			return err
+		}
+		p.Note = &note
+	}
+	return nil
+}
+
+// errSaveJob marks a failure to persist the job, as opposed to a rejected edit.
+var errSaveJob = errors.New("saving job")
+
+func init() {
+	// Tag pills link to the job list filtered by the tag.
+	registerTemplateModule(templateModule{name: "tags", partials: []string{"tags"}})
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}", ID: "getJob", Summary: "One job's metadata.", Response: chunker.Job{}},
+		apiOperation{Method: http.MethodPatch, Path: "/api/v1/jobs/{id}", ID: "updateJob", Summary: "Edit a job's title, tags, and note; omitted fields are left alone.", Request: jobPatch{}, Response: chunker.Job{}},
+	)
+}
+
+// handleAPIJob serves /api/v1/jobs/{id}: GET returns the job and PATCH edits its title,
+// tags, and note. /api/v1/jobs/{id}/chunks is handed to handleAPIJobChunks,
+// /api/v1/jobs/{id}/chunks/{n} to handleAPIChunk (GET) or handleChunkPush (PUT),
+// /api/v1/jobs/{id}/complete to handleAPIJobComplete,
+// /api/v1/jobs/{id}/revisions to handleAPIJobRevisions, /api/v1/jobs/{id}/commands
+// to handleAPIJobCommands, and /api/v1/jobs/{id}/export to handleAPIJobExport.
+func (s *server) handleAPIJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/jobs/"), "/")
+	jobID := parts[0]
+	if !storage.ValidJobID(jobID) {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", nil)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "chunks" {
+		s.handleAPIJobChunks(w, r, jobID)
+		return
+	}
+	if len(parts) == 3 && parts[1] == "chunks" {
+		if r.Method == http.MethodPut {
+			s.handleChunkPush(w, r, jobID, parts[2])
+			return
+		}
+		s.handleAPIChunk(w, r, jobID, parts[2])
+		return
+	}
+	if len(parts) == 2 && parts[1] == "complete" {
+		s.handleAPIJobComplete(w, r, jobID)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "chunks:retryFailed" {
+		s.handleAPIRetryFailedChunks(w, r, jobID)
+		return
+	}
+	if len(parts) >= 2 && parts[1] == "revisions" {
+		s.handleAPIJobRevisions(w, r, jobID, parts[2:])
+		return
+	}
+	if len(parts) == 2 && parts[1] == "commands" {
+		s.handleAPIJobCommands(w, r, jobID)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "export" {
+		s.handleAPIJobExport(w, r, jobID)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "topics" {
+		s.handleAPIJobTopics(w, r, jobID)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "sentiment" {
+		s.handleAPIJobSentiment(w, r, jobID)
+		return
+	}
+	if len(parts) != 1 {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
+		if err != nil {
+			s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	case http.MethodPatch:
+		var patch jobPatch
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxAnnotationBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&patch); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON body: %v.", err), nil)
+			return
+		}
+		if err := patch.normalize(); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		job, err := s.updateAnnotations(jobID, patch)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		case err != nil:
+			s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		default:
+			writeJSON(w, http.StatusOK, job)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PATCH")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+	}
+}
+
+// handleJobAnnotations is the form counterpart of PATCH for the job page. Tags arrive
+// as one comma-separated field.
+func (s *server) handleJobAnnotations(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	back := "/jobs/" + jobID
+	tags, err := chunker.ParseTagList(r.FormValue("tags"))
+	title, note := r.FormValue("title"), r.FormValue("note")
+	patch := jobPatch{Title: &title, Tags: &tags, Note: &note}
+	if err == nil {
+		err = patch.normalize()
+	}
+	if err == nil {
+		_, err = s.updateAnnotations(jobID, patch)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+	case errors.Is(err, errSaveJob):
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+	case err != nil:
+		s.seeOther(w, r, back+"?error="+url.QueryEscape(err.Error()))
+	default:
+		s.seeOther(w, r, back+"?flash="+url.QueryEscape("Job details saved"))
+	}
+}
+
+// updateAnnotations applies an already normalised patch to the stored job and saves it.
+// It works while the job is processing too: the worker adopts the edit on its next save.
+func (s *server) updateAnnotations(jobID string, patch jobPatch) (*chunker.Job, error) {
+	job, err := s.updateJob(storage.JobDir(s.jobsDir, jobID), func(job *chunker.Job) error {
+		if patch.Title != nil {
+			job.Title = *patch.Title
+		}
+		if patch.Tags != nil {
+			job.Tags = *patch.Tags
+		}
+		if patch.Note != nil {
+			job.Note = *patch.Note
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", errSaveJob, err)
+	}
+	return job, err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// errImportCorrupt marks an imported job whose files do not match its manifest.
+var errImportCorrupt = errors.New("archived files do not match the manifest")
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/export", ID: "exportJob", Summary: "The whole job as a tar.gz archive.", ResponseType: "application/gzip"},
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/jobs:import", ID: "importJob", Summary: "Store an exported archive as a new job.",
+			RequestType: "application/gzip", Status: http.StatusCreated, Response: chunker.Job{}},
+	)
+}
+
+// handleAPIJobExport serves GET /api/v1/jobs/{id}/export: the whole job, job.json and
+// every artefact, as a tar.gz archive that POST /api/v1/jobs:import takes on another
+// server. Jobs still processing are refused, as their files are not final.
+func (s *server) handleAPIJobExport(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	if _, err := storage.LoadJob(jobDir); err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	if s.inFlight(jobID) {
+		s.renderError(w, r, http.StatusConflict, "The job is still processing.", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.tar.gz"`, jobID))
+	if err := storage.ExportJob(w, jobDir); err != nil {
+		// The status line is gone by now; the client sees a truncated archive.
+		log.Printf("job %s: export failed: %v", jobID, err)
+	}
+}
+
+// handleAPIJobImport serves POST /api/v1/jobs:import. The body is an archive from
+// handleAPIJobExport; the job is stored under a new ID of this server, with the old one
+// as importedFrom, and answered with 201. A completed job's files are checked against
+// its manifest before it is indexed and sealed here like a freshly processed one; a job
+// exported unfinished is imported as failed, ready to be retried.
+func (s *server) handleAPIJobImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkQuota(w, r) {
+		return
+	}
+	var limit int64
+	if s.quota > 0 {
+		if _, used, err := s.usage.Usage(); err == nil {
+			limit = max(s.quota-used, 1)
+		}
+	}
+
+	newID := func(job *chunker.Job) string {
+		return s.jobIDFunc(job.Source, job.Metadata)()
+	}
+	job, err := storage.ImportJob(r.Body, s.jobsDir, s.router, newID, limit)
+	switch {
+	case errors.Is(err, storage.ErrInvalidArchive):
+		s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("The archive could not be imported: %v.", err), nil)
+		return
+	case errors.Is(err, storage.ErrArchiveTooLarge):
+		s.renderError(w, r, http.StatusInsufficientStorage, fmt.Sprintf("Storage quota exceeded: the archive does not fit in %s.", formatBytes(s.quota)), nil)
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "The archive could not be imported.", err)
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, job.ID)
+	if err := s.adoptImportedJob(jobDir, job); err != nil {
+		if rmErr := storage.RemoveJob(jobDir); rmErr != nil {
+			log.Printf("job %s: failed to remove rejected import: %v", job.ID, rmErr)
+		}
+		if errors.Is(err, errImportCorrupt) {
+			s.renderError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("The archive could not be imported: %v.", err), nil)
+			return
+		}
+		s.renderError(w, r, http.StatusInternalServerError, "The archive could not be imported.", err)
+		return
+	}
+	log.Printf("job %s: imported from job %s", job.ID, job.ImportedFrom)
+	w.Header().Set("Location", s.link("/api/v1/jobs/"+job.ID))
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// adoptImportedJob finishes an unpacked job the way finishJob does a processed one and
+// saves it, which makes it appear in the job list.
+func (s *server) adoptImportedJob(jobDir string, job *chunker.Job) error {
+	if !job.IsDone() {
+		job.ErrorMessage = fmt.Sprintf("The job was still %s when it was exported; retry it to process it here.", job.Status)
+		job.Status = chunker.JobStatusFailed
+		job.EndStages(errors.New(job.ErrorMessage), time.Now())
+	}
+	if job.Status == chunker.JobStatusCompleted {
+		report, err := storage.VerifyJob(jobDir)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return err
+		case len(report.Corrupt) > 0 || len(report.Missing) > 0:
+			return fmt.Errorf("%w: %d corrupt, %d missing", errImportCorrupt, len(report.Corrupt), len(report.Missing))
+		}
+		s.sealArtefacts(jobDir, job)
+	} else {
+		s.writeReport(jobDir, job)
+	}
+	s.remeasure(jobDir, job)
+	return s.saveJob(jobDir, job, storage.ChangeCreated)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"audi/internal/storage"
+)
+
+// assetTypes maps the extensions of job files to the Content-Type they are served with,
+// whatever the host's MIME tables say. Base64 chunks end in .b64.txt and are text.
+var assetTypes = map[string]string{
+	".wav":    "audio/wav",
+	".mp3":    "audio/mpeg",
+	".m4a":    "audio/mp4",
+	".aac":    "audio/aac",
+	".flac":   "audio/flac",
+	".ogg":    "audio/ogg",
+	".opus":   "audio/ogg; codecs=opus",
+	".mp4":    "video/mp4",
+	".m4v":    "video/mp4",
+	".mov":    "video/quicktime",
+	".mkv":    "video/x-matroska",
+	".webm":   "video/webm",
+	".avi":    "video/x-msvideo",
+	".txt":    "text/plain; charset=utf-8",
+	".ffmeta": "text/plain; charset=utf-8",
+	".srt":    "application/x-subrip; charset=utf-8",
+	".vtt":    "text/vtt; charset=utf-8",
+	".json":   "application/json",
+	".csv":    "text/csv; charset=utf-8",
+	".png":    "image/png",
+	".jpg":    "image/jpeg",
+	".gz":     "application/gzip",
+}
+
+// assetCacheControl lets browsers keep job files but makes them revalidate on every use:
+// files keep their names when a job is re-chunked, so only the validator tells whether
+// a copy is current. Revalidation is a cheap 304 from ETag or Last-Modified.
+const assetCacheControl = "private, no-cache"
+
+// setAssetHeaders sets the Content-Type and Cache-Control of the job file rel and, for
+// chunk audio of a job at rest, a strong ETag from the chunk's checksum. With it set,
+// http.ServeContent answers If-None-Match with 304 and honours If-Range, so a player
+// seeking with range requests never mixes bytes of two versions of a chunk.
+func (s *server) setAssetHeaders(w http.ResponseWriter, jobID, rel string) {
+	h := w.Header()
+	if contentType, ok := assetTypes[strings.ToLower(path.Ext(rel))]; ok {
+		h.Set("Content-Type", contentType)
+	}
+	h.Set("Cache-Control", assetCacheControl)
+	if sum := s.chunkChecksum(jobID, rel); sum != "" {
+		h.Set("ETag", `"`+sum+`"`)
+	}
+}
+
+// chunkChecksum returns the recorded checksum of rel if it is a chunk's audio file.
+// Jobs being processed have none, as their chunks may be rewritten before job.json.
+func (s *server) chunkChecksum(jobID, rel string) string {
+	if !strings.HasPrefix(rel, "chunks/") || !storage.ValidJobID(jobID) || s.inFlight(jobID) {
+		return ""
+	}
+	job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
+	if err != nil {
+		return ""
+	}
+	for _, chunk := range job.Chunks {
+		if chunk.AudioFile == rel {
+			return chunk.Checksum
+		}
+	}
+	return ""
+}
+
+// assetHeaders sets the headers of setAssetHeaders for a handler serving the data
+// directory.
+func (s *server) assetHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/"), "/", 3)
+		if len(parts) == 3 && parts[0] == "jobs" {
+			s.setAssetHeaders(w, parts[1], parts[2])
+		}
+		next.ServeHTTP(w, r)
+	})
+}
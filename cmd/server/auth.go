@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"audi/internal/config"
+)
+
+// basicAuth rejects requests that do not carry the configured credentials. Every route
+// also accepts the password alone as a Bearer token: that is how OpenAI SDKs and no-code
+// platforms send an API key, and what `audi chunk --server --token` uses.
+func basicAuth(auth config.AuthConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Load balancers probe the health check without credentials.
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(auth.Password)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		user, pass, ok := r.BasicAuth()
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte(auth.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(auth.Password)) == 1
+		if !ok || !userOK || !passOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="audio-chunker"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/base64"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// handleChunkBase64 serves /jobs/{id}/chunks/{index}/base64, encoding the chunk audio
+// while it streams so no .b64.txt copy has to sit on disk. Add ?download=1 for an
+// attachment instead of inline text.
+func (s *server) handleChunkBase64(w http.ResponseWriter, r *http.Request, jobID string, parts []string) {
+	if len(parts) != 2 || parts[1] != "base64" {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.base64Enabled {
+		s.renderError(w, r, http.StatusNotFound, "Base64 dumps are disabled on this server.", nil)
+		return
+	}
+	_, _, chunk, ok := s.lookupChunkAudio(w, r, jobID, parts[0])
+	if !ok {
+		return
+	}
+
+	file, size, err := s.openJobFile(r.Context(), jobID, chunk.AudioFile)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "The chunk audio is missing.", err)
+		return
+	}
+	defer file.Close()
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "1" {
+		disposition = "attachment"
+	}
+	name := strings.TrimSuffix(filepath.Base(chunk.AudioFile), filepath.Ext(chunk.AudioFile)) + ".b64.txt"
+	w.Header().Set("Content-Type", "text/plain; charset=us-ascii")
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(base64.StdEncoding.EncodedLen(int(size))))
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(encoder, file); err != nil {
+		// Headers are gone; all we can do is log and cut the response short.
+		log.Printf("request %s: streaming base64 for job %s chunk %d: %v", requestID(r.Context()), jobID, chunk.Index, err)
+		return
+	}
+	if err := encoder.Close(); err != nil {
+		log.Printf("request %s: streaming base64 for job %s chunk %d: %v", requestID(r.Context()), jobID, chunk.Index, err)
+	}
+}
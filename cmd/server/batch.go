@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+const (
+	// maxBatchBody bounds batch request bodies, which mostly carry job IDs.
+	maxBatchBody = 256 << 10
+	// maxBatchIDs caps the IDs one batch request may list.
+	maxBatchIDs = 1000
+)
+
+// batchRequest is the body of the /api/v1/jobs:batch* endpoints. It names the jobs
+// either by IDs or by a filter; DryRun reports the matching jobs without touching them.
+type batchRequest struct {
+	IDs    []string     `json:"ids"`
+	Filter *batchFilter `json:"filter"`
+	DryRun bool         `json:"dryRun"`
+	// Add and Remove are the tags batchTag adds to and removes from every job.
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+	// Stage is where batchRetry resumes. A stage after chunk keeps the failed run's
+	// chunks and redoes the rest; earlier stages, or none, run the whole pipeline.
+	Stage chunker.PipelineStage `json:"stage"`
+}
+
+// batchFilter selects jobs by their attributes; every set condition must hold.
+type batchFilter struct {
+	Status        chunker.JobStatus `json:"status"`
+	Tag           string            `json:"tag"`
+	Source        string            `json:"source"`
+	CreatedBefore *time.Time        `json:"createdBefore"`
+	CreatedAfter  *time.Time        `json:"createdAfter"`
+}
+
+func (f batchFilter) empty() bool {
+	return f.Status == "" && f.Tag == "" && f.Source == "" && f.CreatedBefore == nil && f.CreatedAfter == nil
+}
+
+func (f batchFilter) matches(job *chunker.Job) bool {
+	source := job.Source
+	if source == "" {
+		source = jobSourceUpload
+	}
+	switch {
+	case f.Status != "" && job.Status != f.Status:
+		return false
+	case f.Source != "" && source != f.Source:
+		return false
+	case f.CreatedBefore != nil && !job.CreatedAt.Before(*f.CreatedBefore):
+		return false
+	case f.CreatedAfter != nil && !job.CreatedAt.After(*f.CreatedAfter):
+		return false
+	}
+	return true
+}
+
+// batchResult is the outcome for one job. Status is the HTTP status the single-job
+// request would have answered with.
+type batchResult struct {
+	ID     string `json:"id"`
+	OK     bool   `json:"ok"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchResponse lists a result per matched job in the order they were named or listed.
+type batchResponse struct {
+	Matched   int           `json:"matched"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	DryRun    bool          `json:"dryRun,omitempty"`
+	Results   []batchResult `json:"results"`
+}
+
+// batchOp is one of the batch operations. Status is reported for jobs it succeeds on;
+// tags marks the one that takes Add and Remove, and stage the one that takes Stage.
+type batchOp struct {
+	run    func(s *server, jobID string, req batchRequest) error
+	status int
+	tags   bool
+	stage  bool
+}
+
+var (
+	batchDeleteOp = batchOp{run: batchDelete, status: http.StatusOK}
+	batchRetryOp  = batchOp{run: batchRetry, status: http.StatusAccepted, stage: true}
+	batchTagOp    = batchOp{run: batchTag, status: http.StatusOK, tags: true}
+)
+
+// jobOpError is a per-job failure of a batch operation, carrying the status a
+// single-job request would get.
+type jobOpError struct {
+	status int
+	msg    string
+}
+
+func (e *jobOpError) Error() string { return e.msg }
+
+func init() {
+	for _, op := range []struct{ id, summary string }{
+		{"batchDelete", "Delete many jobs."},
+		{"batchRetry", "Run many failed jobs again."},
+		{"batchTag", "Add or remove tags on many jobs."},
+	} {
+		registerAPI(apiOperation{Method: http.MethodPost, Path: "/api/v1/jobs:" + op.id, ID: op.id + "Jobs", Summary: op.summary,
+			Request: batchRequest{}, Response: batchResponse{}})
+	}
+}
+
+// handleBatch serves POST /api/v1/jobs:batchDelete, :batchRetry, and :batchTag, applying
+// op to every job the request names and reporting each outcome. Failing jobs do not stop
+// the others, so the response is 200 whenever the request itself was valid.
+func (s *server) handleBatch(op batchOp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+			return
+		}
+		var req batchRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON body: %v.", err), nil)
+			return
+		}
+		if err := req.normalize(op); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		ids := req.IDs
+		if req.Filter != nil {
+			jobs, err := storage.ListJobsWith(s.jobsDir, storage.ListOptions{SortBy: storage.SortByCreated, Tag: req.Filter.Tag})
+			if err != nil {
+				s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+				return
+			}
+			for _, job := range jobs {
+				if req.Filter.matches(job) {
+					ids = append(ids, job.ID)
+				}
+			}
+		}
+
+		resp := batchResponse{Matched: len(ids), DryRun: req.DryRun, Results: make([]batchResult, 0, len(ids))}
+		for _, id := range ids {
+			result := batchResult{ID: id, OK: true, Status: op.status}
+			var err error
+			if req.DryRun {
+				if _, statErr := storage.LoadJob(storage.JobDir(s.jobsDir, id)); statErr != nil {
+					err = &jobOpError{http.StatusNotFound, "Job not found."}
+				}
+			} else {
+				err = op.run(s, id, req)
+			}
+			var opErr *jobOpError
+			switch {
+			case errors.As(err, &opErr):
+				result.OK, result.Status, result.Error = false, opErr.status, opErr.msg
+			case err != nil:
+				log.Printf("job %s: batch operation failed: %v", id, err)
+				result.OK, result.Status, result.Error = false, http.StatusInternalServerError, "The operation failed."
+			}
+			if result.OK {
+				resp.Succeeded++
+			} else {
+				resp.Failed++
+			}
+			resp.Results = append(resp.Results, result)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// normalize checks the request names jobs one way and cleans up its IDs and, for an
+// operation taking them, its tags and stage.
+func (req *batchRequest) normalize(op batchOp) error {
+	switch {
+	case len(req.IDs) > 0 && req.Filter != nil:
+		return errors.New("Send either ids or a filter, not both.")
+	case len(req.IDs) == 0 && req.Filter == nil:
+		return errors.New("Send the ids of the jobs or a filter selecting them.")
+	case len(req.IDs) > maxBatchIDs:
+		return fmt.Errorf("At most %d ids can be sent at once.", maxBatchIDs)
+	case req.Filter != nil && req.Filter.empty():
+		return errors.New("The filter needs at least one condition.")
+	}
+	if f := req.Filter; f != nil {
+		switch f.Status {
+		case "", chunker.JobStatusPending, chunker.JobStatusProcessing, chunker.JobStatusCompleted, chunker.JobStatusFailed, chunker.JobStatusReceiving:
+		default:
+			return fmt.Errorf("Unknown job status %q.", f.Status)
+		}
+		f.Tag = strings.ToLower(strings.TrimSpace(f.Tag))
+	}
+	ids := make([]string, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		id = strings.TrimSpace(id)
+		// IDs name directories under the jobs root, so they must not carry a path.
+		if !storage.ValidJobID(id) {
+			return fmt.Errorf("Invalid job id %q.", id)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	req.IDs = ids
+
+	switch {
+	case req.Stage != "" && !op.stage:
+		return errors.New("A stage only applies to :batchRetry.")
+	case req.Stage != "" && !chunker.ValidPipelineStage(string(req.Stage)):
+		return fmt.Errorf("Unknown stage %q.", req.Stage)
+	}
+	if !op.tags {
+		if len(req.Add) > 0 || len(req.Remove) > 0 {
+			return errors.New("Tags to add or remove only apply to :batchTag.")
+		}
+		return nil
+	}
+	var err error
+	if req.Add, err = chunker.NormalizeTags(req.Add); err != nil {
+		return err
+	}
+	if req.Remove, err = chunker.NormalizeTags(req.Remove); err != nil {
+		return err
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		return errors.New("Send tags to add or remove.")
+	}
+	return nil
+}
+
+// inFlight reports whether a worker currently holds the job: this server, or another
+// server or worker with a live lease on it.
+func (s *server) inFlight(jobID string) bool {
+	s.mu.Lock()
+	_, ok := s.jobsInFlight[jobID]
+	s.mu.Unlock()
+	return ok || s.leased(jobID)
+}
+
+// batchDelete deletes one job unless it is being processed.
+func batchDelete(s *server, jobID string, _ batchRequest) error {
+	if _, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID)); err != nil {
+		return &jobOpError{http.StatusNotFound, "Job not found."}
+	}
+	if s.inFlight(jobID) {
+		return &jobOpError{http.StatusConflict, "The job is still processing."}
+	}
+	return s.deleteJob(jobID)
+}
+
+// batchTag adds and removes the request's tags on one job.
+func batchTag(s *server, jobID string, req batchRequest) error {
+	_, err := s.updateJob(storage.JobDir(s.jobsDir, jobID), func(job *chunker.Job) error {
+		tags := slices.DeleteFunc(slices.Clone(job.Tags), func(tag string) bool { return slices.Contains(req.Remove, tag) })
+		tags, err := chunker.NormalizeTags(append(tags, req.Add...))
+		if err != nil {
+			return &jobOpError{http.StatusBadRequest, err.Error()}
+		}
+		job.Tags = tags
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return &jobOpError{http.StatusNotFound, "Job not found."}
+	}
+	return err
+}
+
+// batchRetry runs a failed job again in place with its own settings, after clearing the
+// partial output of the failed run. Retried from a stage after chunk, it keeps the chunks.
+func batchRetry(s *server, jobID string, req batchRequest) error {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	switch {
+	case err != nil:
+		return &jobOpError{http.StatusNotFound, "Job not found."}
+	case s.inFlight(jobID):
+		return &jobOpError{http.StatusConflict, "The job is still processing."}
+	case job.Status != chunker.JobStatusFailed:
+		return &jobOpError{http.StatusConflict, "Only failed jobs can be retried."}
+	case job.Source == chunker.JobSourcePush || job.OriginalVideoPath == "" || job.OriginalDiscardedAt != nil:
+		return &jobOpError{http.StatusGone, "The job's original upload is not kept, so it cannot be retried."}
+	}
+
+	keepChunks := slices.Index(chunker.PipelineStages, req.Stage) > slices.Index(chunker.PipelineStages, chunker.PipelineChunk)
+	if keepChunks && job.Stage(chunker.PipelineChunk).Status != chunker.StageCompleted {
+		return &jobOpError{http.StatusConflict, "The job failed before its chunks were cut; retry it from the start."}
+	}
+	return s.rerunJob(job, jobDir, keepChunks)
+}
+
+// rerunJob clears the job's derived artefacts and processes it again from its original
+// with its own settings. With keepChunks the chunks of the last run are linked in
+// rather than cut again.
+func (s *server) rerunJob(job *chunker.Job, jobDir string, keepChunks bool) error {
+	form := url.Values{}
+	inheritSegmentFields(form, job)
+	segment, err := s.parseSegmentForm(&http.Request{Form: form})
+	if err != nil {
+		return &jobOpError{http.StatusConflict, err.Error()}
+	}
+	opts, err := s.jobOptions(job, &segment)
+	switch {
+	case errors.Is(err, errTranscriptSetGone):
+		return &jobOpError{http.StatusConflict, "A transcript set of this job is no longer configured."}
+	case err != nil:
+		return &jobOpError{http.StatusConflict, err.Error()}
+	}
+
+	var keep []string
+	if keepChunks {
+		keep = []string{"chunks"}
+	}
+	if err := storage.ClearDerivedArtefacts(jobDir, keep...); err != nil {
+		return err
+	}
+	s.transcripts.RemoveJob(job.ID)
+	job.ChunkDurationSeconds = segment.chunkDuration
+	job.RequestedChunkSeconds = segment.requestedDuration
+	job.MergedAudioFile = ""
+	job.SubtitlesFile = ""
+	job.SubtitledVideoFile = ""
+	job.Summary = nil
+	job.Chapters = nil
+	job.ChaptersFile = ""
+	job.ChapterMetadataFile = ""
+	job.InjectedFault = ""
+	job.ReusedChunksFrom = ""
+	if keepChunks {
+		// Workers find the chunks by the job they are reused from.
+		opts.ReuseChunksDir = filepath.Join(jobDir, "chunks")
+		job.ReusedChunksFrom = job.ID
+	}
+	job.Chunks = []chunker.Chunk{}
+	job.CompletedAt = nil
+	job.ErrorMessage = ""
+	job.Progress = nil
+	job.Status = chunker.JobStatusPending
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.jobsInFlight[job.ID] = job
+	s.mu.Unlock()
+	go s.processJob(job, jobDir, filepath.Join(jobDir, filepath.FromSlash(job.OriginalVideoPath)), opts)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"audi/internal/config"
+	"audi/pkg/chunker"
+)
+
+// chunkLimitOf converts a configured backend limit.
+func chunkLimitOf(c config.ChunkLimitConfig) chunker.ChunkLimit {
+	return chunker.ChunkLimit{MaxSeconds: c.MaxChunkDuration.Seconds(), MaxBytes: int64(c.MaxChunkSize)}
+}
+
+// maxChunkSeconds is the longest chunk, padding included, that whisper and every one of
+// sets accept, or 0 when nothing limits it or no whisper binary is configured.
+func (s *server) maxChunkSeconds(sets []chunker.TranscriptSet) float64 {
+	if s.processor.WhisperBin == "" {
+		return 0
+	}
+	limit := s.chunkLimit
+	for _, set := range sets {
+		limit = limit.Tighter(s.setChunkLimits[set.Name])
+	}
+	return limit.Seconds()
+}
+
+// chunkLimitsBySet lists the limits the upload form warns about, keyed by transcript
+// set with "" for whisper alone. It is nil when no chunk is limited.
+func (s *server) chunkLimitsBySet() map[string]float64 {
+	base := s.maxChunkSeconds(nil)
+	limits := make(map[string]float64)
+	if base > 0 {
+		limits[""] = base
+	}
+	for _, set := range s.transcriptSets {
+		if max := s.maxChunkSeconds([]chunker.TranscriptSet{set}); max > 0 && max != base {
+			limits[set.Name] = max
+		}
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+	return limits
+}
+
+// limitTo shortens the chunk duration so padded chunks stay within maxSeconds, keeping
+// the asked-for duration in requestedDuration. Count mode is left to the processor, which
+// knows the input's length. Its errors are meant for the user.
+func (f *segmentForm) limitTo(maxSeconds float64) error {
+	f.maxChunkSeconds = maxSeconds
+	if maxSeconds <= 0 || f.chunkCount > 0 {
+		return nil
+	}
+	fit, err := chunker.FitChunkDuration(f.chunkDuration, f.paddingMillis, maxSeconds)
+	if err != nil {
+		return fmt.Errorf("The transcription backend takes chunks of at most %s including padding, which %d ms of padding leaves no room in.", formatSeconds(maxSeconds), f.paddingMillis)
+	}
+	if fit < f.chunkDuration {
+		f.requestedDuration = f.chunkDuration
+		f.chunkDuration = fit
+	}
+	return nil
+}
+
+// limitNotice tells the user their chunk length was shortened, or returns "".
+func (f segmentForm) limitNotice() string {
+	if f.requestedDuration == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Chunk length lowered from %s to %s so the transcription backend accepts every chunk.",
+		formatDurationHuman(f.requestedDuration), formatDurationHuman(f.chunkDuration))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+const (
+	// defaultPeaks is the number of waveform peaks per chunk when ?peaks= is not given.
+	defaultPeaks = 100
+	// maxCachedPeaks bounds peakCache; when it is full it starts over.
+	maxCachedPeaks = 4096
+)
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/chunks", ID: "listChunks", Summary: "Every chunk with timing, waveform peaks, and file URLs.",
+		Query: []apiParam{{Name: "peaks", Type: "integer", Description: "Waveform peaks per chunk, 0 to leave them out."}}, Response: chunkListing{}})
+}
+
+// chunkListing is the answer of GET /api/v1/jobs/{id}/chunks.
+type chunkListing struct {
+	JobID          string       `json:"jobId"`
+	Status         string       `json:"status"`
+	RecordingStart *time.Time   `json:"recordingStart,omitempty"`
+	AudioPurged    bool         `json:"audioPurged,omitempty"`
+	Chunks         []chunkEntry `json:"chunks"`
+}
+
+// chunkEntry is what a player needs of one chunk: its place on the job's timeline, a
+// transcript preview, its waveform, and where to stream or fetch each of its files.
+type chunkEntry struct {
+	Index             int        `json:"index"`
+	Track             int        `json:"track,omitempty"`
+	StartSeconds      float64    `json:"startSeconds"`
+	EndSeconds        float64    `json:"endSeconds"`
+	DurationSeconds   float64    `json:"durationSeconds"`
+	StartsAt          *time.Time `json:"startsAt,omitempty"`
+	TranscriptPreview string     `json:"transcriptPreview,omitempty"`
+	TranscriptError   string     `json:"transcriptError,omitempty"`
+	Confidence        float64    `json:"confidence,omitempty"`
+	Silent            bool       `json:"silent,omitempty"`
+	SkipReason        string     `json:"skipReason,omitempty"`
+	MimeType          string     `json:"mimeType,omitempty"`
+	SizeBytes         int64      `json:"sizeBytes,omitempty"`
+	Peaks             []float64  `json:"peaks,omitempty"`
+	URLs              chunkURLs  `json:"urls"`
+}
+
+// chunkURLs are the locations of a chunk's files; audio and video stream with range
+// requests. Files the job does not have are left out.
+type chunkURLs struct {
+	Detail        string `json:"detail"`
+	Audio         string `json:"audio,omitempty"`
+	Download      string `json:"download,omitempty"`
+	RedactedAudio string `json:"redactedAudio,omitempty"`
+	Video         string `json:"video,omitempty"`
+	Transcript    string `json:"transcript,omitempty"`
+	Words         string `json:"words,omitempty"`
+}
+
+// peakCache keeps computed waveforms, keyed by chunk content, so listing a job again
+// does not read every chunk's audio again.
+type peakCache struct {
+	mu      sync.Mutex
+	entries map[string][]float64
+}
+
+func (c *peakCache) get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	peaks, ok := c.entries[key]
+	return peaks, ok
+}
+
+func (c *peakCache) put(key string, peaks []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxCachedPeaks {
+		c.entries = make(map[string][]float64)
+	}
+	c.entries[key] = peaks
+}
+
+// handleAPIJobChunks serves GET /api/v1/jobs/{id}/chunks: every chunk with its timing,
+// absolute start when the recording start is known, transcript preview, waveform peaks,
+// and file URLs, so a player needs one request per job. ?peaks= sets the number of
+// peaks per chunk (default 100, at most chunker.MaxPeaks; 0 leaves them out). Peaks
+// are computed from chunk audio on this server's disk and omitted for other chunks.
+func (s *server) handleAPIJobChunks(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	peakCount := defaultPeaks
+	if raw := r.URL.Query().Get("peaks"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > chunker.MaxPeaks {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("peaks must be a number from 0 to %d.", chunker.MaxPeaks), nil)
+			return
+		}
+		peakCount = n
+	}
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	// Chunks of a job being processed may still be written.
+	if s.inFlight(jobID) {
+		peakCount = 0
+	}
+
+	listing := chunkListing{
+		JobID:          job.ID,
+		Status:         string(job.Status),
+		RecordingStart: job.RecordingStart,
+		AudioPurged:    job.ArtefactsPurgedAt != nil,
+		Chunks:         make([]chunkEntry, 0, len(job.Chunks)),
+	}
+	for _, chunk := range job.Chunks {
+		entry := chunkEntry{
+			Index:             chunk.Index,
+			Track:             chunk.Track,
+			StartSeconds:      chunk.StartSeconds,
+			EndSeconds:        chunk.StartSeconds + chunk.DurationSeconds,
+			DurationSeconds:   chunk.DurationSeconds,
+			TranscriptPreview: chunk.TranscriptPreview,
+			TranscriptError:   chunk.TranscriptError,
+			Confidence:        chunk.Confidence,
+			Silent:            chunk.Silent,
+			SkipReason:        chunk.SkipReason,
+			URLs: chunkURLs{
+				Detail: s.link(fmt.Sprintf("/api/v1/jobs/%s/chunks/%d", job.ID, chunk.Index)),
+			},
+		}
+		if at, ok := job.ChunkWallClock(chunk); ok {
+			entry.StartsAt = &at
+		}
+		if chunk.TranscriptFile != "" {
+			entry.URLs.Transcript = s.fileURL(job.ID, chunk.TranscriptFile)
+		}
+		if chunk.WordsFile != "" {
+			entry.URLs.Words = entry.URLs.Detail + "?include=words"
+		}
+		if job.ArtefactsPurgedAt == nil {
+			if chunk.AudioFile != "" {
+				entry.MimeType = assetTypes[".wav"]
+				entry.URLs.Audio = s.fileURL(job.ID, chunk.AudioFile)
+				entry.URLs.Download = s.link(fmt.Sprintf("/jobs/%s/download/%d", job.ID, chunk.Index))
+				audioPath, err := s.router.JobFile(job.ID, chunk.AudioFile)
+				if err == nil {
+					if info, err := os.Stat(audioPath); err == nil {
+						entry.SizeBytes = info.Size()
+						if peakCount > 0 {
+							entry.Peaks = s.chunkPeaks(job.ID, chunk, audioPath, info, peakCount)
+						}
+					}
+				}
+			}
+			if chunk.RedactedAudioFile != "" {
+				entry.URLs.RedactedAudio = s.fileURL(job.ID, chunk.RedactedAudioFile)
+			}
+			if chunk.VideoFile != "" {
+				entry.URLs.Video = s.fileURL(job.ID, chunk.VideoFile)
+			}
+		}
+		listing.Chunks = append(listing.Chunks, entry)
+	}
+	writeJSON(w, http.StatusOK, listing)
+}
+
+// chunkPeaks returns the waveform of a chunk's audio, from the cache when the same
+// content was measured before. Audio that cannot be read gets none.
+func (s *server) chunkPeaks(jobID string, chunk chunker.Chunk, audioPath string, info os.FileInfo, n int) []float64 {
+	key := chunk.Checksum
+	if key == "" {
+		key = fmt.Sprintf("%s/%s@%d.%d", jobID, chunk.AudioFile, info.Size(), info.ModTime().UnixNano())
+	}
+	key += "#" + strconv.Itoa(n)
+	if peaks, ok := s.peaks.get(key); ok {
+		return peaks
+	}
+	peaks, err := chunker.WAVPeaks(audioPath, n)
+	if err != nil {
+		return nil
+	}
+	s.peaks.put(key, peaks)
+	return peaks
+}
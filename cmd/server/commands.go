@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/commands", ID: "listJobCommands", Summary: "Every ffmpeg and whisper command the job ran.",
+		Query: []apiParam{{Name: "format", Description: "sh for a shell script instead of JSON."}}, Response: []chunker.CommandRun{}})
+}
+
+// handleAPIJobCommands serves GET /api/v1/jobs/{id}/commands: every ffmpeg and whisper
+// run of the job as JSON, or with ?format=sh as a shell script to replay them from the
+// job directory.
+func (s *server) handleAPIJobCommands(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, job.Commands)
+	case "sh":
+		var b strings.Builder
+		fmt.Fprintf(&b, "#!/bin/sh\n# Commands run for job %s; paths are those of the server.\n", job.ID)
+		for _, run := range job.Commands {
+			fmt.Fprintf(&b, "\n# %s, exit %d after %.2fs", run.StartedAt.UTC().Format("2006-01-02T15:04:05Z"), run.ExitCode, run.DurationSeconds)
+			if run.Error != "" {
+				fmt.Fprintf(&b, ": %s", strings.Join(strings.Fields(run.Error), " "))
+			}
+			fmt.Fprintf(&b, "\n%s\n", run.CommandLine())
+		}
+		w.Header().Set("Content-Type", "text/x-shellscript; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", job.ID+"-commands.sh"))
+		_, _ = w.Write([]byte(b.String()))
+	default:
+		s.renderError(w, r, http.StatusBadRequest, "format must be json or sh.", nil)
+	}
+}
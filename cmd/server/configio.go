@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/storage"
+)
+
+// maxConfigBody bounds imported config files.
+const maxConfigBody = 1 << 20
+
+// presetConfigs converts stored presets back into the config file's form.
+func presetConfigs(presets []storage.Preset) []config.PresetConfig {
+	out := make([]config.PresetConfig, 0, len(presets))
+	for _, p := range presets {
+		options := make(config.FormValues, len(p.Options))
+		for field, values := range p.Options {
+			options[field] = values
+		}
+		out = append(out, config.PresetConfig{Name: p.Name, Description: p.Description, Options: options})
+	}
+	return out
+}
+
+// samePreset reports whether two presets set the same description and options.
+func samePreset(a, b storage.Preset) bool {
+	return a.Description == b.Description && maps.EqualFunc(a.Options, b.Options, slices.Equal)
+}
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/config:export", ID: "exportConfig", Summary: "The running configuration as a YAML config file.",
+			Query: []apiParam{{Name: "secrets", Description: "include to leave secrets in."}}, ResponseType: "application/yaml"},
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/config:import", ID: "importConfig", Summary: "Apply a config file's presets and report other differences.",
+			Query:       []apiParam{{Name: "prune", Type: "boolean"}, {Name: "dry_run", Type: "boolean"}},
+			RequestType: "application/yaml", Response: configImportResult{}},
+	)
+}
+
+// handleAPIConfigExport serves GET /api/v1/config:export: the running configuration,
+// flags and environment included, as a config file whose presets are the whole preset
+// library. Secrets are replaced by config.RedactedSecret unless secrets=include.
+func (s *server) handleAPIConfigExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	cfg := s.cfg
+	cfg.Presets = presetConfigs(s.presets.List())
+	comment := []string{
+		"audi configuration exported " + time.Now().UTC().Format(time.RFC3339),
+		"Start a server with it (-config), or POST it to /api/v1/config:import on a running one.",
+	}
+	if r.URL.Query().Get("secrets") != "include" {
+		var redacted []string
+		if cfg, redacted = cfg.Redact(); len(redacted) > 0 {
+			comment = append(comment, "", "Secrets to fill in before starting a server with it:")
+			for _, path := range redacted {
+				comment = append(comment, "  "+path)
+			}
+		}
+	}
+	data, err := cfg.Marshal(comment)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The configuration could not be exported.", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audi-config.yaml"`)
+	_, _ = w.Write(data)
+}
+
+// configImportResult is the answer of POST /api/v1/config:import.
+type configImportResult struct {
+	DryRun    bool               `json:"dryRun,omitempty"`
+	Created   []string           `json:"created"`
+	Updated   []string           `json:"updated"`
+	Unchanged []string           `json:"unchanged"`
+	Deleted   []string           `json:"deleted"`
+	Skipped   []configImportSkip `json:"skipped"`
+	// RestartRequired lists the top-level settings that differ from the running
+	// configuration; they only take effect once the file is installed and the server
+	// restarted.
+	RestartRequired []string `json:"restartRequired"`
+}
+
+type configImportSkip struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// handleAPIConfigImport serves POST /api/v1/config:import. The body is a config file,
+// usually an export of another server. Its presets are created or updated here at once
+// (prune=true also deletes stored presets it lacks); every other setting is compared with
+// the running configuration and reported. dry_run=true changes nothing.
+func (s *server) handleAPIConfigImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigBody))
+	if err != nil {
+		s.renderError(w, r, http.StatusRequestEntityTooLarge, "The configuration is larger than 1 MB.", err)
+		return
+	}
+	imported, err := config.Parse(data)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("The configuration is invalid: %v.", err), nil)
+		return
+	}
+	query := r.URL.Query()
+	result := configImportResult{
+		DryRun:    query.Get("dry_run") == "true",
+		Created:   []string{},
+		Updated:   []string{},
+		Unchanged: []string{},
+		Deleted:   []string{},
+		Skipped:   []configImportSkip{},
+	}
+	if result.RestartRequired, err = config.Diff(s.cfg, imported.Unredact(s.cfg)); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The configuration could not be compared.", err)
+		return
+	}
+	if result.RestartRequired == nil {
+		result.RestartRequired = []string{}
+	}
+
+	seen := make(map[string]bool, len(imported.Presets))
+	for _, preset := range configPresets(imported.Presets) {
+		seen[preset.Name] = true
+		skip := func(msg string) {
+			result.Skipped = append(result.Skipped, configImportSkip{Name: preset.Name, Error: msg})
+		}
+		if err := storage.ValidatePresetName(preset.Name); err != nil {
+			skip(err.Error())
+			continue
+		}
+		if err := s.validatePreset(preset); err != nil {
+			skip(err.Error())
+			continue
+		}
+		current, exists := s.presets.Get(preset.Name)
+		switch {
+		case exists && samePreset(current, preset):
+			result.Unchanged = append(result.Unchanged, preset.Name)
+			continue
+		case current.ReadOnly:
+			skip("this preset is defined in the server's config file")
+			continue
+		}
+		if !result.DryRun {
+			if _, err := s.presets.Put(preset); err != nil {
+				skip(err.Error())
+				continue
+			}
+		}
+		if exists {
+			result.Updated = append(result.Updated, preset.Name)
+		} else {
+			result.Created = append(result.Created, preset.Name)
+		}
+	}
+
+	if query.Get("prune") == "true" {
+		for _, preset := range s.presets.List() {
+			if preset.ReadOnly || seen[preset.Name] {
+				continue
+			}
+			if !result.DryRun {
+				if err := s.presets.Delete(preset.Name); err != nil {
+					result.Skipped = append(result.Skipped, configImportSkip{Name: preset.Name, Error: err.Error()})
+					continue
+				}
+			}
+			result.Deleted = append(result.Deleted, preset.Name)
+		}
+	}
+
+	if !result.DryRun && len(result.Created)+len(result.Updated)+len(result.Deleted) > 0 {
+		log.Printf("config import: presets created %s; updated %s; deleted %s",
+			listOrNone(result.Created), listOrNone(result.Updated), listOrNone(result.Deleted))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"audi/internal/config"
+)
+
+// corsPaths are the routes pages on other origins may call: the JSON APIs and the job
+// files their answers link to.
+var corsPaths = []string{"/api/", "/v1/", "/files/"}
+
+// corsExposedHeaders are the response headers of the API a cross-origin script may read.
+const corsExposedHeaders = "Location, X-Request-ID, X-Job-Id, X-Duplicate-Of, Content-Disposition, ETag"
+
+// corsPolicy answers preflight requests and marks API responses readable for the origins
+// the cors section of the config allows.
+type corsPolicy struct {
+	origins     map[string]bool
+	anyOrigin   bool
+	methods     string
+	headers     string
+	anyHeader   bool
+	credentials bool
+	maxAge      string
+}
+
+// newCORSPolicy returns the policy of c, or nil when no origin is allowed.
+func newCORSPolicy(c config.CORSConfig) *corsPolicy {
+	if len(c.AllowedOrigins) == 0 {
+		return nil
+	}
+	p := &corsPolicy{
+		origins:     make(map[string]bool, len(c.AllowedOrigins)),
+		methods:     strings.Join(c.AllowedMethods, ", "),
+		headers:     strings.Join(c.AllowedHeaders, ", "),
+		anyHeader:   slices.Contains(c.AllowedHeaders, "*"),
+		credentials: c.AllowCredentials,
+		maxAge:      strconv.Itoa(int(c.MaxAge.Seconds())),
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			p.anyOrigin = true
+		}
+		p.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return p
+}
+
+// allows reports whether r is a cross-origin call to the API from an allowed origin.
+func (p *corsPolicy) allows(r *http.Request) bool {
+	if p == nil {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		return false
+	}
+	if !slices.ContainsFunc(corsPaths, func(prefix string) bool { return strings.HasPrefix(r.URL.Path, prefix) }) {
+		return false
+	}
+	return p.anyOrigin || p.origins[strings.ToLower(origin)]
+}
+
+// withCORS adds the CORS headers to API responses for allowed origins and answers their
+// preflight requests itself: browsers send those without credentials, so they must not
+// reach basic auth.
+func (s *server) withCORS(next http.Handler) http.Handler {
+	p := s.cors
+	if p == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if !p.allows(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		if p.anyOrigin && !p.credentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+		}
+		if p.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", p.methods)
+			if p.anyHeader {
+				h.Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+			} else {
+				h.Set("Access-Control-Allow-Headers", p.headers)
+			}
+			h.Set("Access-Control-Max-Age", p.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// csrfCookie carries the token the forms of a browser must echo in csrfField.
+	csrfCookie = "audi_csrf"
+	csrfField  = "csrf_token"
+)
+
+// sameOrigin refuses state-changing requests a browser sent on behalf of another site:
+// a page elsewhere could otherwise post to a user's instance, which the browser answers
+// with the cached Basic credentials. Requests carrying a Bearer token are exempt, as
+// browsers never add one by themselves, and so are clients that are not browsers,
+// which send neither Sec-Fetch-Site nor Origin, and API calls from origins the CORS
+// configuration allows.
+func (s *server) sameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || !crossOrigin(r) || s.cors.allows(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		s.renderError(w, r, http.StatusForbidden, "Cross-site requests are not accepted.", nil)
+	})
+}
+
+// crossOrigin reports whether a browser says r comes from a page of another origin.
+// Sec-Fetch-Site is trusted when present; older browsers are judged by Origin.
+func crossOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return false
+	case "":
+	default:
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// csrfToken returns the token for the forms of a page answering r, setting the cookie
+// that holds it when the browser has none yet.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookie); err == nil && len(c.Value) == 64 {
+		return c.Value
+	}
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	token := hex.EncodeToString(b[:])
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// checkCSRF renders 403 and returns false when a form a browser submitted lacks the
+// token of its csrf cookie. Call it once the form is parsed. Other clients and Bearer
+// requests, which are not sent on a page's behalf, pass.
+func (s *server) checkCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || r.Header.Get("Sec-Fetch-Site") == "" && r.Header.Get("Origin") == "" {
+		return true
+	}
+	c, err := r.Cookie(csrfCookie)
+	if err == nil && c.Value != "" && subtle.ConstantTimeCompare([]byte(r.FormValue(csrfField)), []byte(c.Value)) == 1 {
+		return true
+	}
+	s.renderError(w, r, http.StatusForbidden, "The form has expired. Reload the page and submit it again.", nil)
+	return false
+}
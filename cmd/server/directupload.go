@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/internal/s3"
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// directUploadGrace is how long after its URL expired an unfinished direct upload is
+// kept before it is swept, object included.
+const directUploadGrace = 24 * time.Hour
+
+// directUploads hands out pre-signed URLs for uploading straight to the S3 bucket and
+// keeps a record of each in dir until the client completes it.
+type directUploads struct {
+	client *s3.Client
+	dir    string
+	prefix string
+	expiry time.Duration
+	keep   bool
+}
+
+// directUpload is the record of one issued upload URL.
+type directUpload struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	FileName  string    `json:"fileName"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// directUploadTicket is the answer of POST /api/v1/uploads.
+type directUploadTicket struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Complete  string    `json:"complete"`
+}
+
+func (d *directUploads) path(id string) string {
+	return filepath.Join(d.dir, id+".json")
+}
+
+// load reads the record of upload id; the error wraps fs.ErrNotExist for unknown IDs.
+func (d *directUploads) load(id string) (*directUpload, error) {
+	for _, r := range id {
+		if (r < 'a' || r > 'f') && (r < '0' || r > '9') {
+			return nil, fs.ErrNotExist
+		}
+	}
+	data, err := os.ReadFile(d.path(id))
+	if err != nil {
+		return nil, err
+	}
+	var upload directUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("reading upload %s: %w", id, err)
+	}
+	return &upload, nil
+}
+
+func (d *directUploads) save(upload *directUpload) error {
+	data, err := json.MarshalIndent(upload, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return err
+	}
+	tmp := d.path(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path(upload.ID))
+}
+
+// claim takes the record of upload id for completing it, so two completions of the
+// same upload cannot both create a job; release hands it back for another attempt.
+func (d *directUploads) claim(id string) (release func(), err error) {
+	claimed := d.path(id) + ".completing"
+	if err := os.Rename(d.path(id), claimed); err != nil {
+		return nil, err
+	}
+	return func() { _ = os.Rename(claimed, d.path(id)) }, nil
+}
+
+// finish drops a completed upload's claimed record and, unless objects are kept, its
+// object.
+func (d *directUploads) finish(upload *directUpload) {
+	if !d.keep {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := d.client.Delete(ctx, upload.Key); err != nil {
+			log.Printf("upload %s: failed to delete the uploaded object: %v", upload.ID, err)
+		}
+	}
+	if err := os.Remove(d.path(upload.ID) + ".completing"); err != nil {
+		log.Printf("upload %s: %v", upload.ID, err)
+	}
+}
+
+// sweep forgets uploads that were never completed, deleting whatever object they left
+// unless objects are kept.
+func (d *directUploads) sweep() {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		upload, err := d.load(id)
+		if err != nil || time.Since(upload.ExpiresAt) < directUploadGrace {
+			continue
+		}
+		if _, err := d.claim(id); err != nil {
+			continue
+		}
+		log.Printf("upload %s: never completed; removing it", id)
+		d.finish(upload)
+	}
+}
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/uploads", ID: "startUpload", Summary: "Get a pre-signed URL to PUT a large file to.",
+			Request: directUploadRequest{}, Status: http.StatusCreated, Response: directUploadTicket{}},
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/uploads/{id}/complete", ID: "completeUpload", Summary: "Turn a direct upload into a job; 200 with the existing job for a duplicate.",
+			Request: uploadForm(), Status: http.StatusAccepted, Response: chunker.Job{}},
+	)
+}
+
+// directUploadRequest is the optional body of POST /api/v1/uploads.
+type directUploadRequest struct {
+	FileName string `json:"fileName"`
+}
+
+// handleAPIUploads serves POST /api/v1/uploads, the first step of a direct upload. The
+// optional JSON body names the file ({"fileName": "talk.mp4"}). The answer carries a
+// pre-signed URL the client PUTs the file to, straight into the S3 bucket, and the
+// route completing the upload afterwards.
+func (s *server) handleAPIUploads(w http.ResponseWriter, r *http.Request) {
+	if s.directUploads == nil {
+		s.renderError(w, r, http.StatusNotFound, "Direct uploads are not configured on this server.", nil)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	var req directUploadRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON body: %v.", err), nil)
+		return
+	}
+	if !s.checkQuota(w, r) {
+		return
+	}
+	s.directUploads.sweep()
+
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The upload could not be prepared.", err)
+		return
+	}
+	d := s.directUploads
+	now := time.Now().UTC()
+	upload := &directUpload{
+		ID:        hex.EncodeToString(b[:]),
+		FileName:  displayFileName(req.FileName),
+		CreatedAt: now,
+		ExpiresAt: now.Add(d.expiry),
+	}
+	upload.Key = d.prefix + upload.ID + "/" + objectName(upload.FileName)
+	if err := d.save(upload); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The upload could not be prepared.", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, directUploadTicket{
+		ID:        upload.ID,
+		Method:    http.MethodPut,
+		URL:       d.client.Presign(http.MethodPut, upload.Key, d.expiry),
+		ExpiresAt: upload.ExpiresAt,
+		Complete:  s.link("/api/v1/uploads/" + upload.ID + "/complete"),
+	})
+}
+
+// handleAPIUploadComplete serves POST /api/v1/uploads/{id}/complete once the client has
+// PUT the file. It takes the upload form's fields, copies the object into a new job's
+// original/ in one streaming pass, and answers 202 with the queued job, or 200 with an
+// existing job processed the same way. The object is then deleted unless
+// s3.keep_objects is set.
+func (s *server) handleAPIUploadComplete(w http.ResponseWriter, r *http.Request) {
+	d := s.directUploads
+	if d == nil {
+		s.renderError(w, r, http.StatusNotFound, "Direct uploads are not configured on this server.", nil)
+		return
+	}
+	id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/uploads/"), "/complete")
+	if !ok || id == "" {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	upload, err := d.load(id)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Upload not found; it was completed already or has expired.", err)
+		return
+	}
+	settings, err := s.parseUploadSettings(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	release, err := d.claim(id)
+	if err != nil {
+		s.renderError(w, r, http.StatusConflict, "The upload is being completed already.", err)
+		return
+	}
+	done := false
+	defer func() {
+		if !done {
+			release()
+		}
+	}()
+
+	size, err := d.client.Size(r.Context(), upload.Key)
+	if errors.Is(err, s3.ErrNotFound) {
+		s.renderError(w, r, http.StatusConflict, "The file has not been uploaded yet.", nil)
+		return
+	}
+	if err != nil {
+		s.renderError(w, r, http.StatusBadGateway, "The uploaded file could not be read from the bucket.", err)
+		return
+	}
+	if s.quota > 0 {
+		if _, used, err := s.usage.Usage(); err == nil && used+size > s.quota {
+			s.renderError(w, r, http.StatusInsufficientStorage, fmt.Sprintf("Storage quota exceeded: %s of %s used.", formatBytes(used), formatBytes(s.quota)), nil)
+			return
+		}
+	}
+
+	jobID, jobDir, err := storage.CreateJobDir(s.jobsDir, s.jobIDFunc("", settings.metadata))
+	if err == nil {
+		err = s.router.PrepareJob(jobDir, storage.ArtefactDirs...)
+	}
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be created.", err)
+		return
+	}
+	removeJob := func() {
+		if err := storage.RemoveJob(jobDir); err != nil {
+			log.Printf("job %s: failed to remove job directory: %v", jobID, err)
+		}
+	}
+	body, err := d.client.Open(r.Context(), upload.Key)
+	if err != nil {
+		removeJob()
+		s.renderError(w, r, http.StatusBadGateway, "The uploaded file could not be read from the bucket.", err)
+		return
+	}
+	originalRel, sum, err := storage.StoreOriginal(jobDir, upload.FileName, body)
+	body.Close()
+	if err != nil {
+		removeJob()
+		s.renderError(w, r, http.StatusBadGateway, "The uploaded file could not be copied from the bucket.", err)
+		return
+	}
+
+	job, opts := s.newUploadJob(settings, upload.FileName, sum)
+	job.ID = jobID
+	job.OriginalVideoPath = originalRel
+	if !settings.force {
+		if dup := s.findDuplicateJob(job, opts); dup != nil {
+			removeJob()
+			done = true
+			d.finish(upload)
+			w.Header().Set("X-Duplicate-Of", dup.ID)
+			writeJSON(w, http.StatusOK, dup)
+			return
+		}
+	}
+	if err := s.startJob(jobDir, job, opts); err != nil {
+		removeJob()
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+	done = true
+	d.finish(upload)
+	log.Printf("job %s: created from direct upload %s", jobID, upload.ID)
+	w.Header().Set("Location", s.link("/api/v1/jobs/"+jobID))
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// objectName reduces a file name to characters that are safe in an object key.
+func objectName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	if strings.Trim(name, "._") == "" {
+		return "upload"
+	}
+	return name
+}
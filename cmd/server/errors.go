@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// errorPage is the data behind error.gohtml and its JSON counterpart.
+type errorPage struct {
+	Status     int    `json:"status"`
+	StatusText string `json:"-"`
+	Message    string `json:"error"`
+	Suggestion string `json:"suggestion,omitempty"`
+	RequestID  string `json:"requestId,omitempty"`
+}
+
+// renderError answers with a rendered error page, or JSON for API clients. message is
+// shown to the user and must not contain internals; cause, when set, is only logged
+// alongside the request ID so operators can find it.
+func (s *server) renderError(w http.ResponseWriter, r *http.Request, status int, message string, cause error) {
+	page := errorPage{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		Suggestion: suggestionFor(status),
+		RequestID:  requestID(r.Context()),
+	}
+	if cause != nil {
+		log.Printf("request %s from %s: %s %s: %s: %v", page.RequestID, clientAddr(r), r.Method, r.URL.Path, message, cause)
+	}
+
+	if wantsJSONError(r) {
+		writeJSON(w, status, page)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, "error.gohtml", page); err != nil {
+		log.Printf("request %s: rendering error page: %v", page.RequestID, err)
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// renderPage executes a page template into a buffer first, so a template failure
+// becomes a clean error page rather than a half-written document. It fills in the
+// page's CSRF token.
+func (s *server) renderPage(w http.ResponseWriter, r *http.Request, name string, data templateData) {
+	data.CSRFToken = csrfToken(w, r)
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The page could not be rendered.", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// wantsJSONError reports whether an error for r should be JSON: API routes always,
+// UI routes when the client negotiated JSON.
+func wantsJSONError(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") || strings.HasSuffix(r.URL.Path, ".json") || wantsJSON(r)
+}
+
+// suggestionFor proposes a next step for the user based on the status code.
+func suggestionFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "Check the submitted values and try again."
+	case http.StatusNotFound:
+		return "The job may have been deleted or the link is mistyped. Return to the job list to find it."
+	case http.StatusMethodNotAllowed:
+		return "This address does not accept that kind of request."
+	case http.StatusConflict:
+		return "Wait for the job to finish processing, then try again."
+	case http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage:
+		return "Delete old jobs to free space, or ask the administrator to raise the limit."
+	}
+	if status >= 500 {
+		return "Try again in a moment. If it keeps happening, share the request ID with the administrator."
+	}
+	return ""
+}
+
+type requestIDKey struct{}
+
+// withRequestID tags every request with an ID, reusing a well-formed X-Request-ID from a
+// proxy, and echoes it in the response so logs and error pages can be correlated.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID withRequestID stored in ctx, or "".
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts short IDs of letters, digits, '-', '_', and '.' so untrusted
+// headers cannot inject anything odd into logs or pages.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
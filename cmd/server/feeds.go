@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// audiNS qualifies the <audi:meta> elements that carry a job's metadata on its episodes.
+const audiNS = "https://github.com/ToniNgethe/audio-chunker/ns/rss"
+
+// rssFeed is an RSS 2.0 document with the iTunes extensions podcast apps look for.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Itunes  string     `xml:"xmlns:itunes,attr"`
+	Audi    string     `xml:"xmlns:audi,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description,omitempty"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	Duration    string       `xml:"itunes:duration"`
+	Meta        []rssMeta    `xml:"audi:meta"`
+}
+
+// rssMeta is one metadata entry of the episode's job.
+type rssMeta struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// handleFeed serves /feeds/{tag}.xml: an RSS feed with one episode per chunk of every
+// completed job carrying the tag, so recordings can be followed in a podcast app. Jobs
+// whose audio was purged or never kept are left out.
+func (s *server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	tag, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/feeds/"), ".xml")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !ok || tag == "" || strings.Contains(tag, "/") {
+		s.renderError(w, r, http.StatusNotFound, "Feeds live at /feeds/<tag>.xml.", nil)
+		return
+	}
+
+	jobs, err := storage.ListJobsWith(s.jobsDir, storage.ListOptions{Tag: tag})
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+		return
+	}
+
+	origin := requestOrigin(r)
+	feed := rssFeed{
+		Version: "2.0",
+		Itunes:  itunesNS,
+		Audi:    audiNS,
+		Channel: rssChannel{
+			Title:       "audio-chunker: " + tag,
+			Link:        origin + s.link("/?tag=") + url.QueryEscape(tag),
+			Description: fmt.Sprintf("Chunked recordings tagged %q.", tag),
+			Items:       []rssItem{},
+		},
+	}
+	var newest time.Time
+	for _, job := range jobs {
+		if job.Status != chunker.JobStatusCompleted || job.ArtefactsPurgedAt != nil {
+			continue
+		}
+		if job.CompletedAt != nil && job.CompletedAt.After(newest) {
+			newest = *job.CompletedAt
+		}
+		feed.Channel.Items = append(feed.Channel.Items, s.feedItems(job, origin)...)
+	}
+	if !newest.IsZero() {
+		feed.Channel.LastBuildDate = newest.UTC().Format(time.RFC1123Z)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	_ = enc.Encode(feed)
+}
+
+// feedItems turns a job's chunks into episodes. Episodes are dated by the recording
+// start when known, otherwise by the job's creation, offset by the chunk start so podcast
+// apps keep them in order.
+func (s *server) feedItems(job *chunker.Job, origin string) []rssItem {
+	jobDir := storage.JobDir(s.jobsDir, job.ID)
+	items := make([]rssItem, 0, len(job.Chunks))
+	var meta []rssMeta
+	for _, key := range slices.Sorted(maps.Keys(job.Metadata)) {
+		meta = append(meta, rssMeta{Name: key, Value: job.Metadata[key]})
+	}
+	for _, chunk := range job.Chunks {
+		if chunk.AudioFile == "" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(jobDir, filepath.FromSlash(chunk.AudioFile)))
+		if err != nil {
+			continue
+		}
+		published, ok := job.ChunkWallClock(chunk)
+		if !ok {
+			published = job.CreatedAt.Add(time.Duration(chunk.StartSeconds * float64(time.Second)))
+		}
+		enclosure := s.fileURL(job.ID, chunk.AudioFile)
+		if strings.HasPrefix(enclosure, "/") {
+			enclosure = origin + enclosure
+		}
+		guid := job.ID + "/" + chunk.AudioFile
+		if chunk.Checksum != "" {
+			guid = job.ID + "/" + chunk.Checksum
+		}
+		item := rssItem{
+			Title:     fmt.Sprintf("%s (part %d of %d)", job.DisplayName(), chunk.Index+1, len(job.Chunks)),
+			Link:      origin + s.link(fmt.Sprintf("/jobs/%s#chunk-%d", job.ID, chunk.Index)),
+			GUID:      rssGUID{Value: guid},
+			PubDate:   published.UTC().Format(time.RFC1123Z),
+			Enclosure: rssEnclosure{URL: enclosure, Length: info.Size(), Type: "audio/wav"},
+			Duration:  formatSeconds(chunk.DurationSeconds),
+			Meta:      meta,
+		}
+		if chunk.TranscriptFile != "" {
+			item.Description = strings.TrimSpace(chunk.TranscriptPreview)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// requestOrigin is the scheme and host the client reached the server under, through a
+// trusted proxy or directly, for links that leave the browser, such as feed enclosures.
+func requestOrigin(r *http.Request) string {
+	return requestScheme(r) + "://" + r.Host
+}
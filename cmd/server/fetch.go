@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+)
+
+// fetchHeaderTimeout bounds the wait for a fetch URL to start answering; the body may
+// then stream for as long as the client keeps reading.
+const fetchHeaderTimeout = 30 * time.Second
+
+// Headers passed between the client and a fetch URL when a file is streamed through.
+var (
+	fetchRequestHeaders  = []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since"}
+	fetchResponseHeaders = []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"}
+)
+
+func newFetchClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = fetchHeaderTimeout
+	return &http.Client{Transport: transport}
+}
+
+// serveJobFile serves a file given relative to the job directory like http.ServeFile,
+// with the headers of setAssetHeaders, reading it from its storage class's fetch URL
+// when it is not on disk. Paths leading out of the job are refused.
+func (s *server) serveJobFile(w http.ResponseWriter, r *http.Request, jobID, rel string) {
+	fullPath, err := s.router.JobFile(jobID, rel)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "Invalid file path.", err)
+		return
+	}
+	s.setAssetHeaders(w, jobID, rel)
+	if _, err := os.Stat(fullPath); errors.Is(err, os.ErrNotExist) {
+		if remote, ok := s.router.Remote(jobID, rel); ok {
+			s.proxyRemoteFile(w, r, jobID, rel, remote)
+			return
+		}
+	}
+	http.ServeFile(w, r, fullPath)
+}
+
+// fetchMissing lets a handler serving the data directory fall back to fetch URLs for
+// job files that are not on disk.
+func (s *server) fetchMissing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/"), "/", 3)
+		if len(parts) == 3 && parts[0] == "jobs" {
+			jobID, rel := parts[1], parts[2]
+			if _, err := os.Stat(filepath.Join(storage.JobDir(s.jobsDir, jobID), filepath.FromSlash(rel))); errors.Is(err, os.ErrNotExist) {
+				if remote, ok := s.router.Remote(jobID, rel); ok {
+					s.proxyRemoteFile(w, r, jobID, rel, remote)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// proxyRemoteFile answers with a job file read from its fetch URL. Cached classes fill
+// the local copy first and serve that, so range requests and repeat reads stay local;
+// others stream the response through, passing range and validator headers along.
+func (s *server) proxyRemoteFile(w http.ResponseWriter, r *http.Request, jobID, rel string, remote storage.RemoteFile) {
+	caching := remote.Cache && s.fetchCache != nil
+	if caching {
+		if file, err := s.fetchCache.Open(jobID, rel); err == nil {
+			defer file.Close()
+			serveOpenFile(w, r, rel, file)
+			return
+		}
+	}
+
+	var forward http.Header
+	if !caching {
+		forward = r.Header
+	}
+	resp, err := s.fetchRemote(r.Context(), remote, forward)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadGateway, "The file could not be fetched from storage.", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		s.renderError(w, r, http.StatusNotFound, "File not found.", nil)
+		return
+	case caching && resp.StatusCode == http.StatusOK:
+		file, err := s.fetchCache.Store(jobID, rel, resp.Body)
+		if err != nil {
+			s.renderError(w, r, http.StatusBadGateway, "The file could not be fetched from storage.", err)
+			return
+		}
+		defer file.Close()
+		serveOpenFile(w, r, rel, file)
+		return
+	case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusPartialContent,
+		resp.StatusCode == http.StatusNotModified, resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+	default:
+		s.renderError(w, r, http.StatusBadGateway, "The file could not be fetched from storage.", errors.New(resp.Status))
+		return
+	}
+
+	// Range and validator headers went to the fetch URL, so only its ETag applies.
+	w.Header().Del("ETag")
+	for _, key := range fetchResponseHeaders {
+		if value := resp.Header.Get(key); value != "" {
+			w.Header().Set(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Printf("job %s: streaming %s from storage: %v", jobID, rel, err)
+	}
+}
+
+// openJobFile opens a job file for reading from disk or, when it is not there, from its
+// fetch URL. The size is -1 when the fetch URL does not report one.
+func (s *server) openJobFile(ctx context.Context, jobID, rel string) (io.ReadCloser, int64, error) {
+	fullPath, err := s.router.JobFile(jobID, rel)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(fullPath)
+	if err == nil {
+		return statFile(file)
+	}
+	remote, ok := s.router.Remote(jobID, rel)
+	if !ok || !errors.Is(err, os.ErrNotExist) {
+		return nil, 0, err
+	}
+	caching := remote.Cache && s.fetchCache != nil
+	if caching {
+		if file, err := s.fetchCache.Open(jobID, rel); err == nil {
+			return statFile(file)
+		}
+	}
+
+	resp, err := s.fetchRemote(ctx, remote, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("fetching %s: %w", rel, os.ErrNotExist)
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("fetching %s: %s", rel, resp.Status)
+	case caching:
+		defer resp.Body.Close()
+		file, err := s.fetchCache.Store(jobID, rel, resp.Body)
+		if err != nil {
+			return nil, 0, err
+		}
+		return statFile(file)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// readJobFile reads a whole job file, from disk or its fetch URL.
+func (s *server) readJobFile(ctx context.Context, jobID, rel string) ([]byte, error) {
+	file, _, err := s.openJobFile(ctx, jobID, rel)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// fetchRemote requests a file from its fetch URL with the class's headers plus the
+// range and validator headers found in forward, if any.
+func (s *server) fetchRemote(ctx context.Context, remote storage.RemoteFile, forward http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remote.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range remote.Headers {
+		req.Header.Set(key, value)
+	}
+	for _, key := range fetchRequestHeaders {
+		if value := forward.Get(key); value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	return s.fetchClient.Do(req)
+}
+
+func statFile(file *os.File) (io.ReadCloser, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// serveOpenFile serves an open file with range support, naming it after rel for the
+// content type.
+func serveOpenFile(w http.ResponseWriter, r *http.Request, rel string, file *os.File) {
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "The file could not be read.", http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, path.Base(rel), info.ModTime(), file)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/setup"
+	"audi/pkg/chunker"
+)
+
+// toolCheckInterval is how old the checks /healthz reports may get before it runs ffmpeg
+// and whisper again.
+const toolCheckInterval = time.Minute
+
+// Health statuses reported by /healthz.
+const (
+	healthOK          = "ok"
+	healthDegraded    = "degraded"
+	healthUnavailable = "unavailable"
+)
+
+// healthReport is the body of GET /healthz.
+type healthReport struct {
+	// Status is "ok"; "degraded" when whisper does not run or a check warns; or
+	// "unavailable", answered with 503, when ffmpeg does not run.
+	Status string              `json:"status"`
+	Tools  []chunker.ToolCheck `json:"tools"`
+}
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/healthz", ID: "getHealth", Summary: "Whether ffmpeg and whisper run, with their versions.", Response: healthReport{}, Public: true})
+}
+
+// toolHealth keeps the latest checks of ffmpeg and whisper, and the versions found at
+// startup, which jobs are recorded with until the server restarts.
+type toolHealth struct {
+	ffmpeg, whisper string
+	bad             map[string][]string
+	startup         []chunker.ToolCheck
+
+	mu     sync.Mutex
+	latest []chunker.ToolCheck
+}
+
+// checkToolsAtStartup checks ffmpeg and whisper and logs what it found. With
+// tools.strict, any error or warning stops the server.
+func checkToolsAtStartup(cfg config.Config) *toolHealth {
+	h := &toolHealth{ffmpeg: cfg.FFmpegBin, whisper: cfg.Whisper.Bin, bad: cfg.Tools.BadVersions}
+	var err error
+	if h.startup, err = setup.CheckTools(cfg, log.Printf); err != nil {
+		log.Fatal(err)
+	}
+	h.latest = h.startup
+	return h
+}
+
+// current returns the checks, running them again once they are older than
+// toolCheckInterval. A binary replaced since startup is warned about, since jobs are
+// still recorded with the version found then.
+func (h *toolHealth) current() []chunker.ToolCheck {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latest) > 0 && time.Since(h.latest[0].CheckedAt) < toolCheckInterval {
+		return h.latest
+	}
+	checks := chunker.CheckTools(context.Background(), h.ffmpeg, h.whisper, h.bad)
+	for i := range checks {
+		if i >= len(h.startup) || !checks[i].OK() || !h.startup[i].OK() {
+			continue
+		}
+		if was := h.startup[i].ToolVersion; checks[i].ToolVersion != was {
+			checks[i].Warnings = append(checks[i].Warnings, fmt.Sprintf("%s changed since the server started (was %s, sha256 %s); restart it so jobs record the new version", checks[i].Name, was.Version, was.SHA256))
+		}
+	}
+	h.latest = checks
+	return checks
+}
+
+// handleHealth serves GET /healthz for load balancers and monitoring. It needs no
+// credentials and reports only tool names, versions, and problems.
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	report := healthReport{Status: healthOK, Tools: s.tools.current()}
+	status := http.StatusOK
+	for _, check := range report.Tools {
+		switch {
+		case !check.OK() && check.Name == chunker.ToolFFmpeg:
+			report.Status = healthUnavailable
+			status = http.StatusServiceUnavailable
+		case (!check.OK() || len(check.Warnings) > 0) && report.Status == healthOK:
+			report.Status = healthDegraded
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+
+	"audi/pkg/chunker"
+)
+
+// commandFailureLog is a processor hook that writes failed ffmpeg and whisper runs to
+// the server log as they happen, tagged with the job, rather than only in the job's
+// processing log once it ends.
+type commandFailureLog struct {
+	jobID string
+}
+
+var _ chunker.CommandHook = commandFailureLog{}
+
+func (l commandFailureLog) OnCommandFinished(_ context.Context, run chunker.CommandRun) {
+	if !run.Failed() {
+		return
+	}
+	log.Printf("job %s: %s failed after %.1fs (exit %d): %s", l.jobID, filepath.Base(run.Args[0]), run.DurationSeconds, run.ExitCode, run.Error)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"audi/internal/config"
+	"audi/pkg/chunker"
+)
+
+// jobSourceUpload names form uploads, which leave Job.Source empty, in job_ids.sources.
+const jobSourceUpload = "upload"
+
+// jobIDPrefixes picks the prefix of a new job ID from its project and source.
+type jobIDPrefixes struct {
+	fallback   string
+	sources    map[string]string
+	projectKey string
+	projects   map[string]string
+}
+
+func newJobIDPrefixes(cfg config.JobIDConfig) (jobIDPrefixes, error) {
+	for source := range cfg.Sources {
+		switch source {
+		case jobSourceUpload, chunker.JobSourcePush, chunker.JobSourceOpenAI, chunker.JobSourceWatch:
+		default:
+			return jobIDPrefixes{}, fmt.Errorf("unknown source %q", source)
+		}
+	}
+	return jobIDPrefixes{
+		fallback:   cfg.Prefix,
+		sources:    cfg.Sources,
+		projectKey: cfg.ProjectKey,
+		projects:   cfg.Projects,
+	}, nil
+}
+
+// prefix returns the prefix for a job from source carrying metadata.
+func (p jobIDPrefixes) prefix(source string, metadata map[string]string) string {
+	if p.projectKey != "" {
+		if prefix, ok := p.projects[metadata[p.projectKey]]; ok {
+			return prefix
+		}
+	}
+	if source == "" {
+		source = jobSourceUpload
+	}
+	if prefix, ok := p.sources[source]; ok {
+		return prefix
+	}
+	return p.fallback
+}
+
+// jobIDFunc returns an ID generator for storage.CreateJobDir that puts the job's prefix
+// in front of an ID of the configured scheme. CreateJobDir asks for another one when a
+// job directory by that name already exists.
+func (s *server) jobIDFunc(source string, metadata map[string]string) func() string {
+	prefix := s.jobIDs.prefix(source, metadata)
+	return func() string {
+		return prefix + s.jobIDScheme.newID(time.Now())
+	}
+}
+
+// jobIDScheme generates the part of a new job ID after its prefix. An ID generated
+// later sorts after an earlier one, so job directories list in creation order.
+type jobIDScheme interface {
+	newID(now time.Time) string
+}
+
+// newJobIDScheme returns the generator of a job_ids.scheme value.
+func newJobIDScheme(name string) (jobIDScheme, error) {
+	switch name {
+	case config.JobIDULID, "":
+		return &ulidIDs{}, nil
+	case config.JobIDUUIDv7:
+		return &uuidv7IDs{}, nil
+	case config.JobIDTimestamp:
+		return timestampIDs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown scheme %q", name)
+	}
+}
+
+// timestampIDs generates the IDs of audi before schemes could be chosen: the local time
+// to the second plus four random digits. They sort by second only, and two jobs in the
+// same second collide once in 10000.
+type timestampIDs struct{}
+
+func (timestampIDs) newID(now time.Time) string {
+	var b [2]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("%s-%04d", now.Format("20060102-150405"), binary.BigEndian.Uint16(b[:])%10000)
+}
+
+// crockford is the alphabet of ULIDs, which leaves out I, L, O, and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidIDs generates ULIDs: 48 bits of Unix milliseconds and 80 random bits.
+type ulidIDs struct {
+	entropy monotonicEntropy
+}
+
+func (g *ulidIDs) newID(now time.Time) string {
+	ms, entropy := g.entropy.next(now, 1)
+	var b [16]byte
+	binary.BigEndian.PutUint16(b[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:], uint32(ms))
+	copy(b[6:], entropy[:])
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// uuidv7IDs generates version 7 UUIDs (RFC 9562): 48 bits of Unix milliseconds and 74
+// random bits around the version and variant, in lowercase hex.
+type uuidv7IDs struct {
+	entropy monotonicEntropy
+}
+
+func (g *uuidv7IDs) newID(now time.Time) string {
+	// Only the top 74 of the 80 bits are used, so stepping by 1<<6 changes them.
+	ms, entropy := g.entropy.next(now, 1<<6)
+	hi, lo := uint64(binary.BigEndian.Uint16(entropy[:2])), binary.BigEndian.Uint64(entropy[2:])
+	hi, lo = hi>>6, lo>>6|hi<<58
+	randA, randB := hi<<2|lo>>62, lo&(1<<62-1)
+
+	var b [16]byte
+	binary.BigEndian.PutUint16(b[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:], uint32(ms))
+	binary.BigEndian.PutUint16(b[6:], 0x7000|uint16(randA))
+	binary.BigEndian.PutUint64(b[8:], 1<<63|randB)
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// monotonicEntropy hands out the time and random bits of ULIDs and UUIDv7s. Within one
+// millisecond, or when the clock goes back, it adds step to the previous random bits
+// instead of drawing new ones, so IDs generated by the server keep sorting in order.
+type monotonicEntropy struct {
+	mu      sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+func (m *monotonicEntropy) next(now time.Time, step byte) (uint64, [10]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ms := uint64(now.UnixMilli()); ms > m.ms {
+		m.ms = ms
+		rand.Read(m.entropy[:])
+		return m.ms, m.entropy
+	}
+	carry := uint16(step)
+	for i := len(m.entropy) - 1; i >= 0 && carry > 0; i-- {
+		sum := uint16(m.entropy[i]) + carry
+		m.entropy[i], carry = byte(sum), sum>>8
+	}
+	if carry > 0 {
+		// The random bits ran over: borrow the next millisecond.
+		m.ms++
+	}
+	return m.ms, m.entropy
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// logFollowInterval is how often GET /jobs/{id}/logs?follow=1 looks for new output.
+const logFollowInterval = 500 * time.Millisecond
+
+// openJobLog opens the job's processing log and returns ctx streaming command output
+// into it, headed by note, with the function that closes it. When the log cannot be
+// opened the output is kept in job.json as before.
+func (s *server) openJobLog(ctx context.Context, job *chunker.Job, jobDir, note string) (context.Context, func()) {
+	jl, err := storage.OpenJobLog(jobDir, int64(s.cfg.JobLogs.MaxSize), s.cfg.JobLogs.Keep)
+	if err != nil {
+		log.Printf("job %s: %v; keeping its output in job.json", job.ID, err)
+		job.LogFile = ""
+		return ctx, func() {}
+	}
+	job.LogFile = storage.JobLogFile
+	output := chunker.NewOutputLog(jl)
+	output.Notes([]string{note})
+	return chunker.WithOutputLog(ctx, output), func() {
+		if err := jl.Close(); err != nil {
+			log.Printf("job %s: closing its log: %v", job.ID, err)
+		}
+	}
+}
+
+// appendProcessingLog adds the log entries of work done on a job after processing,
+// such as a pushed chunk, to job.json, unless they went to the job's log file.
+func appendProcessingLog(job *chunker.Job, logs []string) {
+	if job.LogFile != "" || len(logs) == 0 {
+		return
+	}
+	entry := strings.Join(logs, "\n---\n")
+	if job.ProcessingLog != "" {
+		entry = job.ProcessingLog + "\n---\n" + entry
+	}
+	job.ProcessingLog = entry
+}
+
+// handleJobLogs serves GET /jobs/{id}/logs, the job's processing log as plain text,
+// rotated files first. With follow=1 the response stays open while the job is
+// processed, passing on each line as it is written.
+func (s *server) handleJobLogs(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	files := storage.JobLogFiles(jobDir)
+	follow := r.URL.Query().Get("follow") == "1"
+	if len(files) == 0 && job.ProcessingLog == "" && !(follow && s.inFlight(jobID)) {
+		s.renderError(w, r, http.StatusNotFound, "This job has no processing log.", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Method == http.MethodHead {
+		return
+	}
+	if len(files) == 0 {
+		// Jobs processed before the log file was written keep their output in job.json.
+		io.WriteString(w, job.ProcessingLog)
+	}
+	current := filepath.Join(jobDir, filepath.FromSlash(storage.JobLogFile))
+	for _, name := range files {
+		if name == current && follow {
+			break
+		}
+		if err := copyFileTo(w, name); err != nil {
+			log.Printf("job %s: reading its log: %v", jobID, err)
+			return
+		}
+	}
+	if follow {
+		s.followJobLog(w, r, jobID, current)
+	}
+}
+
+// followJobLog writes the log at path to w as it grows until the job is no longer being
+// processed or the client goes away. A rotated file is read to its end before the new
+// one is opened.
+func (s *server) followJobLog(w http.ResponseWriter, r *http.Request, jobID, path string) {
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	for {
+		// Judge whether the job is still running before reading, so the last lines
+		// written before it stopped are still passed on.
+		running := s.inFlight(jobID)
+		for {
+			if f == nil {
+				if f, _ = os.Open(path); f == nil {
+					break
+				}
+			}
+			if _, err := io.Copy(w, f); err != nil {
+				return
+			}
+			latest, err := os.Stat(path)
+			if err != nil {
+				break
+			}
+			if info, err := f.Stat(); err == nil && os.SameFile(info, latest) {
+				break
+			}
+			f.Close()
+			f = nil
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if !running {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// copyFileTo writes the file at path to w.
+func copyFileTo(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
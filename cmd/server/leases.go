@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// instanceName names this server process as a lease owner.
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "server"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// runRecovery looks for abandoned jobs every lease TTL and runs them again. A job is
+// abandoned when it is unfinished, nobody holds a live lease on it, and it has not been
+// saved for a TTL: its server or worker crashed, or stopped before starting it. With a
+// queue only jobs a worker started count, as pending ones wait in the broker.
+func (s *server) runRecovery() {
+	for range time.Tick(s.leaseTTL) {
+		jobs, err := storage.ListJobs(s.jobsDir)
+		if err != nil {
+			log.Printf("recovery: %v", err)
+			continue
+		}
+		for _, job := range jobs {
+			if s.abandoned(job) && !s.leased(job.ID) {
+				s.recoverJob(job.ID)
+			}
+		}
+	}
+}
+
+// abandoned reports whether job is unfinished, unsaved for a TTL, and not processed by
+// this server; recoverJob checks again under the job's lease.
+func (s *server) abandoned(job *chunker.Job) bool {
+	switch {
+	case job.Status == chunker.JobStatusProcessing:
+	case job.Status == chunker.JobStatusPending && s.queue == nil:
+	default:
+		return false
+	}
+	if job.Source == chunker.JobSourcePush || job.OriginalVideoPath == "" || job.OriginalDiscardedAt != nil {
+		return false
+	}
+	if time.Since(job.UpdatedAt) < s.leaseTTL {
+		return false
+	}
+	// A dispatched job is in flight here while its worker holds the lease; only the
+	// lease tells whether that worker is still alive.
+	if s.queue == nil {
+		s.mu.Lock()
+		_, here := s.jobsInFlight[job.ID]
+		s.mu.Unlock()
+		return !here
+	}
+	return true
+}
+
+// leased reports whether anybody holds a live lease on the job.
+func (s *server) leased(jobID string) bool {
+	lease, err := storage.ReadLease(storage.JobDir(s.jobsDir, jobID))
+	return err == nil && !lease.Expired(time.Now())
+}
+
+// recoverJob runs an abandoned job again. It decides under the job's lease, so no other
+// server recovers the job at the same time, and saves the job before releasing it, so
+// the job no longer looks abandoned to them while processJob or a worker takes it up.
+func (s *server) recoverJob(jobID string) {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	lease, err := storage.AcquireLease(jobDir, s.instance, s.leaseTTL)
+	if errors.Is(err, storage.ErrLeaseHeld) {
+		return
+	}
+	if err != nil {
+		log.Printf("job %s: recovery: %v", jobID, err)
+		return
+	}
+	job, err := storage.LoadJob(jobDir)
+	if err != nil || !s.abandoned(job) {
+		lease.Release()
+		return
+	}
+	err = s.saveJob(jobDir, job, storage.ChangeUpdated)
+	lease.Release()
+	if err != nil {
+		log.Printf("job %s: recovery: %v", jobID, err)
+		return
+	}
+	log.Printf("job %s: was abandoned while %s; running it again", jobID, job.Status)
+	if err := s.rerunJob(job, jobDir, false); err != nil {
+		log.Printf("job %s: recovery: %v", jobID, err)
+	}
+}
@@ -2,56 +2,195 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
-	"io"
+	"io/fs"
 	"log"
+	"maps"
 	"math"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"audi/internal/model"
-	"audi/internal/processor"
+	"audi/internal/config"
+	"audi/internal/queue"
+	"audi/internal/s3"
+	"audi/internal/setup"
 	"audi/internal/storage"
+	"audi/pkg/chunker"
+	"audi/web"
 )
 
 // server coordinates job metadata, templates, and processing workers.
 type server struct {
-	jobsDir      string
-	templates    *template.Template
-	processor    *processor.Processor
-	defaultChunk int
-	makeBase64   bool
-	mu           sync.Mutex
-	jobsInFlight map[string]*model.Job
+	jobsDir          string
+	templates        *template.Template
+	processor        *chunker.Processor
+	tools            *toolHealth
+	defaultChunk     int
+	makeBase64       bool
+	base64Enabled    bool
+	mu               sync.Mutex
+	jobsInFlight     map[string]*chunker.Job
+	workerSlots      chan struct{}
+	chunkConcurrency int
+	journal          *storage.Journal
+	throughput       *storage.Throughput
+	dataDir          string
+	usage            *storage.UsageCounter
+	quota            int64
+	discardOriginals bool
+	transcriptOnly   bool
+	failureInjection bool
+	mergedAudio      chunker.MergedFormat
+	serveOriginals   bool
+	qualityGate      chunker.QualityGate
+	progressWeights  chunker.ProgressWeights
+	transcriptSets   []chunker.TranscriptSet
+	chunkLimit       chunker.ChunkLimit
+	setChunkLimits   map[string]chunker.ChunkLimit
+	escalation       chunker.EscalationPolicy
+	deduper          *storage.Deduper
+	router           *storage.Router
+	transcripts      *storage.TranscriptIndex
+	webhooks         []webhook
+	jobIDs           jobIDPrefixes
+	jobIDScheme      jobIDScheme
+	presets          *storage.PresetStore
+	fetchCache       *storage.FetchCache
+	fetchClient      *http.Client
+	publicURL        string
+	basePath         string
+	cors             *corsPolicy
+	openAPI          []byte
+	keepRevisions    int
+	// queue, when set, hands jobs to workers; dispatched maps the jobs waiting for
+	// one to the channel their finished copy arrives on.
+	queue      *queue.Queue
+	dispatched map[string]chan *chunker.Job
+	// directUploads, when set, issues pre-signed URLs for uploading to the S3 bucket.
+	directUploads *directUploads
+	// peaks caches the waveforms of chunk audio for the chunk listing.
+	peaks peakCache
+	// instance names this server as the owner of the job leases it takes.
+	instance string
+	leaseTTL time.Duration
+	// cfg is the configuration the server started with, flags included.
+	cfg config.Config
 }
 
 // templateData exposes job-related state to HTML templates.
 type templateData struct {
-	Jobs           []*model.Job
-	Job            *model.Job
-	WhisperActive  bool
-	Base64Enabled  bool
-	DefaultChunk   int
-	Error          string
-	ChunkValue     int
-	ChunkUnit      string
-	ChunkUnits     []chunkUnitOption
-	HumanChunk     string
-	TotalDuration  float64
-	ChunkWarning   string
-	Flash          string
+	Jobs          []*chunker.Job
+	Job           *chunker.Job
+	Topics        []topic
+	Sentiment     *jobSentiment
+	WhisperActive bool
+	SummaryActive bool
+	RedactActive  bool
+	GPUActive     bool
+	Base64Enabled bool
+	DefaultChunk  int
+	Error         string
+	ChunkValue    int
+	ChunkUnit     string
+	ChunkUnits    []chunkUnitOption
+	HumanChunk    string
+	TotalDuration float64
+	ChunkWarning  string
+	Flash         string
+	// CSRFToken goes into every form as csrf_token; see checkCSRF.
+	CSRFToken      string
 	DeleteDisabled bool
 	DeleteReason   string
 	HasDuration    bool
+	Remainder      string
+	Remainders     []remainderOption
+	Filters        []selectOption
+	Tracks         []selectOption
+	MergedFormats  []selectOption
+	MergedAudio    string
+	SubtitleModes  []selectOption
+	RedactModes    []selectOption
+	TranscriptSets []chunker.TranscriptSet
+	// ChunkLimits maps "" to whisper's chunk limit in seconds and each transcript set to
+	// the limit that applies when it is picked; empty when nothing is limited.
+	ChunkLimits    map[string]float64
+	WhisperFlags   []string
+	SortBy         string
+	Tag            string
+	Query          string
+	QueryHits      []transcriptHit
+	QueryTotal     int
+	Presets        []storage.Preset
+	Absolute       bool
+	ForceDiscard   bool
+	ForceTextOnly  bool
+	ServeOriginals bool
+	DiskUsed       int64
+	DiskLogical    int64
+	Quota          int64
+}
+
+type remainderOption struct {
+	Label string
+	Value string
+}
+
+var remainderOptions = []remainderOption{
+	{Label: "Keep short final chunk", Value: string(chunker.RemainderKeep)},
+	{Label: "Merge into previous chunk", Value: string(chunker.RemainderMerge)},
+	{Label: "Pad with silence", Value: string(chunker.RemainderPad)},
+}
+
+type selectOption struct {
+	Label string
+	Value string
+}
+
+var filterOptions = []selectOption{
+	{Label: "None", Value: string(chunker.FilterNone)},
+	{Label: "Remove rumble (high-pass)", Value: string(chunker.FilterHighpass)},
+	{Label: "Denoise", Value: string(chunker.FilterDenoise)},
+	{Label: "Normalize loudness", Value: string(chunker.FilterNormalize)},
+	{Label: "Voice cleanup (all of the above)", Value: string(chunker.FilterVoice)},
+}
+
+var trackOptions = []selectOption{
+	{Label: "Track 1", Value: ""},
+	{Label: "Track 2", Value: "2"},
+	{Label: "Track 3", Value: "3"},
+	{Label: "Track 4", Value: "4"},
+	{Label: "All tracks, mixed down", Value: string(chunker.TrackMix)},
+	{Label: "Each track separately", Value: string(chunker.TrackSeparate)},
+}
+
+var mergedOptions = []selectOption{
+	{Label: "None", Value: ""},
+	{Label: "MP3", Value: string(chunker.MergedMP3)},
+	{Label: "Opus", Value: string(chunker.MergedOpus)},
+}
+
+var redactionOptions = []selectOption{
+	{Label: "None", Value: ""},
+	{Label: "Transcripts", Value: string(chunker.RedactText)},
+	{Label: "Transcripts and bleeped audio", Value: string(chunker.RedactAudio)},
+}
+
+var subtitleOptions = []selectOption{
+	{Label: "None", Value: ""},
+	{Label: "SRT for the original", Value: string(chunker.SubtitlesSRT)},
+	{Label: "SRT and MP4 with soft subtitles", Value: string(chunker.SubtitlesMP4)},
 }
 
 type chunkUnitOption struct {
@@ -68,46 +207,257 @@ var chunkUnits = []chunkUnitOption{
 
 // main wires configuration, templates, and HTTP handlers before serving traffic.
 func main() {
-	rand.Seed(time.Now().UnixNano())
-
-	addr := flag.String("addr", ":8080", "HTTP listen address")
-	dataDir := flag.String("data", "data", "root directory for generated files")
-	defaultChunk := flag.Int("chunk", 300, "default chunk length in seconds")
+	configPath := flag.String("config", "", "path to a YAML config file")
+	addr := flag.String("addr", "", "HTTP listen address (overrides config)")
+	dataDir := flag.String("data", "", "root directory for generated files (overrides config)")
+	defaultChunk := flag.Int("chunk", 0, "default chunk length in seconds (overrides config)")
 	disableBase64 := flag.Bool("no-base64", false, "disable generation of base64 dumps")
+	workers := flag.Int("workers", 0, "maximum jobs processed concurrently (overrides config)")
+	encodeWorkers := flag.Int("encode-workers", 0, "maximum ffmpeg encoding passes across all jobs (overrides config)")
+	transcribeWorkers := flag.Int("transcribe-workers", 0, "maximum whisper transcriptions across all jobs (overrides config)")
+	templatesDir := flag.String("templates", "", "load templates from this directory instead of the embedded copy (for development)")
+	watchDir := flag.String("watch", "", "create jobs for media files dropped into this directory (overrides config)")
+	tlsCert := flag.String("tls-cert", "", "serve HTTPS with this PEM certificate file (overrides config)")
+	tlsKey := flag.String("tls-key", "", "private key for -tls-cert (overrides config)")
+	autocertDomains := flag.String("autocert", "", "serve HTTPS with certificates obtained from Let's Encrypt for these comma-separated host names (overrides config)")
+	basePathFlag := flag.String("base-path", "", "serve every route under this path prefix, e.g. /audio-chunker, behind a reverse proxy (overrides config)")
+	httpRedirect := flag.String("http-redirect", "", "also listen for plain HTTP on this address, e.g. :80, and redirect to HTTPS (overrides config)")
 	flag.Parse()
 
-	jobsDir := filepath.Join(*dataDir, "jobs")
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("loading configuration: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "data":
+			cfg.DataDir = *dataDir
+		case "chunk":
+			cfg.DefaultChunkSeconds = *defaultChunk
+		case "no-base64":
+			cfg.DisableBase64 = *disableBase64
+		case "workers":
+			cfg.Workers = *workers
+		case "encode-workers":
+			cfg.EncodeWorkers = *encodeWorkers
+		case "transcribe-workers":
+			cfg.TranscribeWorkers = *transcribeWorkers
+		case "watch":
+			cfg.Watch.Dir = *watchDir
+		case "tls-cert":
+			cfg.TLS.CertFile = *tlsCert
+		case "tls-key":
+			cfg.TLS.KeyFile = *tlsKey
+		case "autocert":
+			cfg.TLS.AutocertDomains = strings.Fields(strings.ReplaceAll(*autocertDomains, ",", " "))
+		case "http-redirect":
+			cfg.TLS.RedirectAddr = *httpRedirect
+		case "base-path":
+			cfg.BasePath = *basePathFlag
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	jobsDir := filepath.Join(cfg.DataDir, "jobs")
 	if err := os.MkdirAll(jobsDir, 0o755); err != nil {
 		log.Fatalf("unable to create jobs directory: %v", err)
 	}
 
-	funcMap := template.FuncMap{
-		"formatSeconds": formatSeconds,
-		"uppercase":     strings.ToUpper,
-		"add": func(a, b float64) float64 {
-			return a + b
-		},
-		"formatDurationHuman": formatDurationHuman,
+	assign := make(map[string]storage.Class, len(cfg.Storage.Artefacts))
+	for artefact, name := range cfg.Storage.Artefacts {
+		class := cfg.Storage.Classes[name]
+		assign[artefact] = storage.Class{
+			Name:         name,
+			Path:         class.Path,
+			URL:          class.URL,
+			Fetch:        class.Fetch,
+			FetchHeaders: class.FetchHeaders,
+			Cache:        class.Cache,
+		}
+	}
+	router, err := storage.NewRouter(jobsDir, assign)
+	if err != nil {
+		log.Fatalf("invalid config: storage: %v", err)
+	}
+
+	templateFS := web.Templates()
+	if *templatesDir != "" {
+		templateFS = os.DirFS(*templatesDir)
 	}
 
-	tmpl, err := template.New("app").Funcs(funcMap).ParseGlob(filepath.Join("web", "templates", "*.gohtml"))
+	basePath := strings.TrimSuffix(cfg.BasePath, "/")
+	tmpl, err := parseTemplates(templateFS, templateEnv{router: router, basePath: basePath})
 	if err != nil {
 		log.Fatalf("parsing templates: %v", err)
 	}
 
-	whisperArgs := strings.Fields(os.Getenv("WHISPER_ARGS"))
+	journal, err := storage.OpenJournal(filepath.Join(cfg.DataDir, "changes.jsonl"))
+	if err != nil {
+		log.Fatalf("opening change journal: %v", err)
+	}
+
+	throughput, err := storage.OpenThroughput(filepath.Join(cfg.DataDir, "throughput.json"))
+	if err != nil {
+		log.Fatalf("opening throughput history: %v", err)
+	}
+
+	deduper, err := storage.OpenDeduper(cfg.DataDir, filepath.Join(cfg.DataDir, "cache", "dedup-index.json"))
+	if err != nil {
+		log.Fatalf("opening dedup index: %v", err)
+	}
+
+	webhooks, err := newWebhooks(cfg.Webhooks)
+	if err != nil {
+		log.Fatalf("parsing webhook templates: %v", err)
+	}
+
+	presets, err := storage.OpenPresets(filepath.Join(cfg.DataDir, "presets.json"), configPresets(cfg.Presets))
+	if err != nil {
+		log.Fatalf("invalid config: presets: %v", err)
+	}
+
+	jobIDs, err := newJobIDPrefixes(cfg.JobIDs)
+	if err != nil {
+		log.Fatalf("invalid config: job_ids.sources: %v", err)
+	}
+	jobIDScheme, err := newJobIDScheme(cfg.JobIDs.Scheme)
+	if err != nil {
+		log.Fatalf("invalid config: job_ids.scheme: %v", err)
+	}
+
+	qualityGate := chunker.QualityGate{
+		MinLoudnessDB:      cfg.QualityGate.MinLoudnessDB,
+		MinDurationSeconds: cfg.QualityGate.MinChunkSeconds,
+		MaxClippingPercent: cfg.QualityGate.MaxClippingPercent,
+	}
+
+	escalation := chunker.EscalationPolicy{
+		MinConfidence: cfg.Whisper.Escalation.MinConfidence,
+		Args:          cfg.Whisper.Escalation.Args,
+	}
+
+	transcriptSets := make([]chunker.TranscriptSet, 0, len(cfg.Whisper.Sets))
+	setChunkLimits := make(map[string]chunker.ChunkLimit, len(cfg.Whisper.Sets))
+	for _, set := range cfg.Whisper.Sets {
+		transcriptSets = append(transcriptSets, chunker.TranscriptSet{Name: set.Name, Args: set.Args})
+		setChunkLimits[set.Name] = chunkLimitOf(set.ChunkLimit)
+	}
+	if err := chunker.ValidateTranscriptSets(transcriptSets); err != nil {
+		log.Fatalf("invalid config: whisper.sets: %v", err)
+	}
+
+	tools := checkToolsAtStartup(cfg)
+	processor, err := setup.Processor(cfg, log.Printf, chunker.WithTools(tools.startup...))
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
 
 	srv := &server{
-		jobsDir:      jobsDir,
-		templates:    tmpl,
-		defaultChunk: *defaultChunk,
-		makeBase64:   !*disableBase64,
-		processor: &processor.Processor{
-			FFmpegBin:   os.Getenv("FFMPEG_BIN"),
-			WhisperBin:  os.Getenv("WHISPER_BIN"),
-			WhisperArgs: whisperArgs,
-		},
-		jobsInFlight: make(map[string]*model.Job),
+		jobsDir:          jobsDir,
+		templates:        tmpl,
+		defaultChunk:     cfg.DefaultChunkSeconds,
+		makeBase64:       !cfg.DisableBase64 && cfg.PregenerateBase64,
+		base64Enabled:    !cfg.DisableBase64,
+		processor:        processor,
+		tools:            tools,
+		chunkConcurrency: cfg.ChunkConcurrency(),
+		cfg:              cfg,
+		jobsInFlight:     make(map[string]*chunker.Job),
+		workerSlots:      make(chan struct{}, cfg.Workers),
+		journal:          journal,
+		throughput:       throughput,
+		dataDir:          cfg.DataDir,
+		quota:            int64(cfg.Quota),
+		discardOriginals: cfg.DiscardOriginals,
+		transcriptOnly:   cfg.TranscriptOnly,
+		failureInjection: cfg.FailureInjection,
+		keepRevisions:    cfg.KeepRevisions,
+		instance:         instanceName(),
+		leaseTTL:         cfg.LeaseTTL,
+		mergedAudio:      chunker.MergedFormat(cfg.MergedAudio),
+		serveOriginals:   !cfg.DisableOriginalDownload,
+		qualityGate:      qualityGate,
+		progressWeights:  setup.ProgressWeights(cfg.ProgressWeights),
+		transcriptSets:   transcriptSets,
+		chunkLimit:       chunkLimitOf(cfg.Whisper.ChunkLimit),
+		setChunkLimits:   setChunkLimits,
+		escalation:       escalation,
+		deduper:          deduper,
+		router:           router,
+		transcripts:      storage.NewTranscriptIndex(),
+		webhooks:         webhooks,
+		publicURL:        strings.TrimSuffix(cfg.PublicURL, "/"),
+		basePath:         basePath,
+		cors:             newCORSPolicy(cfg.CORS),
+		jobIDs:           jobIDs,
+		jobIDScheme:      jobIDScheme,
+		presets:          presets,
+		fetchCache:       storage.NewFetchCache(filepath.Join(cfg.DataDir, "cache", "artefacts"), int64(cfg.Storage.CacheMax)),
+		fetchClient:      newFetchClient(),
+		usage:            storage.NewUsageCounter(cfg.DataDir, usageMaxAge),
+	}
+	for _, preset := range presets.List() {
+		if err := srv.validatePreset(preset); err != nil {
+			log.Fatalf("invalid config: presets: %v", err)
+		}
+	}
+
+	if resident := srv.processor.Resident; resident != nil {
+		if err := resident.Start(); err != nil {
+			log.Fatalf("invalid config: whisper.server: %v", err)
+		}
+		stopResidentOnSignal(resident)
+	}
+
+	if n, err := srv.transcripts.Build(srv.jobsDir); err != nil {
+		log.Printf("indexing transcripts: %v", err)
+	} else {
+		log.Printf("indexed %d chunk transcripts for search", n)
+	}
+
+	if cfg.Queue.URL != "" {
+		broker, err := queue.Open(cfg.Queue.URL)
+		if err != nil {
+			log.Fatalf("invalid config: %v", err)
+		}
+		srv.queue = queue.New(broker, cfg.Queue.Name)
+		srv.dispatched = make(map[string]chan *chunker.Job)
+		go srv.collectFinished()
+		log.Printf("publishing jobs to the %q queue for workers", cfg.Queue.Name)
+	}
+
+	if c := cfg.S3; c.Bucket != "" {
+		client, err := s3.New(c.Endpoint, c.Region, c.Bucket, c.AccessKey, c.SecretKey, c.PathStyle)
+		if err != nil {
+			log.Fatalf("invalid config: %v", err)
+		}
+		srv.directUploads = &directUploads{
+			client: client,
+			dir:    filepath.Join(cfg.DataDir, "uploads"),
+			prefix: c.Prefix,
+			expiry: c.URLExpiry,
+			keep:   c.KeepObjects,
+		}
+		log.Printf("issuing direct upload URLs for bucket %q", c.Bucket)
+	}
+
+	go srv.runRecovery()
+
+	if cfg.Retention > 0 {
+		go srv.runRetention(cfg.Retention)
+	}
+
+	if cfg.Watch.Dir != "" {
+		watcher, err := newFolderWatcher(srv, cfg.Watch, filepath.Join(cfg.DataDir, "watch.json"))
+		if err != nil {
+			log.Fatalf("invalid config: watch: %v", err)
+		}
+		log.Printf("watching %s for new media (%s mode)", cfg.Watch.Dir, cfg.Watch.Mode)
+		go watcher.run()
 	}
 
 	// Register HTTP endpoints for the dashboard, uploads, and per-job assets.
@@ -115,21 +465,74 @@ func main() {
 	mux.HandleFunc("/", srv.handleIndex)
 	mux.HandleFunc("/upload", srv.handleUpload)
 	mux.HandleFunc("/jobs/", srv.handleJobDetail)
+	mux.HandleFunc("/feeds/", srv.handleFeed)
+	mux.HandleFunc("/api/jobs", srv.handleAPIJobs)
+	mux.HandleFunc("/api/jobs/search", srv.handleAPISearch)
+	mux.HandleFunc("/api/v1/changes", srv.handleAPIChanges)
+	mux.HandleFunc("/api/v1/search", srv.handleAPITranscriptSearch)
+	mux.HandleFunc("/api/v1/sentiment", srv.handleAPISentiment)
+	mux.HandleFunc("/api/v1/jobs", srv.handleAPICreateJob)
+	mux.HandleFunc("/api/v1/jobs/plan", srv.handlePlan)
+	mux.HandleFunc("/api/v1/jobs:batchDelete", srv.handleBatch(batchDeleteOp))
+	mux.HandleFunc("/api/v1/jobs:batchRetry", srv.handleBatch(batchRetryOp))
+	mux.HandleFunc("/api/v1/jobs:batchTag", srv.handleBatch(batchTagOp))
+	mux.HandleFunc("/api/v1/jobs:import", srv.handleAPIJobImport)
+	mux.HandleFunc("/api/v1/jobs/", srv.handleAPIJob)
+	mux.HandleFunc("/api/v1/triggers/", srv.handleTrigger)
+	mux.HandleFunc("/api/v1/presets", srv.handleAPIPresets)
+	mux.HandleFunc("/api/v1/presets/", srv.handleAPIPreset)
+	mux.HandleFunc("/api/v1/uploads", srv.handleAPIUploads)
+	mux.HandleFunc("/api/v1/uploads/", srv.handleAPIUploadComplete)
+	mux.HandleFunc("/api/v1/whisper-server", srv.handleAPIWhisperServer)
+	mux.HandleFunc("/api/v1/config:export", srv.handleAPIConfigExport)
+	mux.HandleFunc("/api/v1/config:import", srv.handleAPIConfigImport)
+	mux.HandleFunc("/api/v1/openapi.json", srv.handleOpenAPI)
+	mux.HandleFunc("/healthz", srv.handleHealth)
+	mux.HandleFunc("/v1/audio/transcriptions", srv.handleOpenAITranscription)
+
+	fileServer := http.FileServer(http.Dir(cfg.DataDir))
+	mux.Handle("/files/", http.StripPrefix("/files/", srv.guardOriginals(srv.assetHeaders(srv.fetchMissing(fileServer)))))
+
+	if err := checkAPIRoutes(mux); err != nil {
+		log.Fatal(err)
+	}
+	if srv.openAPI, err = buildOpenAPI(basePath, cfg.Auth.Enabled()); err != nil {
+		log.Fatal(err)
+	}
 
-	fileServer := http.FileServer(http.Dir(*dataDir))
-	mux.Handle("/files/", http.StripPrefix("/files/", fileServer))
+	var handler http.Handler = srv.sameOrigin(mux)
+	if cfg.Auth.Enabled() {
+		handler = basicAuth(cfg.Auth, handler)
+	}
+	handler = withRequestID(srv.mountAt(basePath, srv.withCORS(handler)))
+	handler = fromTrustedProxies(cfg.TrustedProxyNetworks(), handler)
 
-	log.Printf("listening on %s", *addr)
-	if err := http.ListenAndServe(*addr, mux); err != nil {
+	if err := listenAndServe(cfg, handler); err != nil {
 		log.Fatalf("server stopped: %v", err)
 	}
 }
 
 // handleIndex renders the landing page with upload form and job list.
 func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	jobs, err := storage.ListJobs(s.jobsDir)
+	if r.URL.Path != "/" {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	sortBy := parseSortOrder(r.URL.Query().Get("sort"))
+	tag := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+	jobs, err := storage.ListJobsWith(s.jobsDir, storage.ListOptions{SortBy: sortBy, Tag: tag})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to list jobs: %v", err), http.StatusInternalServerError)
+		s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+		return
+	}
+
+	if wantsJSON(r) {
+		if jobs == nil {
+			jobs = []*chunker.Job{}
+		}
+		w.Header().Add("Vary", "Accept")
+		writeJSON(w, http.StatusOK, jobs)
 		return
 	}
 
@@ -137,115 +540,527 @@ func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	flash := r.URL.Query().Get("flash")
 	errorMsg := r.URL.Query().Get("error")
 	data := templateData{
-		Jobs:          jobs,
-		WhisperActive: s.processor.WhisperBin != "",
-		Base64Enabled: s.makeBase64,
-		DefaultChunk:  s.defaultChunk,
-		ChunkValue:    value,
-		ChunkUnit:     unit,
-		ChunkUnits:    chunkUnits,
-		HumanChunk:    formatDurationHuman(s.defaultChunk),
-		Flash:         flash,
-		Error:         errorMsg,
+		Jobs:           jobs,
+		WhisperActive:  s.processor.WhisperBin != "",
+		SummaryActive:  s.processor.Summarizer != nil,
+		RedactActive:   s.processor.Redactor != nil,
+		GPUActive:      s.processor.Acceleration.Active(),
+		Base64Enabled:  s.base64Enabled,
+		DefaultChunk:   s.defaultChunk,
+		ChunkValue:     value,
+		ChunkUnit:      unit,
+		ChunkUnits:     chunkUnits,
+		HumanChunk:     formatDurationHuman(s.defaultChunk),
+		Flash:          flash,
+		Error:          errorMsg,
+		Remainder:      string(chunker.RemainderKeep),
+		Remainders:     remainderOptions,
+		Filters:        filterOptions,
+		Tracks:         trackOptions,
+		MergedFormats:  mergedOptions,
+		MergedAudio:    string(s.mergedAudio),
+		SubtitleModes:  subtitleOptions,
+		RedactModes:    redactionOptions,
+		TranscriptSets: s.transcriptSets,
+		ChunkLimits:    s.chunkLimitsBySet(),
+		WhisperFlags:   chunker.JobWhisperFlags(),
+		SortBy:         string(sortBy),
+		Tag:            tag,
+		Quota:          s.quota,
+		ForceDiscard:   s.discardOriginals,
+		ForceTextOnly:  s.transcriptOnly,
+		Query:          query,
+		Presets:        s.presets.List(),
+	}
+	if query != "" {
+		data.QueryHits, data.QueryTotal = s.searchTranscripts(query, maxSearchLimit)
+	}
+	if logical, physical, err := s.usage.Usage(); err == nil {
+		data.DiskUsed = physical
+		data.DiskLogical = logical
+	}
+	s.renderPage(w, r, "index.gohtml", data)
+}
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/jobs", ID: "listJobs", Summary: "All jobs, newest first.",
+			Query: []apiParam{
+				{Name: "sort", Description: "created (default) or updated."},
+				{Name: "tag", Description: "Only jobs carrying this tag."},
+				{Name: "updated_since", Description: "Only jobs saved after this RFC 3339 timestamp."},
+			},
+			Response: []*chunker.Job{}},
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/changes", ID: "listChanges", Summary: "Job created, updated, and deleted events after a cursor.",
+			Query:    []apiParam{{Name: "since", Description: "The cursor of the previous page."}, {Name: "limit", Type: "integer"}},
+			Response: changesPage{}},
+	)
+}
+
+// changesPage is one page of GET /api/v1/changes.
+type changesPage struct {
+	Changes []storage.Change `json:"changes"`
+	Cursor  string           `json:"cursor"`
+	HasMore bool             `json:"hasMore"`
+	// Truncated reports that changes after the cursor were rotated out of the journal,
+	// so the client should re-list the jobs before following the feed again.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// handleAPIJobs returns job metadata as JSON, supporting ?sort=, ?tag=, and ?updated_since=
+// (RFC 3339) so external mirrors can fetch only what changed since their last sync.
+func (s *server) handleAPIJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
 	}
-	if err := s.templates.ExecuteTemplate(w, "index.gohtml", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+	opts := storage.ListOptions{
+		SortBy: parseSortOrder(r.URL.Query().Get("sort")),
+		Tag:    r.URL.Query().Get("tag"),
+	}
+	if since := strings.TrimSpace(r.URL.Query().Get("updated_since")); since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			s.renderError(w, r, http.StatusBadRequest, "updated_since must be an RFC 3339 timestamp.", nil)
+			return
+		}
+		opts.UpdatedSince = t
+	}
+
+	jobs, err := storage.ListJobsWith(s.jobsDir, opts)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+		return
+	}
+	if jobs == nil {
+		jobs = []*chunker.Job{}
 	}
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleAPIChanges streams the change journal after ?since=<cursor> so mirrors
+// can replay creates, updates, and deletes without re-listing every job.
+func (s *server) handleAPIChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+
+	var cursor uint64
+	if since := strings.TrimSpace(r.URL.Query().Get("since")); since != "" {
+		parsed, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			s.renderError(w, r, http.StatusBadRequest, "Invalid since cursor.", nil)
+			return
+		}
+		cursor = parsed
+	}
+
+	limit := 500
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, "Invalid limit.", nil)
+			return
+		}
+		limit = min(parsed, 5000)
+	}
+
+	truncated := cursor+1 < s.journal.FirstSeq()
+	changes, more, err := s.journal.Since(cursor, limit)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The change feed could not be read.", err)
+		return
+	}
+	if changes == nil {
+		changes = []storage.Change{}
+	}
+
+	next := cursor
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].Seq
+	}
+
+	writeJSON(w, http.StatusOK, changesPage{
+		Changes:   changes,
+		Cursor:    strconv.FormatUint(next, 10),
+		HasMore:   more,
+		Truncated: truncated,
+	})
+}
+
+// usageMaxAge is how old the cached size of the data directory may get before it is
+// walked again; saves and deletions keep it roughly current in between.
+const usageMaxAge = time.Minute
+
+// checkQuota renders 507 and returns false when storing the request body would take the
+// data directory past the quota.
+func (s *server) checkQuota(w http.ResponseWriter, r *http.Request) bool {
+	if s.quota <= 0 {
+		return true
+	}
+	_, used, err := s.usage.Usage()
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "Storage usage could not be checked.", err)
+		return false
+	}
+	if used+max(r.ContentLength, 0) > s.quota {
+		s.renderError(w, r, http.StatusInsufficientStorage, fmt.Sprintf("Storage quota exceeded: %s of %s used.", formatBytes(used), formatBytes(s.quota)), nil)
+		return false
+	}
+	return true
 }
 
 // handleUpload accepts the multipart video upload and enqueues processing.
 func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		s.seeOther(w, r, "/")
+		return
+	}
+
+	if !s.checkQuota(w, r) {
 		return
 	}
 
 	if err := r.ParseMultipartForm(512 << 20); err != nil {
-		http.Error(w, fmt.Sprintf("failed to parse form: %v", err), http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "The upload form could not be read.", err)
+		return
+	}
+	if !s.checkCSRF(w, r) {
 		return
 	}
 
 	file, header, err := r.FormFile("video")
 	if err != nil {
-		http.Error(w, "video field is required", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "Choose a video file to upload.", nil)
 		return
 	}
 	defer file.Close()
 
-	chunkDuration := resolveChunkDuration(r, s.defaultChunk)
-
-	transcribe := r.FormValue("transcribe") == "on"
+	settings, err := s.parseUploadSettings(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
 
-	jobID := newJobID()
-	jobDir := storage.JobDir(s.jobsDir, jobID)
+	jobID, jobDir, err := storage.CreateJobDir(s.jobsDir, s.jobIDFunc("", settings.metadata))
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be created.", err)
+		return
+	}
 
-	if err := storage.EnsureJobSubdirs(jobDir, "original", "chunks", "base64", "transcripts"); err != nil {
-		http.Error(w, fmt.Sprintf("failed to prepare job directories: %v", err), http.StatusInternalServerError)
+	if err := s.router.PrepareJob(jobDir, storage.ArtefactDirs...); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be created.", err)
 		return
 	}
 
-	originalPath := filepath.Join(jobDir, "original", header.Filename)
-	out, err := os.Create(originalPath)
+	displayName := displayFileName(header.Filename)
+	originalRel, sum, err := storage.StoreOriginal(jobDir, displayName, file)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to create file: %v", err), http.StatusInternalServerError)
+		s.renderError(w, r, http.StatusInternalServerError, "The uploaded file could not be saved.", err)
 		return
 	}
-	if _, err := io.Copy(out, file); err != nil {
-		out.Close()
-		http.Error(w, fmt.Sprintf("failed to save upload: %v", err), http.StatusInternalServerError)
-		return
+
+	job, opts := s.newUploadJob(settings, displayName, sum)
+	job.ID = jobID
+	job.OriginalVideoPath = originalRel
+
+	if !settings.force {
+		if dup := s.findDuplicateJob(job, opts); dup != nil {
+			// The job was never saved, so dropping its directory leaves no trace.
+			if err := storage.RemoveJob(jobDir); err != nil {
+				log.Printf("job %s: failed to remove duplicate upload: %v", jobID, err)
+			}
+			w.Header().Set("X-Duplicate-Of", dup.ID)
+			flash := "This file was already processed with the same options, so no new job was created. Upload it again with “Process again” ticked to force a fresh run."
+			s.seeOther(w, r, "/jobs/"+dup.ID+"?flash="+url.QueryEscape(flash))
+			return
+		}
 	}
-	if err := out.Close(); err != nil {
-		http.Error(w, fmt.Sprintf("failed to finalise upload: %v", err), http.StatusInternalServerError)
+
+	if err := s.startJob(jobDir, job, opts); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
 		return
 	}
 
-	job := &model.Job{
-		ID:                     jobID,
-		OriginalFileName:       header.Filename,
-		OriginalVideoPath:      filepath.ToSlash(filepath.Join("original", header.Filename)),
+	target := "/jobs/" + jobID
+	if notice := settings.segment.limitNotice(); notice != "" {
+		target += "?flash=" + url.QueryEscape(notice)
+	}
+	s.seeOther(w, r, target)
+}
+
+// uploadSettings are the validated per-job choices of the upload form.
+type uploadSettings struct {
+	segment         segmentForm
+	title           string
+	metadata        map[string]string
+	transcribe      bool
+	transcriptOnly  bool
+	skipSilence     bool
+	wordTimestamps  bool
+	subtitles       chunker.SubtitleMode
+	summarize       bool
+	keywords        bool
+	sentiment       bool
+	chapters        bool
+	redact          chunker.RedactionMode
+	pii             bool
+	cpuOnly         bool
+	fault           chunker.Fault
+	discardOriginal bool
+	force           bool
+	transcriptSets  []chunker.TranscriptSet
+	whisperArgs     []string
+	recordingStart  *time.Time
+	preset          string
+}
+
+// parseUploadSettings reads everything but the file from a parsed upload form. Its
+// errors are meant for the user.
+func (s *server) parseUploadSettings(r *http.Request) (uploadSettings, error) {
+	var (
+		settings uploadSettings
+		err      error
+	)
+	if settings.preset, err = s.applyPreset(r); err != nil {
+		return settings, err
+	}
+	if settings.segment, err = s.parseSegmentForm(r); err != nil {
+		return settings, err
+	}
+	if settings.title, err = chunker.NormalizeTitle(r.FormValue("title")); err != nil {
+		return settings, err
+	}
+	if settings.metadata, err = chunker.ParseMetadataPairs(r.Form["metadata"]); err != nil {
+		return settings, err
+	}
+
+	settings.transcribe = r.FormValue("transcribe") == "on"
+	settings.transcriptOnly = s.transcriptOnly || r.FormValue("transcript_only") == "on"
+	if s.transcriptOnly {
+		settings.transcribe = true
+	} else if settings.transcriptOnly && !settings.transcribe {
+		return settings, errors.New("Keeping only transcripts needs “Attempt transcription” ticked.")
+	}
+	settings.skipSilence = r.FormValue("skip_silence") == "on"
+	settings.wordTimestamps = settings.transcribe && r.FormValue("word_timestamps") == "on"
+	settings.discardOriginal = s.discardOriginals || r.FormValue("discard_original") == "on"
+	settings.force = r.FormValue("force") == "on"
+	settings.cpuOnly = r.FormValue("cpu_only") == "on"
+	if value := r.FormValue("inject_failure"); value != "" {
+		if !s.failureInjection {
+			return settings, errors.New("Failure injection is disabled on this server.")
+		}
+		if settings.fault, err = chunker.ParseFault(value); err != nil {
+			return settings, err
+		}
+		// A simulated failure must not be answered with an earlier job's result.
+		settings.force = true
+	}
+
+	if settings.transcribe {
+		if settings.transcriptSets, err = chunker.SelectTranscriptSets(s.transcriptSets, r.Form["transcript_set"]); err != nil {
+			return settings, err
+		}
+		if settings.whisperArgs, err = chunker.ParseWhisperArgs(r.FormValue("whisper_args")); err != nil {
+			return settings, err
+		}
+		if err := settings.segment.limitTo(s.maxChunkSeconds(settings.transcriptSets)); err != nil {
+			return settings, err
+		}
+		if settings.subtitles, err = chunker.ParseSubtitleMode(r.FormValue("subtitles")); err != nil {
+			return settings, err
+		}
+		if settings.summarize = r.FormValue("summarize") == "on"; settings.summarize && s.processor.Summarizer == nil {
+			return settings, errors.New("Summaries are not enabled on this server.")
+		}
+		settings.keywords = r.FormValue("keywords") == "on"
+		settings.sentiment = r.FormValue("sentiment") == "on"
+		settings.chapters = r.FormValue("chapters") == "on"
+		settings.pii = r.FormValue("pii") == "on"
+		if settings.redact, err = chunker.ParseRedactionMode(r.FormValue("redact")); err != nil {
+			return settings, err
+		}
+		switch {
+		case settings.redact != chunker.RedactNone && s.processor.Redactor == nil:
+			return settings, errors.New("Redaction is not configured on this server.")
+		case settings.redact == chunker.RedactAudio && !settings.wordTimestamps:
+			return settings, errors.New("Bleeping redacted audio needs “Store word-level timings” ticked.")
+		case settings.redact == chunker.RedactAudio && settings.transcriptOnly:
+			return settings, errors.New("Bleeping redacted audio needs the chunk audio; untick “Keep transcripts only”.")
+		}
+	}
+
+	settings.recordingStart, err = parseRecordingStart(r.FormValue("recording_start"), r.FormValue("tz_offset"))
+	return settings, err
+}
+
+// newUploadJob builds the pending job and processing options for an original with the
+// given SHA-256. The caller fills in the job's ID and OriginalVideoPath.
+func (s *server) newUploadJob(settings uploadSettings, displayName, sum string) (*chunker.Job, chunker.Options) {
+	segment := settings.segment
+	opts := chunker.Options{
+		MakeBase64:     s.makeBase64,
+		Transcribe:     settings.transcribe,
+		SkipSilence:    settings.skipSilence,
+		WordTimestamps: settings.wordTimestamps,
+		Subtitles:      settings.subtitles,
+		Summarize:      settings.summarize,
+		Fault:          settings.fault,
+		TranscriptSets: settings.transcriptSets,
+		WhisperArgs:    settings.whisperArgs,
+		TranscriptOnly: settings.transcriptOnly,
+		CPUOnly:        settings.cpuOnly,
+	}
+	segment.apply(&opts)
+	if settings.transcribe {
+		opts.QualityGate = s.qualityGate
+		opts.Escalation = s.escalation
+		opts.ExtractKeywords = settings.keywords
+		opts.AnalyzeSentiment = settings.sentiment
+		opts.DetectChapters = settings.chapters
+		opts.Redact = settings.redact
+		opts.DetectPII = settings.pii
+	}
+
+	job := &chunker.Job{
+		Title:                  settings.title,
+		Metadata:               settings.metadata,
+		Preset:                 settings.preset,
+		OriginalFileName:       displayName,
 		CreatedAt:              time.Now(),
-		ChunkDurationSeconds:   chunkDuration,
-		TranscriptionRequested: transcribe,
-		Status:                 model.JobStatusPending,
+		ChunkDurationSeconds:   segment.chunkDuration,
+		RequestedChunkSeconds:  segment.requestedDuration,
+		ChunkCount:             segment.chunkCount,
+		TranscriptionRequested: settings.transcribe,
+		TranscriptOnly:         settings.transcriptOnly,
+		SkipSilence:            settings.skipSilence,
+		WordTimestamps:         settings.wordTimestamps,
+		MergedFormat:           string(segment.mergedFormat),
+		VideoClips:             segment.videoClips,
+		Subtitles:              string(settings.subtitles),
+		Summarize:              settings.summarize,
+		ExtractKeywords:        settings.keywords,
+		AnalyzeSentiment:       settings.sentiment,
+		DetectChapters:         settings.chapters,
+		Redact:                 string(settings.redact),
+		DetectPII:              settings.pii,
+		CPUOnly:                settings.cpuOnly,
+		InjectedFault:          string(settings.fault),
+		WhisperArgs:            settings.whisperArgs,
+		RemainderPolicy:        string(segment.remainder),
+		FadeMillis:             segment.fadeMillis,
+		PaddingMillis:          segment.paddingMillis,
+		FilterPreset:           string(segment.filters),
+		TrackMode:              string(segment.trackMode),
+		AudioTrack:             segment.audioTrack,
+		DiscardOriginal:        settings.discardOriginal,
+		OriginalSHA256:         sum,
+		SegmentKey:             opts.SegmentKey(),
+		Status:                 chunker.JobStatusPending,
+	}
+	if opts.QualityGate.Enabled() {
+		gate := opts.QualityGate
+		job.QualityGate = &gate
+	}
+	if opts.Escalation.Enabled() {
+		escalation := opts.Escalation
+		job.Escalation = &escalation
+	}
+	for _, set := range settings.transcriptSets {
+		job.TranscriptSets = append(job.TranscriptSets, set.Name)
 	}
 
-	if err := storage.SaveJob(jobDir, job); err != nil {
-		http.Error(w, fmt.Sprintf("failed to persist job metadata: %v", err), http.StatusInternalServerError)
-		return
+	if settings.recordingStart != nil {
+		job.RecordingStart = settings.recordingStart
+		job.RecordingStartSource = chunker.RecordingStartUser
+	}
+	return job, opts
+}
+
+// startJob saves a newly created job and queues it for processing.
+func (s *server) startJob(jobDir string, job *chunker.Job, opts chunker.Options) error {
+	if err := s.saveJob(jobDir, job, storage.ChangeCreated); err != nil {
+		return err
 	}
 
 	s.mu.Lock()
-	s.jobsInFlight[jobID] = job
+	s.jobsInFlight[job.ID] = job
 	s.mu.Unlock()
 
-	go s.processJob(job, jobDir, originalPath, processor.Options{
-		ChunkDurationSeconds: chunkDuration,
-		MakeBase64:           s.makeBase64,
-		Transcribe:           transcribe,
-	})
-
-	http.Redirect(w, r, "/jobs/"+jobID, http.StatusSeeOther)
+	go s.processJob(job, jobDir, filepath.Join(jobDir, filepath.FromSlash(job.OriginalVideoPath)), opts)
+	return nil
 }
 
 // handleJobDetail serves the detailed view for a single job or its assets.
 func (s *server) handleJobDetail(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
 	if len(parts) == 0 || parts[0] == "" {
-		http.NotFound(w, r)
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
 		return
 	}
 
 	jobID := parts[0]
+	asJSON := wantsJSON(r)
+	if len(parts) == 1 && strings.HasSuffix(jobID, ".json") {
+		jobID = strings.TrimSuffix(jobID, ".json")
+		asJSON = true
+	}
+	if !storage.ValidJobID(jobID) {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", nil)
+		return
+	}
 
 	if len(parts) >= 2 {
 		switch parts[1] {
 		case "delete":
 			s.handleJobDelete(w, r, jobID)
 			return
+		case "original":
+			s.handleOriginalDownload(w, r, jobID)
+			return
+		case "verify":
+			s.handleJobVerify(w, r, jobID)
+			return
+		case "purge":
+			s.handleJobPurge(w, r, jobID)
+			return
+		case "retry-chunks":
+			s.handleJobRetryChunks(w, r, jobID)
+			return
+		case "logs":
+			s.handleJobLogs(w, r, jobID)
+			return
+		case "rechunk":
+			s.handleJobRechunk(w, r, jobID)
+			return
 		case "raw":
 			s.serveJobAsset(w, r, jobID, parts[1:])
 			return
+		case "download":
+			s.handleChunkDownload(w, r, jobID, parts[2:])
+			return
+		case "chunks":
+			s.handleChunkBase64(w, r, jobID, parts[2:])
+			return
+		case "offset":
+			s.handleTimeOffset(w, r, jobID)
+			return
+		case "annotations":
+			s.handleJobAnnotations(w, r, jobID)
+			return
+		case "recording-start":
+			s.handleRecordingStart(w, r, jobID)
+			return
+		case "transcript.txt":
+			s.handleTranscriptExport(w, r, jobID)
+			return
+		case "report", "report.json":
+			s.handleJobReport(w, r, jobID, parts[1] == "report.json")
+			return
 		default:
 			s.serveJobAsset(w, r, jobID, parts[1:])
 			return
@@ -254,20 +1069,39 @@ func (s *server) handleJobDetail(w http.ResponseWriter, r *http.Request) {
 
 	job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to load job: %v", err), http.StatusNotFound)
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	if asJSON {
+		w.Header().Add("Vary", "Accept")
+		writeJSON(w, http.StatusOK, job)
 		return
 	}
 
 	data := templateData{
-		Job:           job,
-		WhisperActive: s.processor.WhisperBin != "",
-		Base64Enabled: s.makeBase64,
-		DefaultChunk:  s.defaultChunk,
-		ChunkUnits:    chunkUnits,
-		HumanChunk:    formatDurationHuman(job.ChunkDurationSeconds),
+		Job:            job,
+		WhisperActive:  s.processor.WhisperBin != "",
+		Base64Enabled:  s.base64Enabled,
+		DefaultChunk:   s.defaultChunk,
+		ChunkUnits:     chunkUnits,
+		HumanChunk:     formatDurationHuman(job.ChunkDurationSeconds),
+		Remainder:      job.RemainderPolicy,
+		Remainders:     remainderOptions,
+		Absolute:       r.URL.Query().Get("timeline") == "absolute" && job.RecordingStart != nil,
+		Flash:          r.URL.Query().Get("flash"),
+		ServeOriginals: s.serveOriginals,
+		Error:          r.URL.Query().Get("error"),
 	}
 
-	totalDuration := totalDurationSeconds(job.Chunks)
+	data.Topics = topicTimeline(job)
+	data.Topics = data.Topics[:min(len(data.Topics), pageTopics)]
+	if average, counts, ok := sentimentOverview(job); ok {
+		data.Sentiment = &jobSentiment{AverageScore: average, Counts: counts}
+	}
+
+	// The time offset moves chunks along the timeline without adding audio.
+	totalDuration := totalDurationSeconds(job.Chunks) - job.TimeOffsetSeconds
 	data.TotalDuration = totalDuration
 	data.ChunkWarning = buildChunkWarning(job, totalDuration)
 	data.HasDuration = totalDuration > 0
@@ -275,95 +1109,605 @@ func (s *server) handleJobDetail(w http.ResponseWriter, r *http.Request) {
 	value, unit := secondsToValueUnit(job.ChunkDurationSeconds)
 	data.ChunkValue = value
 	data.ChunkUnit = unit
-	s.mu.Lock()
-	_, inFlight := s.jobsInFlight[jobID]
-	s.mu.Unlock()
+	inFlight := s.inFlight(jobID)
 	data.DeleteDisabled = inFlight
 	if inFlight {
 		data.DeleteReason = "Job is currently processing. Wait for it to finish before deleting."
 	}
 
-	if err := s.templates.ExecuteTemplate(w, "job.gohtml", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	s.renderPage(w, r, "job.gohtml", data)
 }
 
 // serveJobAsset safely exposes generated files under /jobs/{id}/raw/... .
 func (s *server) serveJobAsset(w http.ResponseWriter, r *http.Request, jobID string, parts []string) {
 	if len(parts) < 2 || parts[0] != "raw" {
-		http.NotFound(w, r)
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return
+	}
+
+	rel := path.Join(parts[1:]...)
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		s.renderError(w, r, http.StatusBadRequest, "Invalid file path.", nil)
 		return
 	}
 
-	assetPath := filepath.Join(parts[1:]...)
-	clean := filepath.Clean(assetPath)
-	if strings.Contains(clean, "..") {
-		http.Error(w, "invalid asset path", http.StatusBadRequest)
+	if isStagingPath(path.Join("jobs", jobID, rel)) {
+		s.renderError(w, r, http.StatusNotFound, "File not found.", nil)
+		return
+	}
+	if !s.serveOriginals && isOriginalPath(path.Join("jobs", jobID, rel)) {
+		s.renderError(w, r, http.StatusForbidden, "Downloading originals is disabled on this server.", nil)
 		return
 	}
 
-	fullPath := filepath.Join(storage.JobDir(s.jobsDir, jobID), clean)
-	http.ServeFile(w, r, fullPath)
+	s.serveJobFile(w, r, jobID, rel)
+}
+
+// handleChunkDownload serves /jobs/{id}/download/{index} as an attachment. With
+// ?naming=timestamp the filename carries the chunk's absolute start time.
+func (s *server) handleChunkDownload(w http.ResponseWriter, r *http.Request, jobID string, parts []string) {
+	if len(parts) != 1 {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return
+	}
+	_, job, chunk, ok := s.lookupChunkAudio(w, r, jobID, parts[0])
+	if !ok {
+		return
+	}
+
+	name := filepath.Base(chunk.AudioFile)
+	if r.URL.Query().Get("naming") == "timestamp" {
+		if stamped, ok := timestampedChunkName(job, chunk); ok {
+			name = stamped
+		}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	s.serveJobFile(w, r, job.ID, chunk.AudioFile)
+}
+
+// lookupChunkAudio resolves a chunk index from the URL to a chunk whose audio is still on
+// disk, rendering the error response itself when it is not.
+func (s *server) lookupChunkAudio(w http.ResponseWriter, r *http.Request, jobID, rawIndex string) (string, *chunker.Job, chunker.Chunk, bool) {
+	index, err := strconv.Atoi(rawIndex)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return "", nil, chunker.Chunk{}, false
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return "", nil, chunker.Chunk{}, false
+	}
+	if index < 0 || index >= len(job.Chunks) {
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		return "", nil, chunker.Chunk{}, false
+	}
+	if job.ArtefactsPurgedAt != nil {
+		s.renderError(w, r, http.StatusGone, "This chunk's audio was purged; only the transcript and metadata remain.", nil)
+		return "", nil, chunker.Chunk{}, false
+	}
+	if job.Chunks[index].AudioFile == "" {
+		s.renderError(w, r, http.StatusGone, "This job kept transcripts only; the chunk audio was not retained.", nil)
+		return "", nil, chunker.Chunk{}, false
+	}
+	return jobDir, job, job.Chunks[index], true
+}
+
+// timestampedChunkName builds e.g. "20240131T093000Z_chunk_003.wav" from the recording start.
+func timestampedChunkName(job *chunker.Job, chunk chunker.Chunk) (string, bool) {
+	start, ok := job.ChunkWallClock(chunk)
+	if !ok {
+		return "", false
+	}
+	return start.UTC().Format("20060102T150405Z") + "_" + filepath.Base(chunk.AudioFile), true
+}
+
+// parseRecordingStart accepts RFC 3339 or an HTML datetime-local value. The latter carries
+// no zone, so the browser's UTC offset in minutes (as from Date.getTimezoneOffset) may be supplied.
+func parseRecordingStart(value, tzOffset string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	loc := time.Local
+	if offset, err := strconv.Atoi(strings.TrimSpace(tzOffset)); err == nil {
+		loc = time.FixedZone("", -offset*60)
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid recording start %q", value)
 }
 
 // processJob runs the ffmpeg/whisper pipeline and persists the job state as it evolves.
-func (s *server) processJob(job *model.Job, jobDir, originalPath string, opts processor.Options) {
-	job.Status = model.JobStatusProcessing
+func (s *server) processJob(job *chunker.Job, jobDir, originalPath string, opts chunker.Options) {
+	if s.queue != nil {
+		s.dispatchJob(job, jobDir, originalPath, opts)
+		return
+	}
+	lease, err := storage.AcquireLease(jobDir, s.instance, s.leaseTTL)
+	if err != nil {
+		log.Printf("job %s: not processing it here: %v", job.ID, err)
+		s.forgetJob(job.ID)
+		return
+	}
+	ctx, release := lease.Hold(context.Background())
+	defer release()
+	if current, err := storage.LoadJob(jobDir); err == nil && current.Status != job.Status {
+		log.Printf("job %s: already %s elsewhere", job.ID, current.Status)
+		s.forgetJob(job.ID)
+		return
+	}
+
+	s.workerSlots <- struct{}{}
+	defer func() { <-s.workerSlots }()
+	opts.TranscribeConcurrency = s.chunkConcurrency
+	opts.FFmpegTimeout = s.cfg.Commands.FFmpegTimeout
+	opts.WhisperTimeoutPerChunk = s.cfg.Commands.WhisperTimeout
+
+	job.Status = chunker.JobStatusProcessing
+	job.ResetStages()
 	job.ErrorMessage = ""
 	job.ProcessingLog = ""
-	if err := storage.SaveJob(jobDir, job); err != nil {
+	job.Commands = nil
+	ctx, closeLog := s.openJobLog(ctx, job, jobDir, "processing started")
+	defer closeLog()
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
 		log.Printf("job %s: failed to update status: %v", job.ID, err)
 	}
 
-	ctx := context.Background()
+	s.adoptChunkDonor(job, &opts)
+
+	tracker := storage.NewProgressTracker(job, jobDir, s.throughput, s.progressWeights, opts.Transcribe && s.processor.WhisperBin != "")
+	// Progress-only saves stay out of the change journal, which would otherwise get an
+	// entry about once a second for every running job.
+	opts.Progress = func(p chunker.Progress) {
+		if err := tracker.Update(p); err != nil {
+			log.Printf("job %s: %v", job.ID, err)
+		}
+	}
+	opts.Hooks = append(opts.Hooks, commandFailureLog{jobID: job.ID}, chunker.StageRecorder{Job: job, Changed: func() {
+		if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+			log.Printf("job %s: failed to save stages: %v", job.ID, err)
+		}
+	}})
+
 	result, err := s.processor.Process(ctx, jobDir, originalPath, opts)
-	if err != nil {
-		job.Status = model.JobStatusFailed
-		job.ErrorMessage = err.Error()
-		job.Chunks = result.Chunks
-		job.ProcessingLog = strings.Join(append(result.Logs, err.Error()), "\n---\n")
-		completed := time.Now()
-		job.CompletedAt = &completed
+	if cause := context.Cause(ctx); errors.Is(cause, storage.ErrLeaseLost) {
+		log.Printf("job %s: %v; leaving the job to its new owner", job.ID, cause)
+		s.forgetJob(job.ID)
+		return
+	}
+	if finishErr := tracker.Finish(err == nil); finishErr != nil {
+		log.Printf("job %s: %v", job.ID, finishErr)
+	}
+	job.ApplyResult(result, err)
+	s.finishJob(job, jobDir, originalPath)
+}
+
+// adoptChunkDonor points opts at the chunks of an earlier job over the same original
+// with the same segment options, when there is one, so they are linked in rather than cut again.
+func (s *server) adoptChunkDonor(job *chunker.Job, opts *chunker.Options) {
+	donor := s.findChunkDonor(job)
+	if donor == nil {
+		return
+	}
+	opts.ReuseChunksDir = filepath.Join(storage.JobDir(s.jobsDir, donor.ID), "chunks")
+	job.ReusedChunksFrom = donor.ID
+	if job.RecordingStart == nil && donor.RecordingStartSource == chunker.RecordingStartMetadata {
+		job.RecordingStart = donor.RecordingStart
+		job.RecordingStartSource = chunker.RecordingStartMetadata
+	}
+}
+
+// finishJob runs once a job's processing ended, here or on a worker: it discards the
+// original if asked to, seals the artefacts, saves the job, and notifies webhooks.
+func (s *server) finishJob(job *chunker.Job, jobDir, originalPath string) {
+	if job.Status == chunker.JobStatusCompleted && job.DiscardOriginal {
+		if err := os.Remove(originalPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("job %s: failed to discard original: %v", job.ID, err)
+		} else {
+			discarded := time.Now()
+			job.OriginalDiscardedAt = &discarded
+		}
+	}
+	if job.Status == chunker.JobStatusCompleted {
+		s.sealArtefacts(jobDir, job)
 	} else {
-		job.Status = model.JobStatusCompleted
-		job.ErrorMessage = ""
-		job.Chunks = result.Chunks
-		job.ProcessingLog = strings.Join(result.Logs, "\n---\n")
-		completed := time.Now()
-		job.CompletedAt = &completed
+		s.writeReport(jobDir, job)
 	}
+	s.remeasure(jobDir, job)
 
-	if err := storage.SaveJob(jobDir, job); err != nil {
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
 		log.Printf("job %s: failed to persist completion: %v", job.ID, err)
 	}
+	if job.Status == chunker.JobStatusCompleted {
+		s.notifyWebhooks(eventCompleted, job)
+	} else {
+		s.notifyWebhooks(eventFailed, job)
+	}
+
+	s.forgetJob(job.ID)
+}
+
+// remeasure refreshes the job's disk usage after its files changed, moving the data
+// directory's cached total by the difference.
+func (s *server) remeasure(jobDir string, job *chunker.Job) {
+	usage, err := storage.MeasureJob(jobDir)
+	if err != nil {
+		log.Printf("job %s: failed to measure disk usage: %v", job.ID, err)
+		return
+	}
+	s.usage.Replace(job.DiskUsage, usage)
+	job.DiskUsage = usage
+}
 
+// forgetJob marks the job as no longer processed by this server.
+func (s *server) forgetJob(jobID string) {
 	s.mu.Lock()
-	delete(s.jobsInFlight, job.ID)
+	delete(s.jobsInFlight, jobID)
 	s.mu.Unlock()
 }
 
+// sealArtefacts runs once a job's artefacts are final: identical files are linked to
+// copies in other jobs, the manifest and integrity report are written, and the
+// transcripts become searchable. Failures are logged, not fatal.
+func (s *server) sealArtefacts(jobDir string, job *chunker.Job) {
+	if _, err := s.transcripts.IndexJob(jobDir, job); err != nil {
+		log.Printf("job %s: failed to index transcripts: %v", job.ID, err)
+	}
+	if saved, err := s.deduper.DedupJob(jobDir); err != nil {
+		log.Printf("job %s: dedup failed: %v", job.ID, err)
+	} else if saved > 0 {
+		log.Printf("job %s: dedup linked %s of identical artefacts", job.ID, formatBytes(saved))
+	}
+	if _, err := storage.WriteManifest(jobDir, job); err != nil {
+		log.Printf("job %s: failed to write manifest: %v", job.ID, err)
+	}
+	s.writeReport(jobDir, job)
+}
+
+// writeReport saves the job's integrity report, logging rather than failing the job.
+func (s *server) writeReport(jobDir string, job *chunker.Job) {
+	if _, err := storage.WriteReport(jobDir, job); err != nil {
+		log.Printf("job %s: failed to write integrity report: %v", job.ID, err)
+	}
+}
+
+// findChunkDonor returns a finished job that chunked the same original with the same
+// segment options and still has its chunk files, or nil when none exists.
+func (s *server) findChunkDonor(job *chunker.Job) *chunker.Job {
+	if job.OriginalSHA256 == "" || job.SegmentKey == "" {
+		return nil
+	}
+	jobs, err := storage.ListJobs(s.jobsDir)
+	if err != nil {
+		return nil
+	}
+	for _, candidate := range jobs {
+		if candidate.ID == job.ID || candidate.Status != chunker.JobStatusCompleted {
+			continue
+		}
+		if candidate.OriginalSHA256 != job.OriginalSHA256 || candidate.SegmentKey != job.SegmentKey {
+			continue
+		}
+		chunks, err := filepath.Glob(filepath.Join(storage.JobDir(s.jobsDir, candidate.ID), "chunks", "chunk_*.wav"))
+		if err != nil || len(chunks) == 0 {
+			continue
+		}
+		return candidate
+	}
+	return nil
+}
+
+// findDuplicateJob returns a completed job whose results a new upload would merely
+// repeat: same original bytes, same segment options, and the same extras requested.
+// Jobs whose artefacts were purged do not count.
+func (s *server) findDuplicateJob(job *chunker.Job, opts chunker.Options) *chunker.Job {
+	if job.OriginalSHA256 == "" {
+		return nil
+	}
+	jobs, err := storage.ListJobs(s.jobsDir)
+	if err != nil {
+		return nil
+	}
+	for _, candidate := range jobs {
+		if candidate.ID == job.ID || candidate.Status != chunker.JobStatusCompleted || candidate.ArtefactsPurgedAt != nil {
+			continue
+		}
+		if candidate.OriginalSHA256 != job.OriginalSHA256 || candidate.SegmentKey != job.SegmentKey {
+			continue
+		}
+		if candidate.TranscriptionRequested != job.TranscriptionRequested || candidate.SkipSilence != job.SkipSilence {
+			continue
+		}
+		// A transcript-only job has no chunk audio to share, and a full job should not
+		// satisfy a request to keep no audio.
+		if candidate.TranscriptOnly != job.TranscriptOnly {
+			continue
+		}
+		if !sameQualityGate(candidate.QualityGate, job.QualityGate) || !slices.Equal(candidate.TranscriptSets, job.TranscriptSets) {
+			continue
+		}
+		if !slices.Equal(candidate.WhisperArgs, job.WhisperArgs) {
+			continue
+		}
+		// Metadata ties a job to a downstream record, so another record's job is no match.
+		if !maps.Equal(candidate.Metadata, job.Metadata) {
+			continue
+		}
+		if !sameEscalation(candidate.Escalation, job.Escalation) {
+			continue
+		}
+		if job.WordTimestamps && !candidate.WordTimestamps {
+			continue
+		}
+		if job.MergedFormat != "" && candidate.MergedFormat != job.MergedFormat {
+			continue
+		}
+		if job.VideoClips && !candidate.VideoClips {
+			continue
+		}
+		if job.Subtitles != "" && candidate.Subtitles != job.Subtitles {
+			continue
+		}
+		if job.Summarize && (candidate.Summary == nil || candidate.Summary.Error != "") {
+			continue
+		}
+		if job.ExtractKeywords && !candidate.ExtractKeywords {
+			continue
+		}
+		if job.AnalyzeSentiment && !candidate.AnalyzeSentiment {
+			continue
+		}
+		if job.DetectChapters && candidate.ChaptersFile == "" {
+			continue
+		}
+		if job.Redact != "" && candidate.Redact != job.Redact {
+			continue
+		}
+		if job.DetectPII && !candidate.DetectPII {
+			continue
+		}
+		if opts.MakeBase64 && len(candidate.Chunks) > 0 && candidate.Chunks[0].Base64File == "" {
+			continue
+		}
+		return candidate
+	}
+	return nil
+}
+
+// sameQualityGate reports whether two jobs were gated with the same thresholds.
+func sameQualityGate(a, b *chunker.QualityGate) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameEscalation reports whether two jobs used the same escalation policy.
+func sameEscalation(a, b *chunker.EscalationPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.MinConfidence == b.MinConfidence && slices.Equal(a.Args, b.Args)
+}
+
+// saveJob persists job metadata and records the change in the journal. Created jobs
+// replace whatever was stored; other saves go through storage.SaveLatest.
+func (s *server) saveJob(jobDir string, job *chunker.Job, changeType storage.ChangeType) error {
+	if changeType == storage.ChangeCreated {
+		if err := storage.CreateJob(jobDir, job); err != nil {
+			return err
+		}
+	} else if err := storage.SaveLatest(jobDir, job); err != nil {
+		return err
+	}
+	if _, err := s.journal.Append(changeType, job.ID, job); err != nil {
+		log.Printf("job %s: failed to record change: %v", job.ID, err)
+	}
+	return nil
+}
+
+// updateJob applies fn to the latest stored copy of the job and saves it atomically,
+// recording the change in the journal.
+func (s *server) updateJob(jobDir string, fn func(*chunker.Job) error) (*chunker.Job, error) {
+	job, err := storage.UpdateJob(jobDir, fn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.journal.Append(storage.ChangeUpdated, job.ID, job); err != nil {
+		log.Printf("job %s: failed to record change: %v", job.ID, err)
+	}
+	return job, nil
+}
+
+// deleteJob removes a job directory and records the deletion in the journal.
+func (s *server) deleteJob(jobID string) error {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	if err := s.deduper.Forget(jobDir); err != nil {
+		log.Printf("job %s: failed to update dedup index: %v", jobID, err)
+	}
+	job, _ := storage.LoadJob(jobDir)
+	if err := storage.RemoveJob(jobDir); err != nil {
+		return err
+	}
+	if job != nil {
+		s.usage.Replace(job.DiskUsage, nil)
+	}
+	s.transcripts.RemoveJob(jobID)
+	if err := s.fetchCache.RemoveJob(jobID); err != nil {
+		log.Printf("job %s: failed to clear fetched artefacts: %v", jobID, err)
+	}
+	if _, err := s.journal.Append(storage.ChangeDeleted, jobID, nil); err != nil {
+		log.Printf("job %s: failed to record deletion: %v", jobID, err)
+	}
+	return nil
+}
+
 func (s *server) handleJobDelete(w http.ResponseWriter, r *http.Request, jobID string) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
 		return
 	}
 
-	s.mu.Lock()
-	_, inFlight := s.jobsInFlight[jobID]
-	s.mu.Unlock()
+	inFlight := s.inFlight(jobID)
 	if inFlight {
-		http.Redirect(w, r, "/?error="+url.QueryEscape("Unable to delete while processing"), http.StatusSeeOther)
+		s.seeOther(w, r, "/?error="+url.QueryEscape("Unable to delete while processing"))
 		return
 	}
 
-	jobDir := storage.JobDir(s.jobsDir, jobID)
-	if err := os.RemoveAll(jobDir); err != nil {
+	if err := s.deleteJob(jobID); err != nil {
 		log.Printf("job %s: delete failed: %v", jobID, err)
-		http.Redirect(w, r, "/?error="+url.QueryEscape("Failed to delete job"), http.StatusSeeOther)
+		s.seeOther(w, r, "/?error="+url.QueryEscape("Failed to delete job"))
+		return
+	}
+
+	s.seeOther(w, r, "/?flash="+url.QueryEscape("Job deleted"))
+}
+
+// handleJobVerify re-hashes a job's artefacts against manifest.json. JSON clients get
+// the full report; the job page gets a flash summary.
+func (s *server) handleJobVerify(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	report, err := storage.VerifyJob(jobDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.renderError(w, r, http.StatusNotFound, "This job has no integrity manifest; it was processed before manifests were written.", nil)
+		return
+	}
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job's files could not be verified.", err)
 		return
 	}
 
-	http.Redirect(w, r, "/?flash="+url.QueryEscape("Job deleted"), http.StatusSeeOther)
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+	jobURL := "/jobs/" + jobID
+	if report.OK() {
+		s.seeOther(w, r, jobURL+"?flash="+url.QueryEscape(fmt.Sprintf("All %d files match the manifest", report.Checked)))
+		return
+	}
+	msg := fmt.Sprintf("Integrity check failed: %d corrupt, %d missing, %d unexpected", len(report.Corrupt), len(report.Missing), len(report.Unexpected))
+	s.seeOther(w, r, jobURL+"?error="+url.QueryEscape(msg))
+}
+
+// handleJobPurge deletes a job's heavy artefacts (original, chunks, base64) but keeps
+// job.json and transcripts, so the job stays listed and searchable.
+func (s *server) handleJobPurge(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	jobURL := "/jobs/" + jobID
+	inFlight := s.inFlight(jobID)
+	if inFlight {
+		s.seeOther(w, r, jobURL+"?error="+url.QueryEscape("Unable to purge while processing"))
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	var before int64
+	if usage, err := storage.MeasureJob(jobDir); err == nil {
+		before = usage.Exclusive
+	}
+	if err := s.deduper.Forget(jobDir); err != nil {
+		log.Printf("job %s: failed to update dedup index: %v", jobID, err)
+	}
+	if err := storage.PurgeArtefacts(jobDir); err != nil {
+		log.Printf("job %s: purge failed: %v", jobID, err)
+		s.seeOther(w, r, jobURL+"?error="+url.QueryEscape("Failed to purge artefacts"))
+		return
+	}
+
+	now := time.Now()
+	job.ArtefactsPurgedAt = &now
+	if job.OriginalDiscardedAt == nil {
+		job.OriginalDiscardedAt = &now
+	}
+	s.remeasure(jobDir, job)
+	if job.DiskUsage != nil {
+		before -= job.DiskUsage.Exclusive
+	}
+	if _, err := storage.WriteManifest(jobDir, job); err != nil {
+		log.Printf("job %s: failed to rewrite manifest: %v", jobID, err)
+	}
+	s.writeReport(jobDir, job)
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+
+	flash := "Artefacts purged"
+	if before > 0 {
+		flash += "; freed " + formatBytes(before)
+	}
+	s.seeOther(w, r, jobURL+"?flash="+url.QueryEscape(flash))
+}
+
+// writeJSON encodes v as an indented JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("encoding JSON response: %v", err)
+	}
+}
+
+// wantsJSON reports whether the client prefers JSON over HTML, so UI routes can
+// double as API endpoints. Browsers list text/html first and keep getting pages.
+func wantsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		switch mediaType {
+		case "application/json":
+			return true
+		case "text/html", "application/xhtml+xml":
+			return false
+		}
+	}
+	return false
+}
+
+// parseSortOrder maps the ?sort= query value onto a storage order, defaulting to creation time.
+func parseSortOrder(value string) storage.SortOrder {
+	if storage.SortOrder(value) == storage.SortByUpdated {
+		return storage.SortByUpdated
+	}
+	return storage.SortByCreated
 }
 
 func resolveChunkDuration(r *http.Request, fallback int) int {
@@ -389,10 +1733,91 @@ func resolveChunkDuration(r *http.Request, fallback int) int {
 	return fallback
 }
 
-// newJobID generates a timestamped identifier that keeps jobs roughly ordered.
-func newJobID() string {
-	timestamp := time.Now().Format("20060102-150405")
-	return fmt.Sprintf("%s-%04d", timestamp, rand.Intn(10000))
+// segmentForm holds the upload form fields that decide how the audio is cut and shaped,
+// shared by uploads and plans.
+type segmentForm struct {
+	chunkDuration int
+	chunkCount    int
+	remainder     chunker.RemainderPolicy
+	filters       chunker.FilterPreset
+	trackMode     chunker.TrackMode
+	audioTrack    int
+	mergedFormat  chunker.MergedFormat
+	videoClips    bool
+	fadeMillis    int
+	paddingMillis int
+	// maxChunkSeconds and requestedDuration are set by limitTo.
+	maxChunkSeconds   float64
+	requestedDuration int
+}
+
+// parseSegmentForm reads the chunking fields of a parsed upload form. Its errors are
+// meant for the user.
+func (s *server) parseSegmentForm(r *http.Request) (segmentForm, error) {
+	f := segmentForm{
+		chunkDuration: resolveChunkDuration(r, s.defaultChunk),
+		fadeMillis:    resolveMillis(r.FormValue("fade_ms")),
+		paddingMillis: resolveMillis(r.FormValue("pad_ms")),
+		videoClips:    r.FormValue("video_clips") == "on",
+	}
+	if r.FormValue("chunk_mode") == "count" {
+		n, err := strconv.Atoi(strings.TrimSpace(r.FormValue("chunk_count")))
+		if err != nil || n <= 0 {
+			return f, errors.New("chunk count must be a positive number")
+		}
+		if n > chunker.MaxChunkCount {
+			return f, fmt.Errorf("chunk count must be at most %d", chunker.MaxChunkCount)
+		}
+		f.chunkCount = n
+	}
+
+	var err error
+	if f.remainder, err = chunker.ParseRemainderPolicy(r.FormValue("remainder")); err != nil {
+		return f, err
+	}
+	if f.filters, err = chunker.ParseFilterPreset(r.FormValue("filters")); err != nil {
+		return f, err
+	}
+	if f.trackMode, f.audioTrack, err = chunker.ParseTrackSelection(r.FormValue("audio_track")); err != nil {
+		return f, err
+	}
+	if f.mergedFormat, err = chunker.ParseMergedFormat(r.FormValue("merged_audio")); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// apply copies the form's chunking settings into opts.
+func (f segmentForm) apply(opts *chunker.Options) {
+	opts.ChunkDurationSeconds = f.chunkDuration
+	opts.ChunkCount = f.chunkCount
+	opts.Remainder = f.remainder
+	opts.FadeMillis = f.fadeMillis
+	opts.PaddingMillis = f.paddingMillis
+	opts.AudioFilters = f.filters.Chain()
+	opts.TrackMode = f.trackMode
+	opts.AudioTrack = f.audioTrack
+	opts.MergedFormat = f.mergedFormat
+	opts.VideoClips = f.videoClips
+	opts.MaxChunkSeconds = f.maxChunkSeconds
+}
+
+// resolveMillis parses an optional non-negative millisecond form value, treating bad input as 0.
+func resolveMillis(value string) int {
+	ms, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || ms < 0 {
+		return 0
+	}
+	return ms
+}
+
+// displayFileName strips any client-supplied directories from an upload name for display.
+func displayFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "upload"
+	}
+	return name
 }
 
 // formatSeconds converts raw seconds into mm:ss or hh:mm:ss for display.
@@ -410,6 +1835,20 @@ func formatSeconds(v float64) string {
 	return fmt.Sprintf("%02d:%02d", minutes, seconds)
 }
 
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func formatDurationHuman(seconds int) string {
 	if seconds <= 0 {
 		return "instant"
@@ -459,7 +1898,7 @@ func multiplierForUnit(unit string) int {
 	return 0
 }
 
-func totalDurationSeconds(chunks []model.Chunk) float64 {
+func totalDurationSeconds(chunks []chunker.Chunk) float64 {
 	var total float64
 	for _, chunk := range chunks {
 		end := chunk.StartSeconds + chunk.DurationSeconds
@@ -470,7 +1909,7 @@ func totalDurationSeconds(chunks []model.Chunk) float64 {
 	return total
 }
 
-func buildChunkWarning(job *model.Job, totalSeconds float64) string {
+func buildChunkWarning(job *chunker.Job, totalSeconds float64) string {
 	chunkSeconds := job.ChunkDurationSeconds
 	totalRounded := int(math.Round(totalSeconds))
 	chunkCount := len(job.Chunks)
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// openAIError is the error body OpenAI clients expect, so SDKs surface our messages.
+type openAIError struct {
+	Error openAIErrorDetail `json:"error"`
+}
+
+type openAIErrorDetail struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// transcriptionSegment is one entry of a verbose_json response; each chunk is a segment.
+type transcriptionSegment struct {
+	ID    int     `json:"id"`
+	Seek  int     `json:"seek"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// transcriptionWord is one entry of the words list returned for timestamp_granularities[]=word.
+type transcriptionWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// verboseTranscription is the verbose_json response shape.
+type verboseTranscription struct {
+	Task     string                 `json:"task"`
+	Language string                 `json:"language"`
+	Duration float64                `json:"duration"`
+	Text     string                 `json:"text"`
+	Segments []transcriptionSegment `json:"segments"`
+	Words    []transcriptionWord    `json:"words,omitempty"`
+}
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodPost, Path: "/v1/audio/transcriptions", ID: "createTranscription", Summary: "OpenAI-compatible transcription; json answers only text.",
+		Request: apiForm{
+			Files:  []string{"file"},
+			Fields: []string{"model", "language", "prompt", "response_format", "temperature", "timestamp_granularities[]"},
+		},
+		Response: verboseTranscription{}})
+}
+
+// handleOpenAITranscription implements POST /v1/audio/transcriptions in the shape of the
+// OpenAI audio API, so existing SDKs can point their base URL at this server. The upload
+// becomes an ordinary job (chunked at the default length and transcribed), the request
+// waits for it, and the chunk transcripts are merged into the response. model, language,
+// prompt, and temperature are accepted for compatibility; the configured whisper
+// arguments decide the model and language. timestamp_granularities[]=word adds word
+// timings to verbose_json responses.
+func (s *server) handleOpenAITranscription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOpenAIError(w, http.StatusMethodNotAllowed, "Use POST.", "invalid_request_error", "")
+		return
+	}
+	if s.processor.WhisperBin == "" {
+		writeOpenAIError(w, http.StatusServiceUnavailable, "Transcription is not configured on this server (set WHISPER_BIN).", "server_error", "")
+		return
+	}
+	if !s.checkQuota(w, r) {
+		return
+	}
+	if err := r.ParseMultipartForm(512 << 20); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "The request must be multipart/form-data.", "invalid_request_error", "")
+		return
+	}
+
+	format := r.FormValue("response_format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "text", "verbose_json", "srt", "vtt":
+	default:
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported response_format %q; use json, text, verbose_json, srt, or vtt.", format), "invalid_request_error", "response_format")
+		return
+	}
+
+	words := slices.Contains(r.MultipartForm.Value["timestamp_granularities[]"], "word")
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "Attach the audio as the file field.", "invalid_request_error", "file")
+		return
+	}
+	defer file.Close()
+
+	jobID, jobDir, err := storage.CreateJobDir(s.jobsDir, s.jobIDFunc(chunker.JobSourceOpenAI, nil))
+	if err == nil {
+		err = s.router.PrepareJob(jobDir, storage.ArtefactDirs...)
+	}
+	if err != nil {
+		log.Printf("openai transcription: creating job: %v", err)
+		writeOpenAIError(w, http.StatusInternalServerError, "The job could not be created.", "server_error", "")
+		return
+	}
+
+	displayName := displayFileName(header.Filename)
+	originalRel, sum, err := storage.StoreOriginal(jobDir, displayName, file)
+	if err != nil {
+		log.Printf("job %s: storing upload: %v", jobID, err)
+		writeOpenAIError(w, http.StatusInternalServerError, "The uploaded file could not be saved.", "server_error", "")
+		return
+	}
+
+	// Requests cannot pick a chunk length, so fit the default to whisper's chunk limit.
+	segment := segmentForm{chunkDuration: s.defaultChunk}
+	if err := segment.limitTo(s.maxChunkSeconds(nil)); err != nil {
+		log.Printf("job %s: %v", jobID, err)
+		writeOpenAIError(w, http.StatusInternalServerError, "The server's chunk limit cannot be met.", "server_error", "")
+		return
+	}
+	opts := chunker.Options{
+		ChunkDurationSeconds: segment.chunkDuration,
+		MaxChunkSeconds:      segment.maxChunkSeconds,
+		MakeBase64:           s.makeBase64,
+		Transcribe:           true,
+		QualityGate:          s.qualityGate,
+		Escalation:           s.escalation,
+		TranscriptOnly:       s.transcriptOnly,
+		WordTimestamps:       words,
+	}
+	job := &chunker.Job{
+		ID:                     jobID,
+		Source:                 chunker.JobSourceOpenAI,
+		OriginalFileName:       displayName,
+		OriginalVideoPath:      originalRel,
+		CreatedAt:              time.Now(),
+		ChunkDurationSeconds:   segment.chunkDuration,
+		RequestedChunkSeconds:  segment.requestedDuration,
+		TranscriptionRequested: true,
+		DiscardOriginal:        s.discardOriginals,
+		TranscriptOnly:         s.transcriptOnly,
+		WordTimestamps:         words,
+		OriginalSHA256:         sum,
+		SegmentKey:             opts.SegmentKey(),
+		Status:                 chunker.JobStatusPending,
+	}
+	if opts.QualityGate.Enabled() {
+		gate := opts.QualityGate
+		job.QualityGate = &gate
+	}
+	if opts.Escalation.Enabled() {
+		escalation := opts.Escalation
+		job.Escalation = &escalation
+	}
+
+	// Answer repeats of an already transcribed file from the finished job.
+	if dup := s.findDuplicateJob(job, opts); dup != nil {
+		if err := storage.RemoveJob(jobDir); err != nil {
+			log.Printf("job %s: failed to remove duplicate upload: %v", jobID, err)
+		}
+		w.Header().Set("X-Duplicate-Of", dup.ID)
+		s.writeTranscription(w, storage.JobDir(s.jobsDir, dup.ID), dup, format, words)
+		return
+	}
+
+	if err := s.saveJob(jobDir, job, storage.ChangeCreated); err != nil {
+		log.Printf("job %s: saving: %v", jobID, err)
+		writeOpenAIError(w, http.StatusInternalServerError, "The job could not be saved.", "server_error", "")
+		return
+	}
+	s.mu.Lock()
+	s.jobsInFlight[jobID] = job
+	s.mu.Unlock()
+
+	s.processJob(job, jobDir, filepath.Join(jobDir, filepath.FromSlash(originalRel)), opts)
+	if job.Status != chunker.JobStatusCompleted {
+		writeOpenAIError(w, http.StatusUnprocessableEntity, "Processing failed: "+job.ErrorMessage, "invalid_request_error", "file")
+		return
+	}
+	w.Header().Set("X-Job-Id", job.ID)
+	s.writeTranscription(w, jobDir, job, format, words)
+}
+
+// writeTranscription merges a job's chunk transcripts into the requested response format.
+func (s *server) writeTranscription(w http.ResponseWriter, jobDir string, job *chunker.Job, format string, withWords bool) {
+	var segments []transcriptionSegment
+	var words []transcriptionWord
+	var texts []string
+	var duration float64
+	for _, chunk := range job.Chunks {
+		end := chunk.StartSeconds + chunk.DurationSeconds
+		duration = max(duration, end)
+		if withWords && chunk.WordsFile != "" {
+			timed, err := chunker.ReadChunkWords(jobDir, chunk)
+			if err != nil {
+				log.Printf("job %s: reading word timings of chunk %d: %v", job.ID, chunk.Index, err)
+			}
+			for _, seg := range timed {
+				for _, word := range seg.Words {
+					words = append(words, transcriptionWord{Word: word.Word, Start: word.Start, End: word.End})
+				}
+			}
+		}
+		if chunk.TranscriptFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+		if err != nil {
+			continue
+		}
+		text := strings.Join(strings.Fields(string(data)), " ")
+		if text == "" {
+			continue
+		}
+		segments = append(segments, transcriptionSegment{
+			ID:    len(segments),
+			Seek:  int(chunk.StartSeconds * 100),
+			Start: chunk.StartSeconds,
+			End:   end,
+			Text:  text,
+		})
+		texts = append(texts, text)
+	}
+	text := strings.Join(texts, " ")
+
+	switch format {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, text)
+	case "srt", "vtt":
+		if format == "vtt" {
+			w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+		}
+		cues := make([]chunker.Cue, len(segments))
+		for i, seg := range segments {
+			cues[i] = chunker.Cue{Start: seg.Start, End: seg.End, Text: seg.Text}
+		}
+		_ = chunker.WriteCues(w, cues, format == "vtt")
+	case "verbose_json":
+		if segments == nil {
+			segments = []transcriptionSegment{}
+		}
+		// whisper.cpp's -l sets the language; without it (or with auto) it is unknown here.
+		language := "unknown"
+		args := s.processor.WhisperArgs
+		for i := 0; i+1 < len(args); i++ {
+			if (args[i] == "-l" || args[i] == "--language") && args[i+1] != "auto" {
+				language = args[i+1]
+			}
+		}
+		writeJSON(w, http.StatusOK, verboseTranscription{
+			Task:     "transcribe",
+			Language: language,
+			Duration: duration,
+			Text:     text,
+			Segments: segments,
+			Words:    words,
+		})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"text": text})
+	}
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, message, kind, param string) {
+	body := openAIError{Error: openAIErrorDetail{Message: message, Type: kind}}
+	if param != "" {
+		body.Error.Param = &param
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// apiOperation documents one method of a JSON route for GET /api/v1/openapi.json. The
+// Request and Response values are only used for their types, which are the structs the
+// handler decodes and encodes, so the document follows the code.
+type apiOperation struct {
+	Method string
+	// Path is the route as an OpenAPI template: /api/v1/jobs/{id}. Path parameters are
+	// strings.
+	Path    string
+	ID      string
+	Summary string
+	Query   []apiParam
+	// Request is a value of the JSON body's type, an apiForm, or nil for no body.
+	// RequestType overrides its media type, e.g. for an archive upload.
+	Request     any
+	RequestType string
+	// Status is the success status, 200 when zero. Response is a value of the JSON
+	// body's type, or nil for no body; ResponseType names a media type other than JSON.
+	Status       int
+	Response     any
+	ResponseType string
+	// Public marks an operation that needs no credentials when auth is enabled.
+	Public bool
+}
+
+// apiParam is a query parameter. Type is a JSON schema type; string when empty.
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// apiForm is a form-encoded body, or a multipart one when it has Files. Every field is a
+// string.
+type apiForm struct {
+	Files  []string
+	Fields []string
+}
+
+// apiOperations holds the registered operations in registration order.
+var apiOperations []apiOperation
+
+// registerAPI documents operations. Handlers call it from init, next to the types they
+// decode and encode.
+func registerAPI(ops ...apiOperation) {
+	apiOperations = append(apiOperations, ops...)
+}
+
+func init() {
+	registerAPI(apiOperation{
+		Method:   http.MethodGet,
+		Path:     "/api/v1/openapi.json",
+		ID:       "getOpenAPI",
+		Summary:  "This document.",
+		Response: map[string]any{},
+	})
+}
+
+// uploadForm is the upload form's fields: what presets may set plus the per-upload ones.
+func uploadForm(files ...string) apiForm {
+	fields := []string{"preset", "title", "recording_start", "tz_offset"}
+	for field := range presetFields {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields[4:])
+	return apiForm{Files: files, Fields: fields}
+}
+
+// buildOpenAPI renders the registered operations as an OpenAPI 3.0 document for a server
+// mounted at basePath.
+func buildOpenAPI(basePath string, authenticated bool) ([]byte, error) {
+	b := &schemaBuilder{components: map[string]any{}, names: map[reflect.Type]string{}}
+	paths := map[string]map[string]any{}
+	ids := map[string]bool{}
+	for _, op := range apiOperations {
+		method := strings.ToLower(op.Method)
+		if ids[op.ID] {
+			return nil, fmt.Errorf("openapi: operation ID %q is used twice", op.ID)
+		}
+		ids[op.ID] = true
+		item := paths[op.Path]
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		if _, ok := item[method]; ok {
+			return nil, fmt.Errorf("openapi: %s %s is registered twice", op.Method, op.Path)
+		}
+		item[method] = b.operation(op)
+	}
+
+	server := basePath
+	if server == "" {
+		server = "/"
+	}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "audio-chunker",
+			"version": "1",
+		},
+		"servers": []any{map[string]any{"url": server}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"basicAuth":  map[string]any{"type": "http", "scheme": "basic"},
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	if authenticated {
+		doc["security"] = []any{map[string]any{"basicAuth": []string{}}, map[string]any{"bearerAuth": []string{}}}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// pathParam matches the {name} parameters of a path template.
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (b *schemaBuilder) operation(op apiOperation) map[string]any {
+	out := map[string]any{"operationId": op.ID, "summary": op.Summary}
+	if op.Public {
+		out["security"] = []any{}
+	}
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	for _, q := range op.Query {
+		kind := q.Type
+		if kind == "" {
+			kind = "string"
+		}
+		param := map[string]any{"name": q.Name, "in": "query", "schema": map[string]any{"type": kind}}
+		if q.Description != "" {
+			param["description"] = q.Description
+		}
+		params = append(params, param)
+	}
+	if params != nil {
+		out["parameters"] = params
+	}
+
+	switch body := op.Request.(type) {
+	case nil:
+		if op.RequestType != "" {
+			out["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+				op.RequestType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+			}}
+		}
+	case apiForm:
+		properties := map[string]any{}
+		for _, field := range body.Fields {
+			properties[field] = map[string]any{"type": "string"}
+		}
+		for _, file := range body.Files {
+			properties[file] = map[string]any{"type": "string", "format": "binary"}
+		}
+		mediaType := "application/x-www-form-urlencoded"
+		if len(body.Files) > 0 {
+			mediaType = "multipart/form-data"
+		}
+		out["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+			mediaType: map[string]any{"schema": map[string]any{"type": "object", "properties": properties}},
+		}}
+	default:
+		mediaType := op.RequestType
+		if mediaType == "" {
+			mediaType = "application/json"
+		}
+		out["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+			mediaType: map[string]any{"schema": b.schema(reflect.TypeOf(body))},
+		}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case op.ResponseType != "":
+		success["content"] = map[string]any{op.ResponseType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}
+	case op.Response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(op.Response))}}
+	}
+	out["responses"] = map[string]any{
+		strconv.Itoa(status): success,
+		"default": map[string]any{
+			"description": "Error",
+			"content":     map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(errorPage{}))}},
+		},
+	}
+	return out
+}
+
+// schemaBuilder turns Go types into JSON schemas the way encoding/json encodes them.
+// Named structs become components referenced by name.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOverrides describe types whose JSON form is not their Go shape.
+var schemaOverrides = map[reflect.Type]map[string]any{
+	reflect.TypeOf(presetFieldVal{}): {
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "number"},
+			map[string]any{"type": "boolean"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	},
+	reflect.TypeOf(json.RawMessage{}): {},
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if override, ok := schemaOverrides[t]; ok {
+		return override
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			b.components[name] = nil // reserve the name while fields refer back to it
+			b.components[name] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// componentName is the exported form of t's name, prefixed with its package's when
+// another type already took it.
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := exportedName(t.Name())
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()
+		name = exportedName(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+	}
+	return name
+}
+
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// object describes a struct's encoded fields, with embedded structs flattened.
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	b.fields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]any) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.fields(embedded, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if slices.Contains(strings.Split(opts, ","), "string") {
+			properties[name] = map[string]any{"type": "string"}
+			continue
+		}
+		properties[name] = b.schema(field.Type)
+	}
+}
+
+// checkAPIRoutes returns an error naming the first documented operation mux does not
+// route to a handler of its own, so a renamed route cannot leave the document stale.
+func checkAPIRoutes(mux *http.ServeMux) error {
+	for _, op := range apiOperations {
+		path := pathParam.ReplaceAllString(op.Path, "x")
+		r, err := http.NewRequest(op.Method, path, nil)
+		if err != nil {
+			return fmt.Errorf("openapi: %s %s: %w", op.Method, op.Path, err)
+		}
+		if _, pattern := mux.Handler(r); pattern == "" || pattern == "/" {
+			return fmt.Errorf("openapi: %s %s is documented but not routed", op.Method, op.Path)
+		}
+	}
+	return nil
+}
+
+// handleOpenAPI serves the document built at startup.
+func (s *server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(s.openAPI)
+}
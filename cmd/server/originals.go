@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// handleOriginalDownload serves /jobs/{id}/original as an attachment named after the
+// upload. http.ServeContent handles Range and If-Range, so interrupted downloads of large
+// videos can resume; the content hash doubles as a strong ETag.
+func (s *server) handleOriginalDownload(w http.ResponseWriter, r *http.Request, jobID string) {
+	if !s.serveOriginals {
+		s.renderError(w, r, http.StatusForbidden, "Downloading originals is disabled on this server.", nil)
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	if job.OriginalDiscardedAt != nil {
+		s.renderError(w, r, http.StatusGone, "The original upload was deleted after processing.", nil)
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": job.OriginalFileName})
+	originalPath, err := s.router.JobFile(job.ID, job.OriginalVideoPath)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "The original upload is missing.", err)
+		return
+	}
+	file, err := os.Open(originalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if remote, ok := s.router.Remote(job.ID, job.OriginalVideoPath); ok {
+			w.Header().Set("Content-Disposition", disposition)
+			s.proxyRemoteFile(w, r, job.ID, job.OriginalVideoPath, remote)
+			return
+		}
+	}
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "The original upload is missing.", err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The original upload could not be read.", err)
+		return
+	}
+
+	if job.OriginalSHA256 != "" {
+		w.Header().Set("ETag", `"`+job.OriginalSHA256+`"`)
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	http.ServeContent(w, r, job.OriginalFileName, info.ModTime(), file)
+}
+
+// guardOriginals hides jobs/<id>/original/ from a handler serving the data directory
+// when original downloads are disabled, and always the unfinished files under a job's
+// staging directory, job files that are symlinks leading out of the job, and
+// everything outside jobs/, such as the certificate cache and upload records.
+func (s *server) guardOriginals(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/"), "/", 3)
+		if parts[0] != "jobs" {
+			s.renderError(w, r, http.StatusNotFound, "File not found.", nil)
+			return
+		}
+		if len(parts) >= 2 {
+			rel := "."
+			if len(parts) == 3 {
+				rel = parts[2]
+			}
+			if _, err := s.router.JobFile(parts[1], rel); err != nil {
+				s.renderError(w, r, http.StatusNotFound, "File not found.", err)
+				return
+			}
+		}
+		if isStagingPath(r.URL.Path) {
+			s.renderError(w, r, http.StatusNotFound, "File not found.", nil)
+			return
+		}
+		if !s.serveOriginals && isOriginalPath(r.URL.Path) {
+			s.renderError(w, r, http.StatusForbidden, "Downloading originals is disabled on this server.", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isOriginalPath reports whether a data-directory-relative URL path points into a
+// job's original/ folder.
+func isOriginalPath(urlPath string) bool {
+	parts := strings.Split(strings.TrimPrefix(path.Clean("/"+urlPath), "/"), "/")
+	return len(parts) >= 3 && parts[0] == "jobs" && parts[2] == "original"
+}
+
+// isStagingPath reports whether a data-directory-relative URL path points into a job's
+// staging directory, where the processor writes files that are not complete yet.
+func isStagingPath(urlPath string) bool {
+	parts := strings.Split(strings.TrimPrefix(path.Clean("/"+urlPath), "/"), "/")
+	return len(parts) >= 3 && parts[0] == "jobs" && parts[2] == chunker.StagingDir
+}
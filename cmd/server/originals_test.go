@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"audi/internal/storage"
+)
+
+func TestJobFileTraversal(t *testing.T) {
+	const id = "20240312-101500-0042"
+	dataDir := t.TempDir()
+	jobsDir := filepath.Join(dataDir, "jobs")
+	if err := storage.EnsureJobSubdirs(storage.JobDir(jobsDir, id), "chunks"); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		filepath.Join(dataDir, "presets.json"):                              "{}",
+		filepath.Join(storage.JobDir(jobsDir, id), "chunks", "chunk_0.wav"): "RIFF",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router, err := storage.NewRouter(jobsDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{dataDir: dataDir, jobsDir: jobsDir, router: router}
+	files := http.StripPrefix("/files/", s.guardOriginals(http.FileServer(http.Dir(dataDir))))
+
+	tests := []struct {
+		handler http.Handler
+		target  string
+		want    int
+	}{
+		{files, "/files/jobs/" + id + "/chunks/chunk_0.wav", http.StatusOK},
+		{files, "/files/jobs/../presets.json", http.StatusNotFound},
+		{files, "/files/jobs/" + id + "/../../presets.json", http.StatusNotFound},
+		{files, "/files/jobs/%2e%2e/presets.json", http.StatusNotFound},
+		{files, "/files/presets.json", http.StatusNotFound},
+		{http.HandlerFunc(s.handleJobDetail), "/jobs/" + id + "/raw/chunks/chunk_0.wav", http.StatusOK},
+		{http.HandlerFunc(s.handleJobDetail), "/jobs/" + id + "/raw/../../x", http.StatusBadRequest},
+		{http.HandlerFunc(s.handleJobDetail), "/jobs/" + id + "/raw/chunks/%2e%2e/%2e%2e/x", http.StatusBadRequest},
+		{http.HandlerFunc(s.handleJobDetail), "/jobs/../raw/presets.json", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.target, rec.Code, tt.want)
+		}
+	}
+}
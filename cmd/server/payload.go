@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// maxInlineAudio caps the audio embedded in a chunk payload. Larger chunks should be
+// fetched from the streaming endpoints rather than held in memory as one JSON string.
+const maxInlineAudio = 32 << 20
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/chunks/{index}", ID: "getChunk", Summary: "One chunk's metadata, with its audio, transcript, or word timings on request.",
+		Query: []apiParam{
+			{Name: "include", Description: "Comma-separated: audio, transcript, words."},
+			{Name: "encoding", Description: "datauri to embed the audio as a data: URI."},
+		},
+		Response: chunkPayload{}})
+}
+
+// chunkPayload is a chunk's metadata plus, on request, its audio and transcript inline,
+// shaped so clients can forward it to LLM or ASR HTTP APIs without a second request.
+type chunkPayload struct {
+	JobID string `json:"jobId"`
+	chunker.Chunk
+	EndSeconds   float64 `json:"endSeconds"`
+	MimeType     string  `json:"mimeType"`
+	SizeBytes    int64   `json:"sizeBytes,omitempty"`
+	AudioBase64  string  `json:"audioBase64,omitempty"`
+	AudioDataURI string  `json:"audioDataUri,omitempty"`
+	Transcript   *string `json:"transcript,omitempty"`
+	// Segments holds the word timings, in job seconds, when ?include=words asks for them.
+	Segments []chunker.TranscriptSegment `json:"segments,omitempty"`
+}
+
+// handleAPIChunk serves GET /api/v1/jobs/{id}/chunks/{n}. ?include= takes a comma-separated
+// list: "audio" embeds the WAV as Base64 (or, with ?encoding=datauri, as a data: URI) and
+// "transcript" embeds the full transcript text, and "words" the word-level timings of jobs
+// transcribed with them.
+func (s *server) handleAPIChunk(w http.ResponseWriter, r *http.Request, jobID, rawIndex string) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+
+	var withAudio, withTranscript, withWords bool
+	for _, item := range strings.Split(r.URL.Query().Get("include"), ",") {
+		switch strings.TrimSpace(item) {
+		case "":
+		case "audio":
+			withAudio = true
+		case "transcript":
+			withTranscript = true
+		case "words":
+			withWords = true
+		default:
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown include %q; use audio, transcript, or words.", item), nil)
+			return
+		}
+	}
+	encoding := r.URL.Query().Get("encoding")
+	if encoding != "" && encoding != "base64" && encoding != "datauri" {
+		s.renderError(w, r, http.StatusBadRequest, "encoding must be base64 or datauri.", nil)
+		return
+	}
+
+	index, err := strconv.Atoi(rawIndex)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Chunk not found.", nil)
+		return
+	}
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	if index < 0 || index >= len(job.Chunks) {
+		s.renderError(w, r, http.StatusNotFound, "Chunk not found.", nil)
+		return
+	}
+	chunk := job.Chunks[index]
+
+	payload := chunkPayload{
+		JobID:      job.ID,
+		Chunk:      chunk,
+		EndSeconds: chunk.StartSeconds + chunk.DurationSeconds,
+		MimeType:   "audio/wav",
+	}
+	if chunk.AudioFile != "" {
+		if info, err := os.Stat(filepath.Join(jobDir, filepath.FromSlash(chunk.AudioFile))); err == nil {
+			payload.SizeBytes = info.Size()
+		}
+	}
+
+	if withAudio {
+		if job.ArtefactsPurgedAt != nil {
+			s.renderError(w, r, http.StatusGone, "This chunk's audio was purged; only the transcript and metadata remain.", nil)
+			return
+		}
+		if chunk.AudioFile == "" {
+			s.renderError(w, r, http.StatusGone, "This job kept transcripts only; the chunk audio was not retained.", nil)
+			return
+		}
+		if payload.SizeBytes > maxInlineAudio {
+			s.renderError(w, r, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("The chunk is too large to inline (%s); download it from /jobs/%s/download/%d or use shorter chunks.", formatBytes(payload.SizeBytes), job.ID, chunk.Index), nil)
+			return
+		}
+		audio, err := s.readJobFile(r.Context(), job.ID, chunk.AudioFile)
+		if err != nil {
+			s.renderError(w, r, http.StatusNotFound, "The chunk audio is missing.", err)
+			return
+		}
+		encoded := base64.StdEncoding.EncodeToString(audio)
+		if encoding == "datauri" {
+			payload.AudioDataURI = "data:" + payload.MimeType + ";base64," + encoded
+		} else {
+			payload.AudioBase64 = encoded
+		}
+	}
+
+	if withTranscript && chunk.TranscriptFile != "" {
+		text, err := s.readJobFile(r.Context(), job.ID, chunk.TranscriptFile)
+		if err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "The transcript could not be read.", err)
+			return
+		}
+		transcript := strings.TrimSpace(string(text))
+		payload.Transcript = &transcript
+	}
+
+	if withWords && chunk.WordsFile != "" {
+		segments, err := chunker.ReadChunkWords(jobDir, chunk)
+		if err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "The word timings could not be read.", err)
+			return
+		}
+		payload.Segments = segments
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
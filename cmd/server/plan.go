@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// planResponse is a chunker.Plan plus the processing time this server's throughput
+// history predicts, omitted until there is history.
+type planResponse struct {
+	chunker.Plan
+	EstimatedProcessingSeconds float64 `json:"estimatedProcessingSeconds,omitempty"`
+	// MaxChunkSeconds is the transcription backend's chunk limit the plan was fitted to;
+	// RequestedChunkSeconds is the asked-for length when that meant shortening it.
+	MaxChunkSeconds       float64 `json:"maxChunkSeconds,omitempty"`
+	RequestedChunkSeconds int     `json:"requestedChunkSeconds,omitempty"`
+}
+
+func init() {
+	form := uploadForm("video")
+	form.Fields = append(form.Fields, "job")
+	registerAPI(apiOperation{Method: http.MethodPost, Path: "/api/v1/jobs/plan", ID: "planJob", Summary: "Predict the outcome of an upload without creating a job.",
+		Request: form, Response: planResponse{}})
+}
+
+// handlePlan serves POST /api/v1/jobs/plan: a dry run that probes a file and predicts
+// the chunks, artefact sizes, and processing time of an upload with the same form
+// fields, without creating a job. The file is either uploaded as "video" or referenced
+// with "job", the ID of an existing job whose original is still kept.
+func (s *server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if err := r.ParseMultipartForm(512 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		s.renderError(w, r, http.StatusBadRequest, "The plan form could not be read.", err)
+		return
+	}
+
+	if r.Form == nil {
+		r.Form = url.Values{}
+	}
+	if _, err := s.applyPreset(r); err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	segment, err := s.parseSegmentForm(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	transcribe := s.transcriptOnly || r.FormValue("transcribe") == "on"
+	if transcribe {
+		sets, err := chunker.SelectTranscriptSets(s.transcriptSets, r.Form["transcript_set"])
+		if err == nil {
+			err = segment.limitTo(s.maxChunkSeconds(sets))
+		}
+		if err != nil {
+			s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	opts := chunker.Options{
+		MakeBase64:     s.makeBase64,
+		Transcribe:     transcribe,
+		TranscriptOnly: transcribe && (s.transcriptOnly || r.FormValue("transcript_only") == "on"),
+	}
+	segment.apply(&opts)
+
+	inputPath, cleanup, ok := s.planInput(w, r)
+	if !ok {
+		return
+	}
+	defer cleanup()
+
+	plan, err := s.processor.Plan(r.Context(), inputPath, opts)
+	if err != nil {
+		s.renderError(w, r, http.StatusUnprocessableEntity, "The file could not be planned: "+err.Error()+".", nil)
+		return
+	}
+
+	resp := planResponse{Plan: plan, MaxChunkSeconds: segment.maxChunkSeconds, RequestedChunkSeconds: segment.requestedDuration}
+	if s.throughput != nil {
+		chunkStage := chunker.StagePostprocess
+		if transcribe && s.processor.WhisperBin != "" {
+			chunkStage = chunker.StageTranscribe
+		}
+		extractRate := s.throughput.Rate(chunker.StageExtract)
+		chunkRate := s.throughput.Rate(chunkStage)
+		if extractRate > 0 && chunkRate > 0 {
+			resp.EstimatedProcessingSeconds = plan.AudioSeconds/extractRate + plan.AudioSeconds/chunkRate
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// planInput returns the path of the file to plan and a cleanup function, or renders an
+// error and returns false. An upload is spooled to a temporary file, since ffmpeg probes
+// paths; a referenced job's original is used in place.
+func (s *server) planInput(w http.ResponseWriter, r *http.Request) (string, func(), bool) {
+	if jobID := strings.TrimSpace(r.FormValue("job")); jobID != "" {
+		// Unlike path segments, a form value can carry slashes.
+		if !storage.ValidJobID(jobID) {
+			s.renderError(w, r, http.StatusNotFound, "Job not found.", nil)
+			return "", nil, false
+		}
+		job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
+		if err != nil {
+			s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+			return "", nil, false
+		}
+		if job.OriginalVideoPath == "" || job.OriginalDiscardedAt != nil {
+			s.renderError(w, r, http.StatusGone, "The job's original upload is not kept, so it cannot be planned.", nil)
+			return "", nil, false
+		}
+		originalPath, err := s.router.JobFile(jobID, job.OriginalVideoPath)
+		if err != nil {
+			s.renderError(w, r, http.StatusGone, "The job's original upload is not kept, so it cannot be planned.", err)
+			return "", nil, false
+		}
+		return originalPath, func() {}, true
+	}
+
+	file, header, err := r.FormFile("video")
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "Upload a file as “video” or name an existing job as “job”.", nil)
+		return "", nil, false
+	}
+	defer file.Close()
+	tmp, err := os.CreateTemp("", "audi-plan-*"+filepath.Ext(displayFileName(header.Filename)))
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The upload could not be stored.", err)
+		return "", nil, false
+	}
+	cleanup := func() { _ = os.Remove(tmp.Name()) }
+	_, err = io.Copy(tmp, file)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		s.renderError(w, r, http.StatusInternalServerError, "The upload could not be stored.", err)
+		return "", nil, false
+	}
+	return tmp.Name(), cleanup, true
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"audi/internal/config"
+	"audi/internal/storage"
+)
+
+// presetFields are the upload form fields a preset may set. The file, title, and
+// recording start describe one upload, so they are left to the form.
+var presetFields = map[string]bool{
+	"chunk_mode":       true,
+	"chunk_value":      true,
+	"chunk_unit":       true,
+	"chunk_count":      true,
+	"remainder":        true,
+	"audio_track":      true,
+	"filters":          true,
+	"merged_audio":     true,
+	"video_clips":      true,
+	"fade_ms":          true,
+	"pad_ms":           true,
+	"metadata":         true,
+	"transcribe":       true,
+	"skip_silence":     true,
+	"word_timestamps":  true,
+	"subtitles":        true,
+	"summarize":        true,
+	"keywords":         true,
+	"sentiment":        true,
+	"chapters":         true,
+	"redact":           true,
+	"pii":              true,
+	"transcript_set":   true,
+	"whisper_args":     true,
+	"transcript_only":  true,
+	"discard_original": true,
+	"force":            true,
+	"cpu_only":         true,
+}
+
+// configPresets converts the config file's presets for storage.OpenPresets.
+func configPresets(cfg []config.PresetConfig) []storage.Preset {
+	presets := make([]storage.Preset, 0, len(cfg))
+	for _, p := range cfg {
+		options := make(map[string][]string, len(p.Options))
+		for field, values := range p.Options {
+			options[field] = values
+		}
+		presets = append(presets, storage.Preset{Name: p.Name, Description: p.Description, Options: options})
+	}
+	return presets
+}
+
+// validatePreset checks that a preset only sets known fields and that its values pass
+// the upload form's own validation. Its errors are meant for the user.
+func (s *server) validatePreset(preset storage.Preset) error {
+	form := url.Values{}
+	for field, values := range preset.Options {
+		if !presetFields[field] {
+			return fmt.Errorf("preset %s: unknown option %q", preset.Name, field)
+		}
+		form[field] = values
+	}
+	if _, err := s.parseUploadSettings(&http.Request{Form: form}); err != nil {
+		return fmt.Errorf("preset %s: %w", preset.Name, err)
+	}
+	return nil
+}
+
+// applyPreset fills the fields a parsed form leaves out from the preset it names with
+// preset=<name>, and returns that name. The upload page sends preset_prefilled=on once
+// its script has copied the preset into the form, so a box the user then unticks stays
+// unticked.
+func (s *server) applyPreset(r *http.Request) (string, error) {
+	name := strings.TrimSpace(r.FormValue("preset"))
+	if name == "" {
+		return "", nil
+	}
+	preset, ok := s.presets.Get(name)
+	if !ok {
+		return "", fmt.Errorf("Unknown preset %q.", name)
+	}
+	if r.FormValue("preset_prefilled") == "on" {
+		return name, nil
+	}
+	for field, values := range preset.Options {
+		if _, sent := r.Form[field]; !sent {
+			r.Form[field] = values
+		}
+	}
+	return name, nil
+}
+
+// presetRequest is the body of PUT /api/v1/presets/{name}.
+type presetRequest struct {
+	Description string                    `json:"description"`
+	Options     map[string]presetFieldVal `json:"options"`
+}
+
+// presetFieldVal is one option's form values. JSON may give a string, a number, a
+// boolean (true ticks a checkbox, false leaves it out), or a list of strings.
+type presetFieldVal []string
+
+func (v *presetFieldVal) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	switch value := raw.(type) {
+	case string:
+		*v = presetFieldVal{value}
+	case float64:
+		*v = presetFieldVal{strings.TrimSpace(string(data))}
+	case bool:
+		*v = nil
+		if value {
+			*v = presetFieldVal{"on"}
+		}
+	case []any:
+		list := make(presetFieldVal, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return errors.New("option lists must hold strings")
+			}
+			list = append(list, s)
+		}
+		*v = list
+	default:
+		return errors.New("options must be strings, numbers, booleans, or lists of strings")
+	}
+	return nil
+}
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/presets", ID: "listPresets", Summary: "Every preset, sorted by name.", Response: []storage.Preset{}},
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/presets/{name}", ID: "getPreset", Summary: "One preset.", Response: storage.Preset{}},
+		apiOperation{Method: http.MethodPut, Path: "/api/v1/presets/{name}", ID: "putPreset", Summary: "Create or replace a preset; 201 when it is new.",
+			Request: presetRequest{}, Response: storage.Preset{}},
+		apiOperation{Method: http.MethodDelete, Path: "/api/v1/presets/{name}", ID: "deletePreset", Summary: "Delete a preset.", Status: http.StatusNoContent},
+	)
+}
+
+// handleAPIPresets serves GET /api/v1/presets, every preset sorted by name.
+func (s *server) handleAPIPresets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.presets.List())
+}
+
+// handleAPIPreset serves GET, PUT, and DELETE on /api/v1/presets/{name}. Presets from
+// the config file can be read but not changed.
+func (s *server) handleAPIPreset(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/presets/")
+	if name == "" || strings.Contains(name, "/") {
+		s.renderError(w, r, http.StatusNotFound, "Preset not found.", nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		preset, ok := s.presets.Get(name)
+		if !ok {
+			s.renderError(w, r, http.StatusNotFound, "Preset not found.", nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, preset)
+	case http.MethodPut:
+		var req presetRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON body: %v.", err), nil)
+			return
+		}
+		if err := storage.ValidatePresetName(name); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		preset := storage.Preset{Name: name, Description: strings.TrimSpace(req.Description), Options: map[string][]string{}}
+		for field, values := range req.Options {
+			if len(values) > 0 {
+				preset.Options[field] = values
+			}
+		}
+		if err := s.validatePreset(preset); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		created, err := s.presets.Put(preset)
+		switch {
+		case errors.Is(err, storage.ErrPresetReadOnly):
+			s.renderError(w, r, http.StatusConflict, "This preset is defined in the server's config file.", nil)
+			return
+		case err != nil:
+			s.renderError(w, r, http.StatusInternalServerError, "The preset could not be saved.", err)
+			return
+		}
+		preset, _ = s.presets.Get(name)
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, preset)
+	case http.MethodDelete:
+		switch err := s.presets.Delete(name); {
+		case errors.Is(err, storage.ErrPresetNotFound):
+			s.renderError(w, r, http.StatusNotFound, "Preset not found.", nil)
+		case errors.Is(err, storage.ErrPresetReadOnly):
+			s.renderError(w, r, http.StatusConflict, "This preset is defined in the server's config file.", nil)
+		case err != nil:
+			s.renderError(w, r, http.StatusInternalServerError, "The preset could not be deleted.", err)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"html/template"
+
+	"audi/pkg/chunker"
+)
+
+func init() {
+	registerTemplateModule(templateModule{
+		name: "progress",
+		funcs: func(templateEnv) template.FuncMap {
+			return template.FuncMap{"stageLabel": stageLabel}
+		},
+		partials: []string{"progress"},
+	})
+}
+
+// stageLabel describes a running stage for the progress bar.
+func stageLabel(stage string) string {
+	switch stage {
+	case chunker.StageExtract:
+		return "Extracting audio"
+	case chunker.StageTranscribe:
+		return "Transcribing chunks"
+	default:
+		return "Preparing chunks"
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"audi/internal/storage"
+)
+
+func init() {
+	registerTemplateModule(templateModule{
+		name: "paths",
+		funcs: func(env templateEnv) template.FuncMap {
+			// base is the path prefix the server is mounted under, for links in pages.
+			return template.FuncMap{"base": func() string { return env.basePath }}
+		},
+	})
+}
+
+// mountAt serves next under basePath: the prefix is stripped before routing, the bare
+// prefix redirects to the prefix with a slash, and everything outside it is not found.
+func (s *server) mountAt(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == basePath:
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, basePath+"/"):
+			stripped.ServeHTTP(w, r)
+		default:
+			s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+		}
+	})
+}
+
+// link returns the path of a route of this server as a browser or client must request
+// it, under the base path.
+func (s *server) link(route string) string {
+	return s.basePath + route
+}
+
+// jobLink is the URL of a job's page for messages read elsewhere, such as webhooks:
+// under public_url when it is set, otherwise a path under the base path.
+func (s *server) jobLink(jobID string) string {
+	if s.publicURL != "" {
+		return s.publicURL + "/jobs/" + jobID
+	}
+	return s.link("/jobs/" + jobID)
+}
+
+// seeOther redirects a submitted form to a page of this server.
+func (s *server) seeOther(w http.ResponseWriter, r *http.Request, route string) {
+	http.Redirect(w, r, s.link(route), http.StatusSeeOther)
+}
+
+// fileURL links a job file like Router.URL, with files served from /files/ under the
+// base path. Storage class URLs are used as configured.
+func (s *server) fileURL(jobID, rel string) string {
+	return localFileURL(s.router, s.basePath, jobID, rel)
+}
+
+func localFileURL(router *storage.Router, basePath, jobID, rel string) string {
+	u := router.URL(jobID, rel)
+	if strings.HasPrefix(u, "/files/") {
+		return basePath + u
+	}
+	return u
+}
+
+type schemeKey struct{}
+
+// fromTrustedProxies takes the client address, scheme, and host of requests relayed by
+// one of trusted from X-Forwarded-For, X-Forwarded-Proto, and X-Forwarded-Host. The
+// client is the last address in X-Forwarded-For that is not a trusted proxy, as earlier
+// entries come from the client itself. Headers from anyone else are ignored.
+func fromTrustedProxies(trusted []netip.Prefix, next http.Handler) http.Handler {
+	if len(trusted) == 0 {
+		return next
+	}
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, network := range trusted {
+			if network.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err != nil || !isTrusted(peer.Addr()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = r.Clone(r.Context())
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(hops[i])
+			if err != nil {
+				break
+			}
+			r.RemoteAddr = net.JoinHostPort(addr.Unmap().String(), "0")
+			if !isTrusted(addr) {
+				break
+			}
+		}
+		if proto := strings.ToLower(firstForwarded(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+			r = r.WithContext(context.WithValue(r.Context(), schemeKey{}, proto))
+		}
+		if host := firstForwarded(r.Header.Get("X-Forwarded-Host")); host != "" && !strings.ContainsAny(host, "/\\@ ") {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// firstForwarded returns the value a chain of proxies appending to a header started
+// with, which is what the client sent to the first of them.
+func firstForwarded(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(first)
+}
+
+// requestScheme is the scheme the client used: the trusted proxy's X-Forwarded-Proto,
+// or whether the request reached the server itself over TLS.
+func requestScheme(r *http.Request) string {
+	if scheme, ok := r.Context().Value(schemeKey{}).(string); ok {
+		return scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// clientAddr is the address of the client a request came from, for logs.
+func clientAddr(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
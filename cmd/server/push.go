@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// maxPushedChunk caps one pushed chunk; an hour of 16 kHz mono PCM is about 115 MB.
+const maxPushedChunk = 512 << 20
+
+// pushJobRequest is the body of POST /api/v1/jobs, which opens a job that receives its
+// chunks from an external producer instead of segmenting an upload.
+type pushJobRequest struct {
+	Title          string            `json:"title"`
+	Tags           []string          `json:"tags"`
+	Note           string            `json:"note"`
+	Metadata       map[string]string `json:"metadata"`
+	Transcribe     bool              `json:"transcribe"`
+	TranscriptSets []string          `json:"transcriptSets"`
+	SkipSilence    bool              `json:"skipSilence"`
+	WordTimestamps bool              `json:"wordTimestamps"`
+	WhisperArgs    []string          `json:"whisperArgs"`
+	RecordingStart *time.Time        `json:"recordingStart"`
+}
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/jobs", ID: "createJob", Summary: "Open a job for chunks pushed one by one.",
+			Request: pushJobRequest{}, Status: http.StatusCreated, Response: chunker.Job{}},
+		apiOperation{Method: http.MethodPut, Path: "/api/v1/jobs/{id}/chunks/{index}", ID: "pushChunk", Summary: "Push one chunk as a WAV body; 200 when it replaces an earlier one.",
+			Query: []apiParam{
+				{Name: "start", Type: "number", Description: "Start in seconds; defaults to the end of the previous chunk."},
+				{Name: "duration", Type: "number", Description: "Length in seconds; defaults to the file's."},
+				{Name: "track", Type: "integer"},
+			},
+			RequestType: "audio/wav", Status: http.StatusCreated, Response: chunker.Chunk{}},
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/jobs/{id}/complete", ID: "completeJob", Summary: "Mark a pushed job as completed.", Response: chunker.Job{}},
+	)
+}
+
+// handleAPICreateJob serves POST /api/v1/jobs. The new job waits in the receiving state
+// for PUT /api/v1/jobs/{id}/chunks/{n} and is closed by POST /api/v1/jobs/{id}/complete.
+func (s *server) handleAPICreateJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+
+	var req pushJobRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.renderError(w, r, http.StatusBadRequest, "The request body is not valid JSON for a job.", err)
+		return
+	}
+
+	patch := jobPatch{Title: &req.Title, Tags: &req.Tags, Note: &req.Note}
+	if err := patch.normalize(); err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	metadata, err := chunker.NormalizeMetadata(req.Metadata)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if req.Transcribe && s.processor.WhisperBin == "" {
+		s.renderError(w, r, http.StatusBadRequest, "Transcription is not configured on this server.", nil)
+		return
+	}
+	if len(req.TranscriptSets) > 0 && !req.Transcribe {
+		s.renderError(w, r, http.StatusBadRequest, "transcriptSets need transcribe to be true.", nil)
+		return
+	}
+	sets, err := chunker.SelectTranscriptSets(s.transcriptSets, req.TranscriptSets)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if len(req.WhisperArgs) > 0 && !req.Transcribe {
+		s.renderError(w, r, http.StatusBadRequest, "whisperArgs need transcribe to be true.", nil)
+		return
+	}
+	if err := chunker.ValidateWhisperArgs(req.WhisperArgs); err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	jobID, jobDir, err := storage.CreateJobDir(s.jobsDir, s.jobIDFunc(chunker.JobSourcePush, metadata))
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be created.", err)
+		return
+	}
+	if err := s.router.PrepareJob(jobDir, storage.ArtefactDirs...); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be created.", err)
+		return
+	}
+
+	job := &chunker.Job{
+		ID:                     jobID,
+		Title:                  *patch.Title,
+		Source:                 chunker.JobSourcePush,
+		Tags:                   *patch.Tags,
+		Note:                   *patch.Note,
+		Metadata:               metadata,
+		CreatedAt:              time.Now(),
+		TranscriptionRequested: req.Transcribe,
+		SkipSilence:            req.SkipSilence,
+		WordTimestamps:         req.WordTimestamps,
+		WhisperArgs:            req.WhisperArgs,
+		Status:                 chunker.JobStatusReceiving,
+		Chunks:                 []chunker.Chunk{},
+	}
+	for _, set := range sets {
+		job.TranscriptSets = append(job.TranscriptSets, set.Name)
+	}
+	if req.Transcribe && s.qualityGate.Enabled() {
+		gate := s.qualityGate
+		job.QualityGate = &gate
+	}
+	if req.Transcribe && s.escalation.Enabled() {
+		escalation := s.escalation
+		job.Escalation = &escalation
+	}
+	if req.RecordingStart != nil {
+		job.RecordingStart = req.RecordingStart
+		job.RecordingStartSource = chunker.RecordingStartUser
+	}
+	// The producer probes and cuts the chunks; each is post-processed as it arrives.
+	job.StartStage(chunker.PipelineUpload, job.CreatedAt)
+	job.SkipStage(chunker.PipelineProbe)
+	job.SkipStage(chunker.PipelineChunk)
+	if s.makeBase64 {
+		job.StartStage(chunker.PipelineBase64, job.CreatedAt)
+	}
+	if req.Transcribe {
+		job.StartStage(chunker.PipelineTranscribe, job.CreatedAt)
+	}
+
+	if err := s.saveJob(jobDir, job, storage.ChangeCreated); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+	w.Header().Set("Location", s.link("/api/v1/jobs/"+jobID))
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// handleChunkPush serves PUT /api/v1/jobs/{id}/chunks/{n}: the body is the chunk as a WAV
+// file. ?start= and ?duration= (seconds) and ?track= describe it; start defaults to the end
+// of the previous chunk and duration to the file's length. Chunks arrive in order, and
+// re-sending an index replaces that chunk, so producers can retry safely.
+func (s *server) handleChunkPush(w http.ResponseWriter, r *http.Request, jobID, rawIndex string) {
+	index, err := strconv.Atoi(rawIndex)
+	if err != nil || index < 0 {
+		s.renderError(w, r, http.StatusNotFound, "Chunk not found.", nil)
+		return
+	}
+	query := r.URL.Query()
+	start, err := optionalSeconds(query.Get("start"))
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "start: "+err.Error(), nil)
+		return
+	}
+	duration, err := optionalSeconds(query.Get("duration"))
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "duration: "+err.Error(), nil)
+		return
+	}
+	var track int
+	if v := query.Get("track"); v != "" {
+		if track, err = strconv.Atoi(v); err != nil || track < 0 {
+			s.renderError(w, r, http.StatusBadRequest, "track must be a non-negative integer.", nil)
+			return
+		}
+	}
+	if !s.checkQuota(w, r) {
+		return
+	}
+
+	jobDir, job, release, ok := s.claimPushJob(w, r, jobID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if index > len(job.Chunks) {
+		s.renderError(w, r, http.StatusConflict, fmt.Sprintf("Push chunk %d first; chunks must arrive in order.", len(job.Chunks)), nil)
+		return
+	}
+	if start < 0 {
+		start = 0
+		if index > 0 {
+			prev := job.Chunks[index-1]
+			start = prev.StartSeconds + prev.DurationSeconds
+		}
+	}
+
+	name := fmt.Sprintf("chunk_%03d.wav", index)
+	chunkPath := filepath.Join(jobDir, "chunks", name)
+	if err := writeChunkBody(chunkPath, http.MaxBytesReader(w, r.Body, maxPushedChunk)); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.renderError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Chunks may be at most %s.", formatBytes(maxPushedChunk)), nil)
+			return
+		}
+		s.renderError(w, r, http.StatusBadRequest, "The chunk must be a PCM WAV file.", err)
+		return
+	}
+
+	opts := chunker.Options{
+		MakeBase64:     s.makeBase64,
+		Transcribe:     job.TranscriptionRequested,
+		SkipSilence:    job.SkipSilence,
+		WordTimestamps: job.WordTimestamps,
+		WhisperArgs:    job.WhisperArgs,
+		Hooks:          []chunker.Hook{commandFailureLog{jobID: job.ID}},
+	}
+	opts.WhisperTimeoutPerChunk = s.cfg.Commands.WhisperTimeout
+	if job.QualityGate != nil {
+		opts.QualityGate = *job.QualityGate
+	}
+	if job.Escalation != nil {
+		opts.Escalation = *job.Escalation
+	}
+	if opts.TranscriptSets, err = chunker.SelectTranscriptSets(s.transcriptSets, job.TranscriptSets); err != nil {
+		s.renderError(w, r, http.StatusConflict, "A transcript set of this job is no longer configured.", err)
+		return
+	}
+
+	commands := &chunker.CommandLog{}
+	ctx, closeLog := s.openJobLog(chunker.WithCommandLog(r.Context(), commands), job, jobDir, fmt.Sprintf("chunk %d pushed", index))
+	defer closeLog()
+	s.workerSlots <- struct{}{}
+	chunk, logs, err := s.processor.AddChunk(ctx, jobDir, chunker.Chunk{
+		Index:           index,
+		Track:           track,
+		StartSeconds:    start,
+		DurationSeconds: duration,
+		AudioFile:       "chunks/" + name,
+	}, opts)
+	<-s.workerSlots
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The chunk could not be processed.", err)
+		return
+	}
+
+	status := http.StatusCreated
+	if index < len(job.Chunks) {
+		job.Chunks[index] = chunk
+		status = http.StatusOK
+	} else {
+		job.Chunks = append(job.Chunks, chunk)
+	}
+	appendProcessingLog(job, logs)
+	job.Commands = append(job.Commands, commands.Runs()...)
+	job.Tools = s.processor.ToolsFor(opts)
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+	writeJSON(w, status, chunk)
+}
+
+// handleAPIJobComplete serves POST /api/v1/jobs/{id}/complete, closing a pushed job once
+// its producer has sent every chunk.
+func (s *server) handleAPIJobComplete(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	jobDir, job, release, ok := s.claimPushJob(w, r, jobID)
+	if !ok {
+		return
+	}
+	defer release()
+
+	if len(job.Chunks) == 0 {
+		s.renderError(w, r, http.StatusConflict, "Push at least one chunk before completing the job.", nil)
+		return
+	}
+	job.Status = chunker.JobStatusCompleted
+	completed := time.Now()
+	job.CompletedAt = &completed
+	job.EndStages(nil, completed)
+	job.CountFailedChunks()
+	s.sealArtefacts(jobDir, job)
+	s.remeasure(jobDir, job)
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+	s.notifyWebhooks(eventCompleted, job)
+	writeJSON(w, http.StatusOK, job)
+}
+
+// claimPushJob loads a pushed job that is still receiving and marks it in flight, so
+// concurrent pushes, edits, and deletes wait their turn. The caller must call release.
+func (s *server) claimPushJob(w http.ResponseWriter, r *http.Request, jobID string) (string, *chunker.Job, func(), bool) {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	s.mu.Lock()
+	if _, busy := s.jobsInFlight[jobID]; busy {
+		s.mu.Unlock()
+		s.renderError(w, r, http.StatusConflict, "Another request for this job is still running; retry once it finishes.", nil)
+		return "", nil, nil, false
+	}
+	s.jobsInFlight[jobID] = nil
+	s.mu.Unlock()
+	release := func() {
+		s.mu.Lock()
+		delete(s.jobsInFlight, jobID)
+		s.mu.Unlock()
+	}
+
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		release()
+		status := http.StatusInternalServerError
+		if errors.Is(err, fs.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		s.renderError(w, r, status, "Job not found.", err)
+		return "", nil, nil, false
+	}
+	if job.Source != chunker.JobSourcePush || job.Status != chunker.JobStatusReceiving {
+		release()
+		s.renderError(w, r, http.StatusConflict, "This job is not accepting pushed chunks.", nil)
+		return "", nil, nil, false
+	}
+	s.mu.Lock()
+	s.jobsInFlight[jobID] = job
+	s.mu.Unlock()
+	return jobDir, job, release, true
+}
+
+// writeChunkBody stores a pushed chunk through a temporary file and checks it is a WAV
+// file before moving it into place, so a failed, partial, or malformed upload never
+// replaces a chunk that was already received.
+func writeChunkBody(path string, body io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "push-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := chunker.WAVDuration(tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// optionalSeconds parses a non-negative number of seconds; empty yields -1.
+func optionalSeconds(value string) (float64, error) {
+	if value == "" {
+		return -1, nil
+	}
+	secs, err := strconv.ParseFloat(value, 64)
+	if err != nil || secs < 0 || math.IsNaN(secs) || math.IsInf(secs, 0) {
+		return 0, fmt.Errorf("%q is not a non-negative number of seconds", value)
+	}
+	return secs, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/internal/queue"
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// publishTimeout bounds handing one job to the broker.
+const publishTimeout = 30 * time.Second
+
+// dispatchPollInterval is how often the server reads the job.json of a job a worker is
+// processing, to put its status and stage changes in the change journal.
+const dispatchPollInterval = 2 * time.Second
+
+// dispatchJob is processJob for a server with a queue: it publishes the job for a worker
+// and returns once the worker is done and the job is finished here.
+func (s *server) dispatchJob(job *chunker.Job, jobDir, originalPath string, opts chunker.Options) {
+	job.Status = chunker.JobStatusPending
+	job.ResetStages()
+	job.ErrorMessage = ""
+	job.ProcessingLog = ""
+	job.Commands = nil
+	job.Worker = ""
+	s.adoptChunkDonor(job, &opts)
+	// Workers may mount the data directory elsewhere; they find the donor by
+	// job.ReusedChunksFrom instead.
+	opts.ReuseChunksDir = ""
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		log.Printf("job %s: failed to update status: %v", job.ID, err)
+	}
+
+	done := make(chan *chunker.Job, 1)
+	s.mu.Lock()
+	s.dispatched[job.ID] = done
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	err := s.queue.Publish(ctx, queue.Task{JobID: job.ID, Options: opts})
+	cancel()
+	if err != nil {
+		s.mu.Lock()
+		delete(s.dispatched, job.ID)
+		s.mu.Unlock()
+		job.ApplyResult(chunker.Result{}, fmt.Errorf("handing the job to a worker: %w", err))
+		s.finishJob(job, jobDir, originalPath)
+		return
+	}
+
+	finished, ok := s.awaitWorker(job, jobDir, done)
+	switch {
+	case finished == nil:
+		s.forgetJob(job.ID)
+	case !ok:
+		*job = *finished
+		s.forgetJob(job.ID)
+	default:
+		*job = *finished
+		s.finishJob(job, jobDir, originalPath)
+	}
+}
+
+// awaitWorker waits for the finished copy of the dispatched job on done. Meanwhile it
+// journals the status and stage changes the worker saves, which do not pass through
+// this server. With several servers sharing the queue another one may receive the
+// finished copy instead and finish the job; once the job has been done for a lease TTL,
+// awaitWorker stops waiting and returns the stored copy and false.
+func (s *server) awaitWorker(job *chunker.Job, jobDir string, done chan *chunker.Job) (*chunker.Job, bool) {
+	ticker := time.NewTicker(min(dispatchPollInterval, s.leaseTTL))
+	defer ticker.Stop()
+	seen := processingState(job)
+	for {
+		select {
+		case finished := <-done:
+			return finished, true
+		case <-ticker.C:
+		}
+		current, err := storage.LoadJob(jobDir)
+		if err != nil {
+			continue
+		}
+		if state := processingState(current); state != seen {
+			if _, err := s.journal.Append(storage.ChangeUpdated, current.ID, current); err != nil {
+				log.Printf("job %s: failed to record change: %v", current.ID, err)
+			}
+			seen = state
+		}
+		if !current.IsDone() || current.CompletedAt == nil || time.Since(*current.CompletedAt) < s.leaseTTL {
+			continue
+		}
+		s.mu.Lock()
+		_, waiting := s.dispatched[job.ID]
+		delete(s.dispatched, job.ID)
+		s.mu.Unlock()
+		if !waiting {
+			// collectFinished took it just now and is sending it.
+			return <-done, true
+		}
+		return current, false
+	}
+}
+
+// collectFinished receives the jobs workers are done with and finishes them: through
+// the dispatchJob call waiting for one, or directly for jobs dispatched before a restart.
+func (s *server) collectFinished() {
+	for {
+		f, err := s.queue.NextFinished(context.Background())
+		if err != nil {
+			log.Printf("queue: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if !storage.ValidJobID(f.JobID) {
+			log.Printf("queue: ignored a finished message for the invalid job ID %q", f.JobID)
+			continue
+		}
+		jobDir := storage.JobDir(s.jobsDir, f.JobID)
+		job, err := storage.LoadJob(jobDir)
+		if err != nil {
+			log.Printf("job %s: worker %s finished it, but it cannot be loaded: %v", f.JobID, f.Worker, err)
+		}
+
+		s.mu.Lock()
+		done, waiting := s.dispatched[f.JobID]
+		delete(s.dispatched, f.JobID)
+		s.mu.Unlock()
+		switch {
+		case waiting:
+			done <- job
+		case job != nil:
+			go s.finishJob(job, jobDir, filepath.Join(jobDir, filepath.FromSlash(job.OriginalVideoPath)))
+		}
+	}
+}
+
+// processingState sums up what a change journal entry is worth recording for a job a
+// worker runs: its status, worker, and the status of each stage, but not its progress.
+func processingState(job *chunker.Job) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", job.Status, job.Worker)
+	for _, stage := range job.Stages {
+		fmt.Fprintf(&b, " %s=%s", stage.Name, stage.Status)
+	}
+	return b.String()
+}
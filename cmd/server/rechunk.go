@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// Rechunk targets accepted in the "target" form field.
+const (
+	rechunkNew     = "new"
+	rechunkReplace = "replace"
+)
+
+// handleJobRechunk serves POST /jobs/{id}/rechunk: the job's kept original is cut again
+// with the chunking fields of the upload form, so settings can be changed without a
+// re-upload. Fields left out keep the job's current value. With target=new (the
+// default) the result is a new job linked through rechunkedFrom; with target=replace
+// the job's chunks, transcripts, and merged file are archived as a revision and rebuilt
+// in place.
+func (s *server) handleJobRechunk(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		s.renderError(w, r, http.StatusBadRequest, "The re-chunk form could not be read.", err)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+	target := r.FormValue("target")
+	if target == "" {
+		target = rechunkNew
+	}
+	if target != rechunkNew && target != rechunkReplace {
+		s.renderError(w, r, http.StatusBadRequest, "target must be “new” or “replace”.", nil)
+		return
+	}
+
+	srcDir := storage.JobDir(s.jobsDir, jobID)
+	src, err := storage.LoadJob(srcDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	inFlight := s.inFlight(jobID)
+	if inFlight || src.Status == chunker.JobStatusReceiving {
+		s.renderError(w, r, http.StatusConflict, "The job is still running; re-chunk it once it has finished.", nil)
+		return
+	}
+	if src.Source == chunker.JobSourcePush || src.OriginalVideoPath == "" || src.OriginalDiscardedAt != nil {
+		s.renderError(w, r, http.StatusGone, "The job's original upload is not kept, so it cannot be re-chunked.", nil)
+		return
+	}
+
+	inheritSegmentFields(r.Form, src)
+	segment, err := s.parseSegmentForm(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	opts, err := s.jobOptions(src, &segment)
+	switch {
+	case errors.Is(err, errTranscriptSetGone):
+		s.renderError(w, r, http.StatusConflict, "A transcript set of this job is no longer configured.", err)
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	job, jobDir := src, srcDir
+	if target == rechunkNew {
+		if job, jobDir, err = s.createRechunkJob(src, srcDir); err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "The new job could not be created.", err)
+			return
+		}
+	} else {
+		if err := s.deduper.Forget(jobDir); err != nil {
+			log.Printf("job %s: failed to update dedup index: %v", jobID, err)
+		}
+		s.transcripts.RemoveJob(jobID)
+		if err := s.archiveRevision(jobDir, job); err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "The previous chunks could not be archived.", err)
+			return
+		}
+		if _, err := storage.WriteManifest(jobDir, job); err != nil {
+			log.Printf("job %s: failed to rewrite manifest: %v", jobID, err)
+		}
+		job.ChunkRevision++
+		// The offset described the old chunks; the new ones start at zero.
+		job.TimeOffsetSeconds = 0
+	}
+
+	job.ChunkDurationSeconds = segment.chunkDuration
+	job.RequestedChunkSeconds = segment.requestedDuration
+	job.ChunkCount = segment.chunkCount
+	job.RemainderPolicy = string(segment.remainder)
+	job.FadeMillis = segment.fadeMillis
+	job.PaddingMillis = segment.paddingMillis
+	job.FilterPreset = string(segment.filters)
+	job.TrackMode = string(segment.trackMode)
+	job.AudioTrack = segment.audioTrack
+	job.MergedFormat = string(segment.mergedFormat)
+	job.MergedAudioFile = ""
+	job.VideoClips = segment.videoClips
+	job.SubtitlesFile = ""
+	job.SubtitledVideoFile = ""
+	job.Summary = nil
+	job.Chapters = nil
+	job.ChaptersFile = ""
+	job.ChapterMetadataFile = ""
+	job.InjectedFault = ""
+	job.SegmentKey = opts.SegmentKey()
+	job.ReusedChunksFrom = ""
+	job.Chunks = []chunker.Chunk{}
+	job.CompletedAt = nil
+	job.ErrorMessage = ""
+	job.Progress = nil
+	job.Status = chunker.JobStatusPending
+
+	change := storage.ChangeUpdated
+	if target == rechunkNew {
+		change = storage.ChangeCreated
+	}
+	if err := s.saveJob(jobDir, job, change); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.jobsInFlight[job.ID] = job
+	s.mu.Unlock()
+	go s.processJob(job, jobDir, filepath.Join(jobDir, filepath.FromSlash(job.OriginalVideoPath)), opts)
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+	flash := "Re-chunking with the new settings."
+	if target == rechunkNew {
+		flash = "Re-chunking into this new job; the original job is unchanged."
+	}
+	if notice := segment.limitNotice(); notice != "" {
+		flash += " " + notice
+	}
+	s.seeOther(w, r, "/jobs/"+job.ID+"?flash="+url.QueryEscape(flash))
+}
+
+// errTranscriptSetGone reports a job whose transcript sets are no longer all configured.
+var errTranscriptSetGone = errors.New("transcript set no longer configured")
+
+// jobOptions rebuilds the processing options of src, with segment's chunking in place of
+// its own. The chunk duration is fitted to the transcription backend's limit.
+func (s *server) jobOptions(src *chunker.Job, segment *segmentForm) (chunker.Options, error) {
+	opts := chunker.Options{
+		MakeBase64:     s.makeBase64,
+		Transcribe:     src.TranscriptionRequested,
+		TranscriptOnly: src.TranscriptOnly,
+		SkipSilence:    src.SkipSilence,
+		WordTimestamps: src.WordTimestamps,
+		Subtitles:      chunker.SubtitleMode(src.Subtitles),
+		Summarize:      src.Summarize,
+		WhisperArgs:    src.WhisperArgs,
+	}
+	opts.ExtractKeywords = src.ExtractKeywords
+	opts.AnalyzeSentiment = src.AnalyzeSentiment
+	opts.DetectChapters = src.DetectChapters
+	opts.Redact = chunker.RedactionMode(src.Redact)
+	opts.DetectPII = src.DetectPII
+	opts.CPUOnly = src.CPUOnly
+	if src.QualityGate != nil {
+		opts.QualityGate = *src.QualityGate
+	}
+	if src.Escalation != nil {
+		opts.Escalation = *src.Escalation
+	}
+	var err error
+	if opts.TranscriptSets, err = chunker.SelectTranscriptSets(s.transcriptSets, src.TranscriptSets); err != nil {
+		return opts, fmt.Errorf("%w: %w", errTranscriptSetGone, err)
+	}
+	if opts.Transcribe {
+		if err := segment.limitTo(s.maxChunkSeconds(opts.TranscriptSets)); err != nil {
+			return opts, err
+		}
+	}
+	segment.apply(&opts)
+	return opts, nil
+}
+
+// createRechunkJob opens a job that shares src's original and copies everything about
+// it except the chunking settings, which the caller fills in.
+func (s *server) createRechunkJob(src *chunker.Job, srcDir string) (*chunker.Job, string, error) {
+	jobID, jobDir, err := storage.CreateJobDir(s.jobsDir, s.jobIDFunc(src.Source, src.Metadata))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.router.PrepareJob(jobDir, storage.ArtefactDirs...); err != nil {
+		return nil, "", err
+	}
+	if err := storage.ShareOriginal(srcDir, jobDir, src.OriginalVideoPath); err != nil {
+		_ = storage.RemoveJob(jobDir)
+		return nil, "", err
+	}
+	job := &chunker.Job{
+		ID:                     jobID,
+		Title:                  src.Title,
+		Source:                 src.Source,
+		OriginalFileName:       src.OriginalFileName,
+		OriginalVideoPath:      src.OriginalVideoPath,
+		DiscardOriginal:        src.DiscardOriginal,
+		OriginalSHA256:         src.OriginalSHA256,
+		RechunkedFrom:          src.ID,
+		CreatedAt:              time.Now(),
+		TranscriptionRequested: src.TranscriptionRequested,
+		TranscriptSets:         src.TranscriptSets,
+		WhisperArgs:            src.WhisperArgs,
+		SkipSilence:            src.SkipSilence,
+		TranscriptOnly:         src.TranscriptOnly,
+		WordTimestamps:         src.WordTimestamps,
+		Subtitles:              src.Subtitles,
+		Summarize:              src.Summarize,
+		ExtractKeywords:        src.ExtractKeywords,
+		AnalyzeSentiment:       src.AnalyzeSentiment,
+		DetectChapters:         src.DetectChapters,
+		Redact:                 src.Redact,
+		DetectPII:              src.DetectPII,
+		CPUOnly:                src.CPUOnly,
+		QualityGate:            src.QualityGate,
+		Escalation:             src.Escalation,
+		RecordingStart:         src.RecordingStart,
+		RecordingStartSource:   src.RecordingStartSource,
+		Tags:                   src.Tags,
+		Note:                   src.Note,
+		Metadata:               src.Metadata,
+	}
+	return job, jobDir, nil
+}
+
+// inheritSegmentFields fills the chunking fields a re-chunk request leaves out with the
+// job's current settings. Giving a duration without chunk_mode switches a job that was
+// split by count back to splitting by duration.
+func inheritSegmentFields(form url.Values, job *chunker.Job) {
+	inherit := func(key, value string) {
+		if _, ok := form[key]; !ok {
+			form.Set(key, value)
+		}
+	}
+	_, hasValue := form["chunk_value"]
+	_, hasLegacy := form["chunk_duration"]
+	if job.ChunkCount > 0 && !hasValue && !hasLegacy {
+		inherit("chunk_mode", "count")
+		inherit("chunk_count", strconv.Itoa(job.ChunkCount))
+	}
+	if !hasValue && !hasLegacy {
+		inherit("chunk_duration", strconv.Itoa(job.ChunkDurationSeconds))
+	}
+	inherit("remainder", job.RemainderPolicy)
+	inherit("fade_ms", strconv.Itoa(job.FadeMillis))
+	inherit("pad_ms", strconv.Itoa(job.PaddingMillis))
+	inherit("filters", job.FilterPreset)
+	inherit("merged_audio", job.MergedFormat)
+	if job.VideoClips {
+		inherit("video_clips", "on")
+	}
+	switch chunker.TrackMode(job.TrackMode) {
+	case chunker.TrackMix, chunker.TrackSeparate:
+		inherit("audio_track", job.TrackMode)
+	default:
+		inherit("audio_track", strconv.Itoa(job.AudioTrack+1))
+	}
+}
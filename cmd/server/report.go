@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"audi/internal/storage"
+)
+
+// handleJobReport serves /jobs/{id}/report as text and /jobs/{id}/report.json. Jobs that
+// finished before reports were written get one built on the fly from job.json.
+func (s *server) handleJobReport(w http.ResponseWriter, r *http.Request, jobID string, asJSON bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	report, err := storage.LoadReport(jobDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		job, loadErr := storage.LoadJob(jobDir)
+		if loadErr != nil {
+			s.renderError(w, r, http.StatusNotFound, "Job not found.", loadErr)
+			return
+		}
+		if !job.IsDone() {
+			s.renderError(w, r, http.StatusConflict, "The integrity report is written once the job finishes.", nil)
+			return
+		}
+		report, err = storage.BuildReport(job), nil
+	}
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The integrity report could not be read.", err)
+		return
+	}
+
+	if asJSON {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, report.Text())
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"audi/internal/storage"
+)
+
+// runRetention periodically deletes finished jobs older than maxAge.
+func (s *server) runRetention(maxAge time.Duration) {
+	interval := maxAge / 4
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.pruneExpiredJobs(maxAge)
+		<-ticker.C
+	}
+}
+
+// pruneExpiredJobs removes completed or failed jobs whose completion is older than maxAge.
+func (s *server) pruneExpiredJobs(maxAge time.Duration) {
+	jobs, err := storage.ListJobs(s.jobsDir)
+	if err != nil {
+		log.Printf("retention: listing jobs: %v", err)
+		return
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, job := range jobs {
+		if !job.IsDone() || job.CompletedAt == nil || job.CompletedAt.After(cutoff) {
+			continue
+		}
+
+		inFlight := s.inFlight(job.ID)
+		if inFlight {
+			continue
+		}
+
+		if err := s.deleteJob(job.ID); err != nil {
+			log.Printf("retention: deleting job %s: %v", job.ID, err)
+			continue
+		}
+		log.Printf("retention: deleted job %s (completed %s)", job.ID, job.CompletedAt.Format(time.RFC3339))
+	}
+}
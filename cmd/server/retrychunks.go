@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+func init() {
+	registerAPI(apiOperation{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/jobs/{id}/chunks:retryFailed",
+		ID:       "retryFailedChunks",
+		Summary:  "Transcribe the chunks whose transcription failed again.",
+		Status:   http.StatusAccepted,
+		Response: chunker.Job{},
+	})
+}
+
+// handleJobRetryChunks serves POST /jobs/{id}/retry-chunks, the job page's button for
+// transcribing its failed chunks again.
+func (s *server) handleJobRetryChunks(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+	jobURL := "/jobs/" + jobID
+	job, err := s.retryFailedChunks(jobID)
+	var opErr *jobOpError
+	switch {
+	case errors.As(err, &opErr):
+		s.seeOther(w, r, jobURL+"?error="+url.QueryEscape(strings.TrimSuffix(opErr.msg, ".")))
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "The failed chunks could not be retried.", err)
+		return
+	}
+	flash := fmt.Sprintf("Transcribing %d failed chunks again", job.FailedChunks)
+	s.seeOther(w, r, jobURL+"?flash="+url.QueryEscape(flash))
+}
+
+// handleAPIRetryFailedChunks serves POST /api/v1/jobs/{id}/chunks:retryFailed. It answers
+// 202 with the job once the retry has started; failedChunks drops as chunks succeed.
+func (s *server) handleAPIRetryFailedChunks(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	job, err := s.retryFailedChunks(jobID)
+	var opErr *jobOpError
+	switch {
+	case errors.As(err, &opErr):
+		s.renderError(w, r, opErr.status, opErr.msg, nil)
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "The failed chunks could not be retried.", err)
+		return
+	}
+	w.Header().Set("Location", s.link("/api/v1/jobs/"+jobID))
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// retryFailedChunks starts transcribing the chunks of a completed job whose
+// transcription failed again, in the background, and returns the job.
+func (s *server) retryFailedChunks(jobID string) (*chunker.Job, error) {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	switch {
+	case err != nil:
+		return nil, &jobOpError{http.StatusNotFound, "Job not found."}
+	case s.inFlight(jobID):
+		return nil, &jobOpError{http.StatusConflict, "The job is still processing."}
+	case job.Status != chunker.JobStatusCompleted:
+		return nil, &jobOpError{http.StatusConflict, "Only completed jobs have failed chunks to retry; retry failed jobs as a whole."}
+	case job.ArtefactsPurgedAt != nil:
+		return nil, &jobOpError{http.StatusGone, "The job's chunk audio was purged, so its chunks cannot be transcribed again."}
+	}
+	var failed []int
+	for i, chunk := range job.Chunks {
+		if chunk.TranscriptError == "" {
+			continue
+		}
+		if chunk.AudioFile == "" {
+			return nil, &jobOpError{http.StatusGone, "This job kept transcripts only, so its chunks cannot be transcribed again."}
+		}
+		failed = append(failed, i)
+	}
+	if len(failed) == 0 {
+		return nil, &jobOpError{http.StatusConflict, "No chunk of this job failed to transcribe."}
+	}
+
+	form := url.Values{}
+	inheritSegmentFields(form, job)
+	segment, err := s.parseSegmentForm(&http.Request{Form: form})
+	if err != nil {
+		return nil, &jobOpError{http.StatusConflict, err.Error()}
+	}
+	opts, err := s.jobOptions(job, &segment)
+	switch {
+	case errors.Is(err, errTranscriptSetGone):
+		return nil, &jobOpError{http.StatusConflict, "A transcript set of this job is no longer configured."}
+	case err != nil:
+		return nil, &jobOpError{http.StatusConflict, err.Error()}
+	}
+	// The Base64 dumps did not fail; only the transcripts are redone.
+	opts.MakeBase64 = false
+	opts.WhisperTimeoutPerChunk = s.cfg.Commands.WhisperTimeout
+	opts.Hooks = append(opts.Hooks, commandFailureLog{jobID: job.ID})
+
+	s.mu.Lock()
+	s.jobsInFlight[job.ID] = job
+	s.mu.Unlock()
+	go s.retryChunks(job, jobDir, failed, opts)
+	return job, nil
+}
+
+// retryChunks transcribes the chunks at the indexes in failed again one by one, saving
+// the job after each, and then seals its artefacts once more.
+func (s *server) retryChunks(job *chunker.Job, jobDir string, failed []int, opts chunker.Options) {
+	defer s.forgetJob(job.ID)
+	ctx, closeLog := s.openJobLog(context.Background(), job, jobDir, fmt.Sprintf("retrying %d failed chunks", len(failed)))
+	defer closeLog()
+	for _, i := range failed {
+		commands := &chunker.CommandLog{}
+		s.workerSlots <- struct{}{}
+		chunk, logs, err := s.processor.RetryTranscription(chunker.WithCommandLog(ctx, commands), jobDir, job.Chunks[i], opts)
+		<-s.workerSlots
+		if err != nil {
+			chunk.TranscriptError = err.Error()
+		}
+		job.Chunks[i] = chunk
+		appendProcessingLog(job, logs)
+		job.Commands = append(job.Commands, commands.Runs()...)
+		job.Tools = s.processor.ToolsFor(opts)
+		job.CountFailedChunks()
+		if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+			log.Printf("job %s: failed to save retried chunk %d: %v", job.ID, job.Chunks[i].Index, err)
+		}
+	}
+
+	s.sealArtefacts(jobDir, job)
+	s.remeasure(jobDir, job)
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		log.Printf("job %s: failed to save retried chunks: %v", job.ID, err)
+	}
+	log.Printf("job %s: retried %d failed chunks, %d still failing", job.ID, len(failed), job.FailedChunks)
+}
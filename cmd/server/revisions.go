@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// revisionSummary describes one revision in the list, without its chunks.
+type revisionSummary struct {
+	Number               int               `json:"number"`
+	Current              bool              `json:"current,omitempty"`
+	ArchivedAt           *time.Time        `json:"archivedAt,omitempty"`
+	Status               chunker.JobStatus `json:"status"`
+	CompletedAt          *time.Time        `json:"completedAt,omitempty"`
+	ChunkDurationSeconds int               `json:"chunkDurationSeconds"`
+	ChunkCount           int               `json:"chunkCount,omitempty"`
+	RemainderPolicy      string            `json:"remainderPolicy,omitempty"`
+	Chunks               int               `json:"chunks"`
+	AudioSeconds         float64           `json:"audioSeconds"`
+	URL                  string            `json:"url"`
+}
+
+// revisionDiff compares two revisions of a job: the settings that differ and every chunk
+// position, matched by track and index.
+type revisionDiff struct {
+	From     int             `json:"from"`
+	To       int             `json:"to"`
+	Settings []settingChange `json:"settings"`
+	Chunks   []chunkChange   `json:"chunks"`
+}
+
+type settingChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// chunkChange is one chunk position in a diff. Status is added, removed, changed, or
+// unchanged; Changes lists what differs: bounds, audio, or transcript.
+type chunkChange struct {
+	Index   int            `json:"index"`
+	Track   int            `json:"track,omitempty"`
+	Status  string         `json:"status"`
+	Changes []string       `json:"changes,omitempty"`
+	From    *chunker.Chunk `json:"from,omitempty"`
+	To      *chunker.Chunk `json:"to,omitempty"`
+}
+
+// archiveRevision moves the job's current artefacts aside as its current revision and
+// records it on the job, then prunes down to the configured number of revisions. The
+// job is not saved.
+func (s *server) archiveRevision(jobDir string, job *chunker.Job) error {
+	n := job.CurrentRevision()
+	if _, err := storage.ArchiveRevision(jobDir, n); err != nil {
+		return err
+	}
+	job.Revisions = append(job.Revisions, job.RevisionOf(time.Now(), func(rel string) string {
+		return storage.RevisionPath(rel, n)
+	}))
+	if s.keepRevisions > 0 {
+		s.pruneRevisions(jobDir, job, s.keepRevisions)
+	}
+	return nil
+}
+
+// pruneRevisions deletes the oldest archived revisions until at most keep remain and
+// returns the numbers removed. A revision whose files could not be deleted is kept.
+func (s *server) pruneRevisions(jobDir string, job *chunker.Job, keep int) []int {
+	var removed []int
+	for len(job.Revisions) > keep {
+		n := job.Revisions[0].Number
+		if err := storage.RemoveRevision(jobDir, n); err != nil {
+			log.Printf("job %s: failed to prune revision %d: %v", job.ID, n, err)
+			break
+		}
+		job.Revisions = job.Revisions[1:]
+		removed = append(removed, n)
+	}
+	return removed
+}
+
+func init() {
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/revisions", ID: "listRevisions", Summary: "The job's revisions, oldest first, ending with the current one.", Response: []revisionSummary{}},
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/revisions/{number}", ID: "getRevision", Summary: "One revision with its chunks; current names the current one.", Response: chunker.Revision{}},
+		apiOperation{Method: http.MethodDelete, Path: "/api/v1/jobs/{id}/revisions/{number}", ID: "deleteRevision", Summary: "Delete an archived revision's files.", Status: http.StatusNoContent},
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/revisions/diff", ID: "diffRevisions", Summary: "Compare two revisions.",
+			Query: []apiParam{{Name: "from"}, {Name: "to"}}, Response: revisionDiff{}},
+		apiOperation{Method: http.MethodPost, Path: "/api/v1/jobs/{id}/revisions/prune", ID: "pruneRevisions", Summary: "Delete all but the newest archived revisions.",
+			Query: []apiParam{{Name: "keep", Type: "integer"}}, Response: []revisionSummary{}},
+	)
+}
+
+// handleAPIJobRevisions serves /api/v1/jobs/{id}/revisions and below:
+//
+//	GET    revisions            list the archived revisions and the current one
+//	GET    revisions/{n}        one revision with its chunks
+//	GET    revisions/diff       compare ?from=n&to=m (default: the last two)
+//	DELETE revisions/{n}        delete an archived revision
+//	POST   revisions/prune      keep only the newest ?keep=n archived revisions (default 0)
+func (s *server) handleAPIJobRevisions(w http.ResponseWriter, r *http.Request, jobID string, parts []string) {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		s.writeRevisionList(w, job)
+	case len(parts) == 1 && parts[0] == "diff" && r.Method == http.MethodGet:
+		s.handleRevisionDiff(w, r, job)
+	case len(parts) == 1 && parts[0] == "prune" && r.Method == http.MethodPost:
+		keep := 0
+		if v := r.FormValue("keep"); v != "" {
+			if keep, err = strconv.Atoi(v); err != nil || keep < 0 {
+				s.renderError(w, r, http.StatusBadRequest, "keep must be a number of revisions, 0 or more.", nil)
+				return
+			}
+		}
+		if !s.revisionsEditable(w, r, jobID) {
+			return
+		}
+		if removed := s.pruneRevisions(jobDir, job, keep); len(removed) > 0 {
+			s.remeasure(jobDir, job)
+			if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+				s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+				return
+			}
+		}
+		s.writeRevisionList(w, job)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		rev, ok := s.lookupRevision(w, r, job, parts[0])
+		if ok {
+			writeJSON(w, http.StatusOK, rev)
+		}
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		rev, ok := s.lookupRevision(w, r, job, parts[0])
+		if !ok {
+			return
+		}
+		if rev.Number == job.CurrentRevision() {
+			s.renderError(w, r, http.StatusConflict, "The current revision cannot be deleted; delete the job or re-chunk it instead.", nil)
+			return
+		}
+		if !s.revisionsEditable(w, r, jobID) {
+			return
+		}
+		if err := storage.RemoveRevision(jobDir, rev.Number); err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "The revision could not be deleted.", err)
+			return
+		}
+		job.Revisions = slices.DeleteFunc(job.Revisions, func(other chunker.Revision) bool { return other.Number == rev.Number })
+		s.remeasure(jobDir, job)
+		if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.renderError(w, r, http.StatusNotFound, "Page not found.", nil)
+	}
+}
+
+// revisionsEditable refuses changes to the revisions of a running job, whose worker
+// would overwrite them when it saves.
+func (s *server) revisionsEditable(w http.ResponseWriter, r *http.Request, jobID string) bool {
+	inFlight := s.inFlight(jobID)
+	if inFlight {
+		s.renderError(w, r, http.StatusConflict, "The job is running; change its revisions once it has finished.", nil)
+		return false
+	}
+	return true
+}
+
+func (s *server) writeRevisionList(w http.ResponseWriter, job *chunker.Job) {
+	list := make([]revisionSummary, 0, len(job.Revisions)+1)
+	for _, rev := range allRevisions(job) {
+		summary := revisionSummary{
+			Number:               rev.Number,
+			Current:              rev.Number == job.CurrentRevision(),
+			Status:               rev.Status,
+			CompletedAt:          rev.CompletedAt,
+			ChunkDurationSeconds: rev.ChunkDurationSeconds,
+			ChunkCount:           rev.ChunkCount,
+			RemainderPolicy:      rev.RemainderPolicy,
+			Chunks:               len(rev.Chunks),
+			AudioSeconds:         totalDurationSeconds(rev.Chunks),
+			URL:                  s.link(fmt.Sprintf("/api/v1/jobs/%s/revisions/%d", job.ID, rev.Number)),
+		}
+		if !summary.Current {
+			archived := rev.ArchivedAt
+			summary.ArchivedAt = &archived
+		}
+		list = append(list, summary)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// allRevisions returns the archived revisions followed by the current result.
+func allRevisions(job *chunker.Job) []chunker.Revision {
+	current := job.RevisionOf(time.Time{}, func(rel string) string { return rel })
+	return append(slices.Clone(job.Revisions), current)
+}
+
+// lookupRevision finds revision n, or "current", or renders a 404.
+func (s *server) lookupRevision(w http.ResponseWriter, r *http.Request, job *chunker.Job, value string) (chunker.Revision, bool) {
+	n := job.CurrentRevision()
+	if value != "current" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil {
+			s.renderError(w, r, http.StatusNotFound, "Revision not found.", nil)
+			return chunker.Revision{}, false
+		}
+	}
+	for _, rev := range allRevisions(job) {
+		if rev.Number == n {
+			return rev, true
+		}
+	}
+	s.renderError(w, r, http.StatusNotFound, "Revision not found.", nil)
+	return chunker.Revision{}, false
+}
+
+func (s *server) handleRevisionDiff(w http.ResponseWriter, r *http.Request, job *chunker.Job) {
+	revisions := allRevisions(job)
+	if len(revisions) < 2 && r.FormValue("from") == "" {
+		s.renderError(w, r, http.StatusNotFound, "The job has only one revision.", nil)
+		return
+	}
+	fromValue, toValue := r.FormValue("from"), r.FormValue("to")
+	if fromValue == "" {
+		fromValue = strconv.Itoa(revisions[len(revisions)-2].Number)
+	}
+	if toValue == "" {
+		toValue = "current"
+	}
+	from, ok := s.lookupRevision(w, r, job, fromValue)
+	if !ok {
+		return
+	}
+	to, ok := s.lookupRevision(w, r, job, toValue)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, diffRevisions(from, to))
+}
+
+// diffRevisions lists the changed settings and compares the chunks position by position.
+func diffRevisions(from, to chunker.Revision) revisionDiff {
+	diff := revisionDiff{From: from.Number, To: to.Number, Settings: []settingChange{}, Chunks: []chunkChange{}}
+	setting := func(field string, a, b any) {
+		if a != b {
+			diff.Settings = append(diff.Settings, settingChange{Field: field, From: a, To: b})
+		}
+	}
+	setting("chunkDurationSeconds", from.ChunkDurationSeconds, to.ChunkDurationSeconds)
+	setting("chunkCount", from.ChunkCount, to.ChunkCount)
+	setting("remainderPolicy", from.RemainderPolicy, to.RemainderPolicy)
+	setting("fadeMillis", from.FadeMillis, to.FadeMillis)
+	setting("paddingMillis", from.PaddingMillis, to.PaddingMillis)
+	setting("filterPreset", from.FilterPreset, to.FilterPreset)
+	setting("trackMode", from.TrackMode, to.TrackMode)
+	setting("audioTrack", from.AudioTrack, to.AudioTrack)
+	setting("mergedFormat", from.MergedFormat, to.MergedFormat)
+	setting("videoClips", from.VideoClips, to.VideoClips)
+	setting("subtitles", from.Subtitles, to.Subtitles)
+	setting("timeOffsetSeconds", from.TimeOffsetSeconds, to.TimeOffsetSeconds)
+
+	type position struct{ track, index int }
+	before := make(map[position]*chunker.Chunk, len(from.Chunks))
+	for i := range from.Chunks {
+		c := &from.Chunks[i]
+		before[position{c.Track, c.Index}] = c
+	}
+	seen := make(map[position]bool, len(to.Chunks))
+	for i := range to.Chunks {
+		b := &to.Chunks[i]
+		pos := position{b.Track, b.Index}
+		seen[pos] = true
+		a, ok := before[pos]
+		if !ok {
+			diff.Chunks = append(diff.Chunks, chunkChange{Index: b.Index, Track: b.Track, Status: "added", To: b})
+			continue
+		}
+		change := chunkChange{Index: b.Index, Track: b.Track, Status: "unchanged", From: a, To: b}
+		if a.StartSeconds != b.StartSeconds || a.DurationSeconds != b.DurationSeconds {
+			change.Changes = append(change.Changes, "bounds")
+		}
+		if a.Checksum != b.Checksum {
+			change.Changes = append(change.Changes, "audio")
+		}
+		if strings.TrimSpace(a.TranscriptPreview) != strings.TrimSpace(b.TranscriptPreview) {
+			change.Changes = append(change.Changes, "transcript")
+		}
+		if len(change.Changes) > 0 {
+			change.Status = "changed"
+		}
+		diff.Chunks = append(diff.Chunks, change)
+	}
+	for i := range from.Chunks {
+		a := &from.Chunks[i]
+		if !seen[position{a.Track, a.Index}] {
+			diff.Chunks = append(diff.Chunks, chunkChange{Index: a.Index, Track: a.Track, Status: "removed", From: a})
+		}
+	}
+	return diff
+}
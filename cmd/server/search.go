@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+func init() {
+	// The command palette on every page queries /api/jobs/search.
+	registerTemplateModule(templateModule{name: "search", partials: []string{"palette"}})
+	limit := apiParam{Name: "limit", Type: "integer", Description: "Most results to return."}
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/jobs/search", ID: "searchJobs", Summary: "Typeahead search over job titles, file names, IDs, and tags.",
+			Query: []apiParam{{Name: "q"}, limit}, Response: []searchHit{}},
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/search", ID: "searchTranscripts", Summary: "Full-text search over chunk transcripts and keywords.",
+			Query: []apiParam{{Name: "q", Description: "Words every matching chunk contains."}, limit}, Response: transcriptSearchResult{}},
+	)
+}
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+// searchHit is the trimmed-down job summary the command palette renders per row.
+type searchHit struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Status    chunker.JobStatus `json:"status"`
+	Tags      []string          `json:"tags,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	URL       string            `json:"url"`
+}
+
+// handleAPISearch serves typeahead results for the command palette. Matches are
+// case-insensitive substrings of the title, file name, job ID, or a tag; prefix matches
+// rank first, then newer jobs. An empty query returns the most recent jobs.
+func (s *server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid limit %q.", raw), nil)
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	jobs, err := storage.ListJobs(s.jobsDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+		return
+	}
+
+	type scored struct {
+		job  *chunker.Job
+		rank int
+	}
+	var matches []scored
+	for _, job := range jobs {
+		if rank, ok := matchJob(job, query); ok {
+			matches = append(matches, scored{job: job, rank: rank})
+		}
+	}
+	// ListJobs returns newest first, so a stable sort keeps recency within a rank.
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].rank < matches[j].rank })
+
+	hits := make([]searchHit, 0, min(limit, len(matches)))
+	for _, m := range matches {
+		if len(hits) == limit {
+			break
+		}
+		hits = append(hits, searchHit{
+			ID:        m.job.ID,
+			Name:      m.job.DisplayName(),
+			Status:    m.job.Status,
+			Tags:      m.job.Tags,
+			CreatedAt: m.job.CreatedAt,
+			URL:       s.link("/jobs/" + m.job.ID),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, hits)
+}
+
+// matchJob reports whether job matches the lower-cased query and how well: 0 for a
+// prefix match, 1 for a match elsewhere in a field.
+func matchJob(job *chunker.Job, query string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	best, found := 0, false
+	fields := append([]string{strings.ToLower(job.Title), strings.ToLower(job.OriginalFileName), strings.ToLower(job.ID)}, job.Tags...)
+	for _, field := range fields {
+		idx := strings.Index(field, query)
+		if idx < 0 {
+			continue
+		}
+		rank := 1
+		if idx == 0 {
+			rank = 0
+		}
+		if !found || rank < best {
+			best, found = rank, true
+		}
+	}
+	return best, found
+}
+
+// snippetRadius is how many bytes of context a transcript snippet keeps around its first match.
+const snippetRadius = 80
+
+// transcriptHit is one chunk matching a transcript search.
+type transcriptHit struct {
+	JobID        string  `json:"jobId"`
+	JobName      string  `json:"jobName"`
+	ChunkIndex   int     `json:"chunkIndex"`
+	StartSeconds float64 `json:"startSeconds"`
+	EndSeconds   float64 `json:"endSeconds"`
+	// MatchSeconds is when the first matching word is spoken, for jobs with word timings.
+	MatchSeconds *float64 `json:"matchSeconds,omitempty"`
+	// Snippet is HTML-escaped transcript text around the first match, with matches in <mark>.
+	Snippet template.HTML `json:"snippet"`
+	URL     string        `json:"url"`
+	// Keywords are the chunk's extracted keywords, which also rank the search.
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// transcriptSearchResult is the body of GET /api/v1/search.
+type transcriptSearchResult struct {
+	Query string          `json:"query"`
+	Total int             `json:"total"`
+	Hits  []transcriptHit `json:"hits"`
+}
+
+// handleAPITranscriptSearch serves GET /api/v1/search?q=, a full-text search over the
+// main transcript and keywords of every chunk. Every word of q must occur in a chunk;
+// chunks with more occurrences, where keyword matches count extra, rank first, then newer
+// jobs.
+func (s *server) handleAPITranscriptSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		s.renderError(w, r, http.StatusBadRequest, "Pass the words to search for as q.", nil)
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid limit %q.", raw), nil)
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	hits, total := s.searchTranscripts(query, limit)
+	if hits == nil {
+		hits = []transcriptHit{}
+	}
+	writeJSON(w, http.StatusOK, transcriptSearchResult{Query: query, Total: total, Hits: hits})
+}
+
+// searchTranscripts runs query against the transcript index and resolves the matches
+// against the current job metadata, so titles and time offsets are up to date.
+func (s *server) searchTranscripts(query string, limit int) ([]transcriptHit, int) {
+	matches, total := s.transcripts.Search(query, limit)
+	terms := make(map[string]bool)
+	for _, token := range storage.Tokenize(query) {
+		terms[token.Term] = true
+	}
+
+	jobs := make(map[string]*chunker.Job)
+	var hits []transcriptHit
+	for _, m := range matches {
+		job, ok := jobs[m.JobID]
+		if !ok {
+			loaded, err := storage.LoadJob(storage.JobDir(s.jobsDir, m.JobID))
+			if err != nil {
+				log.Printf("search: loading job %s: %v", m.JobID, err)
+			}
+			job, jobs[m.JobID] = loaded, loaded
+		}
+		if job == nil || m.Chunk >= len(job.Chunks) {
+			total--
+			continue
+		}
+		chunk := job.Chunks[m.Chunk]
+		hit := transcriptHit{
+			JobID:        job.ID,
+			JobName:      job.DisplayName(),
+			ChunkIndex:   chunk.Index,
+			StartSeconds: chunk.StartSeconds,
+			EndSeconds:   chunk.StartSeconds + chunk.DurationSeconds,
+			Snippet:      highlightSnippet(m.Text, terms),
+			URL:          s.link(fmt.Sprintf("/jobs/%s#chunk-%d", job.ID, chunk.Index)),
+		}
+		for _, k := range chunk.Keywords {
+			hit.Keywords = append(hit.Keywords, k.Text)
+		}
+		if segments, err := chunker.ReadChunkWords(storage.JobDir(s.jobsDir, job.ID), chunk); err == nil {
+			hit.MatchSeconds = firstWordMatch(segments, terms)
+		}
+		hits = append(hits, hit)
+	}
+	return hits, total
+}
+
+// highlightSnippet cuts text to snippetRadius bytes around the first matching word,
+// escapes it, and wraps every matching word in <mark>.
+func highlightSnippet(text string, terms map[string]bool) template.HTML {
+	tokens := storage.Tokenize(text)
+	first := -1
+	for _, token := range tokens {
+		if terms[token.Term] {
+			first = token.Start
+			break
+		}
+	}
+	from, to := 0, len(text)
+	if first >= 0 {
+		from, to = max(0, first-snippetRadius), min(len(text), first+snippetRadius)
+	} else {
+		to = min(len(text), 2*snippetRadius)
+	}
+	// Widen to word boundaries so the cut never splits a word or a UTF-8 sequence.
+	for _, token := range tokens {
+		if token.Start < from && token.End > from {
+			from = token.Start
+		}
+		if token.Start < to && token.End > to {
+			to = token.End
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, token := range tokens {
+		if token.Start < from || token.End > to || !terms[token.Term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:token.Start]))
+		b.WriteString("<mark>" + html.EscapeString(text[token.Start:token.End]) + "</mark>")
+		pos = token.End
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return template.HTML(b.String())
+}
+
+// firstWordMatch returns the start of the first timed word containing a query term.
+func firstWordMatch(segments []chunker.TranscriptSegment, terms map[string]bool) *float64 {
+	for _, seg := range segments {
+		for _, word := range seg.Words {
+			for _, token := range storage.Tokenize(word.Word) {
+				if terms[token.Term] {
+					start := word.Start
+					return &start
+				}
+			}
+		}
+	}
+	return nil
+}
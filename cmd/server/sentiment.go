@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// sentimentFilter selects chunks by their sentiment. Its zero value matches every chunk
+// that has one.
+func init() {
+	filter := []apiParam{
+		{Name: "label", Description: "Comma-separated labels: positive, neutral, negative."},
+		{Name: "min_score", Type: "number"},
+		{Name: "max_score", Type: "number"},
+		{Name: "emotion"},
+	}
+	registerAPI(
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/sentiment", ID: "getJobSentiment", Summary: "A job's chunks rated for sentiment.",
+			Query: filter, Response: jobSentiment{}},
+		apiOperation{Method: http.MethodGet, Path: "/api/v1/sentiment", ID: "searchSentiment", Summary: "Chunks of all jobs matching a sentiment filter, newest jobs first.",
+			Query: append(filter, apiParam{Name: "limit", Type: "integer"}), Response: sentimentResult{}},
+	)
+}
+
+type sentimentFilter struct {
+	labels   []string
+	minScore float64
+	maxScore float64
+	// emotion, when set, must be the chunk's strongest emotion.
+	emotion string
+}
+
+// parseSentimentFilter reads ?label= (comma-separated), ?min_score=, ?max_score=, and
+// ?emotion=.
+func parseSentimentFilter(q url.Values) (sentimentFilter, error) {
+	f := sentimentFilter{minScore: -1, maxScore: 1, emotion: strings.ToLower(strings.TrimSpace(q.Get("emotion")))}
+	for _, label := range strings.Split(q.Get("label"), ",") {
+		switch label = strings.ToLower(strings.TrimSpace(label)); label {
+		case "":
+		case chunker.SentimentPositive, chunker.SentimentNeutral, chunker.SentimentNegative:
+			f.labels = append(f.labels, label)
+		default:
+			return f, fmt.Errorf("Unknown label %q; use positive, neutral, or negative.", label)
+		}
+	}
+	for name, bound := range map[string]*float64{"min_score": &f.minScore, "max_score": &f.maxScore} {
+		raw := strings.TrimSpace(q.Get(name))
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || v < -1 || v > 1 {
+			return f, fmt.Errorf("%s must be a number from -1 to 1.", name)
+		}
+		*bound = v
+	}
+	return f, nil
+}
+
+func (f sentimentFilter) matches(s *chunker.Sentiment) bool {
+	if s == nil || s.Score < f.minScore || s.Score > f.maxScore {
+		return false
+	}
+	if len(f.labels) > 0 && !slices.Contains(f.labels, s.Label) {
+		return false
+	}
+	if f.emotion != "" {
+		strongest, best := "", 0.0
+		for name, score := range s.Emotions {
+			if score > best || (score == best && name < strongest) {
+				strongest, best = name, score
+			}
+		}
+		return strongest == f.emotion
+	}
+	return true
+}
+
+// sentimentHit is one chunk matching a sentiment filter.
+type sentimentHit struct {
+	JobID        string             `json:"jobId"`
+	JobName      string             `json:"jobName"`
+	ChunkIndex   int                `json:"chunkIndex"`
+	StartSeconds float64            `json:"startSeconds"`
+	EndSeconds   float64            `json:"endSeconds"`
+	Sentiment    *chunker.Sentiment `json:"sentiment"`
+	URL          string             `json:"url"`
+}
+
+func newSentimentHit(job *chunker.Job, chunk chunker.Chunk, base string) sentimentHit {
+	return sentimentHit{
+		JobID:        job.ID,
+		JobName:      job.DisplayName(),
+		ChunkIndex:   chunk.Index,
+		StartSeconds: chunk.StartSeconds,
+		EndSeconds:   chunk.StartSeconds + chunk.DurationSeconds,
+		Sentiment:    chunk.Sentiment,
+		URL:          fmt.Sprintf("%s/jobs/%s#chunk-%d", base, job.ID, chunk.Index),
+	}
+}
+
+// jobSentiment is the body of GET /api/v1/jobs/<id>/sentiment.
+type jobSentiment struct {
+	// AverageScore and Counts cover every rated chunk of the job, whatever the filter.
+	AverageScore float64        `json:"averageScore"`
+	Counts       map[string]int `json:"counts"`
+	Chunks       []sentimentHit `json:"chunks"`
+}
+
+// sentimentOverview sums up the rated chunks of a job; ok is false when none are rated.
+func sentimentOverview(job *chunker.Job) (average float64, counts map[string]int, ok bool) {
+	counts = map[string]int{chunker.SentimentPositive: 0, chunker.SentimentNeutral: 0, chunker.SentimentNegative: 0}
+	rated := 0
+	for _, chunk := range job.Chunks {
+		if chunk.Sentiment != nil {
+			average += chunk.Sentiment.Score
+			counts[chunk.Sentiment.Label]++
+			rated++
+		}
+	}
+	if rated == 0 {
+		return 0, counts, false
+	}
+	return math.Round(average/float64(rated)*1000) / 1000, counts, true
+}
+
+// handleAPIJobSentiment serves GET /api/v1/jobs/<id>/sentiment: the job's rated chunks
+// that pass the filter, in order, with the job's average score and label counts.
+func (s *server) handleAPIJobSentiment(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	filter, err := parseSentimentFilter(r.URL.Query())
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	body := jobSentiment{Chunks: []sentimentHit{}}
+	body.AverageScore, body.Counts, _ = sentimentOverview(job)
+	for _, chunk := range job.Chunks {
+		if filter.matches(chunk.Sentiment) {
+			body.Chunks = append(body.Chunks, newSentimentHit(job, chunk, s.basePath))
+		}
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// Limits of GET /api/v1/sentiment.
+const (
+	defaultSentimentLimit = 100
+	maxSentimentLimit     = 1000
+)
+
+// sentimentResult is the body of GET /api/v1/sentiment.
+type sentimentResult struct {
+	Total int            `json:"total"`
+	Hits  []sentimentHit `json:"hits"`
+}
+
+// handleAPISentiment serves GET /api/v1/sentiment, the rated chunks of every job that
+// pass the filter, newest jobs first, up to ?limit=.
+func (s *server) handleAPISentiment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	filter, err := parseSentimentFilter(r.URL.Query())
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	limit := defaultSentimentLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid limit %q.", raw), nil)
+			return
+		}
+		limit = min(n, maxSentimentLimit)
+	}
+
+	jobs, err := storage.ListJobs(s.jobsDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+		return
+	}
+	result := sentimentResult{Hits: []sentimentHit{}}
+	for _, job := range jobs {
+		for _, chunk := range job.Chunks {
+			if !filter.matches(chunk.Sentiment) {
+				continue
+			}
+			result.Total++
+			if len(result.Hits) < limit {
+				result.Hits = append(result.Hits, newSentimentHit(job, chunk, s.basePath))
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
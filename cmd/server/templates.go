@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math"
+	"strings"
+
+	"audi/internal/storage"
+)
+
+// templateModule is one subsystem's contribution to the page templates: helper functions
+// and the partials it owns. Partials live in web/templates like every page and are
+// called with {{template "name" .}}; listing them here makes a missing one fail at
+// startup instead of on the first request that renders it.
+type templateModule struct {
+	name     string
+	funcs    func(env templateEnv) template.FuncMap
+	partials []string
+}
+
+// templateEnv is the server state helper functions may close over.
+type templateEnv struct {
+	router   *storage.Router
+	basePath string
+}
+
+// templateModules holds the registered modules in registration order.
+var templateModules []templateModule
+
+// registerTemplateModule adds a module. Subsystems call it from init, next to the code
+// their helpers belong to, so a new view needs no change to main.
+func registerTemplateModule(m templateModule) {
+	templateModules = append(templateModules, m)
+}
+
+func init() {
+	registerTemplateModule(templateModule{
+		name: "format",
+		funcs: func(templateEnv) template.FuncMap {
+			return template.FuncMap{
+				"formatSeconds":       formatSeconds,
+				"formatDurationHuman": formatDurationHuman,
+				"formatBytes":         formatBytes,
+				"uppercase":           strings.ToUpper,
+				"add": func(a, b float64) float64 {
+					return a + b
+				},
+				// add1 turns 0-based indexes into the 1-based numbers shown to users.
+				"add1": func(i int) int {
+					return i + 1
+				},
+				"percent": func(done, total float64) int {
+					if total <= 0 {
+						return 0
+					}
+					return int(math.Min(100, math.Round(done/total*100)))
+				},
+			}
+		},
+	})
+	registerTemplateModule(templateModule{
+		name: "files",
+		funcs: func(env templateEnv) template.FuncMap {
+			// fileURL links a job file, honouring the URL of the storage class holding it.
+			return template.FuncMap{"fileURL": func(jobID, rel string) string {
+				return localFileURL(env.router, env.basePath, jobID, rel)
+			}}
+		},
+	})
+}
+
+// parseTemplates parses every template in fsys with the helpers of all registered
+// modules. Two modules defining the same function, or a module whose partial is not
+// defined by any template, is an error.
+func parseTemplates(fsys fs.FS, env templateEnv) (*template.Template, error) {
+	funcs := template.FuncMap{}
+	owners := map[string]string{}
+	for _, m := range templateModules {
+		if m.funcs == nil {
+			continue
+		}
+		for name, fn := range m.funcs(env) {
+			if owner, dup := owners[name]; dup {
+				return nil, fmt.Errorf("template function %q is registered by both %s and %s", name, owner, m.name)
+			}
+			owners[name] = m.name
+			funcs[name] = fn
+		}
+	}
+
+	tmpl, err := template.New("app").Funcs(funcs).ParseFS(fsys, "*.gohtml")
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range templateModules {
+		for _, partial := range m.partials {
+			if tmpl.Lookup(partial) == nil {
+				return nil, fmt.Errorf("template module %s: partial %q is not defined", m.name, partial)
+			}
+		}
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+func init() {
+	registerTemplateModule(templateModule{
+		name: "timeline",
+		funcs: func(templateEnv) template.FuncMap {
+			return template.FuncMap{"wallClock": wallClock}
+		},
+	})
+}
+
+// handleRecordingStart updates or clears a finished job's recording start time.
+func (s *server) handleRecordingStart(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	back := "/jobs/" + jobID
+	inFlight := s.inFlight(jobID)
+	if inFlight {
+		s.seeOther(w, r, back+"?error="+url.QueryEscape("Wait for processing to finish before changing the recording start"))
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	start, err := parseRecordingStart(r.FormValue("recording_start"), r.FormValue("tz_offset"))
+	if err != nil {
+		s.seeOther(w, r, back+"?error="+url.QueryEscape(err.Error()))
+		return
+	}
+
+	job.RecordingStart = start
+	job.RecordingStartSource = ""
+	if start != nil {
+		job.RecordingStartSource = chunker.RecordingStartUser
+	}
+
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+
+	s.seeOther(w, r, back+"?flash="+url.QueryEscape("Recording start updated"))
+}
+
+// handleTimeOffset sets the constant shift applied to every chunk's start time. Exports
+// and timestamped downloads read the shifted times, so they follow automatically.
+func (s *server) handleTimeOffset(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodPost {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if !s.checkCSRF(w, r) {
+		return
+	}
+
+	back := "/jobs/" + jobID
+	inFlight := s.inFlight(jobID)
+	if inFlight {
+		s.seeOther(w, r, back+"?error="+url.QueryEscape("Wait for processing to finish before changing the time offset"))
+		return
+	}
+
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	offset, err := parseOffsetSeconds(r.FormValue("offset"))
+	if err == nil {
+		err = job.SetTimeOffset(offset)
+	}
+	if err != nil {
+		s.seeOther(w, r, back+"?error="+url.QueryEscape(err.Error()))
+		return
+	}
+
+	if err := s.saveJob(jobDir, job, storage.ChangeUpdated); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job could not be saved.", err)
+		return
+	}
+
+	s.seeOther(w, r, back+"?flash="+url.QueryEscape("Time offset updated"))
+}
+
+// parseOffsetSeconds accepts signed seconds ("12.5", "-3") or a Go duration ("1m30s").
+// An empty value means no offset.
+func parseOffsetSeconds(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(secs) && !math.IsInf(secs, 0) {
+		return secs, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q (want seconds or a duration such as 1m30s)", value)
+	}
+	return d.Seconds(), nil
+}
+
+// handleTranscriptExport concatenates every chunk transcript into one text file, each
+// section headed by its relative range or, with ?timeline=absolute, its wall-clock range.
+// ?set= exports one of the job's extra transcript sets instead of the main transcript, and
+// ?redacted=1 or ?masked=1 its redacted or PII-masked variant.
+func (s *server) handleTranscriptExport(w http.ResponseWriter, r *http.Request, jobID string) {
+	jobDir := storage.JobDir(s.jobsDir, jobID)
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+
+	set := r.URL.Query().Get("set")
+	if set != "" && !slices.Contains(job.TranscriptSets, set) {
+		s.renderError(w, r, http.StatusNotFound, fmt.Sprintf("This job has no transcript set %q.", set), nil)
+		return
+	}
+	redacted := set == "" && r.URL.Query().Get("redacted") == "1"
+	masked := set == "" && !redacted && r.URL.Query().Get("masked") == "1"
+	absolute := r.URL.Query().Get("timeline") == "absolute" && job.RecordingStart != nil
+
+	var b strings.Builder
+	if job.Title != "" {
+		b.WriteString(job.Title)
+		b.WriteString("\n\n")
+	}
+	for _, chunk := range job.Chunks {
+		file := chunk.TranscriptFile
+		if redacted {
+			file = chunk.RedactedTranscriptFile
+		}
+		if masked {
+			file = chunk.MaskedTranscriptFile
+		}
+		if set != "" {
+			file = ""
+			for _, t := range chunk.Transcripts {
+				if t.Set == set {
+					file = t.File
+				}
+			}
+		}
+		if file == "" {
+			continue
+		}
+		text, err := s.readJobFile(r.Context(), job.ID, file)
+		if err != nil {
+			continue
+		}
+		end := chunk.StartSeconds + chunk.DurationSeconds
+		if absolute {
+			fmt.Fprintf(&b, "[%s - %s]\n", wallClock(job, chunk.StartSeconds), wallClock(job, end))
+		} else {
+			fmt.Fprintf(&b, "[%s - %s]\n", formatSeconds(chunk.StartSeconds), formatSeconds(end))
+		}
+		b.WriteString(strings.TrimSpace(string(text)))
+		b.WriteString("\n\n")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	name := exportBaseName(job) + "-transcript.txt"
+	if set != "" {
+		name = exportBaseName(job) + "-transcript-" + set + ".txt"
+	}
+	if redacted {
+		name = exportBaseName(job) + "-transcript-redacted.txt"
+	}
+	if masked {
+		name = exportBaseName(job) + "-transcript-masked.txt"
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// exportBaseName names downloads after the job's title, or its ID when untitled. Path
+// separators are replaced so the title cannot smuggle directories into the file name.
+func exportBaseName(job *chunker.Job) string {
+	if job.Title == "" {
+		return job.ID
+	}
+	return strings.NewReplacer("/", "-", "\\", "-").Replace(job.Title)
+}
+
+// wallClock renders an offset into the recording as an absolute time in the recording's
+// own zone, adding the date once the offset crosses into a different day.
+func wallClock(job *chunker.Job, offsetSeconds float64) string {
+	if job == nil || job.RecordingStart == nil {
+		return formatSeconds(offsetSeconds)
+	}
+	start := *job.RecordingStart
+	t := start.Add(time.Duration(offsetSeconds * float64(time.Second)))
+	if t.YearDay() != start.YearDay() || t.Year() != start.Year() {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return t.Format("15:04:05")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"audi/internal/config"
+)
+
+// listenAndServe serves handler on cfg.Addr: over plain HTTP, or over HTTPS as cfg.TLS
+// configures it, with the HTTP redirector on tls.redirect_addr alongside.
+func listenAndServe(cfg config.Config, handler http.Handler) error {
+	// Bodies get no deadline, as uploads and downloads of long recordings take as long
+	// as they take; headers and idle keep-alive connections do.
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	t := cfg.TLS
+	if !t.Enabled() {
+		log.Printf("listening on %s", cfg.Addr)
+		return server.ListenAndServe()
+	}
+
+	redirect := httpsRedirect(cfg.Addr)
+	if len(t.AutocertDomains) > 0 {
+		cache := t.AutocertCache
+		if cache == "" {
+			cache = filepath.Join(cfg.DataDir, "autocert")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(t.AutocertDomains...),
+			Cache:      autocert.DirCache(cache),
+			Email:      t.AutocertEmail,
+		}
+		if t.ACMEDirectory != "" {
+			manager.Client = &acme.Client{DirectoryURL: t.ACMEDirectory}
+		}
+		server.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+		log.Printf("obtaining certificates for %v, cached in %s", t.AutocertDomains, cache)
+	} else {
+		certs := &certFiles{certFile: t.CertFile, keyFile: t.KeyFile}
+		if _, err := certs.get(nil); err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certs.get}
+	}
+
+	if t.RedirectAddr != "" {
+		go func() {
+			redirector := &http.Server{Addr: t.RedirectAddr, Handler: redirect, ReadHeaderTimeout: 10 * time.Second, IdleTimeout: 2 * time.Minute}
+			log.Printf("redirecting HTTP on %s to HTTPS", t.RedirectAddr)
+			if err := redirector.ListenAndServe(); err != nil {
+				log.Fatalf("HTTP redirector stopped: %v", err)
+			}
+		}()
+	}
+	log.Printf("listening on %s (HTTPS)", cfg.Addr)
+	return server.ListenAndServeTLS("", "")
+}
+
+// httpsRedirect sends every request to the same URL over HTTPS, on the port of the
+// HTTPS address. Methods other than GET and HEAD keep their method and body.
+func httpsRedirect(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
+
+// certFiles serves a certificate from PEM files, loading them again when either
+// changes, so a renewed certificate is picked up without a restart.
+type certFiles struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func (c *certFiles) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	modified, err := latestModTime(c.certFile, c.keyFile)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || (c.cert != nil && !modified.After(c.modified)) {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, fmt.Errorf("reading TLS certificate: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert != nil {
+			// Keep serving the old pair while the files are half-written.
+			return c.cert, nil
+		}
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	c.cert, c.modified = &cert, modified
+	return c.cert, nil
+}
+
+// latestModTime returns the newest modification time of paths.
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// pageTopics caps the topics listed on the job page; the API returns them all.
+const pageTopics = 30
+
+// topic is one keyword of a job's topic timeline with the chunks it was found in, in
+// timeline order.
+type topic struct {
+	Text     string         `json:"text"`
+	Kind     string         `json:"kind,omitempty"`
+	Mentions []topicMention `json:"mentions"`
+}
+
+type topicMention struct {
+	Chunk        int     `json:"chunk"`
+	StartSeconds float64 `json:"startSeconds"`
+}
+
+// topicTimeline merges the keywords of a job's chunks, matched case-insensitively, into
+// topics. Topics found in more chunks come first, then those mentioned earlier.
+func topicTimeline(job *chunker.Job) []topic {
+	var topics []topic
+	byText := make(map[string]int)
+	for _, chunk := range job.Chunks {
+		for _, k := range chunk.Keywords {
+			key := strings.ToLower(k.Text)
+			i, ok := byText[key]
+			if !ok {
+				i = len(topics)
+				byText[key] = i
+				topics = append(topics, topic{Text: k.Text, Kind: k.Kind})
+			}
+			t := &topics[i]
+			if n := len(t.Mentions); n > 0 && t.Mentions[n-1].Chunk == chunk.Index {
+				continue
+			}
+			t.Mentions = append(t.Mentions, topicMention{Chunk: chunk.Index, StartSeconds: chunk.StartSeconds})
+		}
+	}
+	sort.SliceStable(topics, func(i, j int) bool {
+		a, b := topics[i].Mentions, topics[j].Mentions
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a[0].StartSeconds < b[0].StartSeconds
+	})
+	return topics
+}
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/api/v1/jobs/{id}/topics", ID: "getJobTopics", Summary: "The job's topic timeline.", Response: []topic{}})
+}
+
+// handleAPIJobTopics serves GET /api/v1/jobs/<id>/topics, the job's topic timeline.
+func (s *server) handleAPIJobTopics(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	job, err := storage.LoadJob(storage.JobDir(s.jobsDir, jobID))
+	if err != nil {
+		s.renderError(w, r, http.StatusNotFound, "Job not found.", err)
+		return
+	}
+	topics := topicTimeline(job)
+	if topics == nil {
+		topics = []topic{}
+	}
+	writeJSON(w, http.StatusOK, topics)
+}
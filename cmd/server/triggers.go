@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+const (
+	defaultTriggerLimit = 25
+	maxTriggerLimit     = 100
+)
+
+// triggerItem is one event in a polling trigger response. No-code platforms such as
+// Zapier and Make deduplicate on id, so it names the job and the moment it finished: a
+// job that is retried and finishes again shows up as a new event. Fields are flat and
+// timestamps are RFC 3339 in UTC, because nested objects and local times are awkward to
+// map in their editors.
+type triggerItem struct {
+	ID              string  `json:"id"`
+	JobID           string  `json:"jobId"`
+	Event           string  `json:"event"`
+	Name            string  `json:"name"`
+	Title           string  `json:"title"`
+	FileName        string  `json:"fileName"`
+	Tags            string  `json:"tags"`
+	CreatedAt       string  `json:"createdAt"`
+	FinishedAt      string  `json:"finishedAt"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	ChunkCount      int     `json:"chunkCount"`
+	Transcribed     bool    `json:"transcribed"`
+	Error           string  `json:"error"`
+	JobURL          string  `json:"jobUrl"`
+	TranscriptURL   string  `json:"transcriptUrl,omitempty"`
+}
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/api/v1/triggers/{event}", ID: "pollTrigger", Summary: "The newest completed or failed jobs, for polling automation tools.",
+		Query:    []apiParam{{Name: "since", Description: "Only jobs finished after this RFC 3339 timestamp."}, {Name: "limit", Type: "integer"}},
+		Response: []triggerItem{}})
+}
+
+// handleTrigger serves /api/v1/triggers/completed and /api/v1/triggers/failed: the
+// newest finished jobs as a bare JSON array, newest first, which is the shape polling
+// triggers expect. ?since= (RFC 3339) drops older events and ?limit= caps the list.
+func (s *server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	var status chunker.JobStatus
+	event := strings.TrimPrefix(r.URL.Path, "/api/v1/triggers/")
+	switch event {
+	case eventCompleted:
+		status = chunker.JobStatusCompleted
+	case eventFailed:
+		status = chunker.JobStatusFailed
+	default:
+		s.renderError(w, r, http.StatusNotFound, "Triggers are /api/v1/triggers/completed and /api/v1/triggers/failed.", nil)
+		return
+	}
+
+	var since time.Time
+	if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			s.renderError(w, r, http.StatusBadRequest, "since must be an RFC 3339 timestamp such as 2024-01-31T09:30:00Z.", nil)
+			return
+		}
+		since = parsed
+	}
+	limit := defaultTriggerLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, "Invalid limit.", nil)
+			return
+		}
+		limit = min(parsed, maxTriggerLimit)
+	}
+
+	jobs, err := storage.ListJobs(s.jobsDir)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "The job list could not be loaded.", err)
+		return
+	}
+	var finished []*chunker.Job
+	for _, job := range jobs {
+		if job.Status != status || job.CompletedAt == nil || !job.CompletedAt.After(since) {
+			continue
+		}
+		finished = append(finished, job)
+	}
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].CompletedAt.After(*finished[j].CompletedAt)
+	})
+	if len(finished) > limit {
+		finished = finished[:limit]
+	}
+
+	base := s.publicURL
+	if base == "" {
+		base = requestOrigin(r) + s.basePath
+	}
+	items := make([]triggerItem, 0, len(finished))
+	for _, job := range finished {
+		items = append(items, newTriggerItem(job, event, base))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func newTriggerItem(job *chunker.Job, event, base string) triggerItem {
+	var duration float64
+	for _, chunk := range job.Chunks {
+		duration += chunk.DurationSeconds
+	}
+	item := triggerItem{
+		ID:              fmt.Sprintf("%s-%d", job.ID, job.CompletedAt.Unix()),
+		JobID:           job.ID,
+		Event:           event,
+		Name:            job.DisplayName(),
+		Title:           job.Title,
+		FileName:        job.OriginalFileName,
+		Tags:            strings.Join(job.Tags, ", "),
+		CreatedAt:       job.CreatedAt.UTC().Format(time.RFC3339),
+		FinishedAt:      job.CompletedAt.UTC().Format(time.RFC3339),
+		DurationSeconds: duration,
+		ChunkCount:      len(job.Chunks),
+		Transcribed:     job.TranscriptionRequested,
+		Error:           job.ErrorMessage,
+		JobURL:          base + "/jobs/" + job.ID,
+	}
+	if job.TranscriptionRequested && job.Status == chunker.JobStatusCompleted {
+		item.TranscriptURL = base + "/jobs/" + job.ID + "/transcript.txt"
+	}
+	return item
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+// watchedFile is what the folder watcher knows about one file in the watched directory.
+// Files it has dealt with are persisted, so a restart does not ingest them again.
+type watchedFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// JobID names the job created from the file; DuplicateOf the finished job it repeated.
+	JobID       string `json:"jobId,omitempty"`
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+	// Error records why ingesting failed. The file is tried again once it changes.
+	Error string `json:"error,omitempty"`
+
+	stableSince time.Time
+	overQuota   bool
+}
+
+func (f *watchedFile) handled() bool {
+	return f.JobID != "" || f.DuplicateOf != "" || f.Error != ""
+}
+
+// folderWatcher polls a directory and turns files that have stopped changing into jobs,
+// processed with the options of the configured profile.
+type folderWatcher struct {
+	s         *server
+	dir       string
+	patterns  []string
+	link      bool
+	interval  time.Duration
+	settle    time.Duration
+	settings  uploadSettings
+	statePath string
+	// files is keyed by the slash-separated path relative to dir.
+	files map[string]*watchedFile
+}
+
+// newFolderWatcher validates the watch configuration, including its options profile,
+// and loads what earlier runs ingested from statePath.
+func newFolderWatcher(s *server, cfg config.WatchConfig, statePath string) (*folderWatcher, error) {
+	info, err := os.Stat(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", cfg.Dir)
+	}
+
+	// The profile is written as upload form fields, so it goes through the form's parser.
+	form := url.Values{}
+	for field, values := range cfg.Options {
+		form[field] = values
+	}
+	settings, err := s.parseUploadSettings(&http.Request{Form: form})
+	if err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
+	w := &folderWatcher{
+		s:         s,
+		dir:       cfg.Dir,
+		patterns:  cfg.Patterns,
+		link:      cfg.Mode == config.WatchLink,
+		interval:  cfg.Interval,
+		settle:    cfg.Settle,
+		settings:  settings,
+		statePath: statePath,
+		files:     map[string]*watchedFile{},
+	}
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, &w.files); err != nil {
+		return nil, fmt.Errorf("decoding watch state: %w", err)
+	}
+	return w, nil
+}
+
+// run scans the directory every interval until the process exits.
+func (w *folderWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.scan()
+		<-ticker.C
+	}
+}
+
+// scan notes new and changed files, ingests those that have settled, and forgets files
+// that have gone.
+func (w *folderWatcher) scan() {
+	now := time.Now()
+	present := map[string]bool{}
+	dirty := false
+
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Hidden entries are skipped, which also covers the temporary files rsync and
+		// many copy tools write before renaming into place.
+		if path != w.dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !w.matches(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(w.dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		present[rel] = true
+
+		file := w.files[rel]
+		if file == nil || file.Size != info.Size() || !file.ModTime.Equal(info.ModTime()) {
+			if file != nil && file.handled() {
+				dirty = true
+			}
+			w.files[rel] = &watchedFile{Size: info.Size(), ModTime: info.ModTime(), stableSince: now}
+			return nil
+		}
+		if file.handled() || now.Sub(file.stableSince) < w.settle {
+			return nil
+		}
+		if w.ingest(rel, file) {
+			dirty = true
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("watch: scanning %s: %v", w.dir, err)
+		return
+	}
+
+	for rel, file := range w.files {
+		if !present[rel] {
+			dirty = dirty || file.handled()
+			delete(w.files, rel)
+		}
+	}
+	if dirty {
+		w.saveState()
+	}
+}
+
+func (w *folderWatcher) matches(name string) bool {
+	if len(w.patterns) == 0 {
+		return true
+	}
+	for _, pattern := range w.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ingest creates and queues a job for one settled file. It reports whether the file is
+// now handled; a file waiting for quota is tried again on the next scan.
+func (w *folderWatcher) ingest(rel string, file *watchedFile) bool {
+	path := filepath.Join(w.dir, filepath.FromSlash(rel))
+	fail := func(format string, args ...any) bool {
+		file.Error = fmt.Sprintf(format, args...)
+		log.Printf("watch: %s: %s", rel, file.Error)
+		return true
+	}
+
+	if w.s.quota > 0 {
+		_, used, err := w.s.usage.Usage()
+		if err == nil && used+file.Size > w.s.quota {
+			if !file.overQuota {
+				log.Printf("watch: %s: waiting, storage quota exceeded (%s of %s used)", rel, formatBytes(used), formatBytes(w.s.quota))
+				file.overQuota = true
+			}
+			return false
+		}
+	}
+
+	sum, err := storage.HashFile(path)
+	if err != nil {
+		return fail("hashing: %v", err)
+	}
+	job, opts := w.s.newUploadJob(w.settings, rel, sum)
+	job.Source = chunker.JobSourceWatch
+	if !w.settings.force {
+		if dup := w.s.findDuplicateJob(job, opts); dup != nil {
+			file.DuplicateOf = dup.ID
+			log.Printf("watch: %s: already processed as job %s, left in place", rel, dup.ID)
+			return true
+		}
+	}
+
+	jobID, jobDir, err := storage.CreateJobDir(w.s.jobsDir, w.s.jobIDFunc(chunker.JobSourceWatch, job.Metadata))
+	if err == nil {
+		err = w.s.router.PrepareJob(jobDir, storage.ArtefactDirs...)
+	}
+	if err != nil {
+		return fail("creating job: %v", err)
+	}
+	originalRel, err := storage.AdoptOriginal(jobDir, path, sum, w.link)
+	if err != nil {
+		if rmErr := storage.RemoveJob(jobDir); rmErr != nil {
+			log.Printf("job %s: failed to remove job directory: %v", jobID, rmErr)
+		}
+		return fail("taking file: %v", err)
+	}
+	job.ID = jobID
+	job.OriginalVideoPath = originalRel
+	if err := w.s.startJob(jobDir, job, opts); err != nil {
+		// In move mode the file now lives in the job directory, so keep it.
+		return fail("saving job %s: %v", jobID, err)
+	}
+	file.JobID = jobID
+	log.Printf("watch: %s: queued as job %s", rel, jobID)
+	return true
+}
+
+// saveState persists the handled files, replacing the state file atomically.
+func (w *folderWatcher) saveState() {
+	handled := make(map[string]*watchedFile, len(w.files))
+	for rel, file := range w.files {
+		if file.handled() {
+			handled[rel] = file
+		}
+	}
+	data, err := json.MarshalIndent(handled, "", "  ")
+	if err != nil {
+		log.Printf("watch: encoding state: %v", err)
+		return
+	}
+	tmp := w.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		log.Printf("watch: writing state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, w.statePath); err != nil {
+		log.Printf("watch: replacing state: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"audi/internal/config"
+	"audi/pkg/chunker"
+)
+
+// Webhook events, matching the job's terminal status.
+const (
+	eventCompleted = "completed"
+	eventFailed    = "failed"
+)
+
+const (
+	webhookAttempts = 3
+	webhookTimeout  = 10 * time.Second
+)
+
+// webhook is a configured notification preset with its parsed payload template.
+type webhook struct {
+	name        string
+	url         string
+	events      map[string]bool
+	contentType string
+	headers     map[string]string
+	tmpl        *texttemplate.Template
+}
+
+// webhookEvent is the data payload templates render, and the default JSON body.
+type webhookEvent struct {
+	Event  string       `json:"event"`
+	JobURL string       `json:"jobUrl"`
+	SentAt time.Time    `json:"sentAt"`
+	Job    *chunker.Job `json:"job"`
+	// Metadata repeats job.metadata at the top level, where receivers and templates
+	// correlating the event with their own records find it first.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// webhookFuncs are available in payload templates; json quotes values so templates can
+// build valid JSON bodies from free text such as titles and transcripts.
+var webhookFuncs = texttemplate.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join":          strings.Join,
+	"formatSeconds": formatSeconds,
+	"formatBytes":   formatBytes,
+}
+
+// newWebhooks parses the configured presets; a template that does not parse stops the server.
+func newWebhooks(configs []config.WebhookConfig) ([]webhook, error) {
+	hooks := make([]webhook, 0, len(configs))
+	for _, c := range configs {
+		hook := webhook{name: c.Name, url: c.URL, contentType: c.ContentType, headers: c.Headers}
+		if len(c.Events) > 0 {
+			hook.events = make(map[string]bool, len(c.Events))
+			for _, event := range c.Events {
+				hook.events[event] = true
+			}
+		}
+		if c.Template != "" {
+			tmpl, err := texttemplate.New(c.Name).Funcs(webhookFuncs).Option("missingkey=error").Parse(c.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s: %w", c.Name, err)
+			}
+			hook.tmpl = tmpl
+		}
+		if hook.contentType == "" {
+			hook.contentType = "application/json"
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// render builds the request body for an event.
+func (h webhook) render(event webhookEvent) ([]byte, error) {
+	if h.tmpl == nil {
+		return json.Marshal(event)
+	}
+	var buf bytes.Buffer
+	if err := h.tmpl.Execute(&buf, event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// notifyWebhooks renders the payload of every preset subscribed to event and delivers
+// them in the background, so slow receivers never hold up a worker.
+func (s *server) notifyWebhooks(event string, job *chunker.Job) {
+	if len(s.webhooks) == 0 {
+		return
+	}
+	snapshot := *job
+	payload := webhookEvent{
+		Event:    event,
+		JobURL:   s.jobLink(job.ID),
+		SentAt:   time.Now(),
+		Job:      &snapshot,
+		Metadata: job.Metadata,
+	}
+	for _, hook := range s.webhooks {
+		if hook.events != nil && !hook.events[event] {
+			continue
+		}
+		body, err := hook.render(payload)
+		if err != nil {
+			log.Printf("job %s: webhook %s: rendering payload: %v", job.ID, hook.name, err)
+			continue
+		}
+		go hook.deliver(job.ID, body)
+	}
+}
+
+// deliver posts body, retrying with a growing pause until a 2xx answer or the last attempt.
+func (h webhook) deliver(jobID string, body []byte) {
+	client := &http.Client{Timeout: webhookTimeout}
+	var lastErr error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * 2 * time.Second)
+		}
+		req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("job %s: webhook %s: %v", jobID, h.name, err)
+			return
+		}
+		req.Header.Set("Content-Type", h.contentType)
+		req.Header.Set("User-Agent", "audio-chunker")
+		for name, value := range h.headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return
+		}
+		lastErr = fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	log.Printf("job %s: webhook %s failed after %d attempts: %v", jobID, h.name, webhookAttempts, lastErr)
+}
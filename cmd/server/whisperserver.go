@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"audi/pkg/chunker"
+)
+
+// stopResidentOnSignal stops the resident whisper server before the process exits on
+// SIGINT or SIGTERM, so the child does not outlive it holding the model in memory.
+func stopResidentOnSignal(resident *chunker.ResidentWhisper) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("received %s, stopping the whisper server", sig)
+		if err := resident.Close(); err != nil {
+			log.Printf("stopping the whisper server: %v", err)
+		}
+		os.Exit(0)
+	}()
+}
+
+func init() {
+	registerAPI(apiOperation{Method: http.MethodGet, Path: "/api/v1/whisper-server", ID: "getWhisperServer", Summary: "State of the resident whisper server.", Response: chunker.ResidentStatus{}})
+}
+
+// handleAPIWhisperServer serves GET /api/v1/whisper-server: the resident whisper
+// server's state, for health checks and dashboards.
+func (s *server) handleAPIWhisperServer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		s.renderError(w, r, http.StatusMethodNotAllowed, "Method not allowed.", nil)
+		return
+	}
+	if s.processor.Resident == nil {
+		s.renderError(w, r, http.StatusNotFound, "No whisper server is configured.", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.processor.Resident.Status())
+}
@@ -0,0 +1,223 @@
+// Command worker processes the jobs an audi server publishes to its queue. Run it on
+// any machine that shares the server's data directory, such as over NFS, with the same
+// config file; each worker adds capacity, so GPU machines can join and leave at will.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"audi/internal/config"
+	"audi/internal/queue"
+	"audi/internal/setup"
+	"audi/internal/storage"
+	"audi/pkg/chunker"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to the server's YAML config file")
+	dataDir := flag.String("data", "", "the server's data directory as mounted here (overrides config)")
+	workers := flag.Int("workers", 0, "maximum jobs processed concurrently (overrides config)")
+	name := flag.String("name", "", "name recorded on the jobs this worker processes (default the host name)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("loading configuration: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data":
+			cfg.DataDir = *dataDir
+		case "workers":
+			cfg.Workers = *workers
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	if cfg.Queue.URL == "" {
+		log.Fatalf("invalid configuration: queue.url (or AUDI_QUEUE_URL) is required")
+	}
+	if *name == "" {
+		if *name, err = os.Hostname(); err != nil {
+			log.Fatalf("naming the worker: %v (pass -name)", err)
+		}
+	}
+
+	proc, err := processorOf(cfg)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	throughput, err := storage.OpenThroughput(filepath.Join(cfg.DataDir, "throughput.json"))
+	if err != nil {
+		log.Fatalf("opening throughput history: %v", err)
+	}
+	broker, err := queue.Open(cfg.Queue.URL)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	w := &worker{
+		name:       *name,
+		owner:      fmt.Sprintf("%s:%d", *name, os.Getpid()),
+		jobsDir:    filepath.Join(cfg.DataDir, "jobs"),
+		processor:  proc,
+		queue:      queue.New(broker, cfg.Queue.Name),
+		cfg:        cfg,
+		weights:    setup.ProgressWeights(cfg.ProgressWeights),
+		throughput: throughput,
+	}
+
+	// On SIGINT or SIGTERM stop taking tasks and let the running jobs finish.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if proc.Resident != nil {
+		if err := proc.Resident.Start(); err != nil {
+			log.Fatalf("invalid config: whisper.server: %v", err)
+		}
+		defer proc.Resident.Close()
+	}
+
+	log.Printf("worker %s taking up to %d jobs at a time from the %q queue", w.name, cfg.Workers, cfg.Queue.Name)
+	var wg sync.WaitGroup
+	for range cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(ctx)
+		}()
+	}
+	wg.Wait()
+	log.Printf("worker %s stopped", w.name)
+}
+
+// worker takes tasks from the queue and processes them in the shared jobs directory.
+// owner names it as a lease owner.
+type worker struct {
+	name      string
+	owner     string
+	jobsDir   string
+	processor *chunker.Processor
+	queue     *queue.Queue
+	cfg       config.Config
+	weights   chunker.ProgressWeights
+	// throughput is the server's stage history, shared through the data directory, so
+	// jobs get an ETA here and their stages' speed counts towards the next estimates.
+	throughput *storage.Throughput
+}
+
+// run processes tasks one at a time until ctx ends. A task taken just as ctx ends is
+// still processed, as nobody else will.
+func (w *worker) run(ctx context.Context) {
+	for {
+		task, err := w.queue.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("queue: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		w.process(task)
+	}
+}
+
+// process runs one job under its lease and tells the server it is done. It is not
+// cancelled by a shutdown signal, so a job is never left half-written. Tasks for jobs
+// another worker holds or already finished are dropped: the server may publish a job
+// again when it looks abandoned.
+func (w *worker) process(task queue.Task) {
+	if !storage.ValidJobID(task.JobID) {
+		log.Printf("queue: dropped a task for the invalid job ID %q", task.JobID)
+		return
+	}
+	jobDir := storage.JobDir(w.jobsDir, task.JobID)
+	lease, err := storage.AcquireLease(jobDir, w.owner, w.cfg.LeaseTTL)
+	if err != nil {
+		log.Printf("job %s: skipped: %v", task.JobID, err)
+		return
+	}
+	ctx, release := lease.Hold(context.Background())
+	defer release()
+	job, err := storage.LoadJob(jobDir)
+	if err != nil {
+		log.Printf("job %s: %v", task.JobID, err)
+		w.finish(task.JobID)
+		return
+	}
+	if job.Status != chunker.JobStatusPending && job.Status != chunker.JobStatusProcessing {
+		log.Printf("job %s: skipped: already %s", job.ID, job.Status)
+		return
+	}
+	log.Printf("job %s: processing", job.ID)
+
+	opts := task.Options
+	opts.TranscribeConcurrency = w.cfg.ChunkConcurrency()
+	opts.FFmpegTimeout = w.cfg.Commands.FFmpegTimeout
+	opts.WhisperTimeoutPerChunk = w.cfg.Commands.WhisperTimeout
+	if job.ReusedChunksFrom != "" {
+		opts.ReuseChunksDir = filepath.Join(storage.JobDir(w.jobsDir, job.ReusedChunksFrom), "chunks")
+	}
+	tracker := storage.NewProgressTracker(job, jobDir, w.throughput, w.weights, opts.Transcribe && w.processor.WhisperBin != "")
+	opts.Progress = func(p chunker.Progress) {
+		if err := tracker.Update(p); err != nil {
+			log.Printf("job %s: %v", job.ID, err)
+		}
+	}
+
+	opts.Hooks = append(opts.Hooks, chunker.StageRecorder{Job: job, Changed: func() { w.save(jobDir, job) }})
+
+	job.Status = chunker.JobStatusProcessing
+	job.Worker = w.name
+	if jobLog, err := storage.OpenJobLog(jobDir, int64(w.cfg.JobLogs.MaxSize), w.cfg.JobLogs.Keep); err != nil {
+		log.Printf("job %s: %v; keeping its output in job.json", job.ID, err)
+		job.LogFile = ""
+	} else {
+		defer jobLog.Close()
+		job.LogFile = storage.JobLogFile
+		output := chunker.NewOutputLog(jobLog)
+		output.Notes([]string{"processing started on " + w.name})
+		ctx = chunker.WithOutputLog(ctx, output)
+	}
+	w.save(jobDir, job)
+
+	originalPath := filepath.Join(jobDir, filepath.FromSlash(job.OriginalVideoPath))
+	result, err := w.processor.Process(ctx, jobDir, originalPath, opts)
+	if cause := context.Cause(ctx); errors.Is(cause, storage.ErrLeaseLost) {
+		log.Printf("job %s: %v; leaving the job to its new owner", job.ID, cause)
+		return
+	}
+	if finishErr := tracker.Finish(err == nil); finishErr != nil {
+		log.Printf("job %s: %v", job.ID, finishErr)
+	}
+	job.ApplyResult(result, err)
+	w.save(jobDir, job)
+	log.Printf("job %s: %s", job.ID, job.Status)
+	w.finish(job.ID)
+}
+
+// finish tells the server the worker is done with the job.
+func (w *worker) finish(jobID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := w.queue.Finish(ctx, queue.Finished{JobID: jobID, Worker: w.name}); err != nil {
+		log.Printf("job %s: failed to report it finished: %v", jobID, err)
+	}
+}
+
+// save writes the job, taking over edits made on the server since it was loaded.
+func (w *worker) save(jobDir string, job *chunker.Job) {
+	if err := storage.SaveLatest(jobDir, job); err != nil {
+		log.Printf("job %s: failed to save: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+
+	"audi/internal/config"
+	"audi/internal/setup"
+	"audi/pkg/chunker"
+)
+
+// processorOf builds the processor the way the server does from the same config, so a
+// job comes out the same wherever it runs. With tools.strict, a problem with ffmpeg or
+// whisper is an error.
+func processorOf(cfg config.Config) (*chunker.Processor, error) {
+	tools, err := setup.CheckTools(cfg, log.Printf)
+	if err != nil {
+		return nil, err
+	}
+	return setup.Processor(cfg, log.Printf, chunker.WithTools(tools...))
+}
@@ -0,0 +1,1253 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config gathers every server setting that used to be spread across flags and os.Getenv.
+type Config struct {
+	Addr                string        `yaml:"addr"`
+	DataDir             string        `yaml:"data_dir"`
+	DefaultChunkSeconds int           `yaml:"chunk_seconds"`
+	DisableBase64       bool          `yaml:"disable_base64"`
+	PregenerateBase64   bool          `yaml:"pregenerate_base64"`
+	DiscardOriginals    bool          `yaml:"discard_originals"`
+	TranscriptOnly      bool          `yaml:"transcript_only"`
+	Workers             int           `yaml:"workers"`
+	FFmpegBin           string        `yaml:"ffmpeg_bin"`
+	Whisper             WhisperConfig `yaml:"whisper"`
+	Retention           time.Duration `yaml:"retention"`
+	// EncodeWorkers and TranscribeWorkers cap the ffmpeg passes and whisper runs across
+	// all jobs (0 = no cap beyond Workers); TranscribePerJob is how many chunks of one job
+	// are transcribed at once, defaulting to TranscribeWorkers.
+	EncodeWorkers     int `yaml:"encode_workers"`
+	TranscribeWorkers int `yaml:"transcribe_workers"`
+	TranscribePerJob  int `yaml:"transcribe_per_job"`
+	// KeepRevisions caps how many earlier revisions a re-chunked job keeps; the oldest
+	// are pruned first. Zero keeps them all.
+	KeepRevisions int        `yaml:"keep_revisions"`
+	Auth          AuthConfig `yaml:"auth"`
+	// Quota caps the data directory size; uploads are refused once it is reached. Zero disables it.
+	Quota ByteSize `yaml:"quota"`
+	// DisableOriginalDownload stops the server from serving uploaded originals over HTTP.
+	DisableOriginalDownload bool `yaml:"disable_original_download"`
+	// QualityGate holds thresholds that keep unusable chunks away from the transcriber.
+	QualityGate QualityGateConfig `yaml:"quality_gate"`
+	// Storage routes artefact types to operator-defined storage classes.
+	Storage StorageConfig `yaml:"storage"`
+	// ProgressWeights sets each stage's share of a job's overall percentage.
+	ProgressWeights ProgressWeightsConfig `yaml:"progress_weights"`
+	// PublicURL is the externally reachable base URL, used for links in notifications.
+	PublicURL string `yaml:"public_url"`
+	// BasePath mounts every route under a path prefix such as /audio-chunker, for reverse
+	// proxies that pass a sub-path through unchanged. Empty serves from the root.
+	BasePath string `yaml:"base_path"`
+	// TrustedProxies lists the addresses and CIDR networks of reverse proxies whose
+	// X-Forwarded-For, X-Forwarded-Proto, and X-Forwarded-Host headers are believed.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// CORS lets pages on other origins call the JSON API from the browser.
+	CORS CORSConfig `yaml:"cors"`
+	// MergedAudio preselects the merged listening file format on the upload form: "", "mp3", or "opus".
+	MergedAudio string `yaml:"merged_audio"`
+	// Webhooks are notification presets called when jobs finish.
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+	// Watch turns a directory into a drop box whose media files become jobs.
+	Watch WatchConfig `yaml:"watch"`
+	// JobIDs sets the scheme of new job IDs and prefixes, so logs and webhooks show where a
+	// job came from.
+	JobIDs JobIDConfig `yaml:"job_ids"`
+	// Presets are named upload settings offered on the upload form and the API.
+	Presets []PresetConfig `yaml:"presets"`
+	// Summarization enables the summarize upload option, which sends a transcribed job to
+	// an LLM for a summary, chapters, and action items.
+	Summarization SummarizationConfig `yaml:"summarization"`
+	// Keywords configures the keywords upload option, which stores the key terms and
+	// named entities of each chunk transcript.
+	Keywords KeywordsConfig `yaml:"keywords"`
+	// Sentiment configures the sentiment upload option, which rates the sentiment and
+	// emotions of each chunk transcript.
+	Sentiment SentimentConfig `yaml:"sentiment"`
+	// Redaction lists what the redact upload option removes from transcripts and audio.
+	Redaction RedactionConfig `yaml:"redaction"`
+	// Commands bounds every ffmpeg and whisper run: how long one may take and the
+	// operating-system priority it runs at.
+	Commands CommandsConfig `yaml:"commands"`
+	// Acceleration runs whisper and video decoding on a GPU once a startup check finds one.
+	Acceleration AccelerationConfig `yaml:"acceleration"`
+	// Queue hands jobs to cmd/worker processes through a message broker instead of
+	// processing them in the server.
+	Queue QueueConfig `yaml:"queue"`
+	// S3 lets API clients upload large files straight to a bucket through pre-signed
+	// URLs, instead of through the server.
+	S3 S3Config `yaml:"s3"`
+	// TLS serves HTTPS directly, from certificate files or with certificates obtained
+	// automatically over ACME, so the server can be exposed without a reverse proxy.
+	TLS TLSConfig `yaml:"tls"`
+	// LeaseTTL is how long a server or worker processing a job may go without renewing
+	// its lease on the job before another takes the job over.
+	LeaseTTL time.Duration `yaml:"lease_ttl"`
+	// Tools sets how ffmpeg and whisper are checked at startup and by /healthz.
+	Tools ToolsConfig `yaml:"tools"`
+	// JobLogs bounds the processing log each job writes to logs/processing.log.
+	JobLogs JobLogsConfig `yaml:"job_logs"`
+	// FailureInjection lets an upload ask for a simulated failure with inject_failure,
+	// to rehearse alerting and webhooks. Never enable it on a server users rely on.
+	FailureInjection bool `yaml:"failure_injection"`
+}
+
+// PresetConfig is a named set of upload form fields, such as chunk_value, filters, or
+// whisper_args, that an upload selects with preset=<name>.
+type PresetConfig struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Options     FormValues `yaml:"options"`
+}
+
+// Job ID schemes: how the part of a new job ID after its prefix is generated.
+const (
+	// JobIDULID is a ULID, 26 characters of Crockford base32 such as
+	// 01J8ZK3Q4W2N6V7X9Y0A1B2C3D.
+	JobIDULID = "ulid"
+	// JobIDUUIDv7 is a time-ordered UUID such as 0192a5c4-7f1e-7b3a-9c2d-4e5f60718293.
+	JobIDUUIDv7 = "uuidv7"
+	// JobIDTimestamp is the local time plus four random digits, 20240312-101500-0042.
+	JobIDTimestamp = "timestamp"
+)
+
+// JobIDConfig chooses the scheme and the prefix of a new job ID. A project prefix wins
+// over a source prefix, which wins over Prefix.
+type JobIDConfig struct {
+	// Scheme is JobIDULID, JobIDUUIDv7, or JobIDTimestamp. All of them sort in creation
+	// order.
+	Scheme string `yaml:"scheme"`
+	Prefix string `yaml:"prefix"`
+	// Sources maps a job source (upload, push, openai, or watch) to its prefix.
+	Sources map[string]string `yaml:"sources"`
+	// ProjectKey names the metadata key whose value picks a prefix from Projects.
+	ProjectKey string            `yaml:"project_key"`
+	Projects   map[string]string `yaml:"projects"`
+}
+
+// Watch modes: how a file found in the watched directory gets into its job.
+const (
+	WatchMove = "move"
+	WatchLink = "link"
+)
+
+// WatchConfig describes the watched directory. It is off while Dir is empty.
+type WatchConfig struct {
+	Dir string `yaml:"dir"`
+	// Patterns selects the file names to ingest, e.g. ["*.mp4", "*.wav"]; empty takes all.
+	Patterns []string `yaml:"patterns"`
+	// Mode is "move" (the default) or "link", which hard-links files into jobs and leaves
+	// the folder as it is.
+	Mode string `yaml:"mode"`
+	// Interval is how often the directory is scanned.
+	Interval time.Duration `yaml:"interval"`
+	// Settle is how long a file's size and modification time must stay unchanged before
+	// it is taken, so files still being copied are left alone.
+	Settle time.Duration `yaml:"settle"`
+	// Options are upload form fields applied to every ingested file, e.g. chunk_value,
+	// chunk_unit, transcribe: "on", or metadata.
+	Options FormValues `yaml:"options"`
+}
+
+// FormValues maps form field names to values. In YAML a field takes one value or a list.
+type FormValues map[string]StringList
+
+// StringList accepts a YAML scalar as a one-element list.
+type StringList []string
+
+// UnmarshalYAML accepts either a scalar or a sequence of scalars.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// WebhookConfig is a named notification target. Template, when set, is a Go template
+// over the event and job that renders the request body, so receivers with a fixed schema
+// need no adapter; without it the body is the event and job as JSON.
+type WebhookConfig struct {
+	Name        string            `yaml:"name"`
+	URL         string            `yaml:"url"`
+	Events      []string          `yaml:"events"`
+	ContentType string            `yaml:"content_type"`
+	Headers     map[string]string `yaml:"headers"`
+	Template    string            `yaml:"template"`
+}
+
+// ProgressWeightsConfig mirrors chunker.ProgressWeights.
+type ProgressWeightsConfig struct {
+	Extract     float64 `yaml:"extract"`
+	Postprocess float64 `yaml:"postprocess"`
+	Transcribe  float64 `yaml:"transcribe"`
+}
+
+// StorageConfig defines named storage classes and assigns artefact types ("original",
+// "chunks", "base64", "transcripts", "merged", "video") to them. Unassigned types stay
+// in the job directory.
+type StorageConfig struct {
+	Classes   map[string]StorageClassConfig `yaml:"classes"`
+	Artefacts map[string]string             `yaml:"artefacts"`
+	// CacheMax caps the local cache of files read from fetch URLs. Zero means no limit.
+	CacheMax ByteSize `yaml:"cache_max"`
+	// TranscriptCacheMax caps the transcripts cached under <data_dir>/cache/transcripts
+	// for reprocessed audio. Zero means no limit.
+	TranscriptCacheMax ByteSize `yaml:"transcript_cache_max"`
+}
+
+// StorageClassConfig is a directory (possibly a mounted bucket) and the optional URL it is published under.
+type StorageClassConfig struct {
+	Path string `yaml:"path"`
+	URL  string `yaml:"url"`
+	// Fetch is an HTTP(S) base URL serving the same tree, read when a file is not on disk.
+	Fetch        string            `yaml:"fetch"`
+	FetchHeaders map[string]string `yaml:"fetch_headers"`
+	// Cache keeps fetched files in <data_dir>/cache/artefacts.
+	Cache bool `yaml:"cache"`
+}
+
+// QualityGateConfig mirrors chunker.QualityGate; zero values disable a check.
+type QualityGateConfig struct {
+	MinLoudnessDB      float64 `yaml:"min_loudness_db"`
+	MinChunkSeconds    float64 `yaml:"min_chunk_seconds"`
+	MaxClippingPercent float64 `yaml:"max_clipping_percent"`
+}
+
+// ByteSize is a byte count that can be written as a plain number or with a unit ("500MB", "10GiB").
+type ByteSize int64
+
+// UnmarshalYAML accepts integers or human-readable sizes.
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := ParseByteSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// ParseByteSize parses sizes such as "1024", "500MB", or "10GiB" (decimal and binary units).
+func ParseByteSize(value string) (ByteSize, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	}
+	upper := strings.ToUpper(value)
+	mult := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			mult = unit.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return ByteSize(n * float64(mult)), nil
+}
+
+// WhisperConfig describes the optional transcription binary.
+type WhisperConfig struct {
+	Bin  string   `yaml:"bin"`
+	Args []string `yaml:"args"`
+	// Sets are optional extra transcription passes users can pick per upload.
+	Sets []TranscriptSetConfig `yaml:"sets"`
+	// Escalation re-runs low-confidence chunks with extra args, e.g. a larger model.
+	Escalation EscalationConfig `yaml:"escalation"`
+	// ChunkLimit is the largest chunk the backend accepts; uploads that transcribe are
+	// cut to fit it.
+	ChunkLimit ChunkLimitConfig `yaml:",inline"`
+	// Server, when Bin is set, keeps whisper.cpp's HTTP server running with the model
+	// loaded; Bin above remains the fallback.
+	Server WhisperServerConfig `yaml:"server"`
+}
+
+// WhisperServerConfig describes the resident whisper server (chunker.ResidentWhisper).
+type WhisperServerConfig struct {
+	Bin            string        `yaml:"bin"`
+	Args           []string      `yaml:"args"`
+	Addr           string        `yaml:"addr"`
+	HealthPath     string        `yaml:"health_path"`
+	StartTimeout   time.Duration `yaml:"start_timeout"`
+	HealthInterval time.Duration `yaml:"health_interval"`
+}
+
+// SummarizationConfig describes the OpenAI-compatible chat completions endpoint behind
+// chunker.Summarizer. It is off while URL is empty.
+type SummarizationConfig struct {
+	// URL is the API base, e.g. https://api.openai.com/v1; requests go to its
+	// /chat/completions.
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+	Model  string `yaml:"model"`
+	// Prompt replaces the built-in system prompt; it must ask for the same JSON answer.
+	Prompt string `yaml:"prompt"`
+	// MaxChars truncates longer transcripts before they are sent.
+	MaxChars int           `yaml:"max_chars"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// KeywordsConfig picks the keyword extractor: the built-in one while URL is empty,
+// otherwise the HTTP service at URL (chunker.KeywordService).
+type KeywordsConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Max caps the key terms and the entities kept per chunk (default 8 of each).
+	Max     int           `yaml:"max"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// SentimentConfig picks the sentiment analyzer: the built-in one while URL is empty,
+// otherwise the HTTP service at URL (chunker.SentimentService).
+type SentimentConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// CommandsConfig mirrors chunker.Options.FFmpegTimeout and WhisperTimeoutPerChunk and
+// chunker.CommandPriority; zero values leave commands unbounded at the default priority.
+type CommandsConfig struct {
+	FFmpegTimeout  time.Duration `yaml:"ffmpeg_timeout"`
+	WhisperTimeout time.Duration `yaml:"whisper_timeout"`
+	// Nice runs commands at this niceness, 1 to 19.
+	Nice int `yaml:"nice"`
+	// IOClass is "best-effort", with IOLevel from 0 (highest) to 7, or "idle".
+	IOClass string `yaml:"io_class"`
+	IOLevel int    `yaml:"io_level"`
+	// CPUs pins commands to these CPU numbers.
+	CPUs []int `yaml:"cpus"`
+}
+
+// ToolsConfig sets how the ffmpeg and whisper binaries are checked.
+type ToolsConfig struct {
+	// Strict refuses to start when ffmpeg or a configured whisper does not run, is
+	// listed in BadVersions, or is older than the pipeline is used with.
+	Strict bool `yaml:"strict"`
+	// BadVersions lists known-bad versions under "ffmpeg" and "whisper"; "5.1" matches
+	// every 5.1.x.
+	BadVersions map[string][]string `yaml:"bad_versions"`
+}
+
+// JobLogsConfig sets when a job's processing log is rotated; zero values use the
+// storage defaults of 10 MiB and three rotated files.
+type JobLogsConfig struct {
+	// MaxSize rotates the log to processing.log.1 once it would grow past this size.
+	MaxSize ByteSize `yaml:"max_size"`
+	// Keep is how many rotated logs are kept.
+	Keep int `yaml:"keep"`
+}
+
+// AccelerationConfig mirrors chunker.Acceleration; it is off while GPU is false and
+// FFmpegHWAccel is empty.
+type AccelerationConfig struct {
+	// GPU runs whisper on the GPU with WhisperArgs appended, such as ["-fa"]. CPU runs,
+	// for jobs that ask for one or after a GPU failure, append CPUWhisperArgs instead
+	// (default ["-ng"]).
+	GPU            bool     `yaml:"gpu"`
+	WhisperArgs    []string `yaml:"whisper_args"`
+	CPUWhisperArgs []string `yaml:"cpu_whisper_args"`
+	// Check is a command whose success shows the GPU is usable, e.g. ["nvidia-smi", "-L"].
+	Check []string `yaml:"check"`
+	// FFmpegHWAccel is ffmpeg's -hwaccel method: cuda, videotoolbox, vaapi, qsv, or auto.
+	FFmpegHWAccel string `yaml:"ffmpeg_hwaccel"`
+}
+
+// QueueConfig connects the server and its workers to a broker. The server publishes
+// jobs while URL is set; workers need it set to the same broker.
+type QueueConfig struct {
+	// URL is the broker: redis://[user:password@]host:port[/db], or rediss:// for TLS.
+	URL string `yaml:"url"`
+	// Name prefixes the broker's lists, so several installations can share one broker.
+	Name string `yaml:"name"`
+}
+
+// S3Config is the bucket that takes direct uploads. They are offered while Bucket is set.
+type S3Config struct {
+	// Endpoint is the store's base URL, e.g. https://s3.eu-central-1.amazonaws.com or a
+	// MinIO server's address.
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	// PathStyle addresses the bucket in the path rather than as a subdomain.
+	PathStyle bool `yaml:"path_style"`
+	// Prefix is put in front of every object key, e.g. "incoming/".
+	Prefix string `yaml:"prefix"`
+	// URLExpiry is how long a pre-signed upload URL stays valid.
+	URLExpiry time.Duration `yaml:"url_expiry"`
+	// KeepObjects leaves uploaded objects in the bucket once their job has copied them.
+	KeepObjects bool `yaml:"keep_objects"`
+}
+
+// TLSConfig turns on HTTPS: with CertFile and KeyFile, or with AutocertDomains, for
+// which certificates are obtained and renewed automatically. Without either the server
+// speaks plain HTTP.
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM files: the certificate, followed by any intermediates,
+	// and its private key. They are read again when they change, so renewals need no restart.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// AutocertDomains are the host names to obtain certificates for, from Let's Encrypt
+	// unless ACMEDirectory names another CA. The CA must reach the server on port 443,
+	// or on port 80 for HTTP challenges when RedirectAddr listens there.
+	AutocertDomains []string `yaml:"autocert_domains"`
+	// AutocertEmail is given to the CA for expiry and problem notices.
+	AutocertEmail string `yaml:"autocert_email"`
+	// AutocertCache holds the account key and certificates (default <data_dir>/autocert).
+	AutocertCache string `yaml:"autocert_cache"`
+	// ACMEDirectory is the CA's ACME directory URL, e.g. Let's Encrypt's staging one.
+	ACMEDirectory string `yaml:"acme_directory"`
+	// RedirectAddr, when set, listens for plain HTTP there (usually ":80") and redirects
+	// every request to HTTPS, answering ACME HTTP challenges first.
+	RedirectAddr string `yaml:"redirect_addr"`
+}
+
+// Enabled reports whether the server should speak HTTPS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
+
+// CORSConfig lists the origins whose pages may call the API with fetch or EventSource.
+// Cross-origin calls are refused while AllowedOrigins is empty.
+type CORSConfig struct {
+	// AllowedOrigins are origins such as https://app.example.com; "*" allows any.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// AllowedMethods and AllowedHeaders answer preflight requests.
+	AllowedMethods []string `yaml:"allowed_methods"`
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	// AllowCredentials lets those pages send the browser's Basic credentials and cookies.
+	// It cannot be combined with "*".
+	AllowCredentials bool `yaml:"allow_credentials"`
+	// MaxAge is how long browsers may cache a preflight answer.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// RedactionConfig lists the words (matched case-insensitively as whole words) and Go
+// regular expressions to redact. Redaction is offered once either list is non-empty.
+type RedactionConfig struct {
+	Words    []string `yaml:"words"`
+	Patterns []string `yaml:"patterns"`
+	// Replacement stands in for redacted text (default "[redacted]").
+	Replacement string `yaml:"replacement"`
+	// Audio is "bleep" (default) to cover redacted words with a tone or "mute" to
+	// silence them.
+	Audio string `yaml:"audio"`
+}
+
+// ChunkLimitConfig bounds chunks sent to a transcription backend. Zero values are unlimited.
+type ChunkLimitConfig struct {
+	MaxChunkDuration time.Duration `yaml:"max_chunk_duration"`
+	MaxChunkSize     ByteSize      `yaml:"max_chunk_size"`
+}
+
+// minChunkLimitSize keeps a size limit above a couple of seconds of chunk audio.
+const minChunkLimitSize = 64 * 1000
+
+func (l ChunkLimitConfig) validate(field string) error {
+	if l.MaxChunkDuration != 0 && l.MaxChunkDuration < time.Second {
+		return fmt.Errorf("config: %smax_chunk_duration must be at least 1s", field)
+	}
+	if l.MaxChunkSize != 0 && l.MaxChunkSize < minChunkLimitSize {
+		return fmt.Errorf("config: %smax_chunk_size must be at least 64KB", field)
+	}
+	return nil
+}
+
+// EscalationConfig mirrors chunker.EscalationPolicy; a zero MinConfidence disables it.
+type EscalationConfig struct {
+	MinConfidence float64  `yaml:"min_confidence"`
+	Args          []string `yaml:"args"`
+}
+
+// TranscriptSetConfig names a transcription pass whose Args are appended to the main whisper args.
+type TranscriptSetConfig struct {
+	Name string   `yaml:"name"`
+	Args []string `yaml:"args"`
+	// ChunkLimit applies on top of the main one when the set is picked, e.g. for a model
+	// with a shorter context.
+	ChunkLimit ChunkLimitConfig `yaml:",inline"`
+}
+
+// AuthConfig enables HTTP basic auth when Username is set.
+type AuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Enabled reports whether requests must authenticate.
+func (a AuthConfig) Enabled() bool {
+	return a.Username != ""
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Addr:                ":8080",
+		DataDir:             "data",
+		DefaultChunkSeconds: 300,
+		Workers:             2,
+		ProgressWeights:     ProgressWeightsConfig{Extract: 1, Postprocess: 1, Transcribe: 4},
+		Watch:               WatchConfig{Mode: WatchMove, Interval: 5 * time.Second, Settle: 10 * time.Second},
+		JobIDs:              JobIDConfig{Scheme: JobIDULID},
+		Queue:               QueueConfig{Name: "audi"},
+		LeaseTTL:            time.Minute,
+		S3:                  S3Config{Region: "us-east-1", URLExpiry: time.Hour},
+		Storage:             StorageConfig{TranscriptCacheMax: 1 << 30},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+	}
+}
+
+// Load starts from Default, overlays the YAML file at path (if any), then
+// applies environment variable overrides.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := decode(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parsing config file: %w", err)
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	if path, ok := cfg.redacted(); ok {
+		return cfg, fmt.Errorf("config: %s is still %s from an export; fill in the secret", path, RedactedSecret)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// decode overlays the YAML document in data on cfg. Keys that name no setting are an
+// error, so a misspelled section, such as "auth: {user: admin}", does not quietly
+// leave its defaults in place.
+func decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overrides fields from environment variables when they are set.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	if v, ok := lookup("AUDI_ADDR"); ok {
+		c.Addr = v
+	}
+	if v, ok := lookup("AUDI_DATA_DIR"); ok {
+		c.DataDir = v
+	}
+	if v, ok := lookup("AUDI_CHUNK_SECONDS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_CHUNK_SECONDS: %w", err)
+		}
+		c.DefaultChunkSeconds = n
+	}
+	if v, ok := lookup("AUDI_DISABLE_BASE64"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_DISABLE_BASE64: %w", err)
+		}
+		c.DisableBase64 = b
+	}
+	if v, ok := lookup("AUDI_PREGENERATE_BASE64"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_PREGENERATE_BASE64: %w", err)
+		}
+		c.PregenerateBase64 = b
+	}
+	if v, ok := lookup("AUDI_DISCARD_ORIGINALS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_DISCARD_ORIGINALS: %w", err)
+		}
+		c.DiscardOriginals = b
+	}
+	if v, ok := lookup("AUDI_TRANSCRIPT_ONLY"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_TRANSCRIPT_ONLY: %w", err)
+		}
+		c.TranscriptOnly = b
+	}
+	if v, ok := lookup("AUDI_DISABLE_ORIGINAL_DOWNLOAD"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_DISABLE_ORIGINAL_DOWNLOAD: %w", err)
+		}
+		c.DisableOriginalDownload = b
+	}
+	if v, ok := lookup("AUDI_WORKERS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_WORKERS: %w", err)
+		}
+		c.Workers = n
+	}
+	for key, dst := range map[string]*int{
+		"AUDI_ENCODE_WORKERS":     &c.EncodeWorkers,
+		"AUDI_TRANSCRIBE_WORKERS": &c.TranscribeWorkers,
+		"AUDI_TRANSCRIBE_PER_JOB": &c.TranscribePerJob,
+	} {
+		if v, ok := lookup(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*dst = n
+		}
+	}
+	if v, ok := lookup("AUDI_RETENTION"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_RETENTION: %w", err)
+		}
+		c.Retention = d
+	}
+	if v, ok := lookup("AUDI_KEEP_REVISIONS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_KEEP_REVISIONS: %w", err)
+		}
+		c.KeepRevisions = n
+	}
+	if v, ok := lookup("AUDI_QUOTA"); ok {
+		q, err := ParseByteSize(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_QUOTA: %w", err)
+		}
+		c.Quota = q
+	}
+	if v, ok := lookup("AUDI_GATE_MIN_LOUDNESS_DB"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("AUDI_GATE_MIN_LOUDNESS_DB: %w", err)
+		}
+		c.QualityGate.MinLoudnessDB = n
+	}
+	if v, ok := lookup("AUDI_GATE_MIN_CHUNK_SECONDS"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("AUDI_GATE_MIN_CHUNK_SECONDS: %w", err)
+		}
+		c.QualityGate.MinChunkSeconds = n
+	}
+	if v, ok := lookup("AUDI_GATE_MAX_CLIPPING_PERCENT"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("AUDI_GATE_MAX_CLIPPING_PERCENT: %w", err)
+		}
+		c.QualityGate.MaxClippingPercent = n
+	}
+	if v, ok := lookup("AUDI_WATCH_DIR"); ok {
+		c.Watch.Dir = v
+	}
+	if v, ok := lookup("AUDI_WATCH_MODE"); ok {
+		c.Watch.Mode = v
+	}
+	if v, ok := lookup("AUDI_JOB_ID_SCHEME"); ok {
+		c.JobIDs.Scheme = v
+	}
+	if v, ok := lookup("AUDI_JOB_ID_PREFIX"); ok {
+		c.JobIDs.Prefix = v
+	}
+	if v, ok := lookup("AUDI_PUBLIC_URL"); ok {
+		c.PublicURL = v
+	}
+	if v, ok := lookup("AUDI_BASE_PATH"); ok {
+		c.BasePath = v
+	}
+	if v, ok := lookup("AUDI_TRUSTED_PROXIES"); ok {
+		c.TrustedProxies = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+	if v, ok := lookup("AUDI_CORS_ORIGINS"); ok {
+		c.CORS.AllowedOrigins = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+	if v, ok := lookup("AUDI_MERGED_AUDIO"); ok {
+		c.MergedAudio = v
+	}
+	if v, ok := lookup("AUDI_PROGRESS_WEIGHT_EXTRACT"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("AUDI_PROGRESS_WEIGHT_EXTRACT: %w", err)
+		}
+		c.ProgressWeights.Extract = n
+	}
+	if v, ok := lookup("AUDI_PROGRESS_WEIGHT_POSTPROCESS"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("AUDI_PROGRESS_WEIGHT_POSTPROCESS: %w", err)
+		}
+		c.ProgressWeights.Postprocess = n
+	}
+	if v, ok := lookup("AUDI_PROGRESS_WEIGHT_TRANSCRIBE"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("AUDI_PROGRESS_WEIGHT_TRANSCRIBE: %w", err)
+		}
+		c.ProgressWeights.Transcribe = n
+	}
+	if v, ok := lookup("AUDI_AUTH_USERNAME"); ok {
+		c.Auth.Username = v
+	}
+	if v, ok := lookup("AUDI_AUTH_PASSWORD"); ok {
+		c.Auth.Password = v
+	}
+	if v, ok := lookup("FFMPEG_BIN"); ok {
+		c.FFmpegBin = v
+	}
+	if v, ok := lookup("WHISPER_BIN"); ok {
+		c.Whisper.Bin = v
+	}
+	if v, ok := lookup("WHISPER_ARGS"); ok {
+		c.Whisper.Args = strings.Fields(v)
+	}
+	if v, ok := lookup("WHISPER_ESCALATION_MIN_CONFIDENCE"); ok {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("WHISPER_ESCALATION_MIN_CONFIDENCE: %w", err)
+		}
+		c.Whisper.Escalation.MinConfidence = n
+	}
+	if v, ok := lookup("WHISPER_ESCALATION_ARGS"); ok {
+		c.Whisper.Escalation.Args = strings.Fields(v)
+	}
+	if v, ok := lookup("WHISPER_SERVER_BIN"); ok {
+		c.Whisper.Server.Bin = v
+	}
+	if v, ok := lookup("WHISPER_SERVER_ARGS"); ok {
+		c.Whisper.Server.Args = strings.Fields(v)
+	}
+	if v, ok := lookup("WHISPER_SERVER_ADDR"); ok {
+		c.Whisper.Server.Addr = v
+	}
+	if v, ok := lookup("AUDI_SUMMARY_URL"); ok {
+		c.Summarization.URL = v
+	}
+	if v, ok := lookup("AUDI_SUMMARY_API_KEY"); ok {
+		c.Summarization.APIKey = v
+	}
+	if v, ok := lookup("AUDI_SUMMARY_MODEL"); ok {
+		c.Summarization.Model = v
+	}
+	if v, ok := lookup("AUDI_KEYWORDS_URL"); ok {
+		c.Keywords.URL = v
+	}
+	if v, ok := lookup("AUDI_SENTIMENT_URL"); ok {
+		c.Sentiment.URL = v
+	}
+	for key, dst := range map[string]*time.Duration{
+		"FFMPEG_TIMEOUT":  &c.Commands.FFmpegTimeout,
+		"WHISPER_TIMEOUT": &c.Commands.WhisperTimeout,
+		"AUDI_LEASE_TTL":  &c.LeaseTTL,
+	} {
+		if v, ok := lookup(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*dst = d
+		}
+	}
+	if v, ok := lookup("AUDI_GPU"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_GPU: %w", err)
+		}
+		c.Acceleration.GPU = b
+	}
+	if v, ok := lookup("FFMPEG_HWACCEL"); ok {
+		c.Acceleration.FFmpegHWAccel = v
+	}
+	if v, ok := lookup("AUDI_QUEUE_URL"); ok {
+		c.Queue.URL = v
+	}
+	for key, dst := range map[string]*string{
+		"AUDI_TLS_CERT":       &c.TLS.CertFile,
+		"AUDI_TLS_KEY":        &c.TLS.KeyFile,
+		"AUDI_AUTOCERT_EMAIL": &c.TLS.AutocertEmail,
+		"AUDI_HTTP_REDIRECT":  &c.TLS.RedirectAddr,
+	} {
+		if v, ok := lookup(key); ok {
+			*dst = v
+		}
+	}
+	if v, ok := lookup("AUDI_AUTOCERT_DOMAINS"); ok {
+		c.TLS.AutocertDomains = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	}
+	for key, dst := range map[string]*string{
+		"AUDI_S3_ENDPOINT":   &c.S3.Endpoint,
+		"AUDI_S3_REGION":     &c.S3.Region,
+		"AUDI_S3_BUCKET":     &c.S3.Bucket,
+		"AUDI_S3_ACCESS_KEY": &c.S3.AccessKey,
+		"AUDI_S3_SECRET_KEY": &c.S3.SecretKey,
+	} {
+		if v, ok := lookup(key); ok {
+			*dst = v
+		}
+	}
+	if v, ok := lookup("AUDI_COMMAND_NICE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("AUDI_COMMAND_NICE: %w", err)
+		}
+		c.Commands.Nice = n
+	}
+	if v, ok := lookup("WHISPER_MAX_CHUNK_DURATION"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("WHISPER_MAX_CHUNK_DURATION: %w", err)
+		}
+		c.Whisper.ChunkLimit.MaxChunkDuration = d
+	}
+	if v, ok := lookup("WHISPER_MAX_CHUNK_SIZE"); ok {
+		n, err := ParseByteSize(v)
+		if err != nil {
+			return fmt.Errorf("WHISPER_MAX_CHUNK_SIZE: %w", err)
+		}
+		c.Whisper.ChunkLimit.MaxChunkSize = n
+	}
+	return nil
+}
+
+// Validate rejects settings the server cannot run with.
+func (c Config) Validate() error {
+	if c.DataDir == "" {
+		return errors.New("config: data_dir must not be empty")
+	}
+	if c.DefaultChunkSeconds <= 0 {
+		return errors.New("config: chunk_seconds must be positive")
+	}
+	if c.Workers <= 0 {
+		return errors.New("config: workers must be at least 1")
+	}
+	if c.EncodeWorkers < 0 || c.TranscribeWorkers < 0 || c.TranscribePerJob < 0 {
+		return errors.New("config: encode_workers, transcribe_workers, and transcribe_per_job must not be negative")
+	}
+	if c.Retention < 0 {
+		return errors.New("config: retention must not be negative")
+	}
+	if c.KeepRevisions < 0 {
+		return errors.New("config: keep_revisions must not be negative")
+	}
+	if w := c.ProgressWeights; w.Extract < 0 || w.Postprocess < 0 || w.Transcribe < 0 {
+		return errors.New("config: progress_weights must not be negative")
+	}
+	if c.QualityGate.MinLoudnessDB > 0 {
+		return errors.New("config: quality_gate.min_loudness_db must not be above 0")
+	}
+	if c.QualityGate.MinChunkSeconds < 0 {
+		return errors.New("config: quality_gate.min_chunk_seconds must not be negative")
+	}
+	if c.QualityGate.MaxClippingPercent < 0 || c.QualityGate.MaxClippingPercent > 100 {
+		return errors.New("config: quality_gate.max_clipping_percent must be between 0 and 100")
+	}
+	if e := c.Whisper.Escalation; e.MinConfidence < 0 || e.MinConfidence > 1 {
+		return errors.New("config: whisper.escalation.min_confidence must be between 0 and 1")
+	} else if e.MinConfidence > 0 && len(e.Args) == 0 {
+		return errors.New("config: whisper.escalation.args is required when min_confidence is set")
+	}
+	if ws := c.Whisper.Server; ws.Bin != "" {
+		if c.Whisper.Bin == "" {
+			return errors.New("config: whisper.server needs whisper.bin as its fallback")
+		}
+		if ws.Addr != "" {
+			if _, _, err := net.SplitHostPort(ws.Addr); err != nil {
+				return fmt.Errorf("config: whisper.server.addr: %w", err)
+			}
+		}
+		if ws.HealthPath != "" && !strings.HasPrefix(ws.HealthPath, "/") {
+			return errors.New("config: whisper.server.health_path must start with /")
+		}
+		if ws.StartTimeout < 0 || ws.HealthInterval < 0 {
+			return errors.New("config: whisper.server timeouts must not be negative")
+		}
+	}
+	if err := c.Whisper.ChunkLimit.validate("whisper."); err != nil {
+		return err
+	}
+	for _, set := range c.Whisper.Sets {
+		if err := set.ChunkLimit.validate(fmt.Sprintf("whisper.sets[%s].", set.Name)); err != nil {
+			return err
+		}
+	}
+	if w := c.Watch; w.Dir != "" {
+		if w.Mode != WatchMove && w.Mode != WatchLink {
+			return fmt.Errorf("config: watch.mode must be %s or %s, not %q", WatchMove, WatchLink, w.Mode)
+		}
+		if w.Interval <= 0 {
+			return errors.New("config: watch.interval must be positive")
+		}
+		if w.Settle < 0 {
+			return errors.New("config: watch.settle must not be negative")
+		}
+		for _, pattern := range w.Patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("config: watch.patterns: bad pattern %q", pattern)
+			}
+		}
+	}
+	if err := c.JobIDs.validate(); err != nil {
+		return err
+	}
+	for name, class := range c.Storage.Classes {
+		if class.Path == "" {
+			return fmt.Errorf("config: storage.classes.%s.path must not be empty", name)
+		}
+		if class.Fetch != "" {
+			u, err := url.Parse(class.Fetch)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("config: storage.classes.%s.fetch must be an http or https URL", name)
+			}
+		} else if class.Cache {
+			return fmt.Errorf("config: storage.classes.%s.cache needs a fetch URL", name)
+		}
+	}
+	for artefact, class := range c.Storage.Artefacts {
+		if _, ok := c.Storage.Classes[class]; !ok {
+			return fmt.Errorf("config: storage.artefacts.%s refers to unknown class %q", artefact, class)
+		}
+	}
+	names := make(map[string]bool, len(c.Webhooks))
+	for i, hook := range c.Webhooks {
+		if hook.Name == "" {
+			return fmt.Errorf("config: webhooks[%d].name must not be empty", i)
+		}
+		if names[hook.Name] {
+			return fmt.Errorf("config: webhook %q is defined twice", hook.Name)
+		}
+		names[hook.Name] = true
+		if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+			return fmt.Errorf("config: webhooks.%s.url must be an http(s) URL", hook.Name)
+		}
+		for _, event := range hook.Events {
+			if event != "completed" && event != "failed" {
+				return fmt.Errorf("config: webhooks.%s.events: unknown event %q (use completed or failed)", hook.Name, event)
+			}
+		}
+	}
+	switch c.MergedAudio {
+	case "", "mp3", "opus":
+	default:
+		return fmt.Errorf("config: merged_audio must be mp3 or opus, got %q", c.MergedAudio)
+	}
+	if sc := c.Summarization; sc.URL != "" {
+		u, err := url.Parse(sc.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("config: summarization.url must be an http or https URL")
+		}
+		if sc.Model == "" {
+			return errors.New("config: summarization.model is required when summarization.url is set")
+		}
+		if sc.MaxChars < 0 || sc.Timeout < 0 {
+			return errors.New("config: summarization.max_chars and summarization.timeout must not be negative")
+		}
+	}
+	if kc := c.Keywords; kc.URL != "" {
+		u, err := url.Parse(kc.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("config: keywords.url must be an http or https URL")
+		}
+	}
+	if c.Keywords.Max < 0 || c.Keywords.Timeout < 0 {
+		return errors.New("config: keywords.max and keywords.timeout must not be negative")
+	}
+	if sc := c.Sentiment; sc.URL != "" {
+		u, err := url.Parse(sc.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("config: sentiment.url must be an http or https URL")
+		}
+	}
+	if c.Sentiment.Timeout < 0 {
+		return errors.New("config: sentiment.timeout must not be negative")
+	}
+	for _, pattern := range c.Redaction.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("config: redaction.patterns: %w", err)
+		}
+	}
+	if a := c.Redaction.Audio; a != "" && a != "bleep" && a != "mute" {
+		return fmt.Errorf("config: redaction.audio must be bleep or mute, not %q", a)
+	}
+	for _, r := range c.Acceleration.FFmpegHWAccel {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return fmt.Errorf("config: acceleration.ffmpeg_hwaccel %q is not an ffmpeg -hwaccel method", c.Acceleration.FFmpegHWAccel)
+		}
+	}
+	if len(c.Acceleration.Check) > 0 && c.Acceleration.Check[0] == "" {
+		return errors.New("config: acceleration.check must start with a command")
+	}
+	if c.Queue.URL != "" {
+		u, err := url.Parse(c.Queue.URL)
+		if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+			return errors.New("config: queue.url must be a redis:// or rediss:// URL")
+		}
+		if c.Queue.Name == "" {
+			return errors.New("config: queue.name must not be empty")
+		}
+	}
+	if c.S3.Bucket != "" {
+		u, err := url.Parse(c.S3.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("config: s3.endpoint must be an http:// or https:// URL")
+		}
+		if c.S3.Region == "" || c.S3.AccessKey == "" || c.S3.SecretKey == "" {
+			return errors.New("config: s3 needs region, access_key, and secret_key with a bucket")
+		}
+		if c.S3.URLExpiry < time.Second || c.S3.URLExpiry > 7*24*time.Hour {
+			return errors.New("config: s3.url_expiry must be between 1s and 168h")
+		}
+	}
+	if err := c.TLS.validate(); err != nil {
+		return err
+	}
+	if p := strings.TrimSuffix(c.BasePath, "/"); p != "" {
+		if !strings.HasPrefix(p, "/") || path.Clean(p) != p || strings.ContainsAny(p, "?#%\\ ") {
+			return fmt.Errorf("config: base_path %q must be a path such as /audio-chunker", c.BasePath)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, err := proxyNetwork(proxy); err != nil {
+			return fmt.Errorf("config: trusted_proxies: %w", err)
+		}
+	}
+	if err := c.CORS.validate(); err != nil {
+		return err
+	}
+	if c.LeaseTTL < 3*time.Second {
+		return errors.New("config: lease_ttl must be at least 3s")
+	}
+	for name := range c.Tools.BadVersions {
+		if name != "ffmpeg" && name != "whisper" {
+			return fmt.Errorf("config: tools.bad_versions: unknown tool %q (use ffmpeg or whisper)", name)
+		}
+	}
+	if c.JobLogs.MaxSize < 0 || c.JobLogs.Keep < 0 {
+		return errors.New("config: job_logs.max_size and job_logs.keep cannot be negative")
+	}
+	if err := c.Commands.validate(); err != nil {
+		return err
+	}
+	if c.TranscriptOnly && c.Whisper.Bin == "" {
+		return errors.New("config: transcript_only needs whisper.bin")
+	}
+	if c.Auth.Enabled() && c.Auth.Password == "" {
+		return errors.New("config: auth.password is required when auth.username is set")
+	}
+	return nil
+}
+
+func (c CommandsConfig) validate() error {
+	if c.FFmpegTimeout < 0 || c.WhisperTimeout < 0 {
+		return errors.New("config: commands.ffmpeg_timeout and commands.whisper_timeout must not be negative")
+	}
+	if c.Nice < 0 || c.Nice > 19 {
+		return errors.New("config: commands.nice must be between 0 and 19")
+	}
+	switch c.IOClass {
+	case "", "idle":
+		if c.IOLevel != 0 {
+			return errors.New("config: commands.io_level needs io_class best-effort")
+		}
+	case "best-effort":
+		if c.IOLevel < 0 || c.IOLevel > 7 {
+			return errors.New("config: commands.io_level must be between 0 and 7")
+		}
+	default:
+		return fmt.Errorf("config: commands.io_class must be best-effort or idle, not %q", c.IOClass)
+	}
+	for _, cpu := range c.CPUs {
+		if cpu < 0 || cpu >= maxCPUs {
+			return fmt.Errorf("config: commands.cpus: CPU %d out of range", cpu)
+		}
+	}
+	return nil
+}
+
+// maxCPUs matches the largest CPU number chunker.CommandPriority accepts.
+const maxCPUs = 1024
+
+// maxJobIDPrefix keeps prefixed IDs short enough for directory names and URLs.
+const maxJobIDPrefix = 32
+
+func (c JobIDConfig) validate() error {
+	check := func(field, prefix string) error {
+		if len(prefix) > maxJobIDPrefix {
+			return fmt.Errorf("config: %s must be at most %d characters", field, maxJobIDPrefix)
+		}
+		for i, r := range prefix {
+			ok := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || (r == '.' && i > 0)
+			if !ok {
+				return fmt.Errorf("config: %s %q may only contain letters, digits, '-', '_', and '.' (not first)", field, prefix)
+			}
+		}
+		return nil
+	}
+	switch c.Scheme {
+	case JobIDULID, JobIDUUIDv7, JobIDTimestamp:
+	default:
+		return fmt.Errorf("config: job_ids.scheme must be %s, %s, or %s, not %q", JobIDULID, JobIDUUIDv7, JobIDTimestamp, c.Scheme)
+	}
+	if err := check("job_ids.prefix", c.Prefix); err != nil {
+		return err
+	}
+	for source, prefix := range c.Sources {
+		if err := check("job_ids.sources."+source, prefix); err != nil {
+			return err
+		}
+	}
+	for project, prefix := range c.Projects {
+		if err := check("job_ids.projects."+project, prefix); err != nil {
+			return err
+		}
+	}
+	if len(c.Projects) > 0 && c.ProjectKey == "" {
+		return errors.New("config: job_ids.project_key is required when projects are set")
+	}
+	return nil
+}
+
+func (t TLSConfig) validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("config: tls.cert_file and tls.key_file must be set together")
+	}
+	if t.CertFile != "" && len(t.AutocertDomains) > 0 {
+		return errors.New("config: use either tls.cert_file or tls.autocert_domains, not both")
+	}
+	for _, domain := range t.AutocertDomains {
+		if domain == "" || strings.ContainsAny(domain, "/:*") {
+			return fmt.Errorf("config: tls.autocert_domains: %q is not a host name", domain)
+		}
+	}
+	if t.ACMEDirectory != "" {
+		if u, err := url.Parse(t.ACMEDirectory); err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("config: tls.acme_directory must be an https:// URL")
+		}
+	}
+	if t.RedirectAddr != "" {
+		if !t.Enabled() {
+			return errors.New("config: tls.redirect_addr needs tls.cert_file or tls.autocert_domains")
+		}
+		if _, _, err := net.SplitHostPort(t.RedirectAddr); err != nil {
+			return fmt.Errorf("config: tls.redirect_addr: %w", err)
+		}
+	}
+	return nil
+}
+
+// headerToken matches HTTP method and header names.
+var headerToken = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+func (c CORSConfig) validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return errors.New(`config: cors.allow_credentials cannot be combined with the "*" origin`)
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+			return fmt.Errorf("config: cors.allowed_origins: %q is not an origin such as https://app.example.com", origin)
+		}
+	}
+	for _, name := range append(slices.Clone(c.AllowedMethods), c.AllowedHeaders...) {
+		if !headerToken.MatchString(name) {
+			return fmt.Errorf("config: cors: %q is not a method or header name", name)
+		}
+	}
+	if c.MaxAge < 0 {
+		return errors.New("config: cors.max_age must not be negative")
+	}
+	return nil
+}
+
+// TrustedProxyNetworks returns TrustedProxies as networks; single addresses become
+// networks of one. Entries that do not parse, which Validate refuses, are left out.
+func (c Config) TrustedProxyNetworks() []netip.Prefix {
+	networks := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if network, err := proxyNetwork(proxy); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func proxyNetwork(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		network, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return network.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ChunkConcurrency is how many chunks of one job are transcribed at once.
+func (c Config) ChunkConcurrency() int {
+	switch {
+	case c.TranscribePerJob > 0:
+		return c.TranscribePerJob
+	case c.TranscribeWorkers > 0:
+		return c.TranscribeWorkers
+	}
+	return 1
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedSecret stands in for the secrets an export leaves out. Load refuses a file that
+// still contains it, so an exported config cannot start a server until they are filled
+// in; imports keep the running server's value for them.
+const RedactedSecret = "REDACTED"
+
+// Redact returns a copy of c with the password, the summarization API key, the S3
+// secret key, and every webhook, keyword service, and fetch header value replaced by
+// RedactedSecret, and the paths of the values it replaced.
+func (c Config) Redact() (Config, []string) {
+	var paths []string
+	redact := func(path string, value *string) {
+		if *value != "" {
+			*value = RedactedSecret
+			paths = append(paths, path)
+		}
+	}
+	redact("auth.password", &c.Auth.Password)
+	redact("summarization.api_key", &c.Summarization.APIKey)
+	redact("s3.secret_key", &c.S3.SecretKey)
+
+	c.Webhooks = slices.Clone(c.Webhooks)
+	for i := range c.Webhooks {
+		hook := &c.Webhooks[i]
+		hook.Headers = maps.Clone(hook.Headers)
+		for _, name := range slices.Sorted(maps.Keys(hook.Headers)) {
+			value := hook.Headers[name]
+			redact(fmt.Sprintf("webhooks[%s].headers.%s", hook.Name, name), &value)
+			hook.Headers[name] = value
+		}
+	}
+
+	c.Keywords.Headers = maps.Clone(c.Keywords.Headers)
+	for _, name := range slices.Sorted(maps.Keys(c.Keywords.Headers)) {
+		value := c.Keywords.Headers[name]
+		redact("keywords.headers."+name, &value)
+		c.Keywords.Headers[name] = value
+	}
+	c.Sentiment.Headers = maps.Clone(c.Sentiment.Headers)
+	for _, name := range slices.Sorted(maps.Keys(c.Sentiment.Headers)) {
+		value := c.Sentiment.Headers[name]
+		redact("sentiment.headers."+name, &value)
+		c.Sentiment.Headers[name] = value
+	}
+
+	c.Storage.Classes = maps.Clone(c.Storage.Classes)
+	for _, class := range slices.Sorted(maps.Keys(c.Storage.Classes)) {
+		sc := c.Storage.Classes[class]
+		sc.FetchHeaders = maps.Clone(sc.FetchHeaders)
+		for _, name := range slices.Sorted(maps.Keys(sc.FetchHeaders)) {
+			value := sc.FetchHeaders[name]
+			redact(fmt.Sprintf("storage.classes.%s.fetch_headers.%s", class, name), &value)
+			sc.FetchHeaders[name] = value
+		}
+		c.Storage.Classes[class] = sc
+	}
+	return c, paths
+}
+
+// Unredact returns a copy of c with every RedactedSecret replaced by from's value at the
+// same place, e.g. to compare an imported export with the running config.
+func (c Config) Unredact(from Config) Config {
+	if c.Auth.Password == RedactedSecret {
+		c.Auth.Password = from.Auth.Password
+	}
+	if c.Summarization.APIKey == RedactedSecret {
+		c.Summarization.APIKey = from.Summarization.APIKey
+	}
+	if c.S3.SecretKey == RedactedSecret {
+		c.S3.SecretKey = from.S3.SecretKey
+	}
+
+	fromHooks := make(map[string]WebhookConfig, len(from.Webhooks))
+	for _, hook := range from.Webhooks {
+		fromHooks[hook.Name] = hook
+	}
+	c.Webhooks = slices.Clone(c.Webhooks)
+	for i := range c.Webhooks {
+		hook := &c.Webhooks[i]
+		hook.Headers = unredactMap(hook.Headers, fromHooks[hook.Name].Headers)
+	}
+
+	c.Keywords.Headers = unredactMap(c.Keywords.Headers, from.Keywords.Headers)
+	c.Sentiment.Headers = unredactMap(c.Sentiment.Headers, from.Sentiment.Headers)
+
+	c.Storage.Classes = maps.Clone(c.Storage.Classes)
+	for name, class := range c.Storage.Classes {
+		class.FetchHeaders = unredactMap(class.FetchHeaders, from.Storage.Classes[name].FetchHeaders)
+		c.Storage.Classes[name] = class
+	}
+	return c
+}
+
+func unredactMap(values, from map[string]string) map[string]string {
+	values = maps.Clone(values)
+	for name, value := range values {
+		if value == RedactedSecret {
+			values[name] = from[name]
+		}
+	}
+	return values
+}
+
+// redacted returns the path of a secret still set to RedactedSecret.
+func (c Config) redacted() (string, bool) {
+	if c.Auth.Password == RedactedSecret {
+		return "auth.password", true
+	}
+	if c.Summarization.APIKey == RedactedSecret {
+		return "summarization.api_key", true
+	}
+	if c.S3.SecretKey == RedactedSecret {
+		return "s3.secret_key", true
+	}
+	for _, hook := range c.Webhooks {
+		for name, value := range hook.Headers {
+			if value == RedactedSecret {
+				return fmt.Sprintf("webhooks[%s].headers.%s", hook.Name, name), true
+			}
+		}
+	}
+	for name, value := range c.Keywords.Headers {
+		if value == RedactedSecret {
+			return "keywords.headers." + name, true
+		}
+	}
+	for name, value := range c.Sentiment.Headers {
+		if value == RedactedSecret {
+			return "sentiment.headers." + name, true
+		}
+	}
+	for class, sc := range c.Storage.Classes {
+		for name, value := range sc.FetchHeaders {
+			if value == RedactedSecret {
+				return fmt.Sprintf("storage.classes.%s.fetch_headers.%s", class, name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// Marshal renders c as a config file, with comment as a leading comment block.
+func (c Config) Marshal(comment []string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, line := range comment {
+		buf.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse reads a config file's contents over Default and validates the result. Unlike
+// Load it ignores the environment and accepts RedactedSecret, so it suits exports.
+func Parse(data []byte) (Config, error) {
+	cfg := Default()
+	if err := decode(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing config: %w", err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// Diff lists the top-level settings, by their YAML names, whose values differ between a
+// and b. Presets are left out: they can be changed without a restart.
+func Diff(a, b Config) ([]string, error) {
+	a.Presets, b.Presets = nil, nil
+	left, err := topLevel(a)
+	if err != nil {
+		return nil, err
+	}
+	right, err := topLevel(b)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range left {
+		if !reflect.DeepEqual(left[key], right[key]) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range right {
+		if _, ok := left[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// topLevel decodes c's YAML form into its top-level keys, so values compare the way
+// they would be written.
+func topLevel(c Config) (map[string]any, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, errors.New("config does not encode as a mapping")
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+// Package queue hands jobs from the server to worker processes through a message
+// broker. The server publishes a Task per job and waits for the job's ID on the done
+// list; workers take tasks, process them in the shared jobs directory, and report back.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"audi/pkg/chunker"
+)
+
+// Broker moves messages through named lists. Each message is delivered to one reader.
+type Broker interface {
+	// Push appends msg to list.
+	Push(ctx context.Context, list string, msg []byte) error
+	// Pop removes and returns the oldest message of list, waiting for one until ctx ends.
+	Pop(ctx context.Context, list string) ([]byte, error)
+	Close() error
+}
+
+// Open connects to the broker at rawURL. Only Redis is supported: redis:// and, for
+// TLS, rediss://, with an optional user and password and the database number as path.
+func Open(rawURL string) (Broker, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("queue: %w", err)
+	}
+	switch u.Scheme {
+	case "redis", "rediss":
+		return newRedisBroker(u)
+	default:
+		return nil, fmt.Errorf("queue: unsupported broker %q (want redis:// or rediss://)", u.Scheme)
+	}
+}
+
+// Task asks a worker to process one job. Options are the job's processing options as the
+// server built them; the worker adds its own concurrency and command timeouts.
+type Task struct {
+	JobID   string          `json:"jobId"`
+	Options chunker.Options `json:"options"`
+}
+
+// Finished reports that a worker is done with a job, whether it completed or failed.
+type Finished struct {
+	JobID  string `json:"jobId"`
+	Worker string `json:"worker"`
+}
+
+// Queue is the server and workers' view of a broker, with lists prefixed by name.
+type Queue struct {
+	broker Broker
+	name   string
+}
+
+// New returns a queue using the lists <name>:tasks and <name>:done on broker.
+func New(broker Broker, name string) *Queue {
+	return &Queue{broker: broker, name: name}
+}
+
+// Publish queues t for a worker.
+func (q *Queue) Publish(ctx context.Context, t Task) error {
+	return q.push(ctx, "tasks", t)
+}
+
+// Next waits for the next task.
+func (q *Queue) Next(ctx context.Context) (Task, error) {
+	var t Task
+	err := q.pop(ctx, "tasks", &t)
+	return t, err
+}
+
+// Finish reports f to the server.
+func (q *Queue) Finish(ctx context.Context, f Finished) error {
+	return q.push(ctx, "done", f)
+}
+
+// NextFinished waits for the next job a worker is done with.
+func (q *Queue) NextFinished(ctx context.Context) (Finished, error) {
+	var f Finished
+	err := q.pop(ctx, "done", &f)
+	return f, err
+}
+
+// Close disconnects from the broker.
+func (q *Queue) Close() error {
+	return q.broker.Close()
+}
+
+func (q *Queue) push(ctx context.Context, list string, v any) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("queue: encoding message: %w", err)
+	}
+	return q.broker.Push(ctx, q.name+":"+list, msg)
+}
+
+func (q *Queue) pop(ctx context.Context, list string, v any) error {
+	msg, err := q.broker.Pop(ctx, q.name+":"+list)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(msg, v); err != nil {
+		return fmt.Errorf("queue: decoding message from %s: %w", list, err)
+	}
+	return nil
+}
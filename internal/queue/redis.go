@@ -0,0 +1,242 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// popWait is how long one BRPOP blocks before Pop checks its context again.
+const popWait = 5 * time.Second
+
+// redisBroker keeps Redis lists with LPUSH and BRPOP, speaking RESP over connections it
+// pools. A blocked BRPOP holds its connection, so every concurrent Pop gets its own.
+type redisBroker struct {
+	addr     string
+	tls      bool
+	username string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+func newRedisBroker(u *url.URL) (*redisBroker, error) {
+	b := &redisBroker{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		b.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		b.username = u.User.Username()
+		b.password, _ = u.User.Password()
+		if b.password == "" {
+			// redis://:password@host and redis://password@host both mean a bare password.
+			b.username, b.password = "", b.username
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("queue: invalid Redis database %q", db)
+		}
+		b.db = n
+	}
+	return b, nil
+}
+
+func (b *redisBroker) Push(ctx context.Context, list string, msg []byte) error {
+	_, err := b.do(ctx, 0, "LPUSH", list, string(msg))
+	return err
+}
+
+func (b *redisBroker) Pop(ctx context.Context, list string) ([]byte, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reply, err := b.do(ctx, popWait, "BRPOP", list, strconv.Itoa(int(popWait/time.Second)))
+		if err != nil {
+			return nil, err
+		}
+		// BRPOP answers nil when it timed out and [list, message] otherwise.
+		if pair, ok := reply.([]any); ok && len(pair) == 2 {
+			if msg, ok := pair[1].(string); ok {
+				return []byte(msg), nil
+			}
+		}
+	}
+}
+
+func (b *redisBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, c := range b.idle {
+		c.conn.Close()
+	}
+	b.idle = nil
+	return nil
+}
+
+// do runs one command on a pooled connection. block is how long the server may take to
+// answer beyond the usual round trip, for blocking commands.
+func (b *redisBroker) do(ctx context.Context, block time.Duration, args ...string) (any, error) {
+	c, err := b.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(block + 10*time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+	reply, err := c.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, fmt.Errorf("queue: redis %s: %w", args[0], err)
+	}
+	b.put(c)
+	if err != nil {
+		return nil, fmt.Errorf("queue: redis %s: %w", args[0], err)
+	}
+	return reply, nil
+}
+
+func (b *redisBroker) get(ctx context.Context) (*redisConn, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, errors.New("queue: broker closed")
+	}
+	if n := len(b.idle); n > 0 {
+		c := b.idle[n-1]
+		b.idle = b.idle[:n-1]
+		b.mu.Unlock()
+		return c, nil
+	}
+	b.mu.Unlock()
+	return b.dial(ctx)
+}
+
+func (b *redisBroker) put(c *redisConn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		c.conn.Close()
+		return
+	}
+	b.idle = append(b.idle, c)
+}
+
+// dial connects, authenticates, and selects the database.
+func (b *redisBroker) dial(ctx context.Context) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if b.tls {
+		conn, err = (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", b.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", b.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("queue: connecting to redis: %w", err)
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if b.password != "" {
+		args := []string{"AUTH", b.password}
+		if b.username != "" {
+			args = []string{"AUTH", b.username, b.password}
+		}
+		if _, err := c.do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("queue: redis AUTH: %w", err)
+		}
+	}
+	if b.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(b.db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("queue: redis SELECT: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// redisError is an error reply; the connection stays usable after one.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// do sends a command as an array of bulk strings and reads the reply.
+func (c *redisConn) do(args ...string) (any, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read parses one RESP reply: a string, an int64, nil, a redisError, or a []any.
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch kind, rest := line[0], line[1:]; kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, redisError(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,185 @@
+// Package s3 talks to an S3-compatible object store (AWS S3, MinIO, Ceph, R2, ...)
+// through pre-signed URLs, signed with AWS Signature Version 4. Only what direct uploads
+// need is covered: handing out PUT URLs and reading, sizing, and deleting objects.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxExpiry is the longest validity Signature Version 4 allows for a pre-signed URL.
+const MaxExpiry = 7 * 24 * time.Hour
+
+// ErrNotFound is returned for objects the bucket does not have.
+var ErrNotFound = errors.New("object not found")
+
+// Client signs requests for one bucket.
+type Client struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	http      *http.Client
+}
+
+// New returns a client for bucket at endpoint, e.g. https://s3.eu-central-1.amazonaws.com.
+// With pathStyle the bucket is addressed as the first path segment instead of as a
+// subdomain, as MinIO and most self-hosted stores expect.
+func New(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("s3: endpoint %q must be an http:// or https:// URL", endpoint)
+	}
+	return &Client{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		http:      &http.Client{},
+	}, nil
+}
+
+// Presign returns a URL that performs method on the object key for anyone holding it,
+// until expires has passed. The payload is not signed, so a PUT URL accepts any body.
+func (c *Client) Presign(method, key string, expires time.Duration) string {
+	return c.presign(method, key, expires, time.Now().UTC())
+}
+
+func (c *Client) presign(method, key string, expires time.Duration, now time.Time) string {
+	host, path := c.endpoint.Host, "/"+escapePath(key)
+	if c.pathStyle {
+		path = "/" + escape(c.bucket) + path
+	} else {
+		host = c.bucket + "." + host
+	}
+	date := now.Format("20060102")
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    c.accessKey + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       strconv.Itoa(int(expires / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	canonicalQuery := canonicalQuery(query)
+	request := strings.Join([]string{method, path, canonicalQuery, "host:" + host, "", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	requestHash := sha256.Sum256([]byte(request))
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", query["X-Amz-Date"], scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	signingKey = hmacSHA256(signingKey, c.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, toSign))
+	return c.endpoint.Scheme + "://" + host + path + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// Size returns the size of the object key, or an error wrapping ErrNotFound.
+func (c *Client) Size(ctx context.Context, key string) (int64, error) {
+	resp, err := c.do(ctx, http.MethodHead, key)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+// Open streams the object key; the caller closes the body.
+func (c *Client) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object key. Deleting a missing object succeeds.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a request signed for a minute and fails on any status but 2xx.
+func (c *Client) do(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.presign(method, key, time.Minute, time.Now().UTC()), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s %s: %w", method, key, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("s3: %s: %w", key, ErrNotFound)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, fmt.Errorf("s3: %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query sorted by name, as Signature Version 4 requires.
+func canonicalQuery(query map[string]string) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, escape(name)+"="+escape(query[name]))
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath escapes each segment of an object key, keeping the slashes.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent-encodes everything but the unreserved characters of RFC 3986, the
+// encoding Signature Version 4 signs.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+
+	"audi/internal/config"
+	"audi/pkg/chunker"
+)
+
+// Acceleration checks which of the configured hardware acceleration this machine
+// supports, reporting what it drops and what it uses to logf, or returns nil when none
+// is configured.
+func Acceleration(ffmpeg string, c config.AccelerationConfig, logf Logf) *chunker.Acceleration {
+	if !c.GPU && c.FFmpegHWAccel == "" {
+		return nil
+	}
+	want := chunker.Acceleration{
+		GPU:            c.GPU,
+		WhisperArgs:    c.WhisperArgs,
+		CPUWhisperArgs: c.CPUWhisperArgs,
+		FFmpegHWAccel:  c.FFmpegHWAccel,
+	}
+	if c.GPU && want.CPUWhisperArgs == nil {
+		want.CPUWhisperArgs = []string{"-ng"}
+	}
+	accel, notes := chunker.DetectAcceleration(context.Background(), ffmpeg, want, c.Check)
+	for _, note := range notes {
+		logf.printf("acceleration: %s", note)
+	}
+	if accel.GPU {
+		logf.printf("acceleration: whisper runs on the GPU")
+	}
+	if accel.FFmpegHWAccel != "" {
+		logf.printf("acceleration: ffmpeg decodes video with -hwaccel %s", accel.FFmpegHWAccel)
+	}
+	return accel
+}
+
+// CheckTools checks ffmpeg and whisper so jobs record their versions, reporting what it
+// found to logf. With tools.strict, an unusable program or any warning is an error.
+func CheckTools(cfg config.Config, logf Logf) ([]chunker.ToolCheck, error) {
+	checks := chunker.CheckTools(context.Background(), cfg.FFmpegBin, cfg.Whisper.Bin, cfg.Tools.BadVersions)
+	for _, check := range checks {
+		switch {
+		case !check.OK():
+			logf.printf("tools: %s unusable: %s", check.Name, check.Error)
+		case check.Version == "":
+			logf.printf("tools: %s (version unknown, sha256 %s)", check.Name, check.SHA256)
+		default:
+			logf.printf("tools: %s %s (sha256 %s)", check.Name, check.Version, check.SHA256)
+		}
+		for _, warning := range check.Warnings {
+			logf.printf("tools: warning: %s", warning)
+		}
+	}
+	if cfg.Tools.Strict {
+		for _, check := range checks {
+			if !check.OK() || len(check.Warnings) > 0 {
+				return checks, fmt.Errorf("tools.strict: refusing to run with %s as it is", check.Name)
+			}
+		}
+	}
+	return checks, nil
+}
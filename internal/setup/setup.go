@@ -0,0 +1,120 @@
+// Package setup builds the processor and its stages from the configuration. The server,
+// the workers, and the CLI all go through it, so a job comes out the same wherever it
+// runs.
+package setup
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"audi/internal/config"
+	"audi/pkg/chunker"
+)
+
+// Logf receives what building a stage found out about this machine, such as
+// acceleration falling back to the CPU. log.Printf fits; nil discards it.
+type Logf func(format string, args ...any)
+
+func (f Logf) printf(format string, args ...any) {
+	if f != nil {
+		f(format, args...)
+	}
+}
+
+// Processor builds the processor the server and workers run jobs with: every stage the
+// config enables, with the transcript cache under the data directory. extra is applied
+// last, e.g. chunker.WithTools with the versions found at startup.
+func Processor(cfg config.Config, logf Logf, extra ...chunker.Option) (*chunker.Processor, error) {
+	redactor, err := Redactor(cfg.Redaction)
+	if err != nil {
+		return nil, fmt.Errorf("redaction: %w", err)
+	}
+	opts := []chunker.Option{
+		chunker.WithFFmpeg(cfg.FFmpegBin),
+		chunker.WithWhisper(cfg.Whisper.Bin, cfg.Whisper.Args...),
+		chunker.WithTranscriptCache(chunker.NewDirCache(filepath.Join(cfg.DataDir, "cache", "transcripts"), int64(cfg.Storage.TranscriptCacheMax))),
+		chunker.WithResidentWhisper(ResidentWhisper(cfg.Whisper.Server, logf)),
+		chunker.WithPools(cfg.EncodeWorkers, cfg.TranscribeWorkers),
+		chunker.WithSummarizer(Summarizer(cfg.Summarization)),
+		chunker.WithKeywordExtractor(KeywordExtractor(cfg.Keywords)),
+		chunker.WithSentimentAnalyzer(SentimentAnalyzer(cfg.Sentiment)),
+		chunker.WithRedactor(redactor),
+		chunker.WithCommandPriority(CommandPriority(cfg.Commands)),
+		chunker.WithAcceleration(Acceleration(cfg.FFmpegBin, cfg.Acceleration, logf)),
+	}
+	return chunker.New(append(opts, extra...)...), nil
+}
+
+// ProgressWeights maps the progress_weights config onto the processor's.
+func ProgressWeights(c config.ProgressWeightsConfig) chunker.ProgressWeights {
+	return chunker.ProgressWeights{Extract: c.Extract, Postprocess: c.Postprocess, Transcribe: c.Transcribe}
+}
+
+// CommandPriority maps the commands config onto the priority ffmpeg and whisper run at.
+func CommandPriority(c config.CommandsConfig) chunker.CommandPriority {
+	return chunker.CommandPriority{Nice: c.Nice, IOClass: c.IOClass, IOLevel: c.IOLevel, CPUs: c.CPUs}
+}
+
+// Redactor builds the redaction stage's matcher, or nil when nothing is configured to be
+// redacted.
+func Redactor(c config.RedactionConfig) (*chunker.Redactor, error) {
+	if len(c.Words) == 0 && len(c.Patterns) == 0 {
+		return nil, nil
+	}
+	return chunker.NewRedactor(c.Words, c.Patterns, c.Replacement, c.Audio == "mute")
+}
+
+// ResidentWhisper builds the resident whisper server the config asks for, or nil. Its
+// lifecycle messages go to logf.
+func ResidentWhisper(c config.WhisperServerConfig, logf Logf) *chunker.ResidentWhisper {
+	if c.Bin == "" {
+		return nil
+	}
+	return &chunker.ResidentWhisper{
+		Bin:            c.Bin,
+		Args:           c.Args,
+		Addr:           c.Addr,
+		HealthPath:     c.HealthPath,
+		StartTimeout:   c.StartTimeout,
+		HealthInterval: c.HealthInterval,
+		Logf:           logf,
+	}
+}
+
+// Summarizer builds the summarization stage's client, or nil when it is not configured.
+func Summarizer(c config.SummarizationConfig) *chunker.Summarizer {
+	if c.URL == "" {
+		return nil
+	}
+	return &chunker.Summarizer{
+		URL:      c.URL,
+		APIKey:   c.APIKey,
+		Model:    c.Model,
+		Prompt:   c.Prompt,
+		MaxChars: c.MaxChars,
+		Timeout:  c.Timeout,
+	}
+}
+
+// KeywordExtractor builds the keyword stage's extractor: the built-in one unless a
+// service is configured.
+func KeywordExtractor(c config.KeywordsConfig) chunker.KeywordExtractor {
+	if c.URL == "" {
+		return chunker.LocalKeywords{Max: c.Max}
+	}
+	return &chunker.KeywordService{
+		URL:     c.URL,
+		Headers: c.Headers,
+		Max:     c.Max,
+		Timeout: c.Timeout,
+	}
+}
+
+// SentimentAnalyzer builds the sentiment stage's analyzer: the built-in one unless a
+// service is configured.
+func SentimentAnalyzer(c config.SentimentConfig) chunker.SentimentAnalyzer {
+	if c.URL == "" {
+		return chunker.LocalSentiment{}
+	}
+	return &chunker.SentimentService{URL: c.URL, Headers: c.Headers, Timeout: c.Timeout}
+}
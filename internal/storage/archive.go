@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// maxArchivedJobFile bounds the job.json read from an archive; processing logs and
+// command records keep it far below this.
+const maxArchivedJobFile = 64 << 20
+
+var (
+	// ErrInvalidArchive is returned by ImportJob for input that is not a job archive.
+	ErrInvalidArchive = errors.New("not a valid job archive")
+	// ErrArchiveTooLarge is returned by ImportJob when the unpacked files exceed its limit.
+	ErrArchiveTooLarge = errors.New("job archive too large")
+)
+
+// ExportJob writes the job in jobDir to w as a gzip-compressed tar archive: job.json
+// first, then every other file and directory under their paths in the job directory.
+// Artefact directories on a storage class are read through their link. The staging
+// area, the lease, and temporary files are left out, and so are files that are only
+// available from a class's fetch URL.
+func ExportJob(w io.Writer, jobDir string) error {
+	entries, err := os.ReadDir(jobDir)
+	if err != nil {
+		return fmt.Errorf("reading job directory: %w", err)
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := archiveTree(tw, jobDir, jobFileName); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == jobFileName || !archived(name) {
+			continue
+		}
+		if err := archiveTree(tw, jobDir, name); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
+}
+
+// archived reports whether a top-level entry of a job directory belongs in its archive.
+func archived(name string) bool {
+	return name != "tmp" && !strings.HasPrefix(name, ".") && !strings.HasPrefix(name, LeaseFile) && !strings.HasSuffix(name, ".tmp")
+}
+
+// archiveTree adds the entry name of jobDir to tw, with everything below it when it is
+// a directory.
+func archiveTree(tw *tar.Writer, jobDir, name string) error {
+	root := resolveDir(filepath.Join(jobDir, name))
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("archiving %s: %w", name, err)
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("archiving %s: %w", name, err)
+		}
+		hdr := &tar.Header{Name: path.Join(name, filepath.ToSlash(rel)), ModTime: info.ModTime()}
+		switch {
+		case d.IsDir():
+			hdr.Typeflag, hdr.Name, hdr.Mode = tar.TypeDir, hdr.Name+"/", 0o755
+			return tw.WriteHeader(hdr)
+		case d.Type().IsRegular():
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeReg, 0o644, info.Size()
+		default:
+			return nil
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("archiving %s: %w", hdr.Name, err)
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return fmt.Errorf("archiving %s: %w", hdr.Name, err)
+		}
+		defer f.Close()
+		if _, err := io.Copy(tw, f); err != nil {
+			return fmt.Errorf("archiving %s: %w", hdr.Name, err)
+		}
+		return nil
+	})
+}
+
+// ImportJob unpacks an archive written by ExportJob into a new job directory under
+// jobsRoot, laid out on its storage classes by router, and returns the job under the
+// ID newID picks for it. The archived ID is kept as ImportedFrom; links to other jobs
+// of the exporting server are dropped, and a job.json naming files outside the job is
+// refused. maxBytes, when positive, caps the unpacked size.
+// The caller saves the returned job: until job.json is written the directory is not
+// listed as a job, and the caller removes it with RemoveJob if it gives up.
+func ImportJob(src io.Reader, jobsRoot string, router *Router, newID func(*chunker.Job) string, maxBytes int64) (*chunker.Job, error) {
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if hdr.Name != jobFileName || hdr.Typeflag != tar.TypeReg || hdr.Size > maxArchivedJobFile {
+		return nil, fmt.Errorf("%w: it does not start with %s", ErrInvalidArchive, jobFileName)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	var job chunker.Job
+	if err := json.Unmarshal(data, &job); err != nil || job.ID == "" {
+		return nil, fmt.Errorf("%w: %s is not a job", ErrInvalidArchive, jobFileName)
+	}
+	for _, name := range referencedFiles(&job) {
+		if name != "" && !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("%w: %s refers to %q outside the job", ErrInvalidArchive, jobFileName, name)
+		}
+	}
+
+	id, jobDir, err := CreateJobDir(jobsRoot, func() string { return newID(&job) })
+	if err != nil {
+		return nil, err
+	}
+	if err := unpackJob(tr, gz, jobDir, router, maxBytes); err != nil {
+		if rmErr := RemoveJob(jobDir); rmErr != nil {
+			err = fmt.Errorf("%w (removing the partial job: %w)", err, rmErr)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	job.ImportedFrom, job.ImportedAt = job.ID, &now
+	job.ID = id
+	job.ReusedChunksFrom = ""
+	job.RechunkedFrom = ""
+	job.Progress = nil
+	return &job, nil
+}
+
+// unpackJob extracts the rest of an archive into jobDir. Only plain files and
+// directories inside the job directory are accepted.
+func unpackJob(tr *tar.Reader, gz *gzip.Reader, jobDir string, router *Router, maxBytes int64) error {
+	if err := router.PrepareJob(jobDir, ArtefactDirs...); err != nil {
+		return err
+	}
+	var written int64
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		name := path.Clean(hdr.Name)
+		top, _, _ := strings.Cut(name, "/")
+		if !filepath.IsLocal(filepath.FromSlash(name)) || top == jobFileName || !archived(top) {
+			return fmt.Errorf("%w: unexpected entry %q", ErrInvalidArchive, hdr.Name)
+		}
+		dst := filepath.Join(jobDir, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, 0o755); err != nil {
+				return fmt.Errorf("unpacking %s: %w", name, err)
+			}
+			continue
+		case tar.TypeReg:
+		default:
+			return fmt.Errorf("%w: %q is neither a file nor a directory", ErrInvalidArchive, hdr.Name)
+		}
+		if written += hdr.Size; maxBytes > 0 && written > maxBytes {
+			return ErrArchiveTooLarge
+		}
+		if err := unpackFile(tr, dst, hdr.ModTime); err != nil {
+			return fmt.Errorf("unpacking %s: %w", name, err)
+		}
+	}
+	// Reading to the end makes gzip check the archive's checksum.
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return nil
+}
+
+// unpackFile writes the current archive entry to the new file dst.
+func unpackFile(tr *tar.Reader, dst string, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, modTime, modTime)
+}
+
+// referencedFiles lists the job files job.json names, relative to the job directory,
+// including those of earlier revisions; unset ones are empty.
+func referencedFiles(job *chunker.Job) []string {
+	names := []string{job.OriginalVideoPath, job.MergedAudioFile, job.SubtitlesFile, job.SubtitledVideoFile, job.ChaptersFile, job.ChapterMetadataFile}
+	chunks := job.Chunks
+	for _, rev := range job.Revisions {
+		names = append(names, rev.MergedAudioFile, rev.SubtitlesFile, rev.SubtitledVideoFile, rev.ChaptersFile, rev.ChapterMetadataFile)
+		chunks = append(chunks[:len(chunks):len(chunks)], rev.Chunks...)
+	}
+	for _, c := range chunks {
+		names = append(names, c.AudioFile, c.Base64File, c.TranscriptFile, c.WordsFile, c.VideoFile, c.RedactedTranscriptFile, c.RedactedAudioFile, c.MaskedTranscriptFile)
+		for _, t := range c.Transcripts {
+			names = append(names, t.File)
+		}
+	}
+	return names
+}
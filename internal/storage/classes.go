@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ArtefactDirs are the per-job subdirectories, one per artefact type. Each can be routed
+// to its own storage class.
+var ArtefactDirs = []string{"original", "chunks", "base64", "transcripts", "merged", "video"}
+
+// Class is an operator-defined storage destination: a directory that may be a local SSD,
+// a network share, or an object-storage bucket mounted with a tool such as rclone or
+// s3fs. URL, when set, is where the same tree is published over HTTP; pages link there
+// instead of through the server.
+type Class struct {
+	Name string
+	Path string
+	URL  string
+	// Fetch is an HTTP base URL the server reads the class's tree from when a file is not
+	// on disk, e.g. a bucket endpoint the files were synced to. FetchHeaders are sent
+	// with every request, and Cache keeps fetched files in the local artefact cache.
+	Fetch        string
+	FetchHeaders map[string]string
+	Cache        bool
+}
+
+// RemoteFile is where a job file missing from disk can be read over HTTP.
+type RemoteFile struct {
+	URL     string
+	Headers map[string]string
+	Cache   bool
+}
+
+// Router places artefact directories on their storage classes. A job's routed
+// subdirectory is a symlink to <class path>/<job id>/<artefact>, so everything that
+// reads or writes through the job directory keeps working unchanged. A nil Router keeps
+// every artefact inside the job directory.
+type Router struct {
+	jobsRoot  string
+	artefacts map[string]Class
+}
+
+// NewRouter routes each artefact type in assign to its class. Unassigned types stay local.
+func NewRouter(jobsRoot string, assign map[string]Class) (*Router, error) {
+	r := &Router{jobsRoot: jobsRoot, artefacts: make(map[string]Class, len(assign))}
+	for artefact, class := range assign {
+		if !slices.Contains(ArtefactDirs, artefact) {
+			return nil, fmt.Errorf("unknown artefact type %q (want one of %s)", artefact, strings.Join(ArtefactDirs, ", "))
+		}
+		if class.Path == "" {
+			return nil, fmt.Errorf("storage class %q has no path", class.Name)
+		}
+		abs, err := filepath.Abs(class.Path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving storage class %q: %w", class.Name, err)
+		}
+		if err := os.MkdirAll(abs, 0o755); err != nil {
+			return nil, fmt.Errorf("creating storage class %q: %w", class.Name, err)
+		}
+		class.Path = abs
+		class.URL = strings.TrimSuffix(class.URL, "/")
+		class.Fetch = strings.TrimSuffix(class.Fetch, "/")
+		r.artefacts[artefact] = class
+	}
+	return r, nil
+}
+
+// ClassOf returns the class an artefact type is routed to, if any.
+func (r *Router) ClassOf(artefact string) (Class, bool) {
+	if r == nil {
+		return Class{}, false
+	}
+	class, ok := r.artefacts[artefact]
+	return class, ok
+}
+
+// PrepareJob creates a job's artefact subdirectories, as symlinks into their storage
+// classes for routed types and as plain directories otherwise.
+func (r *Router) PrepareJob(jobDir string, names ...string) error {
+	for _, name := range names {
+		class, ok := r.ClassOf(name)
+		if !ok {
+			if err := EnsureJobSubdirs(jobDir, name); err != nil {
+				return err
+			}
+			continue
+		}
+		target := filepath.Join(class.Path, filepath.Base(jobDir), name)
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return fmt.Errorf("creating %s in storage class %q: %w", name, class.Name, err)
+		}
+		if err := os.Symlink(target, filepath.Join(jobDir, name)); err != nil {
+			return fmt.Errorf("linking %s to storage class %q: %w", name, class.Name, err)
+		}
+	}
+	return nil
+}
+
+// URL returns the link for a job file given relative to its job directory. Files of a
+// routed artefact type use the class URL when it has one and the job actually stores
+// that type on the class; everything else is served from /files/.
+func (r *Router) URL(jobID, rel string) string {
+	local := "/files/jobs/" + jobID + "/" + rel
+	artefact, _, _ := strings.Cut(rel, "/")
+	class, ok := r.ClassOf(artefact)
+	if !ok || class.URL == "" {
+		return local
+	}
+	info, err := os.Lstat(filepath.Join(r.jobsRoot, jobID, artefact))
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return local
+	}
+	return class.URL + "/" + path.Join(jobID, rel)
+}
+
+// Remote returns the fetch location of a job file given relative to its job directory,
+// for routed artefact types whose class has a fetch URL and that the job stores there.
+func (r *Router) Remote(jobID, rel string) (RemoteFile, bool) {
+	artefact, _, _ := strings.Cut(rel, "/")
+	class, ok := r.ClassOf(artefact)
+	if !ok || class.Fetch == "" {
+		return RemoteFile{}, false
+	}
+	info, err := os.Lstat(filepath.Join(r.jobsRoot, jobID, artefact))
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return RemoteFile{}, false
+	}
+	return RemoteFile{URL: class.Fetch + "/" + path.Join(jobID, rel), Headers: class.FetchHeaders, Cache: class.Cache}, true
+}
+
+// RemoveJob deletes a job directory together with any artefact directories it links to
+// on storage classes, including those of archived revisions.
+func RemoveJob(jobDir string) error {
+	entries, err := os.ReadDir(jobDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		target, ok, err := linkedDir(filepath.Join(jobDir, name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("removing %s: %w", name, err)
+		}
+		// Drop the job's now-empty folder on the class; it fails harmlessly if other
+		// artefacts still live there.
+		_ = os.Remove(filepath.Dir(target))
+	}
+	return os.RemoveAll(jobDir)
+}
+
+// linkedDir reports the target of dir when dir is a symlink. A missing dir is not an error.
+func linkedDir(dir string) (string, bool, error) {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", false, nil
+	}
+	target, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", false, fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	return target, true, nil
+}
+
+// resolveDir follows a symlinked artefact directory so walks descend into its target;
+// filepath.WalkDir never follows links, not even the root.
+func resolveDir(dir string) string {
+	if target, ok, err := linkedDir(dir); err == nil && ok {
+		return target
+	}
+	return dir
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// dedupMinSize skips files too small for a link to be worth the hashing.
+const dedupMinSize = 4 << 10
+
+// Deduper replaces byte-identical artefacts across jobs with hard links to a single copy.
+// It keeps a content index (SHA-256 → first known path) rather than a separate blob store,
+// so a file's link count directly reflects how many jobs share it.
+type Deduper struct {
+	mu        sync.Mutex
+	dataDir   string
+	indexPath string
+	index     map[string]string
+}
+
+// OpenDeduper loads the content index stored at indexPath; paths in it are relative to dataDir.
+func OpenDeduper(dataDir, indexPath string) (*Deduper, error) {
+	d := &Deduper{dataDir: dataDir, indexPath: indexPath, index: make(map[string]string)}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+		return nil, fmt.Errorf("reading dedup index: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.index); err != nil {
+		return nil, fmt.Errorf("unmarshalling dedup index: %w", err)
+	}
+	return d, nil
+}
+
+// DedupJob links every sizeable file under jobDir to an identical file already indexed,
+// registering new content as it goes. It returns the bytes no longer stored twice.
+func (d *Deduper) DedupJob(jobDir string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var saved int64
+	err := filepath.WalkDir(jobDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || filepath.Base(path) == jobFileName {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < dedupMinSize {
+			return nil
+		}
+
+		sum, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(d.dataDir, path)
+		if err != nil {
+			return err
+		}
+
+		canonical, known := d.index[sum]
+		if !known {
+			d.index[sum] = filepath.ToSlash(rel)
+			return nil
+		}
+
+		canonicalPath := filepath.Join(d.dataDir, filepath.FromSlash(canonical))
+		canonicalInfo, err := os.Stat(canonicalPath)
+		if err != nil || canonicalInfo.Size() != info.Size() {
+			// The indexed copy is gone or changed; this file becomes the reference.
+			d.index[sum] = filepath.ToSlash(rel)
+			return nil
+		}
+		if os.SameFile(canonicalInfo, info) {
+			return nil
+		}
+
+		tmp := path + ".dedup"
+		_ = os.Remove(tmp)
+		if err := os.Link(canonicalPath, tmp); err != nil {
+			// Different filesystems or no hard-link support: keep the copy.
+			return nil
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+		saved += info.Size()
+		return nil
+	})
+	if err != nil {
+		return saved, fmt.Errorf("deduplicating %s: %w", jobDir, err)
+	}
+
+	return saved, d.saveIndex()
+}
+
+// Forget drops index entries that point inside jobDir, e.g. before it is deleted.
+// Content still linked from other jobs is re-registered by their next dedup pass.
+func (d *Deduper) Forget(jobDir string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rel, err := filepath.Rel(d.dataDir, jobDir)
+	if err != nil {
+		return err
+	}
+	prefix := filepath.ToSlash(rel) + "/"
+	for sum, path := range d.index {
+		if len(path) >= len(prefix) && path[:len(prefix)] == prefix {
+			delete(d.index, sum)
+		}
+	}
+	return d.saveIndex()
+}
+
+// saveIndex persists the content index atomically.
+func (d *Deduper) saveIndex() error {
+	data, err := json.Marshal(d.index)
+	if err != nil {
+		return fmt.Errorf("marshalling dedup index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(d.indexPath), 0o755); err != nil {
+		return fmt.Errorf("creating dedup index directory: %w", err)
+	}
+	tmp := d.indexPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing dedup index: %w", err)
+	}
+	if err := os.Rename(tmp, d.indexPath); err != nil {
+		return fmt.Errorf("persisting dedup index: %w", err)
+	}
+	return nil
+}
+
+// HashFile returns the hex SHA-256 of a file's contents.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FetchCache keeps local copies of artefacts read from a storage class's fetch URL,
+// under <dir>/<job id>/<path in the job directory>. Reading a copy marks it as recently
+// used; once the cache outgrows its limit the least recently used copies are removed.
+type FetchCache struct {
+	dir string
+	max int64
+	mu  sync.Mutex
+}
+
+// NewFetchCache returns a cache in dir holding at most max bytes; 0 means no limit.
+func NewFetchCache(dir string, max int64) *FetchCache {
+	return &FetchCache{dir: dir, max: max}
+}
+
+func (c *FetchCache) path(jobID, rel string) string {
+	return filepath.Join(c.dir, jobID, filepath.FromSlash(rel))
+}
+
+// Open returns the cached copy of a job file and marks it as used.
+func (c *FetchCache) Open(jobID, rel string) (*os.File, error) {
+	p := c.path(jobID, rel)
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+	return file, nil
+}
+
+// Store copies src into the cache as the job file and opens the copy. A partial copy
+// is discarded, so readers never see a truncated file.
+func (c *FetchCache) Store(jobID, rel string, src io.Reader) (*os.File, error) {
+	p := c.path(jobID, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".fetch-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("creating cache file: %w", err)
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("filling cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("closing cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("persisting cache file: %w", err)
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	c.evict(p)
+	return file, nil
+}
+
+// RemoveJob drops every cached file of a job.
+func (c *FetchCache) RemoveJob(jobID string) error {
+	return os.RemoveAll(filepath.Join(c.dir, jobID))
+}
+
+// evict removes the least recently used files until the cache fits its limit, sparing
+// keep, the file just stored.
+func (c *FetchCache) evict(keep string) {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type entry struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	_ = filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".fetch-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{p, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries {
+		if total <= c.max {
+			break
+		}
+		if e.path == keep {
+			continue
+		}
+		if os.Remove(e.path) == nil {
+			total -= e.size
+			// Drop directories the removal emptied; this fails harmlessly otherwise.
+			_ = os.Remove(filepath.Dir(e.path))
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package storage
+
+import "io/fs"
+
+// fileID identifies the inode behind a file so hard links can be told apart from copies.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// inodeInfo reports no inode data on platforms without it; every file then counts as unique.
+func inodeInfo(info fs.FileInfo) (fileID, uint64, bool) {
+	return fileID{}, 1, false
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package storage
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID identifies the inode behind a file so hard links can be told apart from copies.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// inodeInfo returns the inode identity and link count for info, when the platform exposes them.
+func inodeInfo(info fs.FileInfo) (fileID, uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, uint64(st.Nlink), true
+}
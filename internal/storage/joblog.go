@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JobLogFile is a job's processing log, relative to its directory. Once it outgrows its
+// size limit it is renamed to JobLogFile+".1", shifting older ones to ".2" and so on.
+const JobLogFile = "logs/processing.log"
+
+// Defaults for OpenJobLog's limits.
+const (
+	DefaultJobLogMaxSize = 10 << 20
+	DefaultJobLogKeep    = 3
+)
+
+// JobLog appends to a job's processing log, rotating it by size. It is safe for
+// concurrent use.
+type JobLog struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	size    int64
+	maxSize int64
+	keep    int
+}
+
+// OpenJobLog opens the processing log of the job in jobDir for appending, creating
+// logs/ as needed. The log is rotated once it would exceed maxSize bytes, keeping keep
+// rotated files; zero values use DefaultJobLogMaxSize and DefaultJobLogKeep.
+func OpenJobLog(jobDir string, maxSize int64, keep int) (*JobLog, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultJobLogMaxSize
+	}
+	if keep <= 0 {
+		keep = DefaultJobLogKeep
+	}
+	l := &JobLog{path: filepath.Join(jobDir, filepath.FromSlash(JobLogFile)), maxSize: maxSize, keep: keep}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *JobLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening job log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("opening job log: %w", err)
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// Write appends p, rotating the log first when p would take it over its size limit.
+func (l *JobLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return 0, os.ErrClosed
+	}
+	if l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+func (l *JobLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("rotating job log: %w", err)
+	}
+	l.f = nil
+	os.Remove(rotatedJobLog(l.path, l.keep))
+	for i := l.keep - 1; i >= 1; i-- {
+		os.Rename(rotatedJobLog(l.path, i), rotatedJobLog(l.path, i+1))
+	}
+	if err := os.Rename(l.path, rotatedJobLog(l.path, 1)); err != nil {
+		return fmt.Errorf("rotating job log: %w", err)
+	}
+	return l.open()
+}
+
+// Close closes the log file.
+func (l *JobLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+func rotatedJobLog(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// JobLogFiles returns the paths of the processing log files of the job in jobDir that
+// exist, oldest first, so reading them in turn gives the whole log.
+func JobLogFiles(jobDir string) []string {
+	current := filepath.Join(jobDir, filepath.FromSlash(JobLogFile))
+	var files []string
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotatedJobLog(current, i)); err != nil {
+			break
+		}
+		files = append([]string{rotatedJobLog(current, i)}, files...)
+	}
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// ChangeType classifies an entry in the change journal.
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Journal limits. Once the journal would grow past JournalMaxSize it is renamed to
+// path+".1", replacing the previous one, so at most two files' worth of changes are
+// kept. Lines longer than journalMaxLine are skipped when reading.
+const (
+	JournalMaxSize = 64 << 20
+	journalMaxLine = 16 << 20
+)
+
+// Change is a single journal entry. Job carries a metadata snapshot for
+// created/updated entries and is nil for deletions.
+type Change struct {
+	Seq   uint64       `json:"seq"`
+	Type  ChangeType   `json:"type"`
+	JobID string       `json:"jobId"`
+	At    time.Time    `json:"at"`
+	Job   *chunker.Job `json:"job,omitempty"`
+}
+
+// Journal is an append-only JSON-lines log of job changes with increasing sequence numbers.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	// size is the length of the current file, which readers stop at so they never
+	// see a line half written.
+	size    int64
+	maxSize int64
+	lastSeq uint64
+	// firstSeq is the oldest sequence number still kept, and currentFirst the first
+	// one in the current file, which becomes firstSeq when it is rotated.
+	firstSeq     uint64
+	currentFirst uint64
+}
+
+// OpenJournal loads (or starts) the journal at path and resumes its sequence counter.
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path, maxSize: JournalMaxSize}
+	for _, name := range []string{j.rotated(), path} {
+		f, err := os.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("opening change journal: %w", err)
+		}
+		first := uint64(0)
+		err = scanChanges(f, func(c Change) bool {
+			if first == 0 {
+				first = c.Seq
+			}
+			j.lastSeq = max(j.lastSeq, c.Seq)
+			return true
+		})
+		if name == path {
+			j.currentFirst = first
+			if info, statErr := f.Stat(); statErr == nil {
+				j.size = info.Size()
+			}
+		}
+		if j.firstSeq == 0 {
+			j.firstSeq = first
+		}
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// Append records a change and returns it with its assigned sequence number. The job is
+// recorded as a trimmed snapshot; see journalSnapshot.
+func (j *Journal) Append(changeType ChangeType, jobID string, job *chunker.Job) (Change, error) {
+	change := Change{
+		Type:  changeType,
+		JobID: jobID,
+		At:    time.Now(),
+	}
+	if job != nil {
+		change.Job = journalSnapshot(job)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	change.Seq = j.lastSeq + 1
+	line, err := json.Marshal(change)
+	if err != nil {
+		return Change{}, fmt.Errorf("marshalling change: %w", err)
+	}
+	line = append(line, '\n')
+	if j.size > 0 && j.size+int64(len(line)) > j.maxSize {
+		j.rotate()
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return Change{}, fmt.Errorf("opening change journal: %w", err)
+	}
+	n, err := f.Write(line)
+	j.size += int64(n)
+	if err != nil {
+		f.Close()
+		return Change{}, fmt.Errorf("writing change journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return Change{}, fmt.Errorf("closing change journal: %w", err)
+	}
+
+	j.lastSeq = change.Seq
+	if j.currentFirst == 0 {
+		j.currentFirst = change.Seq
+	}
+	if j.firstSeq == 0 {
+		j.firstSeq = change.Seq
+	}
+	return change, nil
+}
+
+// rotate moves the current file to the rotated name. Where that fails, e.g. because a
+// reader still has the file open on Windows, the journal keeps growing and tries again
+// on the next append.
+func (j *Journal) rotate() {
+	if err := os.Rename(j.path, j.rotated()); err != nil {
+		return
+	}
+	j.size = 0
+	j.firstSeq = j.currentFirst
+	j.currentFirst = 0
+}
+
+func (j *Journal) rotated() string {
+	return j.path + ".1"
+}
+
+// Since returns up to limit changes with Seq greater than cursor, oldest first,
+// plus whether more entries remain beyond the returned page. Appends carry on while
+// it reads; it returns the changes written before it was called.
+func (j *Journal) Since(cursor uint64, limit int) ([]Change, bool, error) {
+	files, err := j.openForReading(cursor)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	var changes []Change
+	more := false
+	for _, r := range files {
+		err := scanChanges(r, func(c Change) bool {
+			if c.Seq <= cursor {
+				return true
+			}
+			if limit > 0 && len(changes) == limit {
+				more = true
+				return false
+			}
+			changes = append(changes, c)
+			return true
+		})
+		if err != nil || more {
+			return changes, more, err
+		}
+	}
+	return changes, more, nil
+}
+
+// limitedFile reads an open journal file up to the length it had when it was opened.
+type limitedFile struct {
+	io.Reader
+	f *os.File
+}
+
+func (l limitedFile) Close() error {
+	return l.f.Close()
+}
+
+// openForReading opens the files holding changes after cursor, oldest first, under the
+// lock, so a rotation cannot swap them between the two opens. The rotated file is left
+// out when cursor is already past it.
+func (j *Journal) openForReading(cursor uint64) ([]io.ReadCloser, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var files []io.ReadCloser
+	if j.currentFirst == 0 || cursor+1 < j.currentFirst {
+		f, err := os.Open(j.rotated())
+		switch {
+		case err == nil:
+			files = append(files, f)
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("opening change journal: %w", err)
+		}
+	}
+	f, err := os.Open(j.path)
+	switch {
+	case err == nil:
+		files = append(files, limitedFile{io.LimitReader(f, j.size), f})
+	case !errors.Is(err, os.ErrNotExist):
+		for _, f := range files {
+			f.Close()
+		}
+		return nil, fmt.Errorf("opening change journal: %w", err)
+	}
+	return files, nil
+}
+
+// LastSeq reports the most recent sequence number written.
+func (j *Journal) LastSeq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.lastSeq
+}
+
+// FirstSeq reports the oldest sequence number the journal still holds, or 0 when it is
+// empty. A cursor below FirstSeq-1 has missed changes that were rotated away.
+func (j *Journal) FirstSeq() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.firstSeq
+}
+
+// journalSnapshot returns the copy of job the journal records: its metadata without
+// the chunk list, revisions, processing log, command runs, tool versions, or live
+// progress, which can run to megabytes. Mirrors fetch the job for those.
+func journalSnapshot(job *chunker.Job) *chunker.Job {
+	snapshot := *job
+	snapshot.Chunks = nil
+	snapshot.Revisions = nil
+	snapshot.ProcessingLog = ""
+	snapshot.Commands = nil
+	snapshot.Tools = nil
+	snapshot.Progress = nil
+	return &snapshot
+}
+
+// scanChanges feeds each decodable entry in r to fn until it returns false. Lines that
+// do not decode, or are longer than journalMaxLine, are skipped.
+func scanChanges(r io.Reader, fn func(Change) bool) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var line []byte
+	skipping := false
+	for {
+		part, err := br.ReadSlice('\n')
+		switch {
+		case skipping:
+		case len(line)+len(part) > journalMaxLine:
+			skipping, line = true, nil
+		default:
+			line = append(line, part...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if !skipping && len(bytes.TrimSpace(line)) > 0 {
+			var c Change
+			if json.Unmarshal(line, &c) == nil && !fn(c) {
+				return nil
+			}
+		}
+		line, skipping = line[:0], false
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading change journal: %w", err)
+		}
+	}
+}
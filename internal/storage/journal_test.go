@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"audi/pkg/chunker"
+)
+
+func TestJournalSkipsOverlongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.jsonl")
+	lines := `{"seq":1,"type":"created","jobId":"a"}` + "\n" +
+		`{"seq":2,"type":"updated","jobId":"a","pad":"` + strings.Repeat("x", journalMaxLine) + `"}` + "\n" +
+		"not json\n" +
+		`{"seq":3,"type":"deleted","jobId":"a"}` + "\n"
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+	if got := j.LastSeq(); got != 3 {
+		t.Errorf("LastSeq = %d, want 3", got)
+	}
+	changes, _, err := j.Since(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Seq != 1 || changes[1].Seq != 3 {
+		t.Errorf("Since(0) = %+v, want seqs 1 and 3", changes)
+	}
+}
+
+func TestJournalRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.jsonl")
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.maxSize = 1024
+	job := &chunker.Job{ID: "a", ProcessingLog: strings.Repeat("x", 4096), Chunks: make([]chunker.Chunk, 10)}
+	for range 40 {
+		if _, err := j.Append(ChangeUpdated, job.ID, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > j.maxSize {
+		t.Errorf("journal is %d bytes, want at most %d", info.Size(), j.maxSize)
+	}
+	first := j.FirstSeq()
+	if first <= 1 || first > 40 {
+		t.Fatalf("FirstSeq = %d after rotating", first)
+	}
+
+	changes, more, err := j.Since(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more || len(changes) != int(40-first+1) || changes[0].Seq != first || changes[len(changes)-1].Seq != 40 {
+		t.Errorf("Since(0) returned %d changes from %d, want %d..40", len(changes), changes[0].Seq, first)
+	}
+	if c := changes[0].Job; c.ProcessingLog != "" || c.Chunks != nil {
+		t.Errorf("journal snapshot kept the log or chunks")
+	}
+
+	// A reopened journal picks up where it left off.
+	reopened, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.LastSeq() != 40 || reopened.FirstSeq() != first {
+		t.Errorf("reopened journal: LastSeq %d, FirstSeq %d; want 40, %d", reopened.LastSeq(), reopened.FirstSeq(), first)
+	}
+	page, more, err := reopened.Since(38, 1)
+	if err != nil || !more || len(page) != 1 || page[0].Seq != 39 {
+		t.Errorf("Since(38, 1) = %+v, %v, %v; want seq 39 with more", page, more, err)
+	}
+}
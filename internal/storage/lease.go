@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LeaseFile names the file in a job directory recording who processes the job. Servers
+// and workers sharing a data directory take the lease before processing a job, renew it
+// while they work, and may take over a lease whose owner stopped renewing it. Expiry is
+// judged by each machine's clock, so the clocks must agree to well within the TTL.
+const LeaseFile = "lease.json"
+
+var (
+	// ErrLeaseHeld is returned by AcquireLease while another owner's lease is live.
+	ErrLeaseHeld = errors.New("job is leased by another owner")
+	// ErrLeaseLost is the cause of a held lease's context once it could not be renewed
+	// or another owner took it over.
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// LeaseInfo is the content of a lease file.
+type LeaseInfo struct {
+	Owner      string    `json:"owner"`
+	Token      string    `json:"token"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the owner stopped renewing the lease by now.
+func (l LeaseInfo) Expired(now time.Time) bool {
+	return now.After(l.ExpiresAt)
+}
+
+// ReadLease returns the job's current lease; the error wraps fs.ErrNotExist when the
+// job is not leased.
+func ReadLease(jobDir string) (LeaseInfo, error) {
+	var info LeaseInfo
+	data, err := os.ReadFile(filepath.Join(jobDir, LeaseFile))
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("reading lease: %w", err)
+	}
+	return info, nil
+}
+
+// Lease is a lease this process holds on a job.
+type Lease struct {
+	jobDir string
+	ttl    time.Duration
+	mu     sync.Mutex
+	info   LeaseInfo
+}
+
+// AcquireLease leases the job in jobDir to owner for ttl, taking over an expired lease.
+// It fails with an error wrapping ErrLeaseHeld while another lease is live.
+func AcquireLease(jobDir, owner string, ttl time.Duration) (*Lease, error) {
+	token, err := leaseToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	l := &Lease{jobDir: jobDir, ttl: ttl, info: LeaseInfo{Owner: owner, Token: token, AcquiredAt: now, ExpiresAt: now.Add(ttl)}}
+	path := filepath.Join(jobDir, LeaseFile)
+	for range 3 {
+		err := l.write(func(tmp string) error { return os.Link(tmp, path) })
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("acquiring lease: %w", err)
+		}
+		current, err := ReadLease(jobDir)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, err
+		case !current.Expired(time.Now()):
+			return nil, fmt.Errorf("%w: %s until %s", ErrLeaseHeld, current.Owner, current.ExpiresAt.Format(time.RFC3339))
+		}
+		if err := breakLease(jobDir, current); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: contended", ErrLeaseHeld)
+}
+
+// breakLease removes the expired lease stale. Only one of several processes breaking it
+// at once succeeds in moving it aside; one that moved a fresh lease instead, taken in
+// the meantime, puts it back.
+func breakLease(jobDir string, stale LeaseInfo) error {
+	path := filepath.Join(jobDir, LeaseFile)
+	token, err := leaseToken()
+	if err != nil {
+		return err
+	}
+	aside := path + ".stale-" + token
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("taking over lease: %w", err)
+	}
+	defer os.Remove(aside)
+	moved, err := os.ReadFile(aside)
+	if err != nil {
+		return fmt.Errorf("taking over lease: %w", err)
+	}
+	var info LeaseInfo
+	if json.Unmarshal(moved, &info) == nil && info.Token != stale.Token {
+		_ = os.Link(aside, path)
+		return fmt.Errorf("%w: %s", ErrLeaseHeld, info.Owner)
+	}
+	return nil
+}
+
+// Owner returns the owner the lease was acquired for.
+func (l *Lease) Owner() string {
+	return l.info.Owner
+}
+
+// Renew extends the lease by its TTL. It fails with ErrLeaseLost when the lease file no
+// longer carries this lease, because it expired and another owner took it over.
+func (l *Lease) Renew() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.check(); err != nil {
+		return err
+	}
+	l.info.ExpiresAt = time.Now().Add(l.ttl)
+	path := filepath.Join(l.jobDir, LeaseFile)
+	return l.write(func(tmp string) error { return os.Rename(tmp, path) })
+}
+
+// Release gives the lease up, unless it was already lost.
+func (l *Lease) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.check(); err != nil {
+		if errors.Is(err, ErrLeaseLost) {
+			return nil
+		}
+		return err
+	}
+	return os.Remove(filepath.Join(l.jobDir, LeaseFile))
+}
+
+// Hold renews the lease in the background, every third of its TTL, until release is
+// called, which also gives the lease up. The returned context is cancelled with cause
+// ErrLeaseLost if the lease is taken over or has not been renewed for a whole TTL.
+func (l *Lease) Hold(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := l.Renew()
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			if errors.Is(err, ErrLeaseLost) || time.Since(renewed) >= l.ttl {
+				cancel(fmt.Errorf("%w: %v", ErrLeaseLost, err))
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		close(stop)
+		wg.Wait()
+		cancel(nil)
+		_ = l.Release()
+	}
+}
+
+// check confirms the lease file still carries this lease.
+func (l *Lease) check() error {
+	current, err := ReadLease(l.jobDir)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && current.Token != l.info.Token) {
+		return ErrLeaseLost
+	}
+	return err
+}
+
+// write writes the lease to a temporary file beside the lease file and hands it to
+// place, which moves or links it into position.
+func (l *Lease) write(place func(tmp string) error) error {
+	data, err := json.MarshalIndent(l.info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(l.jobDir, "."+LeaseFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return place(tmp.Name())
+}
+
+func leaseToken() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating lease token: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+const manifestFileName = "manifest.json"
+
+// manifestDirs are the job subdirectories whose files the manifest covers. job.json is
+// left out on purpose: it changes on every save.
+var manifestDirs = []string{"original", "chunks", "base64", "transcripts", "merged", "video"}
+
+// Manifest lists every artefact of a job with its size and SHA-256 at the time it was written.
+type Manifest struct {
+	JobID       string    `json:"jobId"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Metadata repeats the job's caller-supplied metadata, so an exported job directory
+	// can be correlated without job.json.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Files    []ManifestEntry   `json:"files"`
+}
+
+// ManifestEntry is one file, addressed relative to the job directory with forward slashes.
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// VerifyReport is the outcome of re-hashing a job's files against its manifest.
+type VerifyReport struct {
+	JobID      string    `json:"jobId"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+	Checked    int       `json:"checked"`
+	// Corrupt lists files whose size or hash no longer match, e.g. after bit rot or a partial write.
+	Corrupt []string `json:"corrupt,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	// Unexpected lists files present on disk that the manifest does not know about.
+	Unexpected []string `json:"unexpected,omitempty"`
+}
+
+// OK reports whether every file matched and nothing was missing or added.
+func (r VerifyReport) OK() bool {
+	return len(r.Corrupt) == 0 && len(r.Missing) == 0 && len(r.Unexpected) == 0
+}
+
+// WriteManifest hashes the job's artefacts and saves manifest.json next to job.json.
+func WriteManifest(jobDir string, job *chunker.Job) (*Manifest, error) {
+	paths, err := manifestFiles(jobDir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{JobID: job.ID, GeneratedAt: time.Now(), Metadata: job.Metadata, Files: make([]ManifestEntry, 0, len(paths))}
+	for _, rel := range paths {
+		full := filepath.Join(jobDir, filepath.FromSlash(rel))
+		info, err := os.Stat(full)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", rel, err)
+		}
+		sum, err := HashFile(full)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", rel, err)
+		}
+		manifest.Files = append(manifest.Files, ManifestEntry{Path: rel, Size: info.Size(), SHA256: sum})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding manifest: %w", err)
+	}
+	tmp := filepath.Join(jobDir, manifestFileName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, fmt.Errorf("writing manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(jobDir, manifestFileName)); err != nil {
+		return nil, fmt.Errorf("replacing manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// LoadManifest reads a job's manifest.json.
+func LoadManifest(jobDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(jobDir, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// VerifyJob re-hashes every file listed in the job's manifest and reports differences.
+func VerifyJob(jobDir string) (VerifyReport, error) {
+	manifest, err := LoadManifest(jobDir)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	report := VerifyReport{JobID: manifest.JobID, VerifiedAt: time.Now()}
+	known := make(map[string]bool, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		known[entry.Path] = true
+		report.Checked++
+
+		full := filepath.Join(jobDir, filepath.FromSlash(entry.Path))
+		info, err := os.Stat(full)
+		if errors.Is(err, fs.ErrNotExist) {
+			report.Missing = append(report.Missing, entry.Path)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("stat %s: %w", entry.Path, err)
+		}
+		if info.Size() != entry.Size {
+			report.Corrupt = append(report.Corrupt, entry.Path)
+			continue
+		}
+		sum, err := HashFile(full)
+		if err != nil {
+			return report, fmt.Errorf("hashing %s: %w", entry.Path, err)
+		}
+		if sum != entry.SHA256 {
+			report.Corrupt = append(report.Corrupt, entry.Path)
+		}
+	}
+
+	present, err := manifestFiles(jobDir)
+	if err != nil {
+		return report, err
+	}
+	for _, rel := range present {
+		if !known[rel] {
+			report.Unexpected = append(report.Unexpected, rel)
+		}
+	}
+	return report, nil
+}
+
+// manifestFiles lists the regular files under manifestDirs, relative and sorted.
+func manifestFiles(jobDir string) ([]string, error) {
+	var paths []string
+	for _, dir := range manifestDirs {
+		root := resolveDir(filepath.Join(jobDir, dir))
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, dir+"/"+filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", dir, err)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidJobID is returned for job IDs this server could not have issued.
+	ErrInvalidJobID = errors.New("invalid job ID")
+	// ErrUnsafePath is returned for job file paths that lead out of the job.
+	ErrUnsafePath = errors.New("path leads outside the job directory")
+)
+
+// jobIDPattern matches every ID the server hands out: an optional prefix of letters,
+// digits, '-', '_', and '.' (see job_ids in the config) in front of an ID of the
+// configured scheme, a ULID, UUIDv7, or timestamp. It never matches "." or "..", a
+// separator, or a hidden name.
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}$`)
+
+// ValidJobID reports whether id has the shape of a job ID, which makes it a single
+// path element naming a directory directly below the jobs root. IDs taken from a
+// request must pass it before they are handed to JobDir.
+func ValidJobID(id string) bool {
+	return jobIDPattern.MatchString(id)
+}
+
+// JobFile returns the path on disk of a file given slash-separated and relative to
+// the job directory of jobID. The ID must be valid and the path local: not absolute
+// and without "..". Where the file exists, its canonical path, symlinks resolved,
+// must also lie in the job directory or, for an artefact type on a storage class, in
+// the job's directory on that class; a symlink planted anywhere else is refused. A
+// missing file is not an error, so callers can fall back to a fetch URL.
+func (r *Router) JobFile(jobID, rel string) (string, error) {
+	if !ValidJobID(jobID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidJobID, jobID)
+	}
+	local := filepath.FromSlash(rel)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, rel)
+	}
+	jobDir := JobDir(r.jobsRoot, jobID)
+	full := filepath.Join(jobDir, local)
+	real, err := canonical(full)
+	if err != nil {
+		return full, nil
+	}
+	roots := []string{jobDir}
+	artefact, _, _ := strings.Cut(filepath.ToSlash(local), "/")
+	if class, ok := r.ClassOf(artefact); ok {
+		roots = append(roots, filepath.Join(class.Path, jobID, artefact))
+	}
+	for _, root := range roots {
+		realRoot, err := canonical(root)
+		if err != nil {
+			continue
+		}
+		if inside, err := filepath.Rel(realRoot, real); err == nil && filepath.IsLocal(inside) {
+			return full, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsafePath, rel)
+}
+
+// canonical returns the absolute path of p with every symlink resolved.
+func canonical(p string) (string, error) {
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidJobID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"20240312-101500-0042", true},
+		{"01J8ZK3Q4W2N6V7X9Y0A1B2C3D", true},
+		{"0192a5c4-7f1e-7b3a-9c2d-4e5f60718293", true},
+		{"scan-01J8ZK3Q4W2N6V7X9Y0A1B2C3D", true},
+		{"audi.v2-20240312-101500-0042", true},
+		{strings.Repeat("a", 128), true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{".hidden", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{"%2e%2e", false},
+		{"a b", false},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		if got := ValidJobID(tt.id); got != tt.want {
+			t.Errorf("ValidJobID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+// newTestJob creates a job directory for id under a temporary jobs root and returns
+// the root.
+func newTestJob(t *testing.T, id string) string {
+	t.Helper()
+	jobsRoot := filepath.Join(t.TempDir(), "jobs")
+	if err := EnsureJobSubdirs(JobDir(jobsRoot, id), "chunks"); err != nil {
+		t.Fatal(err)
+	}
+	return jobsRoot
+}
+
+// symlink creates a symlink, skipping the test where the platform or user may not.
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("creating symlinks: %v", err)
+	}
+}
+
+func TestJobFile(t *testing.T) {
+	const id = "20240312-101500-0042"
+	jobsRoot := newTestJob(t, id)
+	jobDir := JobDir(jobsRoot, id)
+	if err := os.WriteFile(filepath.Join(jobDir, "chunks", "chunk_000.wav"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRouter(jobsRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id, rel string
+		want    error
+	}{
+		{id, "chunks/chunk_000.wav", nil},
+		{id, "chunks/missing.wav", nil},
+		{id, ".", nil},
+		{id, "../x", ErrUnsafePath},
+		{id, "/etc/passwd", ErrUnsafePath},
+		{id, "chunks/../../x", ErrUnsafePath},
+		{id, "chunks/../../../presets.json", ErrUnsafePath},
+		{"..", "presets.json", ErrInvalidJobID},
+		{".hidden", "job.json", ErrInvalidJobID},
+		{"a/b", "job.json", ErrInvalidJobID},
+		{`a\b`, "job.json", ErrInvalidJobID},
+		{"%2e%2e", "job.json", ErrInvalidJobID},
+		{strings.Repeat("a", 129), "job.json", ErrInvalidJobID},
+	}
+	for _, tt := range tests {
+		_, err := r.JobFile(tt.id, tt.rel)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("JobFile(%q, %q) = %v, want %v", tt.id, tt.rel, err, tt.want)
+		}
+	}
+}
+
+func TestJobFileSymlinkOutsideJob(t *testing.T) {
+	const id = "20240312-101500-0042"
+	jobsRoot := newTestJob(t, id)
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	symlink(t, secret, filepath.Join(JobDir(jobsRoot, id), "chunks", "chunk_000.wav"))
+	symlink(t, filepath.Dir(secret), filepath.Join(JobDir(jobsRoot, id), "merged"))
+
+	r, err := NewRouter(jobsRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"chunks/chunk_000.wav", "merged/secret.txt", "merged"} {
+		if _, err := r.JobFile(id, rel); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("JobFile(%q, %q) = %v, want %v", id, rel, err, ErrUnsafePath)
+		}
+	}
+}
+
+func TestJobFileStorageClass(t *testing.T) {
+	const id = "20240312-101500-0042"
+	jobsRoot := filepath.Join(t.TempDir(), "jobs")
+	jobDir := JobDir(jobsRoot, id)
+	if err := os.MkdirAll(jobDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	classRoot := t.TempDir()
+	r, err := NewRouter(jobsRoot, map[string]Class{"chunks": {Name: "cold", Path: classRoot}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PrepareJob(jobDir, "chunks"); err != nil {
+		t.Skipf("linking a storage class: %v", err)
+	}
+	classDir := filepath.Join(classRoot, id, "chunks")
+	if err := os.WriteFile(filepath.Join(classDir, "chunk_000.wav"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.JobFile(id, "chunks/chunk_000.wav"); err != nil {
+		t.Errorf("JobFile of a file on the storage class: %v", err)
+	}
+
+	// The class holds other jobs' directories too; a link from this job's artefact
+	// directory into another job's is still outside the job.
+	other := filepath.Join(classRoot, "other-job", "chunks")
+	if err := os.MkdirAll(other, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(other, "chunk_000.wav"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	symlink(t, filepath.Join(other, "chunk_000.wav"), filepath.Join(classDir, "stolen.wav"))
+	if _, err := r.JobFile(id, "chunks/stolen.wav"); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("JobFile of a link into another job on the class = %v, want %v", err, ErrUnsafePath)
+	}
+	// An artefact type not routed to the class may not reach into it either.
+	symlink(t, classDir, filepath.Join(jobDir, "merged"))
+	if _, err := r.JobFile(id, "merged/chunk_000.wav"); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("JobFile of merged/ linked into the class = %v, want %v", err, ErrUnsafePath)
+	}
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Preset errors returned by PresetStore.
+var (
+	ErrPresetNotFound = errors.New("preset not found")
+	ErrPresetReadOnly = errors.New("preset is defined in the config file")
+)
+
+// maxPresetName keeps preset names short enough for form values and URLs.
+const maxPresetName = 64
+
+// Preset is a named bundle of upload form fields, e.g. chunk_value, filters, or
+// whisper_args, applied to uploads that select it.
+type Preset struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Options     map[string][]string `json:"options"`
+	// ReadOnly marks presets from the config file, which the API cannot change.
+	ReadOnly  bool       `json:"readOnly,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// ValidatePresetName accepts letters, digits, '-', and '_'.
+func ValidatePresetName(name string) error {
+	if name == "" || len(name) > maxPresetName {
+		return fmt.Errorf("preset names must be 1 to %d characters", maxPresetName)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("preset name %q may only contain letters, digits, '-', and '_'", name)
+		}
+	}
+	return nil
+}
+
+// PresetStore holds the config file's presets and those created through the API, which
+// it persists in a JSON file.
+type PresetStore struct {
+	mu     sync.Mutex
+	path   string
+	fixed  map[string]Preset
+	stored map[string]Preset
+}
+
+// OpenPresets loads the presets saved at path next to the fixed ones from the config
+// file; a missing file starts empty. A saved preset whose name a fixed one now uses is
+// hidden by it.
+func OpenPresets(path string, fixed []Preset) (*PresetStore, error) {
+	p := &PresetStore{path: path, fixed: make(map[string]Preset, len(fixed)), stored: make(map[string]Preset)}
+	for _, preset := range fixed {
+		if err := ValidatePresetName(preset.Name); err != nil {
+			return nil, err
+		}
+		if _, dup := p.fixed[preset.Name]; dup {
+			return nil, fmt.Errorf("duplicate preset %q", preset.Name)
+		}
+		preset.ReadOnly = true
+		p.fixed[preset.Name] = preset
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, fmt.Errorf("reading presets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &p.stored); err != nil {
+		return nil, fmt.Errorf("unmarshalling presets file: %w", err)
+	}
+	return p, nil
+}
+
+// List returns every preset sorted by name.
+func (p *PresetStore) List() []Preset {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	presets := make([]Preset, 0, len(p.fixed)+len(p.stored))
+	for _, preset := range p.fixed {
+		presets = append(presets, preset)
+	}
+	for name, preset := range p.stored {
+		if _, hidden := p.fixed[name]; !hidden {
+			presets = append(presets, preset)
+		}
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+// Get looks a preset up by name.
+func (p *PresetStore) Get(name string) (Preset, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if preset, ok := p.fixed[name]; ok {
+		return preset, true
+	}
+	preset, ok := p.stored[name]
+	return preset, ok
+}
+
+// Put creates or replaces a preset and saves the file. It reports whether the preset
+// is new.
+func (p *PresetStore) Put(preset Preset) (bool, error) {
+	if err := ValidatePresetName(preset.Name); err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.fixed[preset.Name]; ok {
+		return false, ErrPresetReadOnly
+	}
+	prev, existed := p.stored[preset.Name]
+	now := time.Now().UTC()
+	preset.ReadOnly = false
+	preset.UpdatedAt = &now
+	p.stored[preset.Name] = preset
+	if err := p.save(); err != nil {
+		if existed {
+			p.stored[preset.Name] = prev
+		} else {
+			delete(p.stored, preset.Name)
+		}
+		return false, err
+	}
+	return !existed, nil
+}
+
+// Delete removes a preset created through the API and saves the file.
+func (p *PresetStore) Delete(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.fixed[name]; ok {
+		return ErrPresetReadOnly
+	}
+	prev, ok := p.stored[name]
+	if !ok {
+		return ErrPresetNotFound
+	}
+	delete(p.stored, name)
+	if err := p.save(); err != nil {
+		p.stored[name] = prev
+		return err
+	}
+	return nil
+}
+
+func (p *PresetStore) save() error {
+	data, err := json.MarshalIndent(p.stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling presets: %w", err)
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing presets temp file: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("persisting presets file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// ProgressTracker turns processor progress callbacks into the persisted progress of a
+// running job, feeds finished stages into the throughput history, and keeps the ETA
+// current. The server and the workers both run jobs through one.
+type ProgressTracker struct {
+	job        *chunker.Job
+	jobDir     string
+	throughput *Throughput
+	weights    chunker.ProgressWeights
+	chunkStage string
+}
+
+// NewProgressTracker prepares a tracker for job. transcribe tells whether the per-chunk
+// stage that follows extraction is transcription. throughput may be nil, leaving the
+// job without an ETA.
+func NewProgressTracker(job *chunker.Job, jobDir string, throughput *Throughput, weights chunker.ProgressWeights, transcribe bool) *ProgressTracker {
+	stage := chunker.StagePostprocess
+	if transcribe {
+		stage = chunker.StageTranscribe
+	}
+	return &ProgressTracker{job: job, jobDir: jobDir, throughput: throughput, weights: weights, chunkStage: stage}
+}
+
+// Update records p on the job and saves it with SaveLatest. Wrapped, it serves as
+// chunker.Options.Progress.
+func (t *ProgressTracker) Update(p chunker.Progress) error {
+	now := time.Now()
+	current := t.job.Progress
+	var recordErr error
+	if current == nil || current.Stage != p.Stage {
+		recordErr = t.recordStage(now)
+		current = &chunker.JobProgress{Stage: p.Stage, StageStartedAt: now}
+		t.job.Progress = current
+	}
+	current.DoneSeconds = p.DoneSeconds
+	current.TotalSeconds = p.TotalSeconds
+	current.Speed = p.Speed
+	current.Percent = t.weights.Percent(p, t.chunkStage)
+	t.refreshEstimate(now)
+
+	if err := SaveLatest(t.jobDir, t.job); err != nil {
+		return errors.Join(recordErr, fmt.Errorf("saving progress: %w", err))
+	}
+	return recordErr
+}
+
+// Finish records the last stage (when the job succeeded) and clears live progress.
+func (t *ProgressTracker) Finish(succeeded bool) error {
+	var err error
+	if succeeded {
+		err = t.recordStage(time.Now())
+	}
+	t.job.Progress = nil
+	return err
+}
+
+// recordStage stores the throughput of the stage currently held in job.Progress.
+func (t *ProgressTracker) recordStage(now time.Time) error {
+	current := t.job.Progress
+	if current == nil || t.throughput == nil {
+		return nil
+	}
+	wall := now.Sub(current.StageStartedAt).Seconds()
+	if err := t.throughput.Record(current.Stage, current.DoneSeconds, wall); err != nil {
+		return fmt.Errorf("recording throughput: %w", err)
+	}
+	return nil
+}
+
+// refreshEstimate recomputes the remaining time for the running job.
+func (t *ProgressTracker) refreshEstimate(now time.Time) {
+	p := t.job.Progress
+	remaining, ok := t.estimateRemaining(p, now)
+	if !ok {
+		p.RemainingSeconds = 0
+		p.EstimatedCompletion = nil
+		return
+	}
+	eta := now.Add(time.Duration(remaining * float64(time.Second)))
+	p.RemainingSeconds = remaining
+	p.EstimatedCompletion = &eta
+}
+
+// estimateRemaining combines this job's observed speed with stage history.
+func (t *ProgressTracker) estimateRemaining(p *chunker.JobProgress, now time.Time) (float64, bool) {
+	if p.TotalSeconds <= 0 || t.throughput == nil {
+		return 0, false
+	}
+	elapsed := now.Sub(p.StageStartedAt).Seconds()
+
+	if p.Stage == chunker.StageExtract {
+		extractRate := t.throughput.Rate(chunker.StageExtract)
+		chunkRate := t.throughput.Rate(t.chunkStage)
+		if chunkRate <= 0 {
+			return 0, false
+		}
+		var extractLeft float64
+		switch {
+		case p.DoneSeconds > 0 && elapsed > 0:
+			// ffmpeg reports its position live, so this run's own rate is known.
+			extractLeft = (p.TotalSeconds - p.DoneSeconds) / (p.DoneSeconds / elapsed)
+		case extractRate > 0:
+			extractLeft = p.TotalSeconds/extractRate - elapsed
+		default:
+			return 0, false
+		}
+		if p.DoneSeconds >= p.TotalSeconds || extractLeft < 0 {
+			extractLeft = 0
+		}
+		return extractLeft + p.TotalSeconds/chunkRate, true
+	}
+
+	rate := t.throughput.Rate(p.Stage)
+	if p.DoneSeconds > 0 && elapsed > 0 {
+		rate = p.DoneSeconds / elapsed
+	}
+	if rate <= 0 {
+		return 0, false
+	}
+	left := p.TotalSeconds - p.DoneSeconds
+	if left < 0 {
+		left = 0
+	}
+	return left / rate, true
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"audi/pkg/chunker"
+)
+
+func TestProgressTrackerEstimates(t *testing.T) {
+	dir := t.TempDir()
+	jobDir := filepath.Join(dir, "jobs", "20240312-101500-0042")
+	job := &chunker.Job{ID: "20240312-101500-0042", Status: chunker.JobStatusProcessing}
+	if err := EnsureJobSubdirs(jobDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateJob(jobDir, job); err != nil {
+		t.Fatal(err)
+	}
+
+	// A worker and the server open the history separately; each sees the other's records.
+	path := filepath.Join(dir, "throughput.json")
+	server, err := OpenThroughput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker, err := OpenThroughput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Record(chunker.StageTranscribe, 100, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := worker.Rate(chunker.StageTranscribe); got != 10 {
+		t.Fatalf("Rate after another process recorded = %v, want 10", got)
+	}
+
+	tracker := NewProgressTracker(job, jobDir, worker, chunker.ProgressWeights{}, true)
+	if err := tracker.Update(chunker.Progress{Stage: chunker.StageTranscribe, TotalSeconds: 50}); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := LoadJob(jobDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := stored.Progress
+	if p == nil || p.RemainingSeconds != 5 || p.EstimatedCompletion == nil {
+		t.Fatalf("stored progress = %+v, want 5s remaining with an ETA", p)
+	}
+
+	if err := tracker.Finish(true); err != nil {
+		t.Fatal(err)
+	}
+	if job.Progress != nil {
+		t.Errorf("Finish left progress on the job")
+	}
+}
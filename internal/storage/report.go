@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// Report file names, next to job.json and manifest.json.
+const (
+	ReportJSONFileName = "report.json"
+	ReportTextFileName = "report.txt"
+)
+
+// reportGapTolerance ignores sub-frame gaps and overlaps between consecutive chunks.
+const reportGapTolerance = 0.05
+
+// IntegrityReport is the end-of-job summary an auditor can archive: what went in, what
+// came out, how much of it was transcribed, and anything that looks off.
+type IntegrityReport struct {
+	JobID       string            `json:"jobId"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Status      chunker.JobStatus `json:"status"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Input       ReportInput       `json:"input"`
+	// Tracks totals the chunks per audio track; each track covers the whole input.
+	Tracks        []ReportTrack        `json:"tracks"`
+	Transcription *ReportTranscription `json:"transcription,omitempty"`
+	Chunks        []ReportChunk        `json:"chunks"`
+	Warnings      []string             `json:"warnings"`
+}
+
+// ReportInput describes the original upload.
+type ReportInput struct {
+	FileName string `json:"fileName,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	// DurationSeconds is the input length ffmpeg reported, or 0 when unknown.
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+// ReportTrack compares the audio produced for one track with the input.
+type ReportTrack struct {
+	Track           int     `json:"track"`
+	Chunks          int     `json:"chunks"`
+	ProducedSeconds float64 `json:"producedSeconds"`
+	// DifferenceSeconds is produced minus input; omitted when the input length is unknown.
+	DifferenceSeconds *float64 `json:"differenceSeconds,omitempty"`
+}
+
+// ReportTranscription summarises how much of the produced audio has a transcript.
+type ReportTranscription struct {
+	TranscribedSeconds float64 `json:"transcribedSeconds"`
+	CoveragePercent    float64 `json:"coveragePercent"`
+	// Skipped counts chunks left out on purpose (silence or the quality gate).
+	Skipped int `json:"skipped"`
+}
+
+// ReportChunk is one chunk with its checksum and timing.
+type ReportChunk struct {
+	Index           int     `json:"index"`
+	Track           int     `json:"track"`
+	StartSeconds    float64 `json:"startSeconds"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	AudioFile       string  `json:"audioFile,omitempty"`
+	SHA256          string  `json:"sha256,omitempty"`
+	Transcribed     bool    `json:"transcribed"`
+	SkipReason      string  `json:"skipReason,omitempty"`
+}
+
+// BuildReport derives the integrity report from the job as stored.
+func BuildReport(job *chunker.Job) *IntegrityReport {
+	report := &IntegrityReport{
+		JobID:       job.ID,
+		GeneratedAt: time.Now(),
+		Status:      job.Status,
+		Metadata:    job.Metadata,
+		Input: ReportInput{
+			FileName:        job.OriginalFileName,
+			SHA256:          job.OriginalSHA256,
+			DurationSeconds: job.InputDurationSeconds,
+		},
+		Chunks:   make([]ReportChunk, 0, len(job.Chunks)),
+		Warnings: []string{},
+	}
+	warn := func(format string, args ...any) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if job.Status != chunker.JobStatusCompleted {
+		if job.ErrorMessage != "" {
+			warn("job status is %s: %s", job.Status, job.ErrorMessage)
+		} else {
+			warn("job status is %s", job.Status)
+		}
+	}
+	if job.OriginalSHA256 == "" {
+		warn("no input checksum was recorded")
+	}
+	if job.InputDurationSeconds <= 0 {
+		warn("the input duration is unknown, so produced audio cannot be checked against it")
+	}
+	if job.ArtefactsPurgedAt != nil {
+		warn("chunk audio was purged on %s; checksums describe the files as produced", job.ArtefactsPurgedAt.UTC().Format(time.RFC3339))
+	}
+
+	tracks := map[int]*ReportTrack{}
+	var produced, transcribed float64
+	var skipped int
+	prevEnd := map[int]float64{}
+	for _, chunk := range job.Chunks {
+		entry := ReportChunk{
+			Index:           chunk.Index,
+			Track:           chunk.Track,
+			StartSeconds:    chunk.StartSeconds,
+			DurationSeconds: chunk.DurationSeconds,
+			AudioFile:       chunk.AudioFile,
+			SHA256:          chunk.Checksum,
+			Transcribed:     chunk.TranscriptFile != "" || len(chunk.Transcripts) > 0,
+			SkipReason:      chunk.SkipReason,
+		}
+		report.Chunks = append(report.Chunks, entry)
+
+		track := tracks[chunk.Track]
+		if track == nil {
+			track = &ReportTrack{Track: chunk.Track}
+			tracks[chunk.Track] = track
+		}
+		track.Chunks++
+		track.ProducedSeconds += chunk.DurationSeconds
+		produced += chunk.DurationSeconds
+
+		if end, seen := prevEnd[chunk.Track]; seen {
+			switch gap := chunk.StartSeconds - end; {
+			case gap > reportGapTolerance:
+				warn("chunk %d starts %.3fs after the previous chunk ends", chunk.Index, gap)
+			case gap < -reportGapTolerance:
+				warn("chunk %d overlaps the previous chunk by %.3fs", chunk.Index, -gap)
+			}
+		}
+		prevEnd[chunk.Track] = chunk.StartSeconds + chunk.DurationSeconds
+
+		if chunk.Checksum == "" {
+			warn("chunk %d has no checksum", chunk.Index)
+		}
+		switch {
+		case entry.Transcribed:
+			transcribed += chunk.DurationSeconds
+		case chunk.SkipReason != "":
+			skipped++
+		case job.TranscriptionRequested:
+			warn("chunk %d has no transcript", chunk.Index)
+		}
+	}
+	if len(job.Chunks) == 0 {
+		warn("the job produced no chunks")
+	}
+
+	for _, track := range tracks {
+		if job.InputDurationSeconds > 0 {
+			diff := track.ProducedSeconds - job.InputDurationSeconds
+			track.DifferenceSeconds = &diff
+			// Allow a second or half a percent, whichever is larger, for codec framing.
+			if math.Abs(diff) > math.Max(1, job.InputDurationSeconds*0.005) {
+				warn("track %d produced %.3fs of audio for %.3fs of input (%+.3fs)", track.Track, track.ProducedSeconds, job.InputDurationSeconds, diff)
+			}
+		}
+		report.Tracks = append(report.Tracks, *track)
+	}
+	sort.Slice(report.Tracks, func(i, j int) bool { return report.Tracks[i].Track < report.Tracks[j].Track })
+
+	if job.TranscriptionRequested {
+		coverage := 0.0
+		if produced > 0 {
+			coverage = math.Round(transcribed/produced*1000) / 10
+		}
+		report.Transcription = &ReportTranscription{TranscribedSeconds: transcribed, CoveragePercent: coverage, Skipped: skipped}
+	}
+	return report
+}
+
+// Text renders the report for people: a summary, the chunk table, and the warnings.
+func (r *IntegrityReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Integrity report for job %s\n", r.JobID)
+	fmt.Fprintf(&b, "Generated %s\n", r.GeneratedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Status:   %s\n", r.Status)
+	if len(r.Metadata) > 0 {
+		keys := make([]string, 0, len(r.Metadata))
+		for key := range r.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "Meta:     %s=%s\n", key, r.Metadata[key])
+		}
+	}
+
+	b.WriteString("\nInput\n")
+	fmt.Fprintf(&b, "  File:     %s\n", orDash(r.Input.FileName))
+	fmt.Fprintf(&b, "  SHA-256:  %s\n", orDash(r.Input.SHA256))
+	if r.Input.DurationSeconds > 0 {
+		fmt.Fprintf(&b, "  Duration: %.3fs\n", r.Input.DurationSeconds)
+	} else {
+		b.WriteString("  Duration: unknown\n")
+	}
+
+	b.WriteString("\nOutput\n")
+	for _, track := range r.Tracks {
+		fmt.Fprintf(&b, "  Track %d:  %d chunks, %.3fs", track.Track, track.Chunks, track.ProducedSeconds)
+		if track.DifferenceSeconds != nil {
+			fmt.Fprintf(&b, " (%+.3fs vs input)", *track.DifferenceSeconds)
+		}
+		b.WriteString("\n")
+	}
+	if t := r.Transcription; t != nil {
+		fmt.Fprintf(&b, "  Transcribed: %.1f%% of produced audio (%.3fs), %d chunks skipped\n", t.CoveragePercent, t.TranscribedSeconds, t.Skipped)
+	}
+
+	if len(r.Chunks) > 0 {
+		b.WriteString("\nChunks\n")
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  #\ttrack\tstart\tduration\ttranscript\tsha256")
+		for _, chunk := range r.Chunks {
+			transcript := "no"
+			switch {
+			case r.Transcription == nil:
+				transcript = "-"
+			case chunk.Transcribed:
+				transcript = "yes"
+			case chunk.SkipReason != "":
+				transcript = "skipped (" + chunk.SkipReason + ")"
+			}
+			fmt.Fprintf(tw, "  %d\t%d\t%.3f\t%.3f\t%s\t%s\n", chunk.Index, chunk.Track, chunk.StartSeconds, chunk.DurationSeconds, transcript, orDash(chunk.SHA256))
+		}
+		tw.Flush()
+	}
+
+	if len(r.Warnings) == 0 {
+		b.WriteString("\nWarnings: none\n")
+	} else {
+		b.WriteString("\nWarnings\n")
+		for _, w := range r.Warnings {
+			fmt.Fprintf(&b, "  - %s\n", w)
+		}
+	}
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// LoadReport reads a job's report.json.
+func LoadReport(jobDir string) (*IntegrityReport, error) {
+	data, err := os.ReadFile(filepath.Join(jobDir, ReportJSONFileName))
+	if err != nil {
+		return nil, fmt.Errorf("reading report: %w", err)
+	}
+	var report IntegrityReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("decoding report: %w", err)
+	}
+	return &report, nil
+}
+
+// WriteReport builds the job's integrity report and saves it as report.json and
+// report.txt in the job directory.
+func WriteReport(jobDir string, job *chunker.Job) (*IntegrityReport, error) {
+	report := BuildReport(job)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding report: %w", err)
+	}
+	files := map[string][]byte{
+		ReportJSONFileName: data,
+		ReportTextFileName: []byte(report.Text()),
+	}
+	for name, content := range files {
+		tmp := filepath.Join(jobDir, name+".tmp")
+		if err := os.WriteFile(tmp, content, 0o644); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", name, err)
+		}
+		if err := os.Rename(tmp, filepath.Join(jobDir, name)); err != nil {
+			return nil, fmt.Errorf("replacing %s: %w", name, err)
+		}
+	}
+	return report, nil
+}
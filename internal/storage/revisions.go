@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RevisionDir names the directory that keeps an artefact type of an earlier revision,
+// e.g. chunks_v1.
+func RevisionDir(artefact string, n int) string {
+	return artefact + "_v" + strconv.Itoa(n)
+}
+
+// ArchiveRevision moves the job's DerivedArtefactDirs aside as revision n and leaves
+// empty directories in their place. A directory on a storage class is renamed on the
+// class and linked from the job directory, so no audio crosses filesystems. It returns
+// the artefact types that had files to archive.
+func ArchiveRevision(jobDir string, n int) ([]string, error) {
+	var archived []string
+	for _, name := range DerivedArtefactDirs {
+		dir := filepath.Join(jobDir, name)
+		current := resolveDir(dir)
+		entries, err := os.ReadDir(current)
+		if err != nil && !os.IsNotExist(err) {
+			return archived, fmt.Errorf("reading %s: %w", name, err)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		target := filepath.Join(filepath.Dir(current), RevisionDir(name, n))
+		if _, err := os.Lstat(target); err == nil {
+			return archived, fmt.Errorf("revision %d of %s already exists", n, name)
+		}
+		if err := os.Rename(current, target); err != nil {
+			return archived, fmt.Errorf("archiving %s: %w", name, err)
+		}
+		if err := os.MkdirAll(current, 0o755); err != nil {
+			return archived, fmt.Errorf("recreating %s: %w", name, err)
+		}
+		if current != dir {
+			if err := os.Symlink(target, filepath.Join(jobDir, RevisionDir(name, n))); err != nil {
+				return archived, fmt.Errorf("linking archived %s: %w", name, err)
+			}
+		}
+		archived = append(archived, name)
+	}
+	return archived, nil
+}
+
+// RemoveRevision deletes every artefact directory of revision n, including the ones it
+// links to on storage classes. Missing directories are not an error.
+func RemoveRevision(jobDir string, n int) error {
+	for _, name := range DerivedArtefactDirs {
+		dir := filepath.Join(jobDir, RevisionDir(name, n))
+		target, linked, err := linkedDir(dir)
+		if err != nil {
+			return err
+		}
+		if linked {
+			if err := os.RemoveAll(target); err != nil {
+				return fmt.Errorf("removing %s: %w", RevisionDir(name, n), err)
+			}
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("removing %s: %w", RevisionDir(name, n), err)
+		}
+	}
+	return nil
+}
+
+// RevisionPath rewrites a path relative to the job directory, such as
+// chunks/chunk_000.wav, to where ArchiveRevision put it in revision n. Paths outside the
+// archived artefact types are returned unchanged.
+func RevisionPath(rel string, n int) string {
+	artefact, rest, ok := strings.Cut(rel, "/")
+	if !ok {
+		return rel
+	}
+	for _, name := range DerivedArtefactDirs {
+		if artefact == name {
+			return RevisionDir(name, n) + "/" + rest
+		}
+	}
+	return rel
+}
+
+// revisionDirs lists the archived artefact directories in a job directory.
+func revisionDirs(jobDir string) ([]string, error) {
+	entries, err := os.ReadDir(jobDir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		name, n, ok := strings.Cut(entry.Name(), "_v")
+		if !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(n); err != nil {
+			continue
+		}
+		for _, artefact := range DerivedArtefactDirs {
+			if name == artefact {
+				dirs = append(dirs, filepath.Join(jobDir, entry.Name()))
+			}
+		}
+	}
+	return dirs, nil
+}
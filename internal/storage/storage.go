@@ -1,23 +1,165 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"audi/internal/model"
+	"audi/pkg/chunker"
 )
 
 const jobFileName = "job.json"
 
+// SortOrder selects which timestamp ListJobs orders by.
+type SortOrder string
+
+const (
+	SortByCreated SortOrder = "created"
+	SortByUpdated SortOrder = "updated"
+)
+
+// ListOptions narrows and orders the result of ListJobsWith.
+type ListOptions struct {
+	SortBy SortOrder
+	// UpdatedSince, when non-zero, keeps only jobs saved strictly after this instant.
+	UpdatedSince time.Time
+	// Tag, when set, keeps only jobs carrying this tag.
+	Tag string
+}
+
+// ErrVersionConflict is returned by SaveJob when job.json was saved by someone else
+// since the caller loaded it.
+var ErrVersionConflict = errors.New("job was changed by another save")
+
+// jobLocks holds one mutex per job directory, serialising the version check and
+// write so two savers cannot both pass the check.
+var jobLocks sync.Map
+
+func lockJob(jobDir string) func() {
+	v, _ := jobLocks.LoadOrStore(filepath.Clean(jobDir), &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
 // SaveJob serialises job metadata atomically into job.json.
-func SaveJob(jobDir string, job *model.Job) error {
+// It refuses with ErrVersionConflict when the file on disk carries a different Version
+// than job, i.e. it was saved since job was loaded, and bumps Version on success.
+// It stamps UpdatedAt on every save, always moving it forward so consecutive
+// saves never share or reverse a timestamp.
+func SaveJob(jobDir string, job *chunker.Job) error {
+	defer lockJob(jobDir)()
+	stored, err := storedVersion(jobDir)
+	if err != nil {
+		return err
+	}
+	if stored != job.Version {
+		return fmt.Errorf("%w: job.json is at version %d, this copy at %d", ErrVersionConflict, stored, job.Version)
+	}
+	return writeJob(jobDir, job)
+}
+
+// CreateJob writes job.json for a job that starts afresh, replacing whatever an
+// earlier run left in jobDir instead of checking its version.
+func CreateJob(jobDir string, job *chunker.Job) error {
+	defer lockJob(jobDir)()
+	stored, err := storedVersion(jobDir)
+	if err != nil {
+		stored = 0
+	}
+	job.Version = stored
+	return writeJob(jobDir, job)
+}
+
+// UpdateJob loads job.json, applies fn, and saves the result while holding the job's
+// lock, so the change is applied to the latest copy and cannot conflict. If fn returns
+// an error nothing is written.
+func UpdateJob(jobDir string, fn func(*chunker.Job) error) (*chunker.Job, error) {
+	defer lockJob(jobDir)()
+	job, err := LoadJob(jobDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(job); err != nil {
+		return nil, err
+	}
+	if err := writeJob(jobDir, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// SaveLatest saves a job that may have been loaded a while ago, such as the copy a job
+// is processed with. When the title, tags, or note were edited in the meantime it takes
+// those edits over and saves again, rather than writing its stale copy over them.
+func SaveLatest(jobDir string, job *chunker.Job) error {
+	const attempts = 3
+	var err error
+	for range attempts {
+		err = SaveJob(jobDir, job)
+		if !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+		latest, loadErr := LoadJob(jobDir)
+		if loadErr != nil {
+			return fmt.Errorf("%w (reloading: %w)", err, loadErr)
+		}
+		job.AdoptAnnotations(latest)
+	}
+	return err
+}
+
+// storedVersion reads just the version of the job.json in jobDir, 0 when there is none.
+func storedVersion(jobDir string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(jobDir, jobFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading job file: %w", err)
+	}
+	var stored struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return 0, fmt.Errorf("unmarshalling job file: %w", err)
+	}
+	return stored.Version, nil
+}
+
+// writeJob does the actual write; the caller holds the job's lock. On failure job keeps
+// its previous Version and UpdatedAt, so the caller may simply retry.
+func writeJob(jobDir string, job *chunker.Job) error {
 	if err := os.MkdirAll(jobDir, 0o755); err != nil {
 		return fmt.Errorf("creating job directory: %w", err)
 	}
 
+	prevVersion, prevUpdated := job.Version, job.UpdatedAt
+	now := time.Now().Round(0)
+	if !now.After(job.UpdatedAt) {
+		now = job.UpdatedAt.Add(time.Nanosecond)
+	}
+	job.UpdatedAt = now
+	job.Version++
+	if err := writeJobFile(jobDir, job); err != nil {
+		job.Version, job.UpdatedAt = prevVersion, prevUpdated
+		return err
+	}
+	return nil
+}
+
+func writeJobFile(jobDir string, job *chunker.Job) error {
 	data, err := json.MarshalIndent(job, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshalling job data: %w", err)
@@ -36,13 +178,13 @@ func SaveJob(jobDir string, job *model.Job) error {
 }
 
 // LoadJob reads job.json from disk and restores a Job structure.
-func LoadJob(jobDir string) (*model.Job, error) {
+func LoadJob(jobDir string) (*chunker.Job, error) {
 	data, err := os.ReadFile(filepath.Join(jobDir, jobFileName))
 	if err != nil {
 		return nil, fmt.Errorf("reading job file: %w", err)
 	}
 
-	var job model.Job
+	var job chunker.Job
 	if err := json.Unmarshal(data, &job); err != nil {
 		return nil, fmt.Errorf("unmarshalling job file: %w", err)
 	}
@@ -51,7 +193,14 @@ func LoadJob(jobDir string) (*model.Job, error) {
 }
 
 // ListJobs walks all job directories and returns their metadata, newest first.
-func ListJobs(jobsRoot string) ([]*model.Job, error) {
+func ListJobs(jobsRoot string) ([]*chunker.Job, error) {
+	return ListJobsWith(jobsRoot, ListOptions{})
+}
+
+// ListJobsWith lists jobs newest first by the requested timestamp, optionally
+// filtered to those updated after opts.UpdatedSince or carrying opts.Tag. Ties
+// fall back to job ID so the order is stable across calls.
+func ListJobsWith(jobsRoot string, opts ListOptions) ([]*chunker.Job, error) {
 	entries, err := os.ReadDir(jobsRoot)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -60,7 +209,7 @@ func ListJobs(jobsRoot string) ([]*model.Job, error) {
 		return nil, fmt.Errorf("reading jobs directory: %w", err)
 	}
 
-	jobs := make([]*model.Job, 0, len(entries))
+	jobs := make([]*chunker.Job, 0, len(entries))
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -70,21 +219,220 @@ func ListJobs(jobsRoot string) ([]*model.Job, error) {
 		if err != nil {
 			continue
 		}
+		if !opts.UpdatedSince.IsZero() && !jobUpdatedAt(job).After(opts.UpdatedSince) {
+			continue
+		}
+		if opts.Tag != "" && !job.HasTag(opts.Tag) {
+			continue
+		}
 		jobs = append(jobs, job)
 	}
 
+	key := func(job *chunker.Job) time.Time {
+		return job.CreatedAt
+	}
+	if opts.SortBy == SortByUpdated {
+		key = jobUpdatedAt
+	}
+
 	sort.Slice(jobs, func(i, j int) bool {
-		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		ti, tj := key(jobs[i]), key(jobs[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return jobs[i].ID > jobs[j].ID
 	})
 
 	return jobs, nil
 }
 
+// jobUpdatedAt falls back to CreatedAt for metadata written before UpdatedAt existed.
+func jobUpdatedAt(job *chunker.Job) time.Time {
+	if job.UpdatedAt.IsZero() {
+		return job.CreatedAt
+	}
+	return job.UpdatedAt
+}
+
 // JobDir resolves the absolute path for a job inside the data root.
 func JobDir(jobsRoot, jobID string) string {
 	return filepath.Join(jobsRoot, jobID)
 }
 
+// CreateJobDir creates a fresh job directory, asking newID for another identifier
+// whenever the directory already exists so concurrent uploads never share one.
+func CreateJobDir(jobsRoot string, newID func() string) (string, string, error) {
+	if err := os.MkdirAll(jobsRoot, 0o755); err != nil {
+		return "", "", fmt.Errorf("creating jobs directory: %w", err)
+	}
+	for attempt := 0; attempt < 20; attempt++ {
+		id := newID()
+		dir := JobDir(jobsRoot, id)
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return id, dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", "", fmt.Errorf("creating job directory: %w", err)
+		}
+	}
+	return "", "", errors.New("could not allocate a unique job ID")
+}
+
+// StoreOriginal streams an upload into original/ under a content-addressed name
+// (<sha256 prefix><ext>), so the client-supplied filename never becomes a path.
+// It returns the slash-separated path relative to jobDir and the full SHA-256.
+func StoreOriginal(jobDir, displayName string, src io.Reader) (string, string, error) {
+	dir := filepath.Join(jobDir, "original")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("creating original directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "upload-*.tmp")
+	if err != nil {
+		return "", "", fmt.Errorf("creating upload file: %w", err)
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", "", fmt.Errorf("writing upload: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", fmt.Errorf("finalising upload: %w", err)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	name := sum[:16] + safeExtension(displayName)
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", fmt.Errorf("naming upload: %w", err)
+	}
+	return "original/" + name, sum, nil
+}
+
+// safeExtension keeps a short alphanumeric extension so ffmpeg and browsers can still
+// recognise the container, dropping anything else.
+func safeExtension(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if len(ext) < 2 || len(ext) > 9 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// HeavyArtefactDirs are the job subdirectories PurgeArtefacts empties: the source media
+// and the audio and video derived from it. Transcripts and job.json are kept.
+var HeavyArtefactDirs = []string{"original", "chunks", "base64", "merged", "video"}
+
+// PurgeArtefacts deletes the contents of HeavyArtefactDirs, leaving the directories in
+// place so the job keeps its usual layout. Directories on a storage class are emptied
+// through their link.
+func PurgeArtefacts(jobDir string) error {
+	for _, name := range HeavyArtefactDirs {
+		dir := resolveDir(filepath.Join(jobDir, name))
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("purging %s: %w", name, err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("recreating %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DerivedArtefactDirs are the job subdirectories produced from the original, which
+// ArchiveRevision moves aside before the original is processed again.
+var DerivedArtefactDirs = []string{"chunks", "base64", "transcripts", "merged", "video"}
+
+// ClearDerivedArtefacts deletes the contents of DerivedArtefactDirs other than keep, e.g.
+// the partial output of a failed run, so the original can be processed again.
+func ClearDerivedArtefacts(jobDir string, keep ...string) error {
+	for _, name := range DerivedArtefactDirs {
+		if slices.Contains(keep, name) {
+			continue
+		}
+		dir := resolveDir(filepath.Join(jobDir, name))
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("clearing %s: %w", name, err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("recreating %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ShareOriginal makes the original at rel in srcJobDir available under the same relative
+// path in dstJobDir, hard-linked where possible and copied otherwise.
+func ShareOriginal(srcJobDir, dstJobDir, rel string) error {
+	src := filepath.Join(srcJobDir, filepath.FromSlash(rel))
+	dst := filepath.Join(dstJobDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating original directory: %w", err)
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// AdoptOriginal takes a file that already exists on this machine, such as one dropped
+// into a watched folder, as the job's original. It is moved (or, with link, hard-linked)
+// into original/ under the same content-addressed name StoreOriginal uses, and copied
+// when src is on another filesystem; a moved file is removed from src once copied.
+// sum is the file's SHA-256, which the caller has already computed.
+func AdoptOriginal(jobDir, src, sum string, link bool) (string, error) {
+	dir := filepath.Join(jobDir, "original")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating original directory: %w", err)
+	}
+	name := sum[:16] + safeExtension(src)
+	dst := filepath.Join(dir, name)
+
+	if link {
+		if err := os.Link(src, dst); err == nil {
+			return "original/" + name, nil
+		}
+	} else if err := os.Rename(src, dst); err == nil {
+		return "original/" + name, nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return "", err
+	}
+	if !link {
+		if err := os.Remove(src); err != nil {
+			return "", fmt.Errorf("removing moved original: %w", err)
+		}
+	}
+	return "original/" + name, nil
+}
+
+// copyFile copies src to dst, removing the partial copy on failure.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("opening original: %w", err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("creating original copy: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("copying original: %w", err)
+	}
+	return out.Close()
+}
+
 // EnsureJobSubdirs makes sure the expected per-job subdirectories exist.
 func EnsureJobSubdirs(jobDir string, names ...string) error {
 	for _, name := range names {
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"audi/pkg/chunker"
+)
+
+// Token is one word of a text: its lower-cased form and byte span in the original.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into runs of letters and digits, lower-cased for matching.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			tokens = append(tokens, Token{Term: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Term: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+// TranscriptMatch is a chunk whose main transcript or keywords contain every query term.
+type TranscriptMatch struct {
+	JobID string
+	Chunk int
+	// Score counts occurrences of the query terms in the chunk, those in its keywords
+	// keywordBoost times.
+	Score int
+	Text  string
+}
+
+type transcriptDoc struct {
+	jobID string
+	chunk int
+}
+
+// keywordBoost weighs a query term found among a chunk's keywords against one found in
+// its transcript, so chunks about a topic rank above chunks that mention it in passing.
+const keywordBoost = 3
+
+// TranscriptIndex is an in-memory inverted index over the main transcript and keywords
+// of every chunk. It is built from the transcripts on disk at startup and updated as jobs
+// finish or are deleted, so there is no index file that could drift from the jobs.
+type TranscriptIndex struct {
+	mu       sync.RWMutex
+	texts    map[transcriptDoc]string
+	keywords map[transcriptDoc]string
+	terms    map[string]map[transcriptDoc]int
+	jobs     map[string][]transcriptDoc
+}
+
+// NewTranscriptIndex returns an empty index.
+func NewTranscriptIndex() *TranscriptIndex {
+	return &TranscriptIndex{
+		texts:    make(map[transcriptDoc]string),
+		keywords: make(map[transcriptDoc]string),
+		terms:    make(map[string]map[transcriptDoc]int),
+		jobs:     make(map[string][]transcriptDoc),
+	}
+}
+
+// Build indexes every job under jobsDir and returns how many chunks it added.
+func (x *TranscriptIndex) Build(jobsDir string) (int, error) {
+	jobs, err := ListJobs(jobsDir)
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	for _, job := range jobs {
+		n, err := x.IndexJob(JobDir(jobsDir, job.ID), job)
+		if err != nil {
+			return total, fmt.Errorf("indexing job %s: %w", job.ID, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// IndexJob replaces the job's entries with its current chunk transcripts and returns
+// how many chunks were indexed. Transcript files that vanished are skipped.
+func (x *TranscriptIndex) IndexJob(jobDir string, job *chunker.Job) (int, error) {
+	texts := make(map[transcriptDoc]string)
+	keywords := make(map[transcriptDoc]string)
+	for _, chunk := range job.Chunks {
+		if chunk.TranscriptFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		text := strings.Join(strings.Fields(string(data)), " ")
+		if text == "" {
+			continue
+		}
+		doc := transcriptDoc{jobID: job.ID, chunk: chunk.Index}
+		texts[doc] = text
+		var terms []string
+		for _, k := range chunk.Keywords {
+			terms = append(terms, k.Text)
+		}
+		keywords[doc] = strings.Join(terms, " ")
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(job.ID)
+	for doc, text := range texts {
+		x.texts[doc] = text
+		x.keywords[doc] = keywords[doc]
+		x.post(doc, text, 1)
+		x.post(doc, keywords[doc], keywordBoost)
+		x.jobs[job.ID] = append(x.jobs[job.ID], doc)
+	}
+	return len(texts), nil
+}
+
+// post adds weight to doc's count for every token of text.
+func (x *TranscriptIndex) post(doc transcriptDoc, text string, weight int) {
+	for _, token := range Tokenize(text) {
+		postings := x.terms[token.Term]
+		if postings == nil {
+			postings = make(map[transcriptDoc]int)
+			x.terms[token.Term] = postings
+		}
+		postings[doc] += weight
+	}
+}
+
+// RemoveJob drops every entry of a job.
+func (x *TranscriptIndex) RemoveJob(jobID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(jobID)
+}
+
+func (x *TranscriptIndex) removeLocked(jobID string) {
+	for _, doc := range x.jobs[jobID] {
+		for _, token := range Tokenize(x.texts[doc] + " " + x.keywords[doc]) {
+			postings := x.terms[token.Term]
+			delete(postings, doc)
+			if len(postings) == 0 {
+				delete(x.terms, token.Term)
+			}
+		}
+		delete(x.texts, doc)
+		delete(x.keywords, doc)
+	}
+	delete(x.jobs, jobID)
+}
+
+// Search returns up to limit chunks whose transcript or keywords contain every word of
+// query, best scores first and newer jobs first among equals, plus the total number of
+// matching chunks.
+func (x *TranscriptIndex) Search(query string, limit int) ([]TranscriptMatch, int) {
+	var terms []string
+	for _, token := range Tokenize(query) {
+		terms = append(terms, token.Term)
+	}
+	if len(terms) == 0 {
+		return nil, 0
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// Start from the rarest term so the candidate set is as small as possible.
+	sort.Slice(terms, func(i, j int) bool { return len(x.terms[terms[i]]) < len(x.terms[terms[j]]) })
+	var matches []TranscriptMatch
+	for doc, count := range x.terms[terms[0]] {
+		score := count
+		for _, term := range terms[1:] {
+			n := x.terms[term][doc]
+			if n == 0 {
+				score = 0
+				break
+			}
+			score += n
+		}
+		if score > 0 {
+			matches = append(matches, TranscriptMatch{JobID: doc.jobID, Chunk: doc.chunk, Score: score, Text: x.texts[doc]})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.JobID != b.JobID {
+			return a.JobID > b.JobID
+		}
+		return a.Chunk < b.Chunk
+	})
+	total := len(matches)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, total
+}
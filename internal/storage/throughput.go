@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// throughputDecay weights older samples down so the estimate follows hardware or model changes.
+const throughputDecay = 0.8
+
+// StageThroughput accumulates decayed totals of audio handled and wall-clock time spent.
+type StageThroughput struct {
+	AudioSeconds float64 `json:"audioSeconds"`
+	WallSeconds  float64 `json:"wallSeconds"`
+}
+
+// Rate returns audio seconds processed per wall-clock second, or 0 without history.
+func (t StageThroughput) Rate() float64 {
+	if t.AudioSeconds <= 0 || t.WallSeconds <= 0 {
+		return 0
+	}
+	return t.AudioSeconds / t.WallSeconds
+}
+
+// Throughput persists per-stage processing speed history in a JSON file. The server
+// and its workers share the file: each picks up the others' records when it changes.
+type Throughput struct {
+	mu      sync.Mutex
+	path    string
+	stages  map[string]StageThroughput
+	modTime time.Time
+}
+
+// OpenThroughput loads the history at path; a missing file starts empty.
+func OpenThroughput(path string) (*Throughput, error) {
+	t := &Throughput{path: path, stages: make(map[string]StageThroughput)}
+	if err := t.refresh(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// refresh reloads the history when the file changed since it was last read.
+func (t *Throughput) refresh() error {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading throughput file: %w", err)
+	}
+	if info.ModTime().Equal(t.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return fmt.Errorf("reading throughput file: %w", err)
+	}
+	stages := make(map[string]StageThroughput)
+	if err := json.Unmarshal(data, &stages); err != nil {
+		return fmt.Errorf("unmarshalling throughput file: %w", err)
+	}
+	t.stages, t.modTime = stages, info.ModTime()
+	return nil
+}
+
+// Rate reports the historical speed for a stage. When the file cannot be reread it
+// answers from the history it has.
+func (t *Throughput) Rate(stage string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refresh()
+	return t.stages[stage].Rate()
+}
+
+// Record folds one measurement into the stage history and saves it.
+func (t *Throughput) Record(stage string, audioSeconds, wallSeconds float64) error {
+	if audioSeconds <= 0 || wallSeconds <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.refresh(); err != nil {
+		return err
+	}
+	prev := t.stages[stage]
+	t.stages[stage] = StageThroughput{
+		AudioSeconds: prev.AudioSeconds*throughputDecay + audioSeconds,
+		WallSeconds:  prev.WallSeconds*throughputDecay + wallSeconds,
+	}
+
+	data, err := json.MarshalIndent(t.stages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling throughput: %w", err)
+	}
+	// A temporary file of its own, as other processes may be saving the history too.
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing throughput temp file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing throughput temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("persisting throughput file: %w", err)
+	}
+	if info, err := os.Stat(t.path); err == nil {
+		t.modTime = info.ModTime()
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+// MeasureJob sums the artefact subdirectories of a job; Total covers the whole directory
+// plus any artefact directories it links to on storage classes.
+func MeasureJob(jobDir string) (*chunker.DiskUsage, error) {
+	usage := &chunker.DiskUsage{}
+	parts := []struct {
+		dir string
+		dst *int64
+	}{
+		{"original", &usage.Original},
+		{"chunks", &usage.Chunks},
+		{"base64", &usage.Base64},
+		{"transcripts", &usage.Transcripts},
+		{"merged", &usage.Merged},
+		{"video", &usage.Video},
+	}
+	roots := []string{jobDir}
+	revisions, err := revisionDirs(jobDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range revisions {
+		target := resolveDir(dir)
+		if target != dir {
+			roots = append(roots, target)
+		}
+		size, err := DirSize(target)
+		if err != nil {
+			return nil, err
+		}
+		usage.Revisions += size
+	}
+	for _, part := range parts {
+		dir := filepath.Join(jobDir, part.dir)
+		target := resolveDir(dir)
+		if target != dir {
+			roots = append(roots, target)
+		}
+		size, err := DirSize(target)
+		if err != nil {
+			return nil, err
+		}
+		*part.dst = size
+	}
+
+	for _, root := range roots {
+		err := walkFiles(root, func(info fs.FileInfo) {
+			usage.Total += info.Size()
+			if _, links, ok := inodeInfo(info); !ok || links <= 1 {
+				usage.Exclusive += info.Size()
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return usage, nil
+}
+
+// DataUsage reports the logical size of root and the physical size, which counts each
+// hard-linked inode once.
+func DataUsage(root string) (logical, physical int64, err error) {
+	seen := make(map[fileID]bool)
+	err = walkFiles(root, func(info fs.FileInfo) {
+		logical += info.Size()
+		id, _, ok := inodeInfo(info)
+		if ok {
+			if seen[id] {
+				return
+			}
+			seen[id] = true
+		}
+		physical += info.Size()
+	})
+	return logical, physical, err
+}
+
+// UsageCounter keeps the size of a data directory between walks of it: jobs report how
+// their measured usage changed, and the tree is walked again in the background once
+// the totals are older than maxAge, catching whatever was not reported.
+type UsageCounter struct {
+	root   string
+	maxAge time.Duration
+
+	mu                sync.Mutex
+	logical, physical int64
+	measuredAt        time.Time
+	walking           bool
+}
+
+// NewUsageCounter returns a counter for the data directory at root.
+func NewUsageCounter(root string, maxAge time.Duration) *UsageCounter {
+	return &UsageCounter{root: root, maxAge: maxAge}
+}
+
+// Usage returns the logical and physical size of the data directory, as DataUsage does.
+// Only the first call, or one after a walk failed, waits for a walk.
+func (u *UsageCounter) Usage() (logical, physical int64, err error) {
+	u.mu.Lock()
+	if u.measuredAt.IsZero() {
+		u.mu.Unlock()
+		return u.walk()
+	}
+	if time.Since(u.measuredAt) > u.maxAge && !u.walking {
+		u.walking = true
+		go u.walk()
+	}
+	defer u.mu.Unlock()
+	return u.logical, u.physical, nil
+}
+
+// walk measures the data directory and replaces the totals with the result.
+func (u *UsageCounter) walk() (int64, int64, error) {
+	logical, physical, err := DataUsage(u.root)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.walking = false
+	if err != nil {
+		u.measuredAt = time.Time{}
+		return 0, 0, err
+	}
+	u.logical, u.physical, u.measuredAt = logical, physical, time.Now()
+	return logical, physical, nil
+}
+
+// Replace moves the totals by the difference between a job's earlier usage and its new
+// one; nil stands for a job without files, such as a deleted one. The physical total
+// moves by the exclusive size, so files shared with other jobs wait for the next walk.
+func (u *UsageCounter) Replace(old, new *chunker.DiskUsage) {
+	var before, after chunker.DiskUsage
+	if old != nil {
+		before = *old
+	}
+	if new != nil {
+		after = *new
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.logical = max(u.logical+after.Total-before.Total, 0)
+	u.physical = max(u.physical+after.Exclusive-before.Exclusive, 0)
+}
+
+// DirSize returns the total size of regular files below root; a missing root counts as empty.
+func DirSize(root string) (int64, error) {
+	var total int64
+	err := walkFiles(root, func(info fs.FileInfo) {
+		total += info.Size()
+	})
+	return total, err
+}
+
+// walkFiles calls fn for each regular file below root, ignoring files that vanish mid-walk.
+func walkFiles(root string, fn func(fs.FileInfo)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		fn(info)
+		return nil
+	})
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"audi/pkg/chunker"
+)
+
+func TestUsageCounter(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a"), make([]byte, 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	u := NewUsageCounter(root, time.Hour)
+	if logical, physical, err := u.Usage(); err != nil || logical != 1000 || physical != 1000 {
+		t.Fatalf("Usage = %d, %d, %v; want 1000, 1000", logical, physical, err)
+	}
+
+	// Files written since are only seen through the usage jobs report, until the next walk.
+	if err := os.WriteFile(filepath.Join(root, "b"), make([]byte, 500), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := &chunker.DiskUsage{Total: 500, Exclusive: 200}
+	u.Replace(nil, job)
+	if logical, physical, _ := u.Usage(); logical != 1500 || physical != 1200 {
+		t.Errorf("Usage after a job grew = %d, %d; want 1500, 1200", logical, physical)
+	}
+	u.Replace(job, nil)
+	if logical, physical, _ := u.Usage(); logical != 1000 || physical != 1000 {
+		t.Errorf("Usage after the job was deleted = %d, %d; want 1000, 1000", logical, physical)
+	}
+}
@@ -0,0 +1,178 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Acceleration runs whisper and ffmpeg's video decoding on a GPU. Build it with
+// DetectAcceleration, which keeps only what the machine supports.
+type Acceleration struct {
+	// GPU reports whether whisper runs on the GPU. Without it every whisper run is a CPU run.
+	GPU bool
+	// WhisperArgs are appended to whisper's arguments for GPU runs, such as -fa for flash
+	// attention or -dev to pick a device.
+	WhisperArgs []string
+	// CPUWhisperArgs are appended instead for CPU runs, typically -ng so a GPU build of
+	// whisper.cpp leaves the GPU alone.
+	CPUWhisperArgs []string
+	// FFmpegHWAccel is ffmpeg's -hwaccel method (cuda, videotoolbox, vaapi, qsv, or auto)
+	// for the passes that decode the input's video: finding keyframes for video clips.
+	// Extracting audio never decodes video, so it is not affected.
+	FFmpegHWAccel string
+}
+
+// WithAcceleration runs whisper and ffmpeg as a describes; nil runs everything as before.
+func WithAcceleration(a *Acceleration) Option {
+	return func(p *Processor) {
+		p.Acceleration = a
+	}
+}
+
+// Active reports whether a uses any hardware acceleration.
+func (a *Acceleration) Active() bool {
+	return a != nil && (a.GPU || a.FFmpegHWAccel != "")
+}
+
+// DetectAcceleration returns the part of want this machine supports, with a note for
+// each part it had to drop. check is a command whose success shows the GPU is usable,
+// such as ["nvidia-smi", "-L"]; without one, Apple silicon counts as having a GPU
+// (Metal) and other machines need nvidia-smi to list a device. The ffmpeg method must
+// appear in the -hwaccels list of the ffmpeg binary.
+func DetectAcceleration(ctx context.Context, ffmpeg string, want Acceleration, check []string) (*Acceleration, []string) {
+	got := want
+	var notes []string
+	if got.GPU {
+		if err := checkGPU(ctx, check); err != nil {
+			got.GPU = false
+			notes = append(notes, fmt.Sprintf("no usable GPU (%v); whisper runs on the CPU", err))
+		}
+	}
+	if method := got.FFmpegHWAccel; method != "" && method != "auto" {
+		if ffmpeg == "" {
+			ffmpeg = "ffmpeg"
+		}
+		methods, err := hwaccelMethods(ctx, ffmpeg)
+		switch {
+		case err == nil && len(methods) == 0:
+			err = errors.New("ffmpeg offers none")
+		case err == nil && !slices.Contains(methods, method):
+			err = fmt.Errorf("ffmpeg offers %s", strings.Join(methods, ", "))
+		}
+		if err != nil {
+			got.FFmpegHWAccel = ""
+			notes = append(notes, fmt.Sprintf("ffmpeg hardware decoding %q unavailable (%v); decoding on the CPU", method, err))
+		}
+	}
+	return &got, notes
+}
+
+func checkGPU(ctx context.Context, check []string) error {
+	if len(check) == 0 {
+		if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+			return nil
+		}
+		if _, err := exec.LookPath("nvidia-smi"); err != nil {
+			return errors.New("nvidia-smi not found")
+		}
+		check = []string{"nvidia-smi", "-L"}
+	}
+	output, err := runCommand(ctx, check[0], check[1:]...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(check, " "), err)
+	}
+	if check[0] == "nvidia-smi" && !strings.Contains(output, "GPU ") {
+		return errors.New("nvidia-smi lists no GPU")
+	}
+	return nil
+}
+
+// hwaccelMethods lists the methods ffmpeg -hwaccels prints.
+func hwaccelMethods(ctx context.Context, ffmpeg string) ([]string, error) {
+	output, err := runCommand(ctx, ffmpeg, "-hide_banner", "-hwaccels")
+	if err != nil {
+		return nil, err
+	}
+	_, list, _ := strings.Cut(output, "methods:")
+	return strings.Fields(list), nil
+}
+
+// jobAcceleration tracks one Process or AddChunk call: once a GPU run fails, the rest
+// of the call stays on the CPU.
+type jobAcceleration struct {
+	cpu      atomic.Bool
+	fellBack atomic.Bool
+}
+
+type accelerationKey struct{}
+
+// withAcceleration returns a context carrying the call's acceleration state; opts.CPUOnly
+// keeps the whole call on the CPU.
+func (p *Processor) withAcceleration(ctx context.Context, opts Options) context.Context {
+	if p.Acceleration == nil {
+		return ctx
+	}
+	state := &jobAcceleration{}
+	state.cpu.Store(opts.CPUOnly)
+	return context.WithValue(ctx, accelerationKey{}, state)
+}
+
+func contextAcceleration(ctx context.Context) *jobAcceleration {
+	state, _ := ctx.Value(accelerationKey{}).(*jobAcceleration)
+	return state
+}
+
+// onGPU reports whether the next run of the call may use the GPU.
+func (p *Processor) onGPU(ctx context.Context) bool {
+	state := contextAcceleration(ctx)
+	return state != nil && !state.cpu.Load()
+}
+
+// fellBackToCPU reports whether a GPU run of the call failed and the call moved to the CPU.
+func fellBackToCPU(ctx context.Context) bool {
+	state := contextAcceleration(ctx)
+	return state != nil && state.fellBack.Load()
+}
+
+// accelerated runs run once on the GPU, when the call may use it, and again on the CPU
+// should that fail for any reason but the call being cancelled. From then on the call
+// stays on the CPU.
+func (p *Processor) accelerated(ctx context.Context, gpu bool, run func(gpu bool) (string, error)) (string, error) {
+	if !gpu || !p.onGPU(ctx) {
+		return run(false)
+	}
+	logEntry, err := run(true)
+	if err == nil || ctx.Err() != nil {
+		return logEntry, err
+	}
+	state := contextAcceleration(ctx)
+	state.cpu.Store(true)
+	state.fellBack.Store(true)
+	cpuLog, err := run(false)
+	return fmt.Sprintf("%s\nthe GPU run failed; the job continues on the CPU\n%s", logEntry, cpuLog), err
+}
+
+// whisperAccelArgs are the arguments to append to a whisper run.
+func (p *Processor) whisperAccelArgs(gpu bool) []string {
+	if p.Acceleration == nil {
+		return nil
+	}
+	if gpu {
+		return p.Acceleration.WhisperArgs
+	}
+	return p.Acceleration.CPUWhisperArgs
+}
+
+// hwaccelArgs are the arguments to put before the input of a pass decoding its video.
+func (p *Processor) hwaccelArgs(gpu bool) []string {
+	if !gpu || p.Acceleration == nil || p.Acceleration.FFmpegHWAccel == "" {
+		return nil
+	}
+	return []string{"-hwaccel", p.Acceleration.FFmpegHWAccel}
+}
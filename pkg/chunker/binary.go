@@ -0,0 +1,37 @@
+package chunker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// executable returns the path of the running program; tests replace it.
+var executable = os.Executable
+
+// lookBinary finds the program name the way the processor runs it. Like exec.LookPath
+// it searches the PATH for a bare name and, on Windows, tries the PATHEXT suffixes, so
+// FFMPEG_BIN may be "ffmpeg" or "C:\ffmpeg\bin\ffmpeg" without ".exe". A bare name
+// that is not on the PATH is also looked for next to the running executable, where
+// Windows and macOS bundles usually ship ffmpeg and whisper.
+func lookBinary(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err == nil || filepath.Base(name) != name {
+		return path, err
+	}
+	if self, selfErr := executable(); selfErr == nil {
+		if bundled, bundledErr := exec.LookPath(filepath.Join(filepath.Dir(self), name)); bundledErr == nil {
+			return bundled, nil
+		}
+	}
+	return path, err
+}
+
+// commandPath returns what to start for name: the program lookBinary finds, or name
+// itself, so exec reports why it cannot be run.
+func commandPath(name string) string {
+	if path, err := lookBinary(name); err == nil {
+		return path
+	}
+	return name
+}
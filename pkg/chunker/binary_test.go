@@ -0,0 +1,69 @@
+package chunker
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeProgram creates an executable named name in dir and returns its path.
+func writeProgram(t *testing.T, dir, name string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runFrom makes lookBinary believe the running executable lives in dir.
+func runFrom(t *testing.T, dir string) {
+	t.Helper()
+	saved := executable
+	executable = func() (string, error) { return filepath.Join(dir, "audi"), nil }
+	t.Cleanup(func() { executable = saved })
+}
+
+func TestLookBinaryBundled(t *testing.T) {
+	bundle, onPath := t.TempDir(), t.TempDir()
+	t.Setenv("PATH", onPath)
+	runFrom(t, bundle)
+	bundled := writeProgram(t, bundle, "audi-test-tool")
+
+	got, err := lookBinary("audi-test-tool")
+	if err != nil || got != bundled {
+		t.Errorf("lookBinary of a program next to the executable = %q, %v; want %q", got, err, bundled)
+	}
+	if got := commandPath("audi-test-tool"); got != bundled {
+		t.Errorf("commandPath = %q, want %q", got, bundled)
+	}
+
+	// The PATH wins over the bundled copy.
+	installed := writeProgram(t, onPath, "audi-test-tool")
+	if got, err := lookBinary("audi-test-tool"); err != nil || got != installed {
+		t.Errorf("lookBinary with the program also on the PATH = %q, %v; want %q", got, err, installed)
+	}
+}
+
+func TestLookBinaryNotBundled(t *testing.T) {
+	bundle := t.TempDir()
+	t.Setenv("PATH", t.TempDir())
+	runFrom(t, bundle)
+	writeProgram(t, bundle, "audi-test-tool")
+
+	if _, err := lookBinary("audi-missing-tool"); err == nil {
+		t.Error("lookBinary found a program that exists nowhere")
+	}
+	if got := commandPath("audi-missing-tool"); got != "audi-missing-tool" {
+		t.Errorf("commandPath of a missing program = %q, want it unchanged", got)
+	}
+	// Only bare names are looked for next to the executable.
+	rel := filepath.Join("sub", "audi-test-tool")
+	if _, err := lookBinary(rel); err == nil {
+		t.Errorf("lookBinary(%q) looked next to the executable", rel)
+	}
+}
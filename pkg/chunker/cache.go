@@ -0,0 +1,179 @@
+package chunker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscriptCache stores transcripts keyed by audio content and transcription settings.
+type TranscriptCache interface {
+	Get(key string) (string, bool)
+	Put(key, transcript string) error
+}
+
+// DirCache is a TranscriptCache backed by one text file per key under Root. With Max
+// set, reading a transcript marks it as recently used, and once the files outgrow Max
+// bytes the least recently used are removed.
+type DirCache struct {
+	Root string
+	Max  int64
+
+	mu sync.Mutex
+	// size is the total the cache was last measured at plus what was put since, or -1
+	// before the first measurement. Other processes sharing Root are only seen by the
+	// next measurement.
+	size int64
+}
+
+// NewDirCache returns a cache rooted at dir holding at most max bytes, 0 meaning no
+// limit; the directory is created on first write.
+func NewDirCache(dir string, max int64) *DirCache {
+	return &DirCache{Root: dir, Max: max, size: -1}
+}
+
+// Get returns the cached transcript for key, if present.
+func (c *DirCache) Get(key string) (string, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	if c.Max > 0 {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+	}
+	return string(data), true
+}
+
+// Put writes the transcript atomically so concurrent readers never see a partial file,
+// then prunes the cache when it has outgrown Max.
+func (c *DirCache) Put(key, transcript string) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(transcript)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.prune(path, int64(len(transcript)))
+	return nil
+}
+
+// prune adds added bytes to the running total and, once it passes Max, walks the cache
+// and removes the least recently used files, sparing keep, until it is down to 90% of
+// Max, so that the next few puts do not each walk it again.
+func (c *DirCache) prune(keep string, added int64) {
+	if c.Max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size >= 0 {
+		c.size += added
+		if c.size <= c.Max {
+			return
+		}
+	}
+
+	type entry struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	_ = filepath.WalkDir(c.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{p, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	target := total
+	if total > c.Max {
+		target = c.Max / 10 * 9
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries {
+		if total <= target {
+			break
+		}
+		if e.path != keep && os.Remove(e.path) == nil {
+			total -= e.size
+		}
+	}
+	c.size = total
+}
+
+// path shards keys by their first two characters to keep directories small.
+func (c *DirCache) path(key string) string {
+	shard := key
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return filepath.Join(c.Root, shard, key+".txt")
+}
+
+// WithTranscriptCache reuses transcripts for identical chunk audio and whisper settings.
+func WithTranscriptCache(cache TranscriptCache) Option {
+	return func(p *Processor) {
+		p.Cache = cache
+	}
+}
+
+// transcriptCacheKey hashes the chunk bytes together with the whisper binary and arguments,
+// so changing the model (passed via args) or binary never returns a stale transcript.
+func (p *Processor) transcriptCacheKey(chunkPath string, args []string) (string, error) {
+	audio, err := fileSHA256(chunkPath)
+	if err != nil {
+		return "", err
+	}
+
+	key := sha256.New()
+	fmt.Fprintf(key, "%s\x00%s\x00%s", audio, p.WhisperBin, strings.Join(args, "\x00"))
+	return hex.EncodeToString(key.Sum(nil)), nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file's contents.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,55 @@
+package chunker
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirCachePrunesLeastRecentlyUsed(t *testing.T) {
+	root := t.TempDir()
+	cache := NewDirCache(root, 10*1000)
+	transcript := strings.Repeat("x", 1000)
+
+	// Put ten transcripts with increasing times, then read the oldest so it counts
+	// as recently used.
+	start := time.Now().Add(-time.Hour)
+	for i := range 10 {
+		key := fmt.Sprintf("%064d", i)
+		if err := cache.Put(key, transcript); err != nil {
+			t.Fatal(err)
+		}
+		at := start.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(cache.path(key), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := cache.Get(fmt.Sprintf("%064d", 0)); !ok {
+		t.Fatal("transcript 0 missing before the cache was full")
+	}
+	// The eleventh takes the cache past its limit, which is pruned to 90% of it.
+	if err := cache.Put(fmt.Sprintf("%064d", 10), transcript); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range map[int]bool{0: true, 1: false, 2: false, 3: true, 10: true} {
+		if _, ok := cache.Get(fmt.Sprintf("%064d", i)); ok != want {
+			t.Errorf("transcript %d cached = %v, want %v", i, ok, want)
+		}
+	}
+	var total int64
+	filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			info, _ := d.Info()
+			total += info.Size()
+		}
+		return nil
+	})
+	if total > cache.Max {
+		t.Errorf("cache holds %d bytes, want at most %d", total, cache.Max)
+	}
+}
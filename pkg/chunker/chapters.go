@@ -0,0 +1,257 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Chapter is a titled section of the recording, timed against the original upload like
+// the subtitles, so it stays put when the job's time offset changes.
+type Chapter struct {
+	StartSeconds float64 `json:"startSeconds"`
+	EndSeconds   float64 `json:"endSeconds"`
+	Title        string  `json:"title"`
+}
+
+const (
+	// minChapterSeconds keeps detected chapters from being shorter than a minute.
+	minChapterSeconds = 60.0
+	// chapterWindowSeconds is how much transcript on each side of a candidate boundary is
+	// compared to find a change of topic.
+	chapterWindowSeconds = 90.0
+	// chapterPauseSeconds is the gap between segments that counts as a full pause; a
+	// silent chunk leaves a gap at least that long.
+	chapterPauseSeconds = 4.0
+)
+
+// DetectChapters splits a job's main transcript into chapters. Every gap between two
+// subtitle cues is a candidate boundary, scored by how different the words before and
+// after it are and by how long the speakers paused there. Boundaries scoring well above
+// the average become chapter starts, best first, as long as every chapter lasts at least
+// minChapterSeconds. Chapters are titled with their key terms; the first starts at zero.
+func DetectChapters(jobDir string, chunks []Chunk) []Chapter {
+	cues := SubtitleCues(jobDir, chunks)
+	if len(cues) == 0 {
+		return nil
+	}
+	end := cues[len(cues)-1].End
+	for _, chunk := range chunks {
+		if chunk.Track == chunks[0].Track {
+			end = max(end, chunk.StartSeconds+chunk.DurationSeconds)
+		}
+	}
+
+	bags := make([]map[string]int, len(cues))
+	for i, cue := range cues {
+		bags[i] = chapterTerms(cue.Text)
+	}
+	type candidate struct {
+		cue   int
+		score float64
+	}
+	var candidates []candidate
+	var sum, sumSquares float64
+	for i := 1; i < len(cues); i++ {
+		at := cues[i].Start
+		if at < minChapterSeconds || end-at < minChapterSeconds {
+			continue
+		}
+		before, after := make(map[string]int), make(map[string]int)
+		for j := i - 1; j >= 0 && cues[j].End > at-chapterWindowSeconds; j-- {
+			addTerms(before, bags[j])
+		}
+		for j := i; j < len(cues) && cues[j].Start < at+chapterWindowSeconds; j++ {
+			addTerms(after, bags[j])
+		}
+		pause := min(max(cues[i].Start-cues[i-1].End, 0)/chapterPauseSeconds, 1)
+		score := 1 - cosineSimilarity(before, after) + pause/2
+		candidates = append(candidates, candidate{cue: i, score: score})
+		sum += score
+		sumSquares += score * score
+	}
+
+	var starts []float64
+	if n := float64(len(candidates)); n > 0 {
+		mean := sum / n
+		threshold := mean + math.Sqrt(max(sumSquares/n-mean*mean, 0))/2
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+		for _, c := range candidates {
+			if c.score <= threshold {
+				break
+			}
+			at := cues[c.cue].Start
+			fits := true
+			for _, s := range starts {
+				fits = fits && math.Abs(at-s) >= minChapterSeconds
+			}
+			if fits {
+				starts = append(starts, at)
+			}
+		}
+		sort.Float64s(starts)
+	}
+
+	chapters := []Chapter{{StartSeconds: 0}}
+	for _, at := range starts {
+		chapters[len(chapters)-1].EndSeconds = at
+		chapters = append(chapters, Chapter{StartSeconds: at})
+	}
+	chapters[len(chapters)-1].EndSeconds = end
+	for i := range chapters {
+		var text strings.Builder
+		for _, cue := range cues {
+			if cue.Start >= chapters[i].StartSeconds && cue.Start < chapters[i].EndSeconds {
+				text.WriteString(cue.Text + "\n")
+			}
+		}
+		chapters[i].Title = chapterTitle(text.String(), i+1)
+	}
+	return chapters
+}
+
+// chapterTerms counts the words of text that LocalKeywords would consider key terms.
+func chapterTerms(text string) map[string]int {
+	terms := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		if keywordCandidate(word) {
+			terms[word]++
+		}
+	}
+	return terms
+}
+
+func addTerms(to, from map[string]int) {
+	for term, n := range from {
+		to[term] += n
+	}
+}
+
+// cosineSimilarity compares two term counts; it is 0 when either is empty.
+func cosineSimilarity(a, b map[string]int) float64 {
+	var dot, normA, normB float64
+	for term, n := range a {
+		dot += float64(n * b[term])
+		normA += float64(n * n)
+	}
+	for _, n := range b {
+		normB += float64(n * n)
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// chapterTitle names a chapter after its two strongest keywords, skipping one that
+// repeats a word of the first, or by number when it has none.
+func chapterTitle(text string, number int) string {
+	keywords, _ := LocalKeywords{Max: 4}.Keywords(context.Background(), text)
+	sort.SliceStable(keywords, func(i, j int) bool { return keywords[i].Score > keywords[j].Score })
+	var parts []string
+	used := make(map[string]bool)
+	for _, k := range keywords {
+		words := strings.Fields(strings.ToLower(k.Text))
+		if len(parts) == 2 || slices.ContainsFunc(words, func(w string) bool { return used[w] }) {
+			continue
+		}
+		parts = append(parts, k.Text)
+		for _, w := range words {
+			used[w] = true
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("Chapter %d", number)
+	}
+	title := []rune(strings.Join(parts, ", "))
+	title[0] = unicode.ToUpper(title[0])
+	return string(title)
+}
+
+// ChaptersFromSummary turns the chapters an LLM summary proposed into Chapters ending at
+// the next one, the last at end. A first chapter starting after zero is moved to zero,
+// as video platforms require.
+func ChaptersFromSummary(summary []SummaryChapter, end float64) []Chapter {
+	var chapters []Chapter
+	for _, ch := range summary {
+		if ch.StartSeconds >= end {
+			break
+		}
+		if n := len(chapters); n > 0 {
+			if ch.StartSeconds <= chapters[n-1].StartSeconds {
+				continue
+			}
+			chapters[n-1].EndSeconds = ch.StartSeconds
+		}
+		chapters = append(chapters, Chapter{StartSeconds: ch.StartSeconds, EndSeconds: end, Title: ch.Title})
+	}
+	if len(chapters) > 0 {
+		chapters[0].StartSeconds = 0
+	}
+	return chapters
+}
+
+// WriteYouTubeChapters formats chapters as the "0:00 Title" lines video descriptions
+// take, with hours only when the recording needs them.
+func WriteYouTubeChapters(chapters []Chapter) string {
+	hours := len(chapters) > 0 && chapters[len(chapters)-1].StartSeconds >= 3600
+	var b strings.Builder
+	for _, ch := range chapters {
+		s := int(ch.StartSeconds)
+		if hours {
+			fmt.Fprintf(&b, "%d:%02d:%02d %s\n", s/3600, s/60%60, s%60, ch.Title)
+		} else {
+			fmt.Fprintf(&b, "%d:%02d %s\n", s/60, s%60, ch.Title)
+		}
+	}
+	return b.String()
+}
+
+// WriteFFMetadata formats chapters as an ffmpeg metadata file, which ffmpeg -i
+// <file> -map_metadata 1 embeds into MP3, M4A, MP4, or MKV outputs.
+func WriteFFMetadata(chapters []Chapter) string {
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, ch := range chapters {
+		fmt.Fprintf(&b, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(math.Round(ch.StartSeconds*1000)), int64(math.Round(ch.EndSeconds*1000)), escape.Replace(ch.Title))
+	}
+	return b.String()
+}
+
+// writeChapters stores chapters as transcripts/chapters.txt and transcripts/chapters.ffmeta.
+// They come from the summary when the LLM proposed some and are detected otherwise.
+func writeChapters(jobDir string, chunks []Chunk, summary *Summary) (chapters []Chapter, textFile, metadataFile string, logs []string, err error) {
+	if summary != nil && len(summary.Chapters) > 0 {
+		end := 0.0
+		for _, chunk := range chunks {
+			end = max(end, chunk.StartSeconds+chunk.DurationSeconds)
+		}
+		chapters = ChaptersFromSummary(summary.Chapters, end)
+		logs = append(logs, fmt.Sprintf("took %d chapters from the summary", len(chapters)))
+	} else {
+		chapters = DetectChapters(jobDir, chunks)
+		if len(chapters) == 0 {
+			return nil, "", "", []string{"no chunk was transcribed; chapters skipped"}, nil
+		}
+		logs = append(logs, fmt.Sprintf("detected %d chapters", len(chapters)))
+	}
+	if len(chapters) < 3 {
+		logs = append(logs, "YouTube shows chapters only when there are at least three")
+	}
+
+	textFile, metadataFile = "transcripts/chapters.txt", "transcripts/chapters.ffmeta"
+	for rel, content := range map[string]string{textFile: WriteYouTubeChapters(chapters), metadataFile: WriteFFMetadata(chapters)} {
+		if err := writeJobFile(jobDir, rel, []byte(content)); err != nil {
+			return nil, "", "", logs, fmt.Errorf("writing chapters: %w", err)
+		}
+	}
+	return chapters, textFile, metadataFile, logs, nil
+}
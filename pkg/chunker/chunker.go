@@ -0,0 +1,53 @@
+// Package chunker splits media files into fixed-length audio chunks with
+// optional Base64 dumps and whisper.cpp transcripts.
+//
+// It is the same pipeline the audi server and CLI use, exposed so other Go
+// programs can embed it:
+//
+//	c := chunker.New(chunker.WithWhisper("/usr/local/bin/whisper", "-m", "ggml-base.en.bin"))
+//	res, err := c.Process(ctx, "out", "talk.mp4", chunker.Options{ChunkDurationSeconds: 300, Transcribe: true})
+package chunker
+
+import "context"
+
+// Chunker turns an input media file into audio chunks written under jobDir.
+type Chunker interface {
+	Process(ctx context.Context, jobDir, inputPath string, opts Options) (Result, error)
+}
+
+var _ Chunker = (*Processor)(nil)
+
+// Option configures a Processor created by New.
+type Option func(*Processor)
+
+// WithFFmpeg overrides the ffmpeg executable name or path.
+func WithFFmpeg(bin string) Option {
+	return func(p *Processor) {
+		p.FFmpegBin = bin
+	}
+}
+
+// WithWhisper enables transcription using bin, passing args before the per-chunk flags.
+func WithWhisper(bin string, args ...string) Option {
+	return func(p *Processor) {
+		p.WhisperBin = bin
+		p.WhisperArgs = append([]string(nil), args...)
+	}
+}
+
+// WithResidentWhisper transcribes through r, a whisper server the caller starts and
+// closes, falling back to the WithWhisper binary while it is unavailable.
+func WithResidentWhisper(r *ResidentWhisper) Option {
+	return func(p *Processor) {
+		p.Resident = r
+	}
+}
+
+// New builds a Processor; without options it runs "ffmpeg" from $PATH and skips transcription.
+func New(opts ...Option) *Processor {
+	p := &Processor{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
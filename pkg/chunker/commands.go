@@ -0,0 +1,77 @@
+package chunker
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandRun is one external program the processor started: the exact argument vector,
+// when it ran, for how long, and how it ended. Together with the job's files it is
+// enough to re-run a failing ffmpeg or whisper step by hand.
+type CommandRun struct {
+	// Args is the full argument vector; Args[0] is the binary as configured.
+	Args            []string  `json:"args"`
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// ExitCode is the process exit status, or -1 when it could not be started or was killed.
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	// Probe marks an ffmpeg run that only reads the input's header. ffmpeg exits non-zero
+	// without an output file, so its ExitCode does not mean the step failed.
+	Probe bool `json:"probe,omitempty"`
+}
+
+// Failed reports whether the command did not complete successfully. Probes never fail.
+func (c CommandRun) Failed() bool {
+	return !c.Probe && (c.ExitCode != 0 || c.Error != "")
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// CommandLine renders Args for a POSIX shell, quoting where needed, for copy and paste.
+func (c CommandRun) CommandLine() string {
+	quoted := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		if shellSafe.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CommandLog collects the CommandRuns of everything started under a context carrying it.
+// It is safe for concurrent use, since transcript sets run in parallel.
+type CommandLog struct {
+	mu   sync.Mutex
+	runs []CommandRun
+}
+
+type commandLogKey struct{}
+
+// WithCommandLog returns a context under which every external command the processor
+// starts is recorded in log.
+func WithCommandLog(ctx context.Context, log *CommandLog) context.Context {
+	return context.WithValue(ctx, commandLogKey{}, log)
+}
+
+// Runs returns the recorded commands in the order they finished.
+func (l *CommandLog) Runs() []CommandRun {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]CommandRun(nil), l.runs...)
+}
+
+func recordCommand(ctx context.Context, run CommandRun) {
+	log, ok := ctx.Value(commandLogKey{}).(*CommandLog)
+	if !ok {
+		return
+	}
+	log.mu.Lock()
+	log.runs = append(log.runs, run)
+	log.mu.Unlock()
+}
@@ -0,0 +1,123 @@
+package chunker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EscalationPolicy re-transcribes chunks whose confidence is below MinConfidence with
+// Args appended to the whisper arguments, typically a larger model ("-m ggml-large.bin").
+// Easy audio keeps the speed of the default model; only hard chunks pay for the big one.
+type EscalationPolicy struct {
+	// MinConfidence is the mean token probability, between 0 and 1, below which a chunk escalates.
+	MinConfidence float64  `json:"minConfidence"`
+	Args          []string `json:"args"`
+}
+
+// Enabled reports whether the policy has both a threshold and something to escalate to.
+func (e EscalationPolicy) Enabled() bool {
+	return e.MinConfidence > 0 && len(e.Args) > 0
+}
+
+// Validate rejects thresholds outside (0, 1] and thresholds without escalation arguments.
+func (e EscalationPolicy) Validate() error {
+	if e.MinConfidence < 0 || e.MinConfidence > 1 {
+		return fmt.Errorf("escalation confidence %.2f must be between 0 and 1", e.MinConfidence)
+	}
+	if e.MinConfidence > 0 && len(e.Args) == 0 {
+		return errors.New("escalation needs whisper arguments to re-run with, e.g. a larger model")
+	}
+	return nil
+}
+
+// wholeJSONFlag asks whisper.cpp for <prefix>.json including per-token probabilities.
+const wholeJSONFlag = "-ojf"
+
+// transcribeEscalating transcribes a chunk with the job's base arguments, measures the
+// confidence, and re-runs it under the policy when the confidence is too low. The final
+// transcript is cached under a key covering the policy, so a cache hit returns the
+// escalated text too; confidence is then unknown and reported as 0. With keepJSON the
+// final run's transcriptPrefix.json is left for the caller and cached with the text.
+func (p *Processor) transcribeEscalating(ctx context.Context, chunkPath, transcriptPrefix string, baseArgs []string, policy EscalationPolicy, keepJSON bool) (confidence float64, escalated bool, logEntry string, err error) {
+	keyArgs := append(append([]string{}, baseArgs...), "escalate-below="+strconv.FormatFloat(policy.MinConfidence, 'f', -1, 64))
+	keyArgs = append(keyArgs, policy.Args...)
+	if keepJSON {
+		keyArgs = append(keyArgs, wholeJSONFlag)
+	}
+
+	logEntry, err = p.cachedTranscript(chunkPath, transcriptPrefix, keyArgs, func() (string, error) {
+		firstArgs := append(append([]string{}, baseArgs...), wholeJSONFlag)
+		out, err := p.runWhisper(ctx, chunkPath, transcriptPrefix, firstArgs)
+		if err != nil {
+			return out, err
+		}
+		first, err := readWhisperConfidence(transcriptPrefix + ".json")
+		if err != nil {
+			return out + fmt.Sprintf("\nconfidence unavailable, keeping transcript: %v", err), nil
+		}
+		confidence = first
+		if first >= policy.MinConfidence {
+			return out, nil
+		}
+
+		escalatedArgs := append(append(append([]string{}, baseArgs...), policy.Args...), wholeJSONFlag)
+		more, err := p.runWhisper(ctx, chunkPath, transcriptPrefix, escalatedArgs)
+		out += fmt.Sprintf("\nconfidence %.2f below %.2f; re-transcribing with %s\n", first, policy.MinConfidence, strings.Join(policy.Args, " ")) + more
+		if err != nil {
+			return out, err
+		}
+		escalated = true
+		if second, err := readWhisperConfidence(transcriptPrefix + ".json"); err == nil {
+			confidence = second
+		}
+		return out, nil
+	})
+	if !keepJSON {
+		_ = os.Remove(transcriptPrefix + ".json")
+	}
+	return confidence, escalated, logEntry, err
+}
+
+// whisperFullJSON is the subset of whisper.cpp's --output-json-full output we read.
+type whisperFullJSON struct {
+	Transcription []struct {
+		Tokens []struct {
+			Text string  `json:"text"`
+			P    float64 `json:"p"`
+		} `json:"tokens"`
+	} `json:"transcription"`
+}
+
+// readWhisperConfidence returns the mean probability of the text tokens in a whisper.cpp
+// full JSON file, skipping special tokens such as [_BEG_] and [_TT_150].
+func readWhisperConfidence(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var parsed whisperFullJSON
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return 0, fmt.Errorf("decoding whisper JSON: %w", err)
+	}
+
+	var sum float64
+	var n int
+	for _, segment := range parsed.Transcription {
+		for _, token := range segment.Tokens {
+			if strings.HasPrefix(token.Text, "[_") {
+				continue
+			}
+			sum += token.P
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, errors.New("whisper reported no tokens")
+	}
+	return sum / float64(n), nil
+}
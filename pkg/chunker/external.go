@@ -0,0 +1,66 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AddChunk registers a chunk cut outside the processor. The WAV file must already be at
+// chunk.AudioFile inside jobDir; it then gets the same post-processing as a segmented
+// chunk (Base64 dump, checksum, silence check, quality gate, and transcription) according
+// to opts. A zero DurationSeconds is read from the WAV header. whisper.cpp expects 16 kHz
+// 16-bit PCM, so producers that want transcripts should send that format.
+func (p *Processor) AddChunk(ctx context.Context, jobDir string, chunk Chunk, opts Options) (Chunk, []string, error) {
+	chunkPath := filepath.Join(jobDir, filepath.FromSlash(chunk.AudioFile))
+	duration, err := WAVDuration(chunkPath)
+	if err != nil {
+		return chunk, nil, fmt.Errorf("reading chunk audio: %w", err)
+	}
+	if chunk.DurationSeconds <= 0 {
+		chunk.DurationSeconds = duration
+	}
+
+	for _, dir := range []string{"base64", "transcripts"} {
+		if err := os.MkdirAll(filepath.Join(jobDir, dir), 0o755); err != nil {
+			return chunk, nil, fmt.Errorf("creating processing directory: %w", err)
+		}
+	}
+
+	ctx = p.withAcceleration(p.withCommandLimits(p.withHooks(ctx, opts), opts), opts)
+	logs, err := p.finishChunk(ctx, jobDir, chunkPath, &chunk, opts)
+	if log := outputLogOf(ctx); log != nil {
+		log.Notes(logs)
+	}
+	if err == nil {
+		notifyChunkDone(ctx, chunk)
+	}
+	return chunk, logs, err
+}
+
+// RetryTranscription transcribes a chunk already in place under chunks/ again, e.g. one
+// whose TranscriptError shows whisper failed on it, replacing everything derived from
+// its transcript. Options apply as for AddChunk.
+func (p *Processor) RetryTranscription(ctx context.Context, jobDir string, chunk Chunk, opts Options) (Chunk, []string, error) {
+	chunk.TranscriptError = ""
+	chunk.TranscriptPreview = ""
+	chunk.TranscriptFile = ""
+	chunk.WordsFile = ""
+	chunk.Confidence = 0
+	chunk.Escalated = false
+	chunk.Transcripts = nil
+	chunk.Keywords = nil
+	chunk.Sentiment = nil
+	chunk.RedactedTranscriptFile = ""
+	chunk.RedactedAudioFile = ""
+	chunk.Redactions = 0
+	chunk.PII = nil
+	chunk.MaskedTranscriptFile = ""
+	return p.AddChunk(ctx, jobDir, chunk, opts)
+}
+
+// WAVDuration returns the length in seconds of a PCM WAV file, failing for anything else.
+func WAVDuration(path string) (float64, error) {
+	return wavDuration(path)
+}
@@ -0,0 +1,91 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Fault is a failure injected on purpose, so operators can watch a job fail the way a
+// real one would and check their alerting, retries, and webhooks before relying on them.
+// The empty fault injects nothing.
+type Fault string
+
+const (
+	FaultNone Fault = ""
+	// FaultFFmpeg makes the extraction pass exit with status 1; the job fails.
+	FaultFFmpeg Fault = "ffmpeg"
+	// FaultWhisperTimeout times out every whisper run; the chunks are kept without
+	// transcripts, as when whisper hangs.
+	FaultWhisperTimeout Fault = "whisper-timeout"
+	// FaultDiskFull fails writing the first chunk's outputs with ENOSPC; the job fails.
+	FaultDiskFull Fault = "disk-full"
+)
+
+// Faults lists the faults ParseFault accepts.
+var Faults = []Fault{FaultFFmpeg, FaultWhisperTimeout, FaultDiskFull}
+
+// ParseFault validates a user-provided fault; "" and "none" inject nothing.
+func ParseFault(value string) (Fault, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "none" {
+		return FaultNone, nil
+	}
+	for _, f := range Faults {
+		if Fault(value) == f {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Faults))
+	for i, f := range Faults {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown failure %q (want %s)", value, strings.Join(names, ", "))
+}
+
+// errInjected marks every injected failure, so logs and job errors say it was simulated.
+var errInjected = errors.New("injected failure")
+
+// injectedTimeout is the run time reported for an injected whisper timeout.
+const injectedTimeout = 10 * time.Minute
+
+// injectCommandFailure records a failed run of name in the command log, as the real run
+// would have been, and returns its error.
+func injectCommandFailure(ctx context.Context, exitCode int, cause error, name string, args ...string) error {
+	err := fmt.Errorf("%w: %w", errInjected, cause)
+	run := CommandRun{
+		Args:      append([]string{name}, args...),
+		StartedAt: time.Now(),
+		ExitCode:  exitCode,
+		Error:     err.Error(),
+	}
+	if errors.Is(cause, context.DeadlineExceeded) {
+		run.DurationSeconds = injectedTimeout.Seconds()
+	}
+	streamRun(ctx, run)
+	recordCommand(ctx, run)
+	notifyCommandFinished(ctx, run)
+	return err
+}
+
+// injectFFmpegFailure stands in for the extraction pass under FaultFFmpeg.
+func injectFFmpegFailure(ctx context.Context, ffmpeg, inputPath string) (string, error) {
+	err := injectCommandFailure(ctx, 1, errors.New("exit status 1"), ffmpeg, "-i", inputPath)
+	return "ffmpeg: " + err.Error(), fmt.Errorf("running ffmpeg: %w", err)
+}
+
+// injectWhisperTimeout stands in for a whisper run under FaultWhisperTimeout.
+func (p *Processor) injectWhisperTimeout(ctx context.Context, chunkPath string) (string, error) {
+	cause := fmt.Errorf("whisper did not finish within %s: %w", injectedTimeout, context.DeadlineExceeded)
+	err := injectCommandFailure(ctx, -1, cause, p.WhisperBin, "-f", chunkPath)
+	return "whisper: " + err.Error(), err
+}
+
+// injectDiskFull is the error finishing chunkPath fails with under FaultDiskFull.
+func injectDiskFull(chunkPath string) error {
+	return fmt.Errorf("%w: %w", errInjected, &fs.PathError{Op: "write", Path: chunkPath, Err: syscall.ENOSPC})
+}
@@ -0,0 +1,46 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilterPreset names a vetted ffmpeg audio filter chain that can be offered to end users
+// without letting them pass arbitrary filter graphs.
+type FilterPreset string
+
+const (
+	FilterNone      FilterPreset = "none"
+	FilterHighpass  FilterPreset = "highpass"
+	FilterDenoise   FilterPreset = "denoise"
+	FilterNormalize FilterPreset = "normalize"
+	FilterVoice     FilterPreset = "voice"
+)
+
+// FilterPresets lists the presets in the order they should be offered.
+var FilterPresets = []FilterPreset{FilterNone, FilterHighpass, FilterDenoise, FilterNormalize, FilterVoice}
+
+var filterChains = map[FilterPreset]string{
+	FilterNone:      "",
+	FilterHighpass:  "highpass=f=80",
+	FilterDenoise:   "highpass=f=80,afftdn=nf=-25",
+	FilterNormalize: "dynaudnorm",
+	FilterVoice:     "highpass=f=80,afftdn=nf=-25,dynaudnorm",
+}
+
+// Chain returns the ffmpeg -af argument for the preset; empty for FilterNone.
+func (f FilterPreset) Chain() string {
+	return filterChains[f]
+}
+
+// ParseFilterPreset validates a user-provided preset name, defaulting to FilterNone.
+func ParseFilterPreset(value string) (FilterPreset, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return FilterNone, nil
+	}
+	if _, ok := filterChains[FilterPreset(value)]; ok {
+		return FilterPreset(value), nil
+	}
+	return "", fmt.Errorf("unknown filter preset %q (want none, highpass, denoise, normalize, or voice)", value)
+}
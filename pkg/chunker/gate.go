@@ -0,0 +1,129 @@
+package chunker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Skip reasons stored in Chunk.SkipReason when a chunk is left out of transcription.
+const (
+	SkipReasonSilent   = "silent"
+	SkipReasonTooShort = "too_short"
+	SkipReasonTooQuiet = "too_quiet"
+	SkipReasonClipping = "clipping"
+)
+
+// QualityGate holds thresholds a chunk must meet before it is sent to the transcriber.
+// Zero values disable the corresponding check, so the zero gate lets everything through.
+type QualityGate struct {
+	// MinLoudnessDB is the lowest acceptable RMS level of the whole chunk in dBFS (e.g. -45).
+	MinLoudnessDB float64 `json:"minLoudnessDb,omitempty"`
+	// MinDurationSeconds rejects chunks shorter than this, typically a stray final remainder.
+	MinDurationSeconds float64 `json:"minDurationSeconds,omitempty"`
+	// MaxClippingPercent rejects chunks with more than this share of samples at full scale.
+	MaxClippingPercent float64 `json:"maxClippingPercent,omitempty"`
+}
+
+// Enabled reports whether any check is configured.
+func (g QualityGate) Enabled() bool {
+	return g.MinLoudnessDB != 0 || g.MinDurationSeconds > 0 || g.MaxClippingPercent > 0
+}
+
+// Validate rejects thresholds that can never be met or make no sense.
+func (g QualityGate) Validate() error {
+	if g.MinLoudnessDB > 0 {
+		return fmt.Errorf("minimum loudness %.1f dBFS must not be above 0", g.MinLoudnessDB)
+	}
+	if g.MinDurationSeconds < 0 {
+		return errors.New("minimum chunk duration must not be negative")
+	}
+	if g.MaxClippingPercent < 0 || g.MaxClippingPercent > 100 {
+		return fmt.Errorf("maximum clipping %.1f%% must be between 0 and 100", g.MaxClippingPercent)
+	}
+	return nil
+}
+
+// Check measures the chunk at path and returns the first failed check as a skip reason
+// plus a human-readable detail, or "" when the chunk passes. durationSeconds is the
+// chunk's source duration, without padding.
+func (g QualityGate) Check(path string, durationSeconds float64) (reason, detail string, err error) {
+	if g.MinDurationSeconds > 0 && durationSeconds < g.MinDurationSeconds {
+		return SkipReasonTooShort, fmt.Sprintf("%.2fs is shorter than %.2fs", durationSeconds, g.MinDurationSeconds), nil
+	}
+	if g.MinLoudnessDB == 0 && g.MaxClippingPercent <= 0 {
+		return "", "", nil
+	}
+
+	levels, err := measureLevels(path)
+	if err != nil {
+		return "", "", err
+	}
+	if g.MinLoudnessDB != 0 && levels.rmsDB < g.MinLoudnessDB {
+		return SkipReasonTooQuiet, fmt.Sprintf("%.1f dBFS RMS is below %.1f dBFS", levels.rmsDB, g.MinLoudnessDB), nil
+	}
+	if g.MaxClippingPercent > 0 && levels.clippedPercent > g.MaxClippingPercent {
+		return SkipReasonClipping, fmt.Sprintf("%.2f%% of samples clip, above %.2f%%", levels.clippedPercent, g.MaxClippingPercent), nil
+	}
+	return "", "", nil
+}
+
+// audioLevels summarises a chunk's signal for the quality gate.
+type audioLevels struct {
+	rmsDB          float64
+	clippedPercent float64
+}
+
+// measureLevels reads a 16-bit PCM WAV file once and computes its overall RMS level and
+// the share of samples at or beyond full scale. Padding added around the chunk counts
+// towards the RMS, which lowers it slightly for very short chunks.
+func measureLevels(path string) (audioLevels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return audioLevels{}, err
+	}
+	defer file.Close()
+
+	info, err := readWAVHeader(file)
+	if err != nil {
+		return audioLevels{}, err
+	}
+	if info.bitsPerSample != 16 {
+		return audioLevels{}, errors.New("level measurement needs 16-bit PCM")
+	}
+
+	reader := bufio.NewReader(io.LimitReader(file, int64(info.dataSize)))
+	buf := make([]byte, 64<<10)
+	var sum float64
+	var samples, clipped int64
+	for {
+		n, err := io.ReadFull(reader, buf)
+		for i := 0; i+1 < n; i += 2 {
+			v := int16(binary.LittleEndian.Uint16(buf[i:]))
+			if v >= math.MaxInt16 || v <= math.MinInt16+1 {
+				clipped++
+			}
+			f := float64(v)
+			sum += f * f
+			samples++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return audioLevels{}, err
+		}
+	}
+
+	if samples == 0 || sum == 0 {
+		return audioLevels{rmsDB: math.Inf(-1)}, nil
+	}
+	return audioLevels{
+		rmsDB:          10 * math.Log10(sum/float64(samples)/(math.MaxInt16*math.MaxInt16)),
+		clippedPercent: 100 * float64(clipped) / float64(samples),
+	}, nil
+}
@@ -0,0 +1,100 @@
+package chunker
+
+import "context"
+
+// Hook observes a Process or AddChunk call. A hook implements any of PhaseHook,
+// StageHook, ChunkHook, and CommandHook; the processor calls whichever of them it finds. Hooks run
+// synchronously on the processing goroutine, so they should return quickly; command
+// hooks may be called concurrently while transcript sets run in parallel.
+type Hook any
+
+// PhaseHook is told when processing enters a stage (StageExtract, StagePostprocess, or
+// StageTranscribe).
+type PhaseHook interface {
+	OnPhaseStart(ctx context.Context, stage string)
+}
+
+// StageHook is told when a pipeline stage starts and when it ends successfully. A
+// failure is not reported here; Job.EndStages charges the error Process returns to the
+// stage it happened in.
+type StageHook interface {
+	OnStageStart(ctx context.Context, stage PipelineStage)
+	OnStageEnd(ctx context.Context, stage PipelineStage)
+}
+
+// ChunkHook is told when a chunk is fully post-processed, before the next one starts.
+// The chunk's paths are relative to the job directory.
+type ChunkHook interface {
+	OnChunkDone(ctx context.Context, chunk Chunk)
+}
+
+// CommandHook is told about every external command once it has exited, whether or not
+// it succeeded.
+type CommandHook interface {
+	OnCommandFinished(ctx context.Context, run CommandRun)
+}
+
+// WithHooks attaches hooks that observe every call on the Processor. Per-call hooks go
+// in Options.Hooks.
+func WithHooks(hooks ...Hook) Option {
+	return func(p *Processor) {
+		p.Hooks = append(p.Hooks, hooks...)
+	}
+}
+
+type hooksKey struct{}
+
+// withHooks returns a context carrying the processor's hooks plus the call's own, so
+// helpers deep in the pipeline reach them without extra parameters.
+func (p *Processor) withHooks(ctx context.Context, opts Options) context.Context {
+	if len(p.Hooks) == 0 && len(opts.Hooks) == 0 {
+		return ctx
+	}
+	hooks := append(append([]Hook(nil), p.Hooks...), opts.Hooks...)
+	return context.WithValue(ctx, hooksKey{}, hooks)
+}
+
+func contextHooks(ctx context.Context) []Hook {
+	hooks, _ := ctx.Value(hooksKey{}).([]Hook)
+	return hooks
+}
+
+func notifyPhaseStart(ctx context.Context, stage string) {
+	for _, h := range contextHooks(ctx) {
+		if h, ok := h.(PhaseHook); ok {
+			h.OnPhaseStart(ctx, stage)
+		}
+	}
+}
+
+func notifyStageStart(ctx context.Context, stage PipelineStage) {
+	for _, h := range contextHooks(ctx) {
+		if h, ok := h.(StageHook); ok {
+			h.OnStageStart(ctx, stage)
+		}
+	}
+}
+
+func notifyStageEnd(ctx context.Context, stage PipelineStage) {
+	for _, h := range contextHooks(ctx) {
+		if h, ok := h.(StageHook); ok {
+			h.OnStageEnd(ctx, stage)
+		}
+	}
+}
+
+func notifyChunkDone(ctx context.Context, chunk Chunk) {
+	for _, h := range contextHooks(ctx) {
+		if h, ok := h.(ChunkHook); ok {
+			h.OnChunkDone(ctx, chunk)
+		}
+	}
+}
+
+func notifyCommandFinished(ctx context.Context, run CommandRun) {
+	for _, h := range contextHooks(ctx) {
+		if h, ok := h.(CommandHook); ok {
+			h.OnCommandFinished(ctx, run)
+		}
+	}
+}
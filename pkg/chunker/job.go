@@ -0,0 +1,390 @@
+package chunker
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// JobStatus represents the lifecycle stage of a processing job.
+type JobStatus string
+
+const (
+	JobStatusPending    JobStatus = "pending"
+	JobStatusProcessing JobStatus = "processing"
+	JobStatusCompleted  JobStatus = "completed"
+	JobStatusFailed     JobStatus = "failed"
+	// JobStatusReceiving marks a pushed job still accepting chunks from its producer.
+	JobStatusReceiving JobStatus = "receiving"
+)
+
+// Job sources stored in Job.Source; uploads through the web form leave it empty.
+const (
+	// JobSourcePush marks jobs whose chunks were cut elsewhere and pushed over the API, so
+	// there is no original upload.
+	JobSourcePush = "push"
+	// JobSourceOpenAI marks jobs created through the OpenAI-compatible transcription endpoint.
+	JobSourceOpenAI = "openai"
+	// JobSourceWatch marks jobs created from a file dropped into the watched directory.
+	JobSourceWatch = "watch"
+)
+
+// Chunk captures metadata for a single audio slice derived from the upload.
+type Chunk struct {
+	Index             int               `json:"index"`
+	Track             int               `json:"track,omitempty"`
+	StartSeconds      float64           `json:"startSeconds"`
+	DurationSeconds   float64           `json:"durationSeconds"`
+	AudioFile         string            `json:"audioFile,omitempty"`
+	Checksum          string            `json:"checksum,omitempty"`
+	Base64File        string            `json:"base64File,omitempty"`
+	TranscriptFile    string            `json:"transcriptFile,omitempty"`
+	TranscriptPreview string            `json:"transcriptPreview,omitempty"`
+	TranscriptError   string            `json:"transcriptError,omitempty"`
+	WordsFile         string            `json:"wordsFile,omitempty"`
+	Confidence        float64           `json:"confidence,omitempty"`
+	Escalated         bool              `json:"escalated,omitempty"`
+	Silent            bool              `json:"silent,omitempty"`
+	SkipReason        string            `json:"skipReason,omitempty"`
+	Transcripts       []ChunkTranscript `json:"transcripts,omitempty"`
+	Keywords          []Keyword         `json:"keywords,omitempty"`
+	Sentiment         *Sentiment        `json:"sentiment,omitempty"`
+	// VideoFile is the stream-copied video clip containing the chunk's start. It begins
+	// on a keyframe, at VideoStartSeconds, so its span is close to but not exactly the chunk's.
+	VideoFile            string  `json:"videoFile,omitempty"`
+	VideoStartSeconds    float64 `json:"videoStartSeconds,omitempty"`
+	VideoDurationSeconds float64 `json:"videoDurationSeconds,omitempty"`
+	// RedactedTranscriptFile and RedactedAudioFile are the redacted variants of the
+	// transcript and audio, when requested; Redactions counts the passages removed.
+	RedactedTranscriptFile string `json:"redactedTranscriptFile,omitempty"`
+	RedactedAudioFile      string `json:"redactedAudioFile,omitempty"`
+	Redactions             int    `json:"redactions,omitempty"`
+	// PII lists the personal information found in the main transcript, which
+	// MaskedTranscriptFile repeats with every finding masked.
+	PII                  []PIIFinding `json:"pii,omitempty"`
+	MaskedTranscriptFile string       `json:"maskedTranscriptFile,omitempty"`
+}
+
+// Job persists everything the UI needs to render the processing results.
+type Job struct {
+	ID                     string            `json:"id"`
+	Title                  string            `json:"title,omitempty"`
+	Source                 string            `json:"source,omitempty"`
+	Preset                 string            `json:"preset,omitempty"`
+	OriginalFileName       string            `json:"originalFileName"`
+	OriginalVideoPath      string            `json:"originalVideoPath"`
+	DiscardOriginal        bool              `json:"discardOriginal,omitempty"`
+	OriginalDiscardedAt    *time.Time        `json:"originalDiscardedAt,omitempty"`
+	OriginalSHA256         string            `json:"originalSha256,omitempty"`
+	ArtefactsPurgedAt      *time.Time        `json:"artefactsPurgedAt,omitempty"`
+	SegmentKey             string            `json:"segmentKey,omitempty"`
+	ReusedChunksFrom       string            `json:"reusedChunksFrom,omitempty"`
+	RechunkedFrom          string            `json:"rechunkedFrom,omitempty"`
+	ImportedFrom           string            `json:"importedFrom,omitempty"`
+	ImportedAt             *time.Time        `json:"importedAt,omitempty"`
+	ChunkRevision          int               `json:"chunkRevision,omitempty"`
+	Revisions              []Revision        `json:"revisions,omitempty"`
+	CreatedAt              time.Time         `json:"createdAt"`
+	UpdatedAt              time.Time         `json:"updatedAt"`
+	Version                int64             `json:"version,omitempty"`
+	CompletedAt            *time.Time        `json:"completedAt,omitempty"`
+	ChunkDurationSeconds   int               `json:"chunkDurationSeconds"`
+	RequestedChunkSeconds  int               `json:"requestedChunkSeconds,omitempty"`
+	ChunkCount             int               `json:"chunkCount,omitempty"`
+	InputDurationSeconds   float64           `json:"inputDurationSeconds,omitempty"`
+	RemainderPolicy        string            `json:"remainderPolicy,omitempty"`
+	FadeMillis             int               `json:"fadeMillis,omitempty"`
+	PaddingMillis          int               `json:"paddingMillis,omitempty"`
+	FilterPreset           string            `json:"filterPreset,omitempty"`
+	TrackMode              string            `json:"trackMode,omitempty"`
+	AudioTrack             int               `json:"audioTrack,omitempty"`
+	AudioStreams           []AudioStream     `json:"audioStreams,omitempty"`
+	TranscriptionRequested bool              `json:"transcriptionRequested"`
+	TranscriptSets         []string          `json:"transcriptSets,omitempty"`
+	WhisperArgs            []string          `json:"whisperArgs,omitempty"`
+	SkipSilence            bool              `json:"skipSilence,omitempty"`
+	TranscriptOnly         bool              `json:"transcriptOnly,omitempty"`
+	WordTimestamps         bool              `json:"wordTimestamps,omitempty"`
+	MergedFormat           string            `json:"mergedFormat,omitempty"`
+	MergedAudioFile        string            `json:"mergedAudioFile,omitempty"`
+	VideoClips             bool              `json:"videoClips,omitempty"`
+	Subtitles              string            `json:"subtitles,omitempty"`
+	SubtitlesFile          string            `json:"subtitlesFile,omitempty"`
+	SubtitledVideoFile     string            `json:"subtitledVideoFile,omitempty"`
+	Summarize              bool              `json:"summarize,omitempty"`
+	Summary                *Summary          `json:"summary,omitempty"`
+	ExtractKeywords        bool              `json:"extractKeywords,omitempty"`
+	AnalyzeSentiment       bool              `json:"analyzeSentiment,omitempty"`
+	DetectChapters         bool              `json:"detectChapters,omitempty"`
+	Redact                 string            `json:"redact,omitempty"`
+	DetectPII              bool              `json:"detectPII,omitempty"`
+	CPUOnly                bool              `json:"cpuOnly,omitempty"`
+	CPUFallback            bool              `json:"cpuFallback,omitempty"`
+	Worker                 string            `json:"worker,omitempty"`
+	Chapters               []Chapter         `json:"chapters,omitempty"`
+	ChaptersFile           string            `json:"chaptersFile,omitempty"`
+	ChapterMetadataFile    string            `json:"chapterMetadataFile,omitempty"`
+	QualityGate            *QualityGate      `json:"qualityGate,omitempty"`
+	Escalation             *EscalationPolicy `json:"escalation,omitempty"`
+	InjectedFault          string            `json:"injectedFault,omitempty"`
+	Status                 JobStatus         `json:"status"`
+	Stages                 []StageState      `json:"stages,omitempty"`
+	RecordingStart         *time.Time        `json:"recordingStart,omitempty"`
+	RecordingStartSource   string            `json:"recordingStartSource,omitempty"`
+	TimeOffsetSeconds      float64           `json:"timeOffsetSeconds,omitempty"`
+	Tags                   []string          `json:"tags,omitempty"`
+	Note                   string            `json:"note,omitempty"`
+	Metadata               map[string]string `json:"metadata,omitempty"`
+	ErrorMessage           string            `json:"errorMessage,omitempty"`
+	FailedChunks           int               `json:"failedChunks,omitempty"`
+	Chunks                 []Chunk           `json:"chunks"`
+	ProcessingLog          string            `json:"processingLog,omitempty"`
+	LogFile                string            `json:"logFile,omitempty"`
+	Commands               []CommandRun      `json:"commands,omitempty"`
+	Tools                  []ToolVersion     `json:"tools,omitempty"`
+	Progress               *JobProgress      `json:"progress,omitempty"`
+	DiskUsage              *DiskUsage        `json:"diskUsage,omitempty"`
+}
+
+// Revision is an earlier result of a job, kept when the job was re-chunked in place. Its
+// chunks point into the archived directories, e.g. chunks_v1/chunk_000.wav.
+type Revision struct {
+	Number               int        `json:"number"`
+	ArchivedAt           time.Time  `json:"archivedAt"`
+	Status               JobStatus  `json:"status"`
+	CompletedAt          *time.Time `json:"completedAt,omitempty"`
+	ChunkDurationSeconds int        `json:"chunkDurationSeconds"`
+	ChunkCount           int        `json:"chunkCount,omitempty"`
+	RemainderPolicy      string     `json:"remainderPolicy,omitempty"`
+	FadeMillis           int        `json:"fadeMillis,omitempty"`
+	PaddingMillis        int        `json:"paddingMillis,omitempty"`
+	FilterPreset         string     `json:"filterPreset,omitempty"`
+	TrackMode            string     `json:"trackMode,omitempty"`
+	AudioTrack           int        `json:"audioTrack,omitempty"`
+	MergedFormat         string     `json:"mergedFormat,omitempty"`
+	MergedAudioFile      string     `json:"mergedAudioFile,omitempty"`
+	VideoClips           bool       `json:"videoClips,omitempty"`
+	Subtitles            string     `json:"subtitles,omitempty"`
+	SubtitlesFile        string     `json:"subtitlesFile,omitempty"`
+	SubtitledVideoFile   string     `json:"subtitledVideoFile,omitempty"`
+	ChaptersFile         string     `json:"chaptersFile,omitempty"`
+	ChapterMetadataFile  string     `json:"chapterMetadataFile,omitempty"`
+	TimeOffsetSeconds    float64    `json:"timeOffsetSeconds,omitempty"`
+	SegmentKey           string     `json:"segmentKey,omitempty"`
+	Chunks               []Chunk    `json:"chunks"`
+}
+
+// CurrentRevision is the number of the job's current result: 1 until it is first
+// re-chunked in place.
+func (j *Job) CurrentRevision() int {
+	return j.ChunkRevision + 1
+}
+
+// RevisionOf captures the job's current result as a revision, with its file paths
+// mapped by path, e.g. to the directories the artefacts are archived in.
+func (j *Job) RevisionOf(archivedAt time.Time, path func(string) string) Revision {
+	rev := Revision{
+		Number:               j.CurrentRevision(),
+		ArchivedAt:           archivedAt,
+		Status:               j.Status,
+		CompletedAt:          j.CompletedAt,
+		ChunkDurationSeconds: j.ChunkDurationSeconds,
+		ChunkCount:           j.ChunkCount,
+		RemainderPolicy:      j.RemainderPolicy,
+		FadeMillis:           j.FadeMillis,
+		PaddingMillis:        j.PaddingMillis,
+		FilterPreset:         j.FilterPreset,
+		TrackMode:            j.TrackMode,
+		AudioTrack:           j.AudioTrack,
+		MergedFormat:         j.MergedFormat,
+		VideoClips:           j.VideoClips,
+		Subtitles:            j.Subtitles,
+		TimeOffsetSeconds:    j.TimeOffsetSeconds,
+		SegmentKey:           j.SegmentKey,
+		Chunks:               make([]Chunk, len(j.Chunks)),
+	}
+	mapPath := func(rel string) string {
+		if rel == "" {
+			return ""
+		}
+		return path(rel)
+	}
+	rev.MergedAudioFile = mapPath(j.MergedAudioFile)
+	rev.SubtitlesFile = mapPath(j.SubtitlesFile)
+	rev.SubtitledVideoFile = mapPath(j.SubtitledVideoFile)
+	rev.ChaptersFile = mapPath(j.ChaptersFile)
+	rev.ChapterMetadataFile = mapPath(j.ChapterMetadataFile)
+	for i, c := range j.Chunks {
+		c.AudioFile = mapPath(c.AudioFile)
+		c.Base64File = mapPath(c.Base64File)
+		c.TranscriptFile = mapPath(c.TranscriptFile)
+		c.WordsFile = mapPath(c.WordsFile)
+		c.VideoFile = mapPath(c.VideoFile)
+		c.RedactedTranscriptFile = mapPath(c.RedactedTranscriptFile)
+		c.RedactedAudioFile = mapPath(c.RedactedAudioFile)
+		c.MaskedTranscriptFile = mapPath(c.MaskedTranscriptFile)
+		if c.Transcripts != nil {
+			sets := make([]ChunkTranscript, len(c.Transcripts))
+			for k, t := range c.Transcripts {
+				t.File = mapPath(t.File)
+				sets[k] = t
+			}
+			c.Transcripts = sets
+		}
+		rev.Chunks[i] = c
+	}
+	return rev
+}
+
+// DiskUsage breaks down the bytes a job occupies on disk by artefact type.
+type DiskUsage struct {
+	Original    int64 `json:"original"`
+	Chunks      int64 `json:"chunks"`
+	Base64      int64 `json:"base64"`
+	Transcripts int64 `json:"transcripts"`
+	Merged      int64 `json:"merged"`
+	Video       int64 `json:"video,omitempty"`
+	// Revisions covers the artefacts of earlier revisions kept after re-chunking.
+	Revisions int64 `json:"revisions,omitempty"`
+	// Total is the logical size of everything in the job directory.
+	Total int64 `json:"total"`
+	// Exclusive counts bytes not hard-linked into any other job; deleting the job frees this much.
+	Exclusive int64 `json:"exclusive"`
+}
+
+// JobProgress is the live position of a running job, cleared once it finishes.
+type JobProgress struct {
+	Stage          string    `json:"stage"`
+	StageStartedAt time.Time `json:"stageStartedAt"`
+	DoneSeconds    float64   `json:"doneSeconds"`
+	TotalSeconds   float64   `json:"totalSeconds,omitempty"`
+	// Percent is the overall completion across all stages, weighted by ProgressWeights.
+	Percent float64 `json:"percent"`
+	// Speed is ffmpeg's live speed while extracting, in seconds of audio per second.
+	Speed float64 `json:"speed,omitempty"`
+	// RemainingSeconds and EstimatedCompletion are omitted until there is throughput history.
+	RemainingSeconds    float64    `json:"remainingSeconds,omitempty"`
+	EstimatedCompletion *time.Time `json:"estimatedCompletion,omitempty"`
+}
+
+// Recording start sources stored in Job.RecordingStartSource.
+const (
+	RecordingStartUser     = "user"
+	RecordingStartMetadata = "metadata"
+)
+
+// ChunkWallClock returns the absolute time a chunk starts at, when the recording start is known.
+func (j *Job) ChunkWallClock(c Chunk) (time.Time, bool) {
+	if j.RecordingStart == nil {
+		return time.Time{}, false
+	}
+	offset := time.Duration(c.StartSeconds * float64(time.Second))
+	return j.RecordingStart.Add(offset), true
+}
+
+// SetTimeOffset shifts every chunk so it starts offset seconds later than in the
+// processed audio, e.g. to account for a leading gap trimmed before upload. It replaces
+// any earlier offset, so zero restores the original timeline.
+func (j *Job) SetTimeOffset(offset float64) error {
+	delta := offset - j.TimeOffsetSeconds
+	for _, c := range j.Chunks {
+		if c.StartSeconds+delta < 0 {
+			return fmt.Errorf("offset %.3fs would move chunk %d before the start of the recording", offset, c.Index)
+		}
+	}
+	for i := range j.Chunks {
+		j.Chunks[i].StartSeconds += delta
+	}
+	if j.Summary != nil {
+		for i := range j.Summary.Chapters {
+			j.Summary.Chapters[i].StartSeconds = max(0, j.Summary.Chapters[i].StartSeconds+delta)
+		}
+	}
+	j.TimeOffsetSeconds = offset
+	return nil
+}
+
+// DisplayName is the job's title, falling back to the uploaded file name and then the ID.
+func (j *Job) DisplayName() string {
+	if j.Title != "" {
+		return j.Title
+	}
+	if j.OriginalFileName == "" {
+		return j.ID
+	}
+	return j.OriginalFileName
+}
+
+// IsDone reports whether the job reached a terminal state.
+func (j *Job) IsDone() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed
+}
+
+// CountFailedChunks sets FailedChunks to the number of chunks whose transcription
+// failed and notes it on a completed transcribe stage. Such chunks do not fail the job,
+// which completes with warnings instead.
+func (j *Job) CountFailedChunks() {
+	j.FailedChunks = 0
+	for _, c := range j.Chunks {
+		if c.TranscriptError != "" {
+			j.FailedChunks++
+		}
+	}
+	for i := range j.Stages {
+		if s := &j.Stages[i]; s.Name == PipelineTranscribe && s.Status == StageCompleted {
+			s.Error = ""
+			if j.FailedChunks > 0 {
+				s.Error = fmt.Sprintf("%d of %d chunks failed to transcribe", j.FailedChunks, len(j.Chunks))
+			}
+		}
+	}
+}
+
+// HasWarnings reports whether the job completed with chunks that failed to transcribe.
+func (j *Job) HasWarnings() bool {
+	return j.Status == JobStatusCompleted && j.FailedChunks > 0
+}
+
+// ApplyResult records the outcome of processing the job: err, if not nil, fails it.
+func (j *Job) ApplyResult(result Result, err error) {
+	j.AudioStreams = result.AudioStreams
+	j.Commands = result.Commands
+	j.CPUFallback = result.CPUFallback
+	j.Tools = result.Tools
+	j.Chunks = result.Chunks
+	completed := time.Now()
+	j.EndStages(err, completed)
+	j.CountFailedChunks()
+	if err != nil {
+		j.Status = JobStatusFailed
+		j.ErrorMessage = err.Error()
+		j.ProcessingLog = strings.Join(append(result.Logs, err.Error()), "\n---\n")
+	} else {
+		j.Status = JobStatusCompleted
+		j.ErrorMessage = ""
+		j.MergedAudioFile = result.MergedAudioFile
+		j.SubtitlesFile = result.SubtitlesFile
+		j.SubtitledVideoFile = result.SubtitledVideoFile
+		j.Summary = result.Summary
+		j.Chapters = result.Chapters
+		j.ChaptersFile = result.ChaptersFile
+		j.ChapterMetadataFile = result.ChapterMetadataFile
+		if j.ChunkCount > 0 && result.SegmentSeconds > 0 {
+			j.ChunkDurationSeconds = int(math.Round(result.SegmentSeconds))
+		}
+		j.ProcessingLog = strings.Join(result.Logs, "\n---\n")
+	}
+	if j.LogFile != "" {
+		// The output went to the log file as it was printed.
+		j.ProcessingLog = ""
+	}
+	j.CompletedAt = &completed
+	if result.InputSeconds > 0 {
+		j.InputDurationSeconds = result.InputSeconds
+	}
+	if j.RecordingStart == nil && result.MediaCreationTime != nil {
+		j.RecordingStart = result.MediaCreationTime
+		j.RecordingStartSource = RecordingStartMetadata
+	}
+}
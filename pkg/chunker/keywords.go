@@ -0,0 +1,323 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Keyword is a key term or named entity found in a chunk's transcript.
+type Keyword struct {
+	Text string `json:"text"`
+	// Kind is empty for a key term and names the entity type otherwise: "entity" from
+	// LocalKeywords, or whatever a KeywordService reports, such as "person" or "org".
+	Kind string `json:"kind,omitempty"`
+	// Score orders keywords of the same chunk, higher first; scales differ by extractor.
+	Score float64 `json:"score,omitempty"`
+}
+
+// KeywordKindEntity marks the named entities LocalKeywords finds; it cannot tell people
+// from places.
+const KeywordKindEntity = "entity"
+
+// KeywordExtractor finds the keywords and named entities of one transcript.
+type KeywordExtractor interface {
+	Keywords(ctx context.Context, text string) ([]Keyword, error)
+}
+
+// WithKeywordExtractor replaces the LocalKeywords used for Options.ExtractKeywords.
+func WithKeywordExtractor(x KeywordExtractor) Option {
+	return func(p *Processor) {
+		p.Keywords = x
+	}
+}
+
+// defaultMaxKeywords caps the key terms, and separately the entities, kept per chunk.
+const defaultMaxKeywords = 8
+
+// LocalKeywords extracts keywords without any service: words and repeated word pairs that
+// are not stop words, ranked by frequency, plus runs of capitalised words as entities.
+// Its stop words are English, so other languages get noisier key terms.
+type LocalKeywords struct {
+	// Max caps the key terms and the entities kept per chunk; zero means 8 of each.
+	Max int
+}
+
+// Keywords implements KeywordExtractor.
+func (l LocalKeywords) Keywords(_ context.Context, text string) ([]Keyword, error) {
+	limit := l.Max
+	if limit <= 0 {
+		limit = defaultMaxKeywords
+	}
+
+	entities := make(map[string]int)
+	entityWords := make(map[string]bool)
+	terms := make(map[string]int)
+	for _, sentence := range splitSentences(text) {
+		words := strings.FieldsFunc(sentence, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+		})
+		var run []string
+		runStart := 0
+		flush := func() {
+			// A lone capitalised word opening a sentence is usually just capitalised.
+			if len(run) > 1 || (len(run) == 1 && runStart > 0) {
+				name := strings.Join(run, " ")
+				entities[name]++
+				for _, w := range run {
+					entityWords[strings.ToLower(w)] = true
+				}
+			}
+			run = nil
+		}
+		prev := ""
+		for i, word := range words {
+			word = strings.Trim(word, "'-")
+			lower := strings.ToLower(word)
+			if isCapitalised(word) && !stopWords[lower] && len(word) > 1 {
+				if len(run) == 0 {
+					runStart = i
+				}
+				run = append(run, word)
+			} else {
+				flush()
+			}
+			if !keywordCandidate(lower) {
+				prev = ""
+				continue
+			}
+			terms[lower]++
+			if prev != "" {
+				terms[prev+" "+lower]++
+			}
+			prev = lower
+		}
+		flush()
+	}
+
+	var keywords []Keyword
+	for name, count := range entities {
+		keywords = append(keywords, Keyword{Text: name, Kind: KeywordKindEntity, Score: float64(count)})
+	}
+	keywords = topKeywords(keywords, limit)
+
+	var candidates []Keyword
+	for term, count := range terms {
+		pair := strings.Contains(term, " ")
+		switch {
+		case pair && count < 2:
+			// A word pair counts only once it repeats.
+			continue
+		case slices.ContainsFunc(strings.Fields(term), func(w string) bool { return entityWords[w] }):
+			continue
+		case pair:
+			candidates = append(candidates, Keyword{Text: term, Score: float64(count) * 2})
+		default:
+			candidates = append(candidates, Keyword{Text: term, Score: float64(count)})
+		}
+	}
+	return append(keywords, topKeywords(candidates, limit)...), nil
+}
+
+// topKeywords keeps the limit highest scores, longer texts first among equals.
+func topKeywords(keywords []Keyword, limit int) []Keyword {
+	sort.Slice(keywords, func(i, j int) bool {
+		a, b := keywords[i], keywords[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Text) != len(b.Text) {
+			return len(a.Text) > len(b.Text)
+		}
+		return a.Text < b.Text
+	})
+	return keywords[:min(len(keywords), limit)]
+}
+
+func splitSentences(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r == '.' || r == '!' || r == '?' || r == '\n' || r == ';' || r == ':'
+	})
+}
+
+func isCapitalised(word string) bool {
+	for _, r := range word {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// keywordCandidate reports whether a lower-cased word may be a key term: not a stop
+// word, not a number, and at least four letters long.
+func keywordCandidate(word string) bool {
+	if len([]rune(word)) < 4 || stopWords[word] {
+		return false
+	}
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// stopWords are English function words and transcript filler never worth a keyword.
+var stopWords = func() map[string]bool {
+	words := strings.Fields(`a about above after again against all also am an and any are aren't as at
+		be because been before being below between both but by can can't cannot could couldn't
+		did didn't do does doesn't doing don't down during each even ever every few for from further
+		get gets getting go goes going gonna got had hadn't has hasn't have haven't having he he'd
+		he'll he's her here here's hers herself him himself his how how's i i'd i'll i'm i've if in
+		into is isn't it it's its itself just know let's like maybe me more most much must mustn't my
+		myself need no nor not now of off okay ok on once one only or other ought our ours ourselves
+		out over own really right said same say says see shall shan't she she'd she'll she's should
+		shouldn't so some something still such sure than that that's the their theirs them
+		themselves then there there's these they they'd they'll they're they've thing things think
+		this those though through to too uh um under until up us very via want wanna was wasn't we
+		we'd we'll we're we've well were weren't what what's when when's where where's which while
+		who who's whom why why's will with won't would wouldn't yeah yes yet you you'd you'll you're
+		you've your yours yourself yourselves actually basically kind lot lots mean mm hmm oh
+		anyway anything everything nothing someone somebody everyone everybody always never
+		today tomorrow yesterday come comes came make makes made take takes took look looks
+		thank thanks please`)
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}()
+
+// KeywordService extracts keywords through an HTTP endpoint. It posts
+// {"text": "...", "max": n} as JSON and accepts {"keywords": [...], "entities": [...]},
+// where each entry is a string or an object with "text", an optional "kind" (or "type"
+// or "label"), and an optional "score". Keywords listed under "entities" without a kind
+// get KeywordKindEntity.
+type KeywordService struct {
+	URL     string
+	Headers map[string]string
+	// Max is sent to the service and caps each list of its answer; zero means 8.
+	Max     int
+	Timeout time.Duration
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Keywords implements KeywordExtractor.
+func (k *KeywordService) Keywords(ctx context.Context, text string) ([]Keyword, error) {
+	limit := k.Max
+	if limit <= 0 {
+		limit = defaultMaxKeywords
+	}
+	body, err := json.Marshal(map[string]any{"text": text, "max": limit})
+	if err != nil {
+		return nil, err
+	}
+	if k.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range k.Headers {
+		req.Header.Set(name, value)
+	}
+	client := k.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("keyword request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading keyword response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("keyword service answered %s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 300)])))
+	}
+	var answer struct {
+		Keywords []serviceKeyword `json:"keywords"`
+		Entities []serviceKeyword `json:"entities"`
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return nil, fmt.Errorf("decoding keyword response: %w", err)
+	}
+	var keywords []Keyword
+	for _, entry := range answer.Entities[:min(len(answer.Entities), limit)] {
+		if entry.Kind == "" {
+			entry.Kind = KeywordKindEntity
+		}
+		keywords = append(keywords, Keyword(entry))
+	}
+	for _, entry := range answer.Keywords[:min(len(answer.Keywords), limit)] {
+		keywords = append(keywords, Keyword(entry))
+	}
+	return keywords, nil
+}
+
+// serviceKeyword decodes a KeywordService entry from a string or an object.
+type serviceKeyword Keyword
+
+func (s *serviceKeyword) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Text); err == nil {
+		return nil
+	}
+	var entry struct {
+		Text  string  `json:"text"`
+		Kind  string  `json:"kind"`
+		Type  string  `json:"type"`
+		Label string  `json:"label"`
+		Score float64 `json:"score"`
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	s.Text, s.Score = entry.Text, entry.Score
+	for _, kind := range []string{entry.Kind, entry.Type, entry.Label} {
+		if kind != "" {
+			s.Kind = strings.ToLower(kind)
+			break
+		}
+	}
+	return nil
+}
+
+// extractKeywords fills in chunk.Keywords from its main transcript. A failing extractor
+// is logged; the chunk keeps its transcript.
+func (p *Processor) extractKeywords(ctx context.Context, jobDir string, chunk *Chunk) []string {
+	data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+	if err != nil {
+		return []string{fmt.Sprintf("keywords for chunk %d unavailable: %v", chunk.Index, err)}
+	}
+	var extractor KeywordExtractor = LocalKeywords{}
+	if p.Keywords != nil {
+		extractor = p.Keywords
+	}
+	keywords, err := extractor.Keywords(ctx, string(data))
+	if err != nil {
+		return []string{fmt.Sprintf("keywords for chunk %d unavailable: %v", chunk.Index, err)}
+	}
+	chunk.Keywords = nil
+	for _, k := range keywords {
+		if k.Text = strings.TrimSpace(k.Text); k.Text != "" {
+			chunk.Keywords = append(chunk.Keywords, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package chunker
+
+import (
+	"fmt"
+	"math"
+)
+
+// ChunkLimit is the largest chunk a transcription backend accepts, by length and by
+// file size. A zero field means no limit of that kind.
+type ChunkLimit struct {
+	MaxSeconds float64
+	MaxBytes   int64
+}
+
+// Seconds converts the limit into the longest chunk audio, padding included, that
+// satisfies both fields, or 0 when there is no limit. Sizes are those of the WAV chunks.
+func (l ChunkLimit) Seconds() float64 {
+	seconds := l.MaxSeconds
+	if l.MaxBytes > 0 {
+		bySize := float64(l.MaxBytes-wavHeaderBytes) / chunkBytesPerSecond
+		if seconds == 0 || bySize < seconds {
+			seconds = bySize
+		}
+	}
+	return seconds
+}
+
+// Tighter returns the stricter value of each field of l and other.
+func (l ChunkLimit) Tighter(other ChunkLimit) ChunkLimit {
+	if other.MaxSeconds > 0 && (l.MaxSeconds == 0 || other.MaxSeconds < l.MaxSeconds) {
+		l.MaxSeconds = other.MaxSeconds
+	}
+	if other.MaxBytes > 0 && (l.MaxBytes == 0 || other.MaxBytes < l.MaxBytes) {
+		l.MaxBytes = other.MaxBytes
+	}
+	return l
+}
+
+// FitChunkDuration returns the longest whole-second chunk duration, at most seconds, whose
+// chunks stay within maxSeconds once paddingMillis of silence is added on both sides. A
+// maxSeconds of 0 returns seconds unchanged.
+func FitChunkDuration(seconds, paddingMillis int, maxSeconds float64) (int, error) {
+	if maxSeconds <= 0 {
+		return seconds, nil
+	}
+	fit := int(math.Floor(maxSeconds - 2*float64(paddingMillis)/1000))
+	if fit < 1 {
+		return 0, fmt.Errorf("%d ms of padding leaves no room in the %s chunk limit", paddingMillis, formatLimit(maxSeconds))
+	}
+	if seconds > fit {
+		return fit, nil
+	}
+	return seconds, nil
+}
+
+// limitedChunkCount raises count until chunks of inputSeconds/count plus padding fit
+// within opts.MaxChunkSeconds.
+func limitedChunkCount(inputSeconds float64, count int, opts Options) int {
+	room := opts.MaxChunkSeconds - 2*float64(opts.PaddingMillis)/1000
+	if opts.MaxChunkSeconds <= 0 || room <= 0 || inputSeconds <= 0 {
+		return count
+	}
+	if need := int(math.Ceil(inputSeconds / room)); need > count {
+		return need
+	}
+	return count
+}
+
+// fitsLimit reports whether a chunk of the given audio length stays within
+// opts.MaxChunkSeconds once padded.
+func fitsLimit(seconds float64, opts Options) bool {
+	return opts.MaxChunkSeconds <= 0 || seconds+2*float64(opts.PaddingMillis)/1000 <= opts.MaxChunkSeconds+remainderTolerance
+}
+
+func formatLimit(seconds float64) string {
+	return fmt.Sprintf("%gs", math.Round(seconds*1000)/1000)
+}
@@ -0,0 +1,12 @@
+package chunker
+
+import "strings"
+
+// extendedLengthPath returns the Windows extended-length form of the absolute path
+// abs: \\?\C:\… for a drive path, \\?\UNC\server\share\… for a network one.
+func extendedLengthPath(abs string) string {
+	if unc, ok := strings.CutPrefix(abs, `\\`); ok {
+		return `\\?\UNC\` + unc
+	}
+	return `\\?\` + abs
+}
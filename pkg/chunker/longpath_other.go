@@ -0,0 +1,8 @@
+//go:build !windows
+
+package chunker
+
+// commandArg passes arguments through unchanged; only Windows limits path lengths.
+func commandArg(arg string) string {
+	return arg
+}
@@ -0,0 +1,15 @@
+package chunker
+
+import "testing"
+
+func TestExtendedLengthPath(t *testing.T) {
+	tests := []struct{ abs, want string }{
+		{`C:\data\jobs\01J8ZK3Q4W2N6V7X9Y0A1B2C3D\chunks\chunk_000.wav`, `\\?\C:\data\jobs\01J8ZK3Q4W2N6V7X9Y0A1B2C3D\chunks\chunk_000.wav`},
+		{`\\nas\media\jobs\chunk_000.wav`, `\\?\UNC\nas\media\jobs\chunk_000.wav`},
+	}
+	for _, tt := range tests {
+		if got := extendedLengthPath(tt.abs); got != tt.want {
+			t.Errorf("extendedLengthPath(%q) = %q, want %q", tt.abs, got, tt.want)
+		}
+	}
+}
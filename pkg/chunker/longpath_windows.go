@@ -0,0 +1,33 @@
+//go:build windows
+
+package chunker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is Windows' MAX_PATH less room for a file name, the length past which
+// programs built without long path support, such as many whisper.cpp builds, fail to
+// open a path.
+const maxPath = 248
+
+// commandArg rewrites an argument that is a path too long for MAX_PATH into its
+// extended-length form (\\?\C:\… or \\?\UNC\server\share\…), which the Win32 file
+// functions accept at any length. Relative paths are made absolute first, since the
+// prefix needs one; arguments that are not paths into an existing directory are left
+// alone.
+func commandArg(arg string) string {
+	if arg == "" || strings.HasPrefix(arg, `\\?\`) || strings.HasPrefix(arg, "-") {
+		return arg
+	}
+	abs, err := filepath.Abs(arg)
+	if err != nil || len(abs) < maxPath {
+		return arg
+	}
+	if info, err := os.Stat(filepath.Dir(abs)); err != nil || !info.IsDir() {
+		return arg
+	}
+	return extendedLengthPath(abs)
+}
@@ -0,0 +1,36 @@
+package chunker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandArg(t *testing.T) {
+	short := filepath.Join(t.TempDir(), "chunk_000.wav")
+	long := t.TempDir()
+	for len(long) < maxPath {
+		long = filepath.Join(long, strings.Repeat("d", 40))
+	}
+	if err := os.MkdirAll(long, 0o755); err != nil {
+		t.Skipf("creating a long directory: %v", err)
+	}
+	longFile := filepath.Join(long, "chunk_000.wav")
+	missing := filepath.Join(long, "missing", "chunk_000.wav")
+
+	tests := []struct{ arg, want string }{
+		{"", ""},
+		{short, short},
+		{"-i", "-i"},
+		{"-" + longFile, "-" + longFile},
+		{`\\?\` + longFile, `\\?\` + longFile},
+		{longFile, `\\?\` + longFile},
+		{missing, missing},
+	}
+	for _, tt := range tests {
+		if got := commandArg(tt.arg); got != tt.want {
+			t.Errorf("commandArg(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MergedFormat is the codec of the optional single listening file written next to the
+// chunks. The empty format writes none.
+type MergedFormat string
+
+const (
+	MergedNone MergedFormat = ""
+	MergedMP3  MergedFormat = "mp3"
+	MergedOpus MergedFormat = "opus"
+)
+
+// mergedLoudnorm normalises the listening file to the common podcast target so
+// recordings of different loudness play back at a similar level.
+const mergedLoudnorm = "loudnorm=I=-16:TP=-1.5:LRA=11"
+
+var mergedCodecArgs = map[MergedFormat][]string{
+	MergedMP3:  {"-c:a", "libmp3lame", "-b:a", "128k"},
+	MergedOpus: {"-c:a", "libopus", "-b:a", "64k"},
+}
+
+// ParseMergedFormat validates a user-provided format; "", "none", and "off" mean no file.
+func ParseMergedFormat(value string) (MergedFormat, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", "none", "off":
+		return MergedNone, nil
+	}
+	if _, ok := mergedCodecArgs[MergedFormat(value)]; ok {
+		return MergedFormat(value), nil
+	}
+	return "", fmt.Errorf("unknown merged audio format %q (want mp3 or opus)", value)
+}
+
+// writeMergedAudio encodes the whole input audio once more, loudness-normalised, into
+// merged/audio.<format>, returning its path relative to jobDir and the ffmpeg log. It
+// picks the same stream as the chunks; separately chunked tracks are mixed into one
+// file. The filter preset is meant for speech recognition and is not applied.
+func (p *Processor) writeMergedAudio(ctx context.Context, ffmpeg, jobDir, inputPath string, streamCount int, opts Options) (string, string, error) {
+	mergedDir := filepath.Join(jobDir, "merged")
+	if err := os.MkdirAll(mergedDir, 0o755); err != nil {
+		return "", "", fmt.Errorf("creating merged audio directory: %w", err)
+	}
+
+	trackOpts := opts
+	trackOpts.AudioFilters = mergedLoudnorm
+	if trackOpts.TrackMode == TrackSeparate {
+		trackOpts.TrackMode = TrackMix
+	}
+	runs, err := trackRuns(trackOpts, streamCount)
+	if err != nil {
+		return "", "", err
+	}
+
+	name := "audio." + string(opts.MergedFormat)
+	args := []string{"-y", "-i", inputPath, "-vn"}
+	args = append(args, runs[0].args...)
+	args = append(args, "-ar", "48000")
+	args = append(args, mergedCodecArgs[opts.MergedFormat]...)
+	args = append(args, filepath.Join(mergedDir, name))
+
+	logEntry, err := runCommand(ctx, ffmpeg, args...)
+	if err != nil {
+		return "", logEntry, fmt.Errorf("encoding merged audio: %w", err)
+	}
+	return filepath.ToSlash(filepath.Join("merged", name)), logEntry, nil
+}
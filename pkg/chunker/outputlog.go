@@ -0,0 +1,147 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutputLog streams what the external commands started under a context carrying it
+// print into a writer, such as a job's log file, as they print it. Every line is
+// timestamped and tagged with the command that printed it, e.g. "whisper-cli#4", since
+// chunks are transcribed in parallel. The processor's notes follow once it is done, so
+// a job's log can be read while it runs without the output being kept in the job.
+// It is safe for concurrent use.
+type OutputLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+	// streamed holds the digests of the outputs already written, so the notes that
+	// repeat a command's whole output are not written twice.
+	streamed map[[sha256.Size]byte]bool
+}
+
+// NewOutputLog returns an OutputLog writing to w.
+func NewOutputLog(w io.Writer) *OutputLog {
+	return &OutputLog{w: w, streamed: map[[sha256.Size]byte]bool{}}
+}
+
+type outputLogKey struct{}
+
+// WithOutputLog returns a context under which the output of every external command
+// the processor starts is streamed into log.
+func WithOutputLog(ctx context.Context, log *OutputLog) context.Context {
+	return context.WithValue(ctx, outputLogKey{}, log)
+}
+
+// Notes writes the processor's log entries, such as Result.Logs, leaving out those that
+// repeat the output of a command already streamed.
+func (l *OutputLog) Notes(entries []string) {
+	for _, entry := range entries {
+		entry = strings.TrimRight(entry, "\n")
+		if entry == "" {
+			continue
+		}
+		l.mu.Lock()
+		seen := l.streamed[sha256.Sum256([]byte(entry))]
+		l.mu.Unlock()
+		if seen {
+			continue
+		}
+		for _, line := range strings.Split(entry, "\n") {
+			l.writeLine("note", "| ", line)
+		}
+	}
+}
+
+func (l *OutputLog) writeLine(tag, sep, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "%s %s %s%s\n", time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), tag, sep, line)
+}
+
+// outputLogOf returns the context's OutputLog, or nil.
+func outputLogOf(ctx context.Context) *OutputLog {
+	log, _ := ctx.Value(outputLogKey{}).(*OutputLog)
+	return log
+}
+
+// start opens the stream of one run of args[0], writing its command line.
+func (l *OutputLog) start(args []string) *commandOutput {
+	l.mu.Lock()
+	l.seq++
+	tag := fmt.Sprintf("%s#%d", filepath.Base(args[0]), l.seq)
+	l.mu.Unlock()
+	l.writeLine(tag, "$ ", CommandRun{Args: args}.CommandLine())
+	return &commandOutput{log: l, tag: tag}
+}
+
+// maxOutputLine is how much of a line without a newline is held before it is written
+// as it is.
+const maxOutputLine = 64 << 10
+
+// commandOutput is the writer a command prints into, cutting what it receives into
+// lines for the OutputLog. ffmpeg ends its status lines with a carriage return, which
+// counts as a line end too.
+type commandOutput struct {
+	log     *OutputLog
+	tag     string
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (o *commandOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.partial = append(o.partial, p...)
+	for {
+		i := bytes.IndexAny(o.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := o.partial[:i]; len(line) > 0 {
+			o.log.writeLine(o.tag, "| ", string(line))
+		}
+		o.partial = o.partial[i+1:]
+	}
+	if len(o.partial) > maxOutputLine {
+		o.log.writeLine(o.tag, "| ", string(o.partial))
+		o.partial = nil
+	}
+	return len(p), nil
+}
+
+// finish writes what is left of the output and how the run ended, and remembers the
+// whole output so Notes does not repeat it.
+func (o *commandOutput) finish(run CommandRun, output string) {
+	o.mu.Lock()
+	if len(o.partial) > 0 {
+		o.log.writeLine(o.tag, "| ", string(o.partial))
+		o.partial = nil
+	}
+	o.mu.Unlock()
+	ended := fmt.Sprintf("exit %d after %.1fs", run.ExitCode, run.DurationSeconds)
+	if run.Error != "" {
+		ended += ": " + run.Error
+	}
+	o.log.writeLine(o.tag, "= ", ended)
+	if output = strings.TrimRight(output, "\n"); output != "" {
+		o.log.mu.Lock()
+		o.log.streamed[sha256.Sum256([]byte(output))] = true
+		o.log.mu.Unlock()
+	}
+}
+
+// streamRun writes a run that printed nothing of its own, such as a request to the
+// resident whisper server, to the context's OutputLog, if any.
+func streamRun(ctx context.Context, run CommandRun) {
+	if log := outputLogOf(ctx); log != nil {
+		log.start(run.Args).finish(run, "")
+	}
+}
@@ -0,0 +1,82 @@
+package chunker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// MaxPeaks bounds the number of waveform peaks WAVPeaks computes for one file.
+const MaxPeaks = 2000
+
+// WAVPeaks reads the PCM WAV file at path and returns its waveform as n peaks: the
+// loudest sample of each of n equal stretches, across channels, as a fraction of full
+// scale rounded to three decimals. Files shorter than n frames give one peak per frame.
+func WAVPeaks(path string, n int) ([]float64, error) {
+	if n <= 0 || n > MaxPeaks {
+		return nil, fmt.Errorf("peaks must be between 1 and %d", MaxPeaks)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := readWAVHeader(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	sampleSize := int(info.bitsPerSample / 8)
+	if sampleSize < 1 || sampleSize > 4 || info.bitsPerSample%8 != 0 {
+		return nil, fmt.Errorf("reading %s: unsupported sample size of %d bits", path, info.bitsPerSample)
+	}
+	frameSize := info.frameSize()
+	frames := int64(info.dataSize) / int64(frameSize)
+	if frames == 0 {
+		return []float64{}, nil
+	}
+	if int64(n) > frames {
+		n = int(frames)
+	}
+
+	fullScale := math.Ldexp(1, int(info.bitsPerSample)-1)
+	peaks := make([]float64, n)
+	r := bufio.NewReaderSize(io.LimitReader(file, frames*int64(frameSize)), 64<<10)
+	frame := make([]byte, frameSize)
+	for i := int64(0); i < frames; i++ {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				// A truncated file keeps the peaks read so far.
+				break
+			}
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		bucket := int(i * int64(n) / frames)
+		for off := 0; off < frameSize; off += sampleSize {
+			if level := math.Abs(pcmSample(frame[off:off+sampleSize])) / fullScale; level > peaks[bucket] {
+				peaks[bucket] = level
+			}
+		}
+	}
+	for i, peak := range peaks {
+		peaks[i] = math.Round(min(peak, 1)*1000) / 1000
+	}
+	return peaks, nil
+}
+
+// pcmSample decodes one little-endian PCM sample; 8-bit WAV samples are unsigned, wider
+// ones signed.
+func pcmSample(b []byte) float64 {
+	if len(b) == 1 {
+		return float64(int(b[0]) - 128)
+	}
+	var v int32
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | int32(b[i])
+	}
+	shift := 32 - 8*len(b)
+	return float64(v << shift >> shift)
+}
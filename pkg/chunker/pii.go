@@ -0,0 +1,149 @@
+package chunker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of personal information DetectPII finds.
+const (
+	PIIEmail = "email"
+	PIIPhone = "phone"
+	PIICard  = "card"
+)
+
+// PIIFinding is a piece of personal information found in a chunk's main transcript. The
+// value itself is not stored, only where it is and a masked hint of it.
+type PIIFinding struct {
+	Kind string `json:"kind"`
+	// Start and End are byte offsets into the main transcript.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Masked is the value as the masked transcript shows it: a card keeps its last four
+	// digits, a phone number its last two, and an email address its first letter and domain.
+	Masked string `json:"masked"`
+}
+
+var (
+	piiEmailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)
+	// piiNumberPattern matches digit runs broken up by the spaces, dots, dashes, and
+	// brackets transcripts write phone and card numbers with.
+	piiNumberPattern = regexp.MustCompile(`\+?\(?\d(?:[ .()-]{0,2}\d)+`)
+)
+
+// DetectPII finds the email addresses, phone numbers, and card numbers in text, in order.
+// A run of 13 to 19 digits is a card number when it passes the Luhn check or has the 16
+// digits of most cards; other runs of 9 to 15 digits are phone numbers. Shorter runs are
+// left alone, since transcripts are full of years, amounts, and counts.
+func DetectPII(text string) []PIIFinding {
+	var findings []PIIFinding
+	for _, m := range piiEmailPattern.FindAllStringIndex(text, -1) {
+		findings = append(findings, PIIFinding{Kind: PIIEmail, Start: m[0], End: m[1], Masked: maskEmail(text[m[0]:m[1]])})
+	}
+	for _, m := range piiNumberPattern.FindAllStringIndex(text, -1) {
+		if overlapsFinding(findings, m[0], m[1]) {
+			// Digits inside an email address.
+			continue
+		}
+		value := text[m[0]:m[1]]
+		var digits []byte
+		for i := 0; i < len(value); i++ {
+			if value[i] >= '0' && value[i] <= '9' {
+				digits = append(digits, value[i])
+			}
+		}
+		switch n := len(digits); {
+		case n >= 13 && n <= 19 && (luhnValid(digits) || n == 16):
+			findings = append(findings, PIIFinding{Kind: PIICard, Start: m[0], End: m[1], Masked: maskDigits(value, 4)})
+		case n >= 9 && n <= 15:
+			findings = append(findings, PIIFinding{Kind: PIIPhone, Start: m[0], End: m[1], Masked: maskDigits(value, 2)})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+func overlapsFinding(findings []PIIFinding, start, end int) bool {
+	for _, f := range findings {
+		if f.Start < end && f.End > start {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid runs the checksum every payment card number satisfies.
+func luhnValid(digits []byte) bool {
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// maskDigits replaces every digit of value but the last keep with '*', leaving the
+// separators so the masked number keeps its shape.
+func maskDigits(value string, keep int) string {
+	b := []byte(value)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '0' || b[i] > '9' {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		b[i] = '*'
+	}
+	return string(b)
+}
+
+func maskEmail(value string) string {
+	local, domain, _ := strings.Cut(value, "@")
+	return local[:1] + "***@" + domain
+}
+
+// MaskPII replaces every finding in text, which must be the text they were found in,
+// with its masked hint.
+func MaskPII(text string, findings []PIIFinding) string {
+	var b strings.Builder
+	pos := 0
+	for _, f := range findings {
+		b.WriteString(text[pos:f.Start])
+		b.WriteString(f.Masked)
+		pos = f.End
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// maskChunkPII fills in chunk.PII from its main transcript and writes the masked variant to
+// transcripts/<chunk>.masked.txt, also when nothing was found, so the masked transcripts
+// cover every chunk.
+func maskChunkPII(jobDir string, chunk *Chunk) []string {
+	data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+	if err != nil {
+		return []string{fmt.Sprintf("PII detection for chunk %d failed: %v", chunk.Index, err)}
+	}
+	findings := DetectPII(string(data))
+	maskedFile := strings.TrimSuffix(chunk.TranscriptFile, ".txt") + ".masked.txt"
+	if err := writeJobFile(jobDir, maskedFile, []byte(MaskPII(string(data), findings))); err != nil {
+		return []string{fmt.Sprintf("PII detection for chunk %d failed: %v", chunk.Index, err)}
+	}
+	chunk.PII = findings
+	chunk.MaskedTranscriptFile = maskedFile
+	if len(findings) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("chunk %d: masked %d PII findings", chunk.Index, len(findings))}
+}
@@ -0,0 +1,219 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Chunks are written as 16 kHz mono 16-bit PCM with a canonical WAV header.
+const (
+	chunkBytesPerSecond = 16000 * 2
+	wavHeaderBytes      = 44
+)
+
+// mergedBytesPerSecond follows the bitrates in mergedCodecArgs.
+var mergedBytesPerSecond = map[MergedFormat]float64{
+	MergedMP3:  128000 / 8,
+	MergedOpus: 64000 / 8,
+}
+
+// PlannedChunk is one chunk a run with the planned options is expected to produce.
+type PlannedChunk struct {
+	Index           int     `json:"index"`
+	Track           int     `json:"track,omitempty"`
+	StartSeconds    float64 `json:"startSeconds"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// VideoStartSeconds and VideoDurationSeconds preview the keyframe-aligned video clip
+	// containing the chunk's start, when video clips are planned.
+	VideoStartSeconds    float64 `json:"videoStartSeconds,omitempty"`
+	VideoDurationSeconds float64 `json:"videoDurationSeconds,omitempty"`
+}
+
+// PlanSizes estimates the bytes each kind of artefact will take. Transcripts are small
+// and left out.
+type PlanSizes struct {
+	Chunks int64 `json:"chunks"`
+	Base64 int64 `json:"base64"`
+	Merged int64 `json:"merged"`
+	// Video is the input's size: clips are copied from it without re-encoding.
+	Video int64 `json:"video,omitempty"`
+	Total int64 `json:"total"`
+}
+
+// Plan predicts the outcome of processing a file without running the pipeline. The
+// boundaries follow the container duration; ffmpeg cuts on packet boundaries, so real
+// chunks can differ by a few milliseconds.
+type Plan struct {
+	InputSeconds   float64        `json:"inputSeconds"`
+	SegmentSeconds float64        `json:"segmentSeconds"`
+	AudioStreams   []AudioStream  `json:"audioStreams"`
+	ChunkCount     int            `json:"chunkCount"`
+	Chunks         []PlannedChunk `json:"chunks"`
+	// AudioSeconds is the chunk audio summed over all tracks, which processing time scales with.
+	AudioSeconds   float64   `json:"audioSeconds"`
+	EstimatedBytes PlanSizes `json:"estimatedBytes"`
+	// VideoClips is the number of video clips planned; 0 when the input has no video.
+	VideoClips int `json:"videoClips,omitempty"`
+}
+
+// Plan probes inputPath and works out the chunks and artefact sizes Process would
+// produce with opts. It runs ffmpeg to read the input's header and, for video clips, to
+// find the keyframes.
+func (p *Processor) Plan(ctx context.Context, inputPath string, opts Options) (Plan, error) {
+	inputSeconds, err := p.ProbeDuration(ctx, inputPath)
+	if err != nil {
+		return Plan{}, fmt.Errorf("probing input: %w", err)
+	}
+	streams, _ := p.ProbeAudioStreams(ctx, inputPath)
+	if streams == nil {
+		streams = []AudioStream{}
+	}
+	if _, err := trackRuns(opts, len(streams)); err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{
+		InputSeconds:   inputSeconds,
+		SegmentSeconds: float64(opts.ChunkDurationSeconds),
+		AudioStreams:   streams,
+	}
+	if opts.ChunkCount > 0 {
+		opts.ChunkCount = limitedChunkCount(inputSeconds, opts.ChunkCount, opts)
+		// Same rounding as segment, so the boundaries match a real run.
+		plan.SegmentSeconds = math.Ceil(inputSeconds/float64(opts.ChunkCount)*1000) / 1000
+	}
+	if plan.SegmentSeconds <= 0 {
+		return Plan{}, errors.New("chunk length must be positive")
+	}
+
+	tracks := []int{opts.AudioTrack}
+	if opts.TrackMode == TrackSeparate {
+		tracks = tracks[:0]
+		for i := range streams {
+			tracks = append(tracks, i)
+		}
+	}
+	durations := plannedDurations(inputSeconds, plan.SegmentSeconds, opts)
+	for _, track := range tracks {
+		var start float64
+		for _, duration := range durations {
+			plan.Chunks = append(plan.Chunks, PlannedChunk{
+				Index:           len(plan.Chunks),
+				Track:           track,
+				StartSeconds:    start,
+				DurationSeconds: duration,
+			})
+			start += duration
+			plan.AudioSeconds += duration
+		}
+	}
+	plan.ChunkCount = len(plan.Chunks)
+	plan.EstimatedBytes = plannedSizes(plan, opts)
+
+	if opts.VideoClips {
+		ffmpeg := p.FFmpegBin
+		if ffmpeg == "" {
+			ffmpeg = "ffmpeg"
+		}
+		keyframes, err := p.probeKeyframes(ctx, ffmpeg, inputPath)
+		if err != nil {
+			return Plan{}, err
+		}
+		if keyframes != nil {
+			plan.VideoClips = planVideoClips(plan.Chunks, keyframes, inputSeconds)
+			if info, err := os.Stat(inputPath); err == nil {
+				plan.EstimatedBytes.Video = info.Size()
+				plan.EstimatedBytes.Total += info.Size()
+			}
+		}
+	}
+	return plan, nil
+}
+
+// planVideoClips fills in the video clip of every chunk the way writeVideoClips cuts
+// them, returning the number of clips.
+func planVideoClips(chunks []PlannedChunk, keyframes []float64, inputSeconds float64) int {
+	if len(chunks) == 0 {
+		return 0
+	}
+	var starts []float64
+	for _, c := range chunks {
+		if c.Track == chunks[0].Track {
+			starts = append(starts, c.StartSeconds)
+		}
+	}
+	bounds := append([]float64{0}, keyframeCuts(starts, keyframes)...)
+	for i := range chunks {
+		t := nearestKeyframe(chunks[i].StartSeconds, keyframes)
+		j := len(bounds) - 1
+		for j > 0 && bounds[j] > t+remainderTolerance {
+			j--
+		}
+		end := inputSeconds
+		if j+1 < len(bounds) {
+			end = bounds[j+1]
+		}
+		chunks[i].VideoStartSeconds = bounds[j]
+		chunks[i].VideoDurationSeconds = end - bounds[j]
+	}
+	return len(bounds)
+}
+
+// plannedDurations splits one track into chunk lengths, applying the chunk count or
+// the remainder policy the way segmentTrack does.
+func plannedDurations(inputSeconds, segmentSeconds float64, opts Options) []float64 {
+	var durations []float64
+	for i := 0; ; i++ {
+		left := inputSeconds - float64(i)*segmentSeconds
+		if left <= 0.001 {
+			break
+		}
+		durations = append(durations, math.Min(segmentSeconds, left))
+	}
+	if opts.ChunkCount > 0 {
+		for len(durations) > opts.ChunkCount && len(durations) > 1 {
+			last := len(durations) - 1
+			durations[last-1] += durations[last]
+			durations = durations[:last]
+		}
+		return durations
+	}
+
+	last := len(durations) - 1
+	if last < 0 || durations[last] >= segmentSeconds-remainderTolerance {
+		return durations
+	}
+	switch opts.Remainder {
+	case RemainderMerge:
+		if last > 0 && fitsLimit(durations[last-1]+durations[last], opts) {
+			durations[last-1] += durations[last]
+			durations = durations[:last]
+		}
+	case RemainderPad:
+		durations[last] = segmentSeconds
+	}
+	return durations
+}
+
+// plannedSizes estimates the artefact sizes, counting the padding added around chunks.
+func plannedSizes(plan Plan, opts Options) PlanSizes {
+	var sizes PlanSizes
+	if !opts.TranscriptOnly {
+		padding := 2 * float64(opts.PaddingMillis) / 1000
+		for _, chunk := range plan.Chunks {
+			wav := wavHeaderBytes + int64(math.Round((chunk.DurationSeconds+padding)*chunkBytesPerSecond))
+			sizes.Chunks += wav
+			if opts.MakeBase64 {
+				sizes.Base64 += (wav + 2) / 3 * 4
+			}
+		}
+	}
+	if rate, ok := mergedBytesPerSecond[opts.MergedFormat]; ok {
+		sizes.Merged = int64(math.Round(plan.InputSeconds * rate))
+	}
+	sizes.Total = sizes.Chunks + sizes.Base64 + sizes.Merged
+	return sizes
+}
@@ -0,0 +1,127 @@
+package chunker
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool bounds how many runs of one kind execute at once across every job sharing it. A
+// nil Pool does not limit anything.
+type Pool struct {
+	slots chan struct{}
+}
+
+// NewPool returns a pool of size slots, or nil for an unlimited pool when size <= 0.
+func NewPool(size int) *Pool {
+	if size <= 0 {
+		return nil
+	}
+	return &Pool{slots: make(chan struct{}, size)}
+}
+
+// Size is the number of slots, or 0 when unlimited.
+func (p *Pool) Size() int {
+	if p == nil {
+		return 0
+	}
+	return cap(p.slots)
+}
+
+// InUse is the number of slots currently held.
+func (p *Pool) InUse() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.slots)
+}
+
+// acquire waits for a slot; the caller must release it once done.
+func (p *Pool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	if p != nil {
+		<-p.slots
+	}
+}
+
+// WithPools bounds ffmpeg encoding passes and whisper transcriptions separately across
+// every job the Processor runs, so a GPU box can transcribe several chunks at once while
+// a single ffmpeg runs. A size of 0 leaves that kind unlimited.
+func WithPools(encode, transcribe int) Option {
+	return func(p *Processor) {
+		p.EncodePool = NewPool(encode)
+		p.TranscribePool = NewPool(transcribe)
+	}
+}
+
+// encoding runs fn, an ffmpeg pass over the whole input, holding an encode slot.
+func (p *Processor) encoding(ctx context.Context, fn func() error) error {
+	if err := p.EncodePool.acquire(ctx); err != nil {
+		return err
+	}
+	defer p.EncodePool.release()
+	return fn()
+}
+
+// forEachChunk calls fn for chunks 0..n-1, running up to limit of them at once. The first
+// error cancels the chunks still running and is returned; later ones are dropped.
+func forEachChunk(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	if limit <= 1 {
+		for i := range n {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		next     = make(chan int)
+	)
+	for range min(limit, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+feed:
+	for i := range n {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
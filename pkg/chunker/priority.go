@@ -0,0 +1,144 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommandPriority lowers the operating-system priority of the ffmpeg and whisper
+// processes the Processor starts, so heavy jobs leave room for everything else on the
+// machine. Zero fields keep the default. Only Linux supports every field; elsewhere a
+// non-zero priority is noted in the command's log and otherwise ignored.
+type CommandPriority struct {
+	// Nice is the niceness commands run at, from 1 (slightly lower priority) to 19 (lowest).
+	Nice int
+	// IOClass is the I/O scheduling class: IOClassBestEffort or IOClassIdle, which only
+	// gets the disk when nothing else wants it.
+	IOClass string
+	// IOLevel is the priority within IOClassBestEffort, from 0 (highest) to 7 (lowest).
+	IOLevel int
+	// CPUs pins commands to these CPU numbers.
+	CPUs []int
+}
+
+// I/O scheduling classes for CommandPriority.IOClass.
+const (
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+// IsZero reports whether p leaves commands at the default priority.
+func (p CommandPriority) IsZero() bool {
+	return p.Nice == 0 && p.IOClass == "" && p.IOLevel == 0 && len(p.CPUs) == 0
+}
+
+// Validate checks the ranges the operating system accepts.
+func (p CommandPriority) Validate() error {
+	if p.Nice < 0 || p.Nice > 19 {
+		return fmt.Errorf("nice must be between 0 and 19, not %d", p.Nice)
+	}
+	switch p.IOClass {
+	case "", IOClassBestEffort, IOClassIdle:
+	default:
+		return fmt.Errorf("unknown I/O class %q (want %s or %s)", p.IOClass, IOClassBestEffort, IOClassIdle)
+	}
+	if p.IOLevel < 0 || p.IOLevel > 7 {
+		return fmt.Errorf("I/O level must be between 0 and 7, not %d", p.IOLevel)
+	}
+	if p.IOLevel != 0 && p.IOClass != IOClassBestEffort {
+		return fmt.Errorf("an I/O level needs the %s I/O class", IOClassBestEffort)
+	}
+	for _, cpu := range p.CPUs {
+		if cpu < 0 || cpu >= maxCPUs {
+			return fmt.Errorf("CPU %d out of range", cpu)
+		}
+	}
+	return nil
+}
+
+// maxCPUs bounds the CPU numbers CommandPriority.CPUs accepts.
+const maxCPUs = 1024
+
+// ParseCPUList reads a CPU list as taskset and cgroups write it, such as "0-3,6".
+func ParseCPUList(value string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil || to < from {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		if from < 0 || to >= maxCPUs {
+			return nil, fmt.Errorf("CPU range %q out of range", part)
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			seen[cpu] = true
+		}
+	}
+	cpus := make([]int, 0, len(seen))
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// WithCommandPriority runs every ffmpeg and whisper command at priority.
+func WithCommandPriority(priority CommandPriority) Option {
+	return func(p *Processor) {
+		p.Priority = priority
+	}
+}
+
+// commandLimits bound the external commands started under a context carrying them.
+type commandLimits struct {
+	priority CommandPriority
+	// timeouts maps a binary, as configured, to how long one run of it may take.
+	timeouts map[string]time.Duration
+}
+
+type commandLimitsKey struct{}
+
+// withCommandLimits returns a context under which commands run at the processor's
+// priority and within the call's timeouts.
+func (p *Processor) withCommandLimits(ctx context.Context, opts Options) context.Context {
+	if p.Priority.IsZero() && opts.FFmpegTimeout <= 0 && opts.WhisperTimeoutPerChunk <= 0 {
+		return ctx
+	}
+	ffmpeg := p.FFmpegBin
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	limits := commandLimits{priority: p.Priority, timeouts: make(map[string]time.Duration)}
+	if opts.FFmpegTimeout > 0 {
+		limits.timeouts[ffmpeg] = opts.FFmpegTimeout
+	}
+	if opts.WhisperTimeoutPerChunk > 0 && p.WhisperBin != "" {
+		limits.timeouts[p.WhisperBin] = opts.WhisperTimeoutPerChunk
+	}
+	return context.WithValue(ctx, commandLimitsKey{}, limits)
+}
+
+func contextLimits(ctx context.Context) commandLimits {
+	limits, _ := ctx.Value(commandLimitsKey{}).(commandLimits)
+	return limits
+}
+
+// timeoutError describes a command stopped by its timeout.
+func timeoutError(name string, timeout time.Duration) error {
+	return fmt.Errorf("%s did not finish within %s: %w", filepath.Base(name), timeout, context.DeadlineExceeded)
+}
@@ -0,0 +1,23 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package chunker
+
+import (
+	"errors"
+	"syscall"
+)
+
+// applyPriority renices the freshly started process pid. macOS and the BSDs have no
+// per-process I/O classes or CPU affinity the way Linux does, so those settings fail.
+func applyPriority(pid int, p CommandPriority) error {
+	var errs []error
+	if p.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, p.Nice); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.IOClass != "" || len(p.CPUs) > 0 {
+		errs = append(errs, errors.New("I/O classes and CPU pinning are only supported on Linux"))
+	}
+	return errors.Join(errs...)
+}
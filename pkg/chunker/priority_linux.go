@@ -0,0 +1,48 @@
+//go:build linux
+
+package chunker
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// Linux's ioprio_set encoding: the class sits above the 13 bits of the level.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+)
+
+// applyPriority lowers the priority of the freshly started process pid. It runs right
+// after the start; the worker threads ffmpeg and whisper start once they have read their
+// input inherit the settings.
+func applyPriority(pid int, p CommandPriority) error {
+	var errs []error
+	if p.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, p.Nice); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.IOClass != "" {
+		prio := ioprioClassIdle << ioprioClassShift
+		if p.IOClass == IOClassBestEffort {
+			prio = ioprioClassBE<<ioprioClassShift | p.IOLevel
+		}
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(prio)); errno != 0 {
+			errs = append(errs, errno)
+		}
+	}
+	if len(p.CPUs) > 0 {
+		mask := make([]uint64, maxCPUs/64)
+		for _, cpu := range p.CPUs {
+			mask[cpu/64] |= 1 << (cpu % 64)
+		}
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(pid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0]))); errno != 0 {
+			errs = append(errs, errno)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package chunker
+
+import "errors"
+
+// applyPriority is unsupported outside Linux, macOS, and the BSDs; commands keep the
+// default priority.
+func applyPriority(pid int, p CommandPriority) error {
+	return errors.New("command priorities are only supported on Linux, macOS, and the BSDs")
+}
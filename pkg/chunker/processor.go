@@ -0,0 +1,1303 @@
+package chunker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Processor wraps the external binaries used to transform uploaded media.
+type Processor struct {
+	FFmpegBin   string
+	WhisperBin  string
+	WhisperArgs []string
+	// Cache, when set, short-circuits whisper for audio it has already transcribed.
+	Cache TranscriptCache
+	// Resident, when set and ready, transcribes instead of starting WhisperBin per chunk.
+	Resident *ResidentWhisper
+	// EncodePool and TranscribePool bound the ffmpeg passes over whole inputs and the
+	// whisper transcriptions running at once across all jobs; see WithPools.
+	EncodePool     *Pool
+	TranscribePool *Pool
+	// Hooks observe every Process and AddChunk call; see Hook.
+	Hooks []Hook
+	// Summarizer, when set, serves Options.Summarize.
+	Summarizer *Summarizer
+	// Keywords serves Options.ExtractKeywords; nil means LocalKeywords.
+	Keywords KeywordExtractor
+	// Sentiment serves Options.AnalyzeSentiment; nil means LocalSentiment.
+	Sentiment SentimentAnalyzer
+	// Redactor, when set, serves Options.Redact.
+	Redactor *Redactor
+	// Priority lowers the OS priority of every ffmpeg and whisper run; see
+	// CommandPriority.
+	Priority CommandPriority
+	// Acceleration, when set, runs whisper and video decoding on a GPU; see
+	// DetectAcceleration.
+	Acceleration *Acceleration
+	// Tools are the ffmpeg and whisper versions recorded on each Result; see WithTools.
+	Tools []ToolVersion
+}
+
+// RemainderPolicy decides what happens to a final chunk shorter than the requested duration.
+type RemainderPolicy string
+
+const (
+	// RemainderKeep leaves the short final chunk as ffmpeg produced it.
+	RemainderKeep RemainderPolicy = "keep"
+	// RemainderMerge appends the short final chunk onto the previous one.
+	RemainderMerge RemainderPolicy = "merge"
+	// RemainderPad extends the short final chunk with silence to the full duration.
+	RemainderPad RemainderPolicy = "pad"
+)
+
+// remainderTolerance ignores sub-frame differences when detecting a short final chunk.
+const remainderTolerance = 0.05
+
+// ParseRemainderPolicy validates a policy name; an empty string maps to RemainderKeep.
+func ParseRemainderPolicy(value string) (RemainderPolicy, error) {
+	switch RemainderPolicy(strings.ToLower(strings.TrimSpace(value))) {
+	case "", RemainderKeep:
+		return RemainderKeep, nil
+	case RemainderMerge:
+		return RemainderMerge, nil
+	case RemainderPad:
+		return RemainderPad, nil
+	}
+	return "", fmt.Errorf("unknown remainder policy %q (want keep, merge, or pad)", value)
+}
+
+// MaxChunkCount bounds Options.ChunkCount, so a single request cannot have a job write
+// an unbounded number of files.
+const MaxChunkCount = 10000
+
+// Options tunes how audio chunks are generated and whether extras are produced.
+type Options struct {
+	ChunkDurationSeconds int
+	MakeBase64           bool
+	Transcribe           bool
+	Remainder            RemainderPolicy
+	// ChunkCount, when positive, splits the input into exactly this many equal chunks
+	// instead of using ChunkDurationSeconds. It may be at most MaxChunkCount.
+	ChunkCount int
+	// FadeMillis applies a fade-in and fade-out of this length to every chunk.
+	FadeMillis int
+	// PaddingMillis adds this much silence before and after every chunk.
+	PaddingMillis int
+	// MaxChunkSeconds, when positive, is the longest chunk, padding included, the
+	// transcription backend accepts. ChunkCount mode adds chunks to stay under it and a
+	// merged remainder is kept apart if merging would exceed it; a ChunkDurationSeconds
+	// that does not fit fails the run, so callers shorten it with FitChunkDuration.
+	MaxChunkSeconds float64
+	// Progress, when set, is called as each stage starts and as chunks finish.
+	Progress func(Progress) `json:"-"`
+	// Hooks observe this call only, after the Processor's own hooks; see Hook.
+	Hooks []Hook `json:"-"`
+	// ReuseChunksDir points at the chunks directory of an earlier run over the same input
+	// with the same SegmentKey; its files are linked in instead of re-running ffmpeg.
+	ReuseChunksDir string
+	// AudioFilters is an ffmpeg filter chain (the -af argument) applied while extracting
+	// audio, before segmenting. See FilterPreset for vetted chains.
+	AudioFilters string
+	// TrackMode picks the audio stream(s) to chunk; empty means TrackSingle.
+	TrackMode TrackMode
+	// AudioTrack is the 0-based audio stream used in TrackSingle mode.
+	AudioTrack int
+	// SkipSilence marks chunks that never rise above SilenceThresholdDB as silent and
+	// leaves them out of transcription.
+	SkipSilence bool
+	// SilenceThresholdDB overrides DefaultSilenceThresholdDB when non-zero.
+	SilenceThresholdDB float64
+	// Escalation re-transcribes low-confidence chunks with heavier whisper arguments.
+	Escalation EscalationPolicy
+	// TranscriptSets adds transcription passes with their own whisper arguments, run in
+	// parallel with the main transcript of each chunk.
+	TranscriptSets []TranscriptSet
+	// QualityGate skips transcription of chunks that are too short, too quiet, or clip
+	// too much. Chunks are still written; only whisper is spared.
+	QualityGate QualityGate
+	// TranscriptOnly cuts chunks into a temporary directory outside the job and deletes
+	// them once transcribed. Chunks keep their timing, checksum, and transcripts but no
+	// AudioFile, and no Base64 dumps are written. Requires Transcribe.
+	TranscriptOnly bool
+	// WordTimestamps asks whisper for its full JSON output and stores the words of each
+	// main transcript with their timings and confidence as transcripts/<chunk>.words.json.
+	WordTimestamps bool
+	// WhisperArgs are appended after Processor.WhisperArgs for every transcription of
+	// this job, before any escalation or transcript set arguments. Callers taking them
+	// from end users should check them with ValidateWhisperArgs.
+	WhisperArgs []string
+	// MergedFormat, when set, also encodes the full extracted audio into a single
+	// loudness-normalised listening file under merged/.
+	MergedFormat MergedFormat
+	// TranscribeConcurrency is how many chunks of this job are post-processed and
+	// transcribed at once; 0 and 1 take them one at a time. Whisper runs still wait for
+	// the Processor's TranscribePool.
+	TranscribeConcurrency int
+	// VideoClips also copies the input's video into clips under video/, cut without
+	// re-encoding at the keyframes nearest the chunk boundaries. See Chunk.VideoFile.
+	VideoClips bool
+	// Subtitles writes subtitled outputs from the main transcripts once every chunk is
+	// transcribed. It has no effect without transcription; cues are finest with
+	// WordTimestamps, which gives them whisper's segment timings.
+	Subtitles SubtitleMode
+	// Summarize sends the transcribed job to the Processor's Summarizer for a summary,
+	// chapters, and action items. A failed request does not fail the run.
+	Summarize bool
+	// ExtractKeywords stores the keywords and named entities of each main transcript as
+	// Chunk.Keywords, using the Processor's Keywords extractor.
+	ExtractKeywords bool
+	// AnalyzeSentiment stores the sentiment and emotions of each main transcript as
+	// Chunk.Sentiment, using the Processor's Sentiment analyzer.
+	AnalyzeSentiment bool
+	// DetectChapters writes the chapters of the transcribed job, taken from the summary
+	// when it has some and detected otherwise, as YouTube-style text and FFMETADATA.
+	DetectChapters bool
+	// Redact writes redacted variants of each main transcript, and for RedactAudio of the
+	// chunk audio, with what the Processor's Redactor matches removed.
+	Redact RedactionMode
+	// DetectPII flags the email addresses, phone numbers, and card numbers in each main
+	// transcript as Chunk.PII and writes a masked variant of it.
+	DetectPII bool
+	// FFmpegTimeout, when positive, stops any single ffmpeg run taking longer and fails
+	// the stage it belongs to.
+	FFmpegTimeout time.Duration
+	// WhisperTimeoutPerChunk, when positive, stops a whisper run on one chunk taking
+	// longer. The chunk fails as it would on any whisper error.
+	WhisperTimeoutPerChunk time.Duration
+	// CPUOnly keeps this job off the GPU even when the Processor has Acceleration.
+	CPUOnly bool
+	// Fault injects a simulated failure into this run. See Fault.
+	Fault Fault
+}
+
+// SegmentKey fingerprints the options that change the chunk audio itself. Two runs
+// over the same input with equal keys produce identical chunk files.
+func (o Options) SegmentKey() string {
+	remainder := o.Remainder
+	if remainder == "" {
+		remainder = RemainderKeep
+	}
+	var key string
+	if o.ChunkCount > 0 {
+		key = fmt.Sprintf("v1;count=%d;fade=%d;pad=%d", o.ChunkCount, o.FadeMillis, o.PaddingMillis)
+	} else {
+		key = fmt.Sprintf("v1;duration=%d;remainder=%s;fade=%d;pad=%d", o.ChunkDurationSeconds, remainder, o.FadeMillis, o.PaddingMillis)
+	}
+	// Appended only when set so keys of default jobs stay comparable with older ones.
+	if o.AudioFilters != "" {
+		key += ";af=" + o.AudioFilters
+	}
+	switch {
+	case o.TrackMode == TrackMix || o.TrackMode == TrackSeparate:
+		key += ";tracks=" + string(o.TrackMode)
+	case o.AudioTrack > 0:
+		key += fmt.Sprintf(";track=%d", o.AudioTrack)
+	}
+	return key
+}
+
+// Result captures the generated chunks alongside the command output.
+type Result struct {
+	Chunks []Chunk
+	Logs   []string
+	// MediaCreationTime is the container's creation_time tag, when ffmpeg reported one.
+	MediaCreationTime *time.Time
+	// SegmentSeconds is the segment length actually used; in ChunkCount mode it is derived
+	// from the input duration.
+	SegmentSeconds float64
+	// InputSeconds is the input's duration as probed by ffmpeg, or 0 when it was not reported.
+	InputSeconds float64
+	// AudioStreams lists the input's audio streams, when ffmpeg reported them.
+	AudioStreams []AudioStream
+	// MergedAudioFile is the listening file relative to the job directory, when requested.
+	MergedAudioFile string
+	// SubtitlesFile and SubtitledVideoFile are the subtitled outputs relative to the job
+	// directory, when requested and written.
+	SubtitlesFile      string
+	SubtitledVideoFile string
+	// Summary is the summarization stage's outcome, when requested.
+	Summary *Summary
+	// Chapters and the files listing them, relative to the job directory, when requested
+	// and written.
+	Chapters            []Chapter
+	ChaptersFile        string
+	ChapterMetadataFile string
+	// Commands lists every ffmpeg and whisper invocation, including those of a failed run.
+	Commands []CommandRun
+	// CPUFallback reports that a GPU run failed and the rest of the job ran on the CPU.
+	CPUFallback bool
+	// Tools are the versions of the programs the run used, as far as they are known.
+	Tools []ToolVersion
+}
+
+// Process runs ffmpeg (and optionally Whisper) to populate the job directory.
+func (p *Processor) Process(ctx context.Context, jobDir, inputPath string, opts Options) (Result, error) {
+	commands := &CommandLog{}
+	ctx = p.withCommandLimits(p.withHooks(WithCommandLog(ctx, commands), opts), opts)
+	ctx = p.withAcceleration(ctx, opts)
+	result, err := p.process(ctx, jobDir, inputPath, opts)
+	if log := outputLogOf(ctx); log != nil {
+		log.Notes(result.Logs)
+		if err != nil {
+			log.Notes([]string{"failed: " + err.Error()})
+		}
+	}
+	result.Commands = commands.Runs()
+	result.CPUFallback = fellBackToCPU(ctx)
+	result.Tools = p.ToolsFor(opts)
+	return result, err
+}
+
+func (p *Processor) process(ctx context.Context, jobDir, inputPath string, opts Options) (Result, error) {
+	ffmpeg := p.FFmpegBin
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	if _, err := lookBinary(ffmpeg); err != nil {
+		return Result{}, fmt.Errorf("ffmpeg binary not found: %w", err)
+	}
+
+	// Leftovers of a crashed run are never picked up; chunks are cut into the staging
+	// directory and published once final.
+	staging := filepath.Join(jobDir, StagingDir)
+	if err := os.RemoveAll(staging); err != nil {
+		return Result{}, fmt.Errorf("clearing staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	chunksDir := filepath.Join(staging, "chunks")
+
+	if opts.TranscriptOnly {
+		if !opts.Transcribe || p.WhisperBin == "" {
+			return Result{}, errors.New("transcript-only mode needs transcription")
+		}
+		tmp, err := os.MkdirTemp("", "audi-chunks-*")
+		if err != nil {
+			return Result{}, fmt.Errorf("creating temporary chunk directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		chunksDir = tmp
+		opts.MakeBase64 = false
+	}
+
+	for _, dir := range []string{chunksDir, filepath.Join(jobDir, "chunks"), filepath.Join(jobDir, "base64"), filepath.Join(jobDir, "transcripts")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return Result{}, fmt.Errorf("creating processing directory: %w", err)
+		}
+	}
+
+	var (
+		seg segmentation
+		err error
+	)
+	notifyStageStart(ctx, PipelineProbe)
+	seg.inputSeconds, _ = p.ProbeDuration(ctx, inputPath)
+	seg.streams, _ = p.ProbeAudioStreams(ctx, inputPath)
+	notifyStageEnd(ctx, PipelineProbe)
+
+	notifyStageStart(ctx, PipelineChunk)
+	notifyPhaseStart(ctx, StageExtract)
+	if opts.ReuseChunksDir != "" {
+		seg.files, err = linkChunks(opts.ReuseChunksDir, chunksDir)
+		seg.logs = []string{fmt.Sprintf("reused %d chunks from %s", len(seg.files), opts.ReuseChunksDir)}
+		if err != nil {
+			return Result{Logs: seg.logs}, fmt.Errorf("reusing chunks: %w", err)
+		}
+	} else if opts.Fault == FaultFFmpeg {
+		var logEntry string
+		logEntry, err = injectFFmpegFailure(ctx, ffmpeg, inputPath)
+		return Result{Logs: []string{logEntry}}, err
+	} else {
+		err = p.encoding(ctx, func() (err error) {
+			seg, err = p.segment(ctx, ffmpeg, inputPath, chunksDir, seg, opts)
+			return err
+		})
+		if err != nil {
+			return Result{Logs: seg.logs}, err
+		}
+	}
+	chunkFiles, logs, creationTime := seg.files, seg.logs, seg.creationTime
+	if !opts.TranscriptOnly {
+		if chunkFiles, err = publishChunks(jobDir, chunkFiles); err != nil {
+			return Result{Logs: logs}, err
+		}
+	}
+
+	var mergedFile string
+	if opts.MergedFormat != MergedNone {
+		var mergedLog string
+		err = p.encoding(ctx, func() (err error) {
+			mergedFile, mergedLog, err = p.writeMergedAudio(ctx, ffmpeg, jobDir, inputPath, len(seg.streams), opts)
+			return err
+		})
+		logs = append(logs, mergedLog)
+		if err != nil {
+			return Result{Logs: logs}, err
+		}
+	}
+	notifyStageEnd(ctx, PipelineChunk)
+
+	transcribe := opts.Transcribe && p.WhisperBin != ""
+
+	chunkStage := StagePostprocess
+	if transcribe {
+		chunkStage = StageTranscribe
+	}
+	notifyPhaseStart(ctx, chunkStage)
+	var audioTotal, audioDone, startSeconds float64
+	if opts.Progress != nil {
+		for _, chunkPath := range chunkFiles {
+			if d, err := wavDuration(chunkPath); err == nil {
+				audioTotal += d
+			}
+		}
+		opts.report(StageExtract, audioTotal, audioTotal)
+		opts.report(chunkStage, 0, audioTotal)
+	}
+
+	// Timings come from the files, so every chunk is placed before any is finished.
+	chunks := make([]Chunk, 0, len(chunkFiles))
+	for idx, chunkPath := range chunkFiles {
+		duration, err := wavDuration(chunkPath)
+		if err != nil {
+			return Result{Logs: logs}, fmt.Errorf("determining chunk duration: %w", err)
+		}
+
+		// Report the source audio covered, not the silence added around it.
+		if opts.PaddingMillis > 0 {
+			duration = math.Max(0, duration-2*float64(opts.PaddingMillis)/1000)
+		}
+
+		track := opts.AudioTrack
+		if t, ok := chunkTrack(filepath.Base(chunkPath)); ok {
+			track = t
+		}
+		// Each separately chunked stream starts again at zero.
+		if idx > 0 && track != chunks[idx-1].Track {
+			startSeconds = 0
+		}
+
+		chunks = append(chunks, Chunk{
+			Index:           idx,
+			Track:           track,
+			StartSeconds:    startSeconds,
+			DurationSeconds: duration,
+			AudioFile:       filepath.ToSlash(filepath.Join("chunks", filepath.Base(chunkPath))),
+		})
+		startSeconds += duration
+	}
+
+	if opts.MakeBase64 {
+		notifyStageStart(ctx, PipelineBase64)
+	}
+	if transcribe {
+		notifyStageStart(ctx, PipelineTranscribe)
+	}
+	// Chunks may finish out of order; hooks and progress are reported one at a time.
+	var mu sync.Mutex
+	chunkLogs := make([][]string, len(chunks))
+	err = forEachChunk(ctx, len(chunks), opts.TranscribeConcurrency, func(ctx context.Context, i int) error {
+		chunk := &chunks[i]
+		var err error
+		chunkLogs[i], err = p.finishChunk(ctx, jobDir, chunkFiles[i], chunk, opts)
+		if err != nil {
+			return err
+		}
+		if opts.TranscriptOnly {
+			chunk.AudioFile = ""
+			_ = os.Remove(chunkFiles[i])
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		notifyChunkDone(ctx, *chunk)
+		audioDone += chunk.DurationSeconds
+		opts.report(chunkStage, audioDone, audioTotal)
+		return nil
+	})
+	for _, entries := range chunkLogs {
+		logs = append(logs, entries...)
+	}
+	if err != nil {
+		return Result{Logs: logs}, err
+	}
+	if opts.MakeBase64 {
+		notifyStageEnd(ctx, PipelineBase64)
+	}
+	if transcribe {
+		notifyStageEnd(ctx, PipelineTranscribe)
+	}
+
+	notifyStageStart(ctx, PipelinePostprocess)
+
+	if opts.VideoClips {
+		var videoLogs []string
+		err := p.encoding(ctx, func() (err error) {
+			videoLogs, err = p.writeVideoClips(ctx, ffmpeg, jobDir, inputPath, chunks)
+			return err
+		})
+		logs = append(logs, videoLogs...)
+		if err != nil {
+			return Result{Logs: logs}, err
+		}
+	}
+
+	var subtitlesFile, subtitledVideo string
+	if transcribe && opts.Subtitles != SubtitlesNone {
+		var subtitleLogs []string
+		err := p.encoding(ctx, func() (err error) {
+			subtitlesFile, subtitledVideo, subtitleLogs, err = p.writeSubtitles(ctx, ffmpeg, jobDir, inputPath, chunks, opts.Subtitles)
+			return err
+		})
+		logs = append(logs, subtitleLogs...)
+		if err != nil {
+			return Result{Logs: logs}, err
+		}
+	}
+
+	var summary *Summary
+	if transcribe && opts.Summarize && p.Summarizer != nil {
+		var summaryLogs []string
+		summary, summaryLogs = p.summarize(ctx, jobDir, chunks)
+		logs = append(logs, summaryLogs...)
+	}
+
+	var chapters []Chapter
+	var chaptersFile, chapterMetadataFile string
+	if transcribe && opts.DetectChapters {
+		var chapterLogs []string
+		chapters, chaptersFile, chapterMetadataFile, chapterLogs, err = writeChapters(jobDir, chunks, summary)
+		logs = append(logs, chapterLogs...)
+		if err != nil {
+			return Result{Logs: logs}, err
+		}
+	}
+
+	notifyStageEnd(ctx, PipelinePostprocess)
+	return Result{Chunks: chunks, Logs: logs, MediaCreationTime: creationTime, SegmentSeconds: seg.segmentSeconds, InputSeconds: seg.inputSeconds, AudioStreams: seg.streams, MergedAudioFile: mergedFile, SubtitlesFile: subtitlesFile, SubtitledVideoFile: subtitledVideo, Summary: summary, Chapters: chapters, ChaptersFile: chaptersFile, ChapterMetadataFile: chapterMetadataFile}, nil
+}
+
+// finishChunk post-processes one chunk file already in place under chunks/: it writes the
+// Base64 dump, hashes the audio, applies the silence check and quality gate, and runs the
+// transcription passes, filling in chunk as it goes.
+func (p *Processor) finishChunk(ctx context.Context, jobDir, chunkPath string, chunk *Chunk, opts Options) ([]string, error) {
+	transcribe := opts.Transcribe && p.WhisperBin != ""
+	base := strings.TrimSuffix(filepath.Base(chunkPath), filepath.Ext(chunkPath))
+	var logs []string
+
+	if opts.Fault == FaultDiskFull && chunk.Index == 0 {
+		return logs, &stageError{PipelineChunk, fmt.Errorf("writing chunk: %w", injectDiskFull(chunkPath))}
+	}
+
+	if opts.MakeBase64 {
+		base64File := "base64/" + base + ".b64.txt"
+		base64Path, err := stagingPath(jobDir, base64File)
+		if err == nil {
+			err = writeBase64File(chunkPath, base64Path)
+		}
+		if err == nil {
+			err = publish(jobDir, base64File)
+		}
+		if err != nil {
+			return logs, &stageError{PipelineBase64, fmt.Errorf("creating base64 dump: %w", err)}
+		}
+		chunk.Base64File = base64File
+	}
+
+	checksum, err := fileSHA256(chunkPath)
+	if err != nil {
+		return logs, fmt.Errorf("hashing chunk: %w", err)
+	}
+	chunk.Checksum = checksum
+
+	if opts.SkipSilence {
+		threshold := opts.SilenceThresholdDB
+		if threshold == 0 {
+			threshold = DefaultSilenceThresholdDB
+		}
+		silent, err := chunkIsSilent(chunkPath, threshold)
+		if err != nil {
+			logs = append(logs, fmt.Sprintf("silence check for chunk %d failed: %v", chunk.Index, err))
+		}
+		chunk.Silent = silent
+		if silent {
+			chunk.SkipReason = SkipReasonSilent
+		}
+	}
+
+	if transcribe && !chunk.Silent && opts.QualityGate.Enabled() {
+		reason, detail, err := opts.QualityGate.Check(chunkPath, chunk.DurationSeconds)
+		if err != nil {
+			logs = append(logs, fmt.Sprintf("quality check for chunk %d failed: %v", chunk.Index, err))
+		} else if reason != "" {
+			chunk.SkipReason = reason
+			logs = append(logs, fmt.Sprintf("chunk %d failed the quality gate (%s: %s); skipping transcription", chunk.Index, reason, detail))
+		}
+	}
+
+	if transcribe && chunk.Silent {
+		logs = append(logs, fmt.Sprintf("chunk %d is silent; skipping transcription", chunk.Index))
+	} else if transcribe && chunk.SkipReason == "" {
+		baseArgs := slices.Concat(p.WhisperArgs, opts.WhisperArgs)
+		var setLogs []string
+		setsDone := make(chan struct{})
+		go func() {
+			defer close(setsDone)
+			if len(opts.TranscriptSets) > 0 {
+				chunk.Transcripts, setLogs = p.transcribeSets(ctx, jobDir, chunkPath, baseArgs, opts.TranscriptSets)
+			}
+		}()
+
+		// Whisper writes into the staging directory; the transcript is published once read back.
+		transcriptFile := "transcripts/" + base + ".txt"
+		transcriptPath, err := stagingPath(jobDir, transcriptFile)
+		if err != nil {
+			<-setsDone
+			return logs, &stageError{PipelineTranscribe, err}
+		}
+		transcriptPrefix := strings.TrimSuffix(transcriptPath, ".txt")
+
+		var transcribeLog string
+		if opts.Fault == FaultWhisperTimeout {
+			transcribeLog, err = p.injectWhisperTimeout(ctx, chunkPath)
+		} else if opts.Escalation.Enabled() {
+			chunk.Confidence, chunk.Escalated, transcribeLog, err = p.transcribeEscalating(ctx, chunkPath, transcriptPrefix, baseArgs, opts.Escalation, opts.WordTimestamps)
+		} else if opts.WordTimestamps {
+			transcribeLog, err = p.transcribeChunkWith(ctx, chunkPath, transcriptPrefix, append(baseArgs, wholeJSONFlag))
+		} else {
+			transcribeLog, err = p.transcribeChunkWith(ctx, chunkPath, transcriptPrefix, baseArgs)
+		}
+		logs = append(logs, transcribeLog)
+		if opts.WordTimestamps {
+			if err == nil {
+				wordsFile := "transcripts/" + base + ".words.json"
+				wordsErr := writeWordTranscript(transcriptPrefix+".json", transcriptPrefix+".words.json", chunk.StartSeconds)
+				if wordsErr == nil {
+					wordsErr = publish(jobDir, wordsFile)
+				}
+				if wordsErr != nil {
+					logs = append(logs, fmt.Sprintf("word timings for chunk %d unavailable: %v", chunk.Index, wordsErr))
+				} else {
+					chunk.WordsFile = wordsFile
+				}
+			}
+			_ = os.Remove(transcriptPrefix + ".json")
+		}
+		if err != nil {
+			chunk.TranscriptError = fmt.Sprintf("transcription failed: %v", err)
+		} else {
+			preview, readErr := readPreview(transcriptPath, 400)
+			if readErr == nil {
+				readErr = publish(jobDir, transcriptFile)
+			}
+			if readErr != nil {
+				chunk.TranscriptError = fmt.Sprintf("unable to read transcript: %v", readErr)
+			} else {
+				chunk.TranscriptPreview = preview
+				chunk.TranscriptFile = transcriptFile
+				if opts.ExtractKeywords {
+					logs = append(logs, p.extractKeywords(ctx, jobDir, chunk)...)
+				}
+				if opts.AnalyzeSentiment {
+					logs = append(logs, p.analyzeSentiment(ctx, jobDir, chunk)...)
+				}
+				if opts.DetectPII {
+					logs = append(logs, maskChunkPII(jobDir, chunk)...)
+				}
+				if mode := opts.Redact; mode != RedactNone && p.Redactor != nil {
+					if opts.TranscriptOnly {
+						// The chunk audio is not kept, so neither is a redacted copy.
+						mode = RedactText
+					}
+					logs = append(logs, p.redactChunk(ctx, jobDir, chunkPath, chunk, mode)...)
+				}
+			}
+		}
+		<-setsDone
+		logs = append(logs, setLogs...)
+	}
+	return logs, nil
+}
+
+// creationTimePattern matches the creation_time metadata line ffmpeg prints for the input.
+var creationTimePattern = regexp.MustCompile(`(?m)^\s*creation_time\s*:\s*(\S+)`)
+
+// parseCreationTime extracts the first creation_time tag from ffmpeg output.
+func parseCreationTime(output string) *time.Time {
+	match := creationTimePattern.FindStringSubmatch(output)
+	if match == nil {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, match[1])
+	if err != nil || t.IsZero() || t.Year() < 1971 {
+		return nil
+	}
+	return &t
+}
+
+// segmentation is what segment produced: final chunk files plus ffmpeg details.
+type segmentation struct {
+	files          []string
+	logs           []string
+	creationTime   *time.Time
+	segmentSeconds float64
+	inputSeconds   float64
+	streams        []AudioStream
+}
+
+// segment runs ffmpeg to split the input into chunk files, then applies the remainder
+// policy (or chunk-count correction) and edge shaping so the returned files are final.
+// In TrackSeparate mode this happens once per audio stream. probed carries the input's
+// duration and streams.
+func (p *Processor) segment(ctx context.Context, ffmpeg, inputPath, chunksDir string, probed segmentation, opts Options) (segmentation, error) {
+	inputSeconds := probed.inputSeconds
+
+	seg := segmentation{segmentSeconds: float64(opts.ChunkDurationSeconds), inputSeconds: inputSeconds, streams: probed.streams}
+	segmentTime := strconv.Itoa(opts.ChunkDurationSeconds)
+	if opts.ChunkCount > MaxChunkCount {
+		return seg, fmt.Errorf("chunk count %d exceeds the maximum of %d", opts.ChunkCount, MaxChunkCount)
+	}
+	if opts.ChunkCount > 0 {
+		if inputSeconds <= 0 {
+			return seg, &stageError{PipelineProbe, errors.New("chunk count mode needs the input duration, but ffmpeg did not report one")}
+		}
+		// Round up to the millisecond so N segments always cover the input; the last
+		// chunk then falls short by at most a few milliseconds instead of becoming a sliver.
+		if count := limitedChunkCount(inputSeconds, opts.ChunkCount, opts); count != opts.ChunkCount {
+			seg.logs = append(seg.logs, fmt.Sprintf("chunk count raised from %d to %d to keep chunks within the %s limit", opts.ChunkCount, count, formatLimit(opts.MaxChunkSeconds)))
+			opts.ChunkCount = count
+		}
+		seg.segmentSeconds = math.Ceil(inputSeconds/float64(opts.ChunkCount)*1000) / 1000
+		segmentTime = strconv.FormatFloat(seg.segmentSeconds, 'f', 3, 64)
+	} else if !fitsLimit(seg.segmentSeconds, opts) {
+		return seg, fmt.Errorf("chunks of %ds with %d ms padding exceed the %s chunk limit", opts.ChunkDurationSeconds, opts.PaddingMillis, formatLimit(opts.MaxChunkSeconds))
+	}
+
+	runs, err := trackRuns(opts, len(seg.streams))
+	if err != nil {
+		return seg, err
+	}
+
+	// Every run decodes the whole input, so together they have it to get through once each.
+	extractTotal := inputSeconds * float64(len(runs))
+	opts.report(StageExtract, 0, extractTotal)
+
+	for i, run := range runs {
+		before := inputSeconds * float64(i)
+		progress := func(seconds, speed float64) {
+			if inputSeconds > 0 {
+				seconds = min(seconds, inputSeconds)
+			}
+			opts.reportSpeed(StageExtract, before+seconds, extractTotal, speed)
+		}
+		files, err := p.segmentTrack(ctx, ffmpeg, inputPath, chunksDir, segmentTime, run, opts, &seg, progress)
+		if err != nil {
+			return seg, err
+		}
+		seg.files = append(seg.files, files...)
+	}
+	return seg, nil
+}
+
+// segmentTrack performs one segmenting pass and post-processes the chunks it produced.
+func (p *Processor) segmentTrack(ctx context.Context, ffmpeg, inputPath, chunksDir, segmentTime string, run trackRun, opts Options, seg *segmentation, progress func(seconds, speed float64)) ([]string, error) {
+	args := []string{
+		"-y",
+		"-i", inputPath,
+		"-vn",
+	}
+	args = append(args, run.args...)
+	args = append(args,
+		"-acodec", "pcm_s16le",
+		"-ar", "16000",
+		"-ac", "1",
+		"-f", "segment",
+		"-segment_time", segmentTime,
+		"-reset_timestamps", "1",
+		filepath.Join(chunksDir, run.pattern),
+	)
+
+	logEntry, err := runFFmpegProgress(ctx, ffmpeg, progress, args...)
+	seg.logs = append(seg.logs, logEntry)
+	if err != nil {
+		return nil, fmt.Errorf("running ffmpeg: %w", err)
+	}
+	if seg.creationTime == nil {
+		seg.creationTime = parseCreationTime(logEntry)
+	}
+
+	chunkFiles, err := globChunkFiles(filepath.Join(chunksDir, run.glob))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ChunkCount > 0 {
+		// Packet boundaries can still leave a tiny extra segment; fold it back in.
+		for len(chunkFiles) > opts.ChunkCount && len(chunkFiles) > 1 {
+			mergeLog, err := mergeLastChunk(ctx, ffmpeg, chunkFiles)
+			seg.logs = append(seg.logs, mergeLog)
+			if err != nil {
+				return nil, fmt.Errorf("merging surplus chunk: %w", err)
+			}
+			chunkFiles = chunkFiles[:len(chunkFiles)-1]
+		}
+	} else {
+		var remainderLogs []string
+		chunkFiles, remainderLogs, err = applyRemainderPolicy(ctx, ffmpeg, chunkFiles, opts)
+		seg.logs = append(seg.logs, remainderLogs...)
+		if err != nil {
+			return nil, fmt.Errorf("handling remainder chunk: %w", err)
+		}
+	}
+
+	for _, chunkPath := range chunkFiles {
+		duration, err := wavDuration(chunkPath)
+		if err != nil {
+			return nil, fmt.Errorf("determining chunk duration: %w", err)
+		}
+		if edgeFilter := edgeFilterChain(duration, opts); edgeFilter != "" {
+			edgeLog, err := filterChunkInPlace(ctx, ffmpeg, chunkPath, edgeFilter)
+			seg.logs = append(seg.logs, edgeLog)
+			if err != nil {
+				return nil, fmt.Errorf("shaping chunk edges: %w", err)
+			}
+		}
+	}
+
+	return chunkFiles, nil
+}
+
+// globChunks lists chunk WAV files in dir in index order.
+func globChunks(dir string) ([]string, error) {
+	return globChunkFiles(filepath.Join(dir, "chunk_*.wav"))
+}
+
+// globChunkFiles returns the files matching pattern in index order, failing when there
+// are none.
+func globChunkFiles(pattern string) ([]string, error) {
+	chunkFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("locating chunks: %w", err)
+	}
+	sortChunkFiles(chunkFiles)
+	if len(chunkFiles) == 0 {
+		return nil, errors.New("no audio chunks produced")
+	}
+	return chunkFiles, nil
+}
+
+// sortChunkFiles sorts chunk file names by the number ffmpeg wrote before the
+// extension, which only pads to three digits: chunk_1000.wav follows chunk_999.wav,
+// not chunk_100.wav. Names without a number sort by name, after those with one.
+func sortChunkFiles(files []string) {
+	key := func(name string) (string, int, bool) {
+		base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		digits := len(base) - len(strings.TrimRight(base, "0123456789"))
+		n, err := strconv.Atoi(base[len(base)-digits:])
+		return base[:len(base)-digits], n, digits > 0 && err == nil
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		pi, ni, oki := key(files[i])
+		pj, nj, okj := key(files[j])
+		switch {
+		case oki != okj:
+			return oki
+		case pi != pj || !oki:
+			return files[i] < files[j]
+		default:
+			return ni < nj
+		}
+	})
+}
+
+// linkChunks hard-links (or copies, across filesystems) the chunk files of srcDir into dstDir.
+func linkChunks(srcDir, dstDir string) ([]string, error) {
+	sources, err := globChunks(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	linked := make([]string, 0, len(sources))
+	for _, src := range sources {
+		dst := filepath.Join(dstDir, filepath.Base(src))
+		if err := linkOrCopy(src, dst); err != nil {
+			return nil, err
+		}
+		linked = append(linked, dst)
+	}
+	return linked, nil
+}
+
+// linkOrCopy hard-links src to dst, falling back to a byte copy when linking is not possible.
+func linkOrCopy(src, dst string) error {
+	_ = os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// transcribeChunkWith writes transcriptPrefix.txt for a chunk, from the cache when
+// possible, otherwise by running whisper with whisperArgs and caching its output.
+func (p *Processor) transcribeChunkWith(ctx context.Context, chunkPath, transcriptPrefix string, whisperArgs []string) (string, error) {
+	return p.cachedTranscript(chunkPath, transcriptPrefix, whisperArgs, func() (string, error) {
+		return p.runWhisper(ctx, chunkPath, transcriptPrefix, whisperArgs)
+	})
+}
+
+// cachedTranscript writes transcriptPrefix.txt from the cache entry for the chunk and
+// keyArgs when there is one. Otherwise it calls run and caches the transcript run wrote.
+// When keyArgs request whisper's full JSON, transcriptPrefix.json is cached alongside.
+func (p *Processor) cachedTranscript(chunkPath, transcriptPrefix string, keyArgs []string, run func() (string, error)) (string, error) {
+	transcriptPath := transcriptPrefix + ".txt"
+	jsonPath := transcriptPrefix + ".json"
+	keepJSON := slices.Contains(keyArgs, wholeJSONFlag)
+
+	var cacheKey string
+	if p.Cache != nil {
+		key, err := p.transcriptCacheKey(chunkPath, keyArgs)
+		if err == nil {
+			cacheKey = key
+			text, ok := p.Cache.Get(key)
+			var full string
+			if ok && keepJSON {
+				full, ok = p.Cache.Get(key + "-json")
+			}
+			if ok {
+				if err := os.WriteFile(transcriptPath, []byte(text), 0o644); err != nil {
+					return "", err
+				}
+				if keepJSON {
+					if err := os.WriteFile(jsonPath, []byte(full), 0o644); err != nil {
+						return "", err
+					}
+				}
+				return fmt.Sprintf("transcript cache hit for %s (%s)", filepath.Base(chunkPath), key[:12]), nil
+			}
+		}
+	}
+
+	logEntry, err := run()
+	if err != nil {
+		return logEntry, err
+	}
+
+	if cacheKey != "" {
+		if text, readErr := os.ReadFile(transcriptPath); readErr == nil {
+			if putErr := p.Cache.Put(cacheKey, string(text)); putErr != nil {
+				logEntry += fmt.Sprintf("\ntranscript cache write failed: %v", putErr)
+			}
+		}
+		if keepJSON {
+			if full, readErr := os.ReadFile(jsonPath); readErr == nil {
+				if putErr := p.Cache.Put(cacheKey+"-json", string(full)); putErr != nil {
+					logEntry += fmt.Sprintf("\ntranscript cache write failed: %v", putErr)
+				}
+			}
+		}
+	}
+	return logEntry, nil
+}
+
+// runWhisper transcribes one chunk into transcriptPrefix.txt (plus any extra outputs the
+// arguments request), bypassing the cache, once a TranscribePool slot is free.
+func (p *Processor) runWhisper(ctx context.Context, chunkPath, transcriptPrefix string, whisperArgs []string) (string, error) {
+	if err := p.TranscribePool.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer p.TranscribePool.release()
+
+	var note string
+	if p.Resident != nil {
+		if fields, ok := residentArgs(whisperArgs, p.WhisperArgs); ok {
+			logEntry, err := p.Resident.transcribe(ctx, chunkPath, transcriptPrefix, fields)
+			if err == nil {
+				return logEntry, nil
+			}
+			if !errors.Is(err, errResidentUnavailable) {
+				note = fmt.Sprintf("resident whisper server failed (%v), running %s instead\n", err, p.WhisperBin)
+			}
+		}
+	}
+	logEntry, err := p.accelerated(ctx, p.Acceleration != nil && p.Acceleration.GPU, func(gpu bool) (string, error) {
+		args := slices.Concat(whisperArgs, p.whisperAccelArgs(gpu))
+		args = append(args,
+			"-f", chunkPath,
+			"-otxt",
+			"-of", transcriptPrefix,
+		)
+		return runCommand(ctx, p.WhisperBin, args...)
+	})
+	return note + logEntry, err
+}
+
+// applyRemainderPolicy merges or pads a short final chunk according to opts.Remainder.
+func applyRemainderPolicy(ctx context.Context, ffmpeg string, chunkFiles []string, opts Options) ([]string, []string, error) {
+	if opts.Remainder == "" || opts.Remainder == RemainderKeep || opts.ChunkDurationSeconds <= 0 {
+		return chunkFiles, nil, nil
+	}
+
+	lastPath := chunkFiles[len(chunkFiles)-1]
+	lastDuration, err := wavDuration(lastPath)
+	if err != nil {
+		return chunkFiles, nil, err
+	}
+	target := float64(opts.ChunkDurationSeconds)
+	if lastDuration >= target-remainderTolerance {
+		return chunkFiles, nil, nil
+	}
+
+	switch opts.Remainder {
+	case RemainderMerge:
+		if len(chunkFiles) < 2 {
+			return chunkFiles, nil, nil
+		}
+		if opts.MaxChunkSeconds > 0 {
+			prevDuration, err := wavDuration(chunkFiles[len(chunkFiles)-2])
+			if err != nil {
+				return chunkFiles, nil, err
+			}
+			if !fitsLimit(prevDuration+lastDuration, opts) {
+				return chunkFiles, []string{"remainder kept as its own chunk: merging would exceed the " + formatLimit(opts.MaxChunkSeconds) + " chunk limit"}, nil
+			}
+		}
+		logEntry, err := mergeLastChunk(ctx, ffmpeg, chunkFiles)
+		if err != nil {
+			return chunkFiles, []string{logEntry}, err
+		}
+		return chunkFiles[:len(chunkFiles)-1], []string{logEntry}, nil
+	case RemainderPad:
+		logEntry, err := filterChunkInPlace(ctx, ffmpeg, lastPath, "apad=whole_dur="+strconv.Itoa(opts.ChunkDurationSeconds))
+		return chunkFiles, []string{logEntry}, err
+	}
+
+	return chunkFiles, nil, fmt.Errorf("unknown remainder policy %q", opts.Remainder)
+}
+
+// mergeLastChunk appends the final chunk file onto the one before it and removes it.
+func mergeLastChunk(ctx context.Context, ffmpeg string, chunkFiles []string) (string, error) {
+	lastPath := chunkFiles[len(chunkFiles)-1]
+	prevPath := chunkFiles[len(chunkFiles)-2]
+	tmpPath := strings.TrimSuffix(prevPath, filepath.Ext(prevPath)) + ".tmp.wav"
+	logEntry, err := runCommand(ctx, ffmpeg,
+		"-y",
+		"-i", prevPath,
+		"-i", lastPath,
+		"-filter_complex", "[0:a][1:a]concat=n=2:v=0:a=1",
+		"-acodec", "pcm_s16le",
+		tmpPath,
+	)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return logEntry, err
+	}
+	if err := os.Rename(tmpPath, prevPath); err != nil {
+		return logEntry, err
+	}
+	return logEntry, os.Remove(lastPath)
+}
+
+// edgeFilterChain builds the ffmpeg -af chain for fades and padding, or "" when neither is set.
+// Fades are applied before padding so the silence stays clean.
+func edgeFilterChain(duration float64, opts Options) string {
+	var filters []string
+
+	if opts.FadeMillis > 0 {
+		fade := float64(opts.FadeMillis) / 1000
+		if fade > duration/2 {
+			fade = duration / 2
+		}
+		if fade > 0 {
+			filters = append(filters,
+				fmt.Sprintf("afade=t=in:st=0:d=%.3f", fade),
+				fmt.Sprintf("afade=t=out:st=%.3f:d=%.3f", duration-fade, fade),
+			)
+		}
+	}
+
+	if opts.PaddingMillis > 0 {
+		filters = append(filters,
+			fmt.Sprintf("adelay=delays=%d:all=1", opts.PaddingMillis),
+			fmt.Sprintf("apad=pad_dur=%.3f", float64(opts.PaddingMillis)/1000),
+		)
+	}
+
+	return strings.Join(filters, ",")
+}
+
+// filterChunkInPlace re-encodes a chunk through an audio filter chain and replaces the original.
+func filterChunkInPlace(ctx context.Context, ffmpeg, chunkPath, filter string) (string, error) {
+	tmpPath := strings.TrimSuffix(chunkPath, filepath.Ext(chunkPath)) + ".tmp.wav"
+	logEntry, err := runCommand(ctx, ffmpeg,
+		"-y",
+		"-i", chunkPath,
+		"-af", filter,
+		"-acodec", "pcm_s16le",
+		tmpPath,
+	)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return logEntry, err
+	}
+	return logEntry, os.Rename(tmpPath, chunkPath)
+}
+
+// runCommand executes an external binary and captures combined output. The run is
+// recorded in the context's CommandLog, if any, streamed into its OutputLog, and passed
+// to its command hooks.
+func runCommand(ctx context.Context, name string, args ...string) (string, error) {
+	return execCommand(ctx, false, nil, name, args...)
+}
+
+// runFFmpegProgress runs ffmpeg like runCommand while passing the position and speed it
+// reports through -progress to onProgress as it goes. The progress lines stay out of
+// the returned output.
+func runFFmpegProgress(ctx context.Context, ffmpeg string, onProgress func(seconds, speed float64), args ...string) (string, error) {
+	args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
+	progress := &ffmpegProgress{report: onProgress}
+	output, err := execCommand(ctx, false, progress, ffmpeg, args...)
+	progress.flush()
+	return output, err
+}
+
+// commandWaitDelay is how long a command's output is still read after it was killed.
+const commandWaitDelay = 5 * time.Second
+
+// runProbe has ffmpeg print the input's header. The error is dropped: ffmpeg always
+// exits non-zero here, and callers judge the output instead.
+func runProbe(ctx context.Context, ffmpeg, inputPath string) string {
+	output, _ := execCommand(ctx, true, nil, ffmpeg, "-hide_banner", "-i", inputPath)
+	return output
+}
+
+// execCommand runs name and returns what it printed. stdout, when set, receives the
+// standard output instead, leaving only standard error in the result.
+// The context's command limits set the run's priority and timeout.
+func execCommand(ctx context.Context, probe bool, stdout io.Writer, name string, args ...string) (string, error) {
+	limits := contextLimits(ctx)
+	parent := ctx
+	timeout := limits.timeouts[name]
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// The run is recorded with the arguments as given; only what is started is adapted
+	// to the platform.
+	cmdArgs := make([]string, len(args))
+	for i, arg := range args {
+		cmdArgs[i] = commandArg(arg)
+	}
+	cmd := exec.CommandContext(ctx, commandPath(name), cmdArgs...)
+	// Children of a killed command may hold its output open; stop waiting for them.
+	cmd.WaitDelay = commandWaitDelay
+	var output strings.Builder
+	var sink io.Writer = &output
+	var streamed *commandOutput
+	if log := outputLogOf(ctx); log != nil {
+		streamed = log.start(append([]string{name}, args...))
+		sink = io.MultiWriter(&output, streamed)
+	}
+	cmd.Stdout = sink
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	cmd.Stderr = sink
+	started := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		if !limits.priority.IsZero() {
+			if perr := applyPriority(cmd.Process.Pid, limits.priority); perr != nil {
+				fmt.Fprintf(&output, "could not lower the command's priority: %v\n", perr)
+			}
+		}
+		err = cmd.Wait()
+	}
+	if err != nil && timeout > 0 && ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		err = timeoutError(name, timeout)
+	}
+
+	run := CommandRun{
+		Args:            append([]string{name}, args...),
+		StartedAt:       started,
+		DurationSeconds: time.Since(started).Seconds(),
+		ExitCode:        -1,
+		Probe:           probe,
+	}
+	if cmd.ProcessState != nil {
+		run.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if streamed != nil {
+		streamed.finish(run, output.String())
+	}
+	recordCommand(ctx, run)
+	notifyCommandFinished(ctx, run)
+	return output.String(), err
+}
+
+// writeBase64File streams WAV bytes into a matching .b64.txt file.
+func writeBase64File(srcPath, dstPath string) error {
+	in, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	encoder := base64.NewEncoder(base64.StdEncoding, out)
+	if _, err := io.Copy(encoder, in); err != nil {
+		_ = encoder.Close()
+		return err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// wavDuration inspects a PCM WAV header to compute the clip length.
+func wavDuration(path string) (float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := readWAVHeader(file)
+	if err != nil {
+		return 0, err
+	}
+
+	duration := float64(info.dataSize) / float64(info.frameSize()) / float64(info.sampleRate)
+	if math.IsNaN(duration) || math.IsInf(duration, 0) {
+		return 0, errors.New("invalid duration computed")
+	}
+
+	return duration, nil
+}
+
+// wavInfo is the format of a PCM WAV file as declared in its header.
+type wavInfo struct {
+	channels      uint16
+	sampleRate    uint32
+	bitsPerSample uint16
+	dataSize      uint32
+}
+
+// frameSize is the number of bytes holding one sample for every channel.
+func (w wavInfo) frameSize() int {
+	return int(w.bitsPerSample/8) * int(w.channels)
+}
+
+// readWAVHeader parses the RIFF header and leaves r positioned at the start of the sample data.
+func readWAVHeader(r io.ReadSeeker) (wavInfo, error) {
+	var info wavInfo
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return info, err
+	}
+
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return info, errors.New("not a WAV file")
+	}
+
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(r, chunkHeader[:]); err != nil {
+			return info, err
+		}
+
+		chunkID := string(chunkHeader[0:4])
+		chunkSize := binary.LittleEndian.Uint32(chunkHeader[4:8])
+
+		switch chunkID {
+		case "fmt ":
+			buf := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return info, err
+			}
+			if len(buf) < 16 {
+				return info, errors.New("invalid fmt chunk")
+			}
+			info.channels = binary.LittleEndian.Uint16(buf[2:4])
+			info.sampleRate = binary.LittleEndian.Uint32(buf[4:8])
+			info.bitsPerSample = binary.LittleEndian.Uint16(buf[14:16])
+		case "data":
+			info.dataSize = chunkSize
+		default:
+			skip := int64(chunkSize)
+			if skip%2 == 1 {
+				skip++
+			}
+			if _, err := r.Seek(skip, io.SeekCurrent); err != nil {
+				return info, err
+			}
+		}
+
+		if chunkID == "data" {
+			break
+		}
+	}
+
+	if info.sampleRate == 0 || info.channels == 0 || info.bitsPerSample == 0 {
+		return info, errors.New("missing audio format information")
+	}
+	if info.frameSize() == 0 {
+		return info, errors.New("invalid bytes per sample")
+	}
+
+	return info, nil
+}
+
+// readPreview loads a short transcript prefix for display in the UI.
+func readPreview(path string, limit int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) <= limit {
+		return string(data), nil
+	}
+	return string(data[:limit]) + "...", nil
+}
@@ -0,0 +1,58 @@
+package chunker
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSortChunkFiles(t *testing.T) {
+	const n = 1200
+	var want []string
+	for i := 0; i < n; i++ {
+		want = append(want, fmt.Sprintf("chunk_%03d.wav", i))
+	}
+	files := append([]string(nil), want...)
+	rand.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+	sortChunkFiles(files)
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("position %d holds %s, want %s", i, files[i], want[i])
+		}
+	}
+}
+
+func TestSortChunkFilesByTrack(t *testing.T) {
+	files := []string{"chunk_a01_1000.wav", "chunk_a00_1000.wav", "chunk_a01_999.wav", "chunk_a00_999.wav", "chunk_a00_100.wav"}
+	sortChunkFiles(files)
+	want := []string{"chunk_a00_100.wav", "chunk_a00_999.wav", "chunk_a00_1000.wav", "chunk_a01_999.wav", "chunk_a01_1000.wav"}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("got %v, want %v", files, want)
+		}
+	}
+}
+
+func TestGlobChunksPastThreeDigits(t *testing.T) {
+	dir := t.TempDir()
+	const n = 1005
+	for i := 0; i < n; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("chunk_%03d.wav", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := globChunks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != n {
+		t.Fatalf("globChunks found %d files, want %d", len(files), n)
+	}
+	for i, name := range files {
+		if want := fmt.Sprintf("chunk_%03d.wav", i); filepath.Base(name) != want {
+			t.Fatalf("chunk %d is %s, want %s", i, filepath.Base(name), want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Processing stages reported through Options.Progress.
+const (
+	// StageExtract covers ffmpeg decoding the input and writing chunk files.
+	StageExtract = "extract"
+	// StagePostprocess covers per-chunk work (edges, Base64) without transcription.
+	StagePostprocess = "postprocess"
+	// StageTranscribe covers per-chunk work when transcription is enabled.
+	StageTranscribe = "transcribe"
+)
+
+// Progress describes how far a Process call has got through its current stage.
+type Progress struct {
+	Stage string
+	// DoneSeconds is the amount of audio finished within Stage.
+	DoneSeconds float64
+	// TotalSeconds is the audio length the stage has to get through, or 0 when unknown.
+	TotalSeconds float64
+	// Speed is how many seconds of audio ffmpeg gets through per second while it
+	// extracts, as it reports; 0 otherwise.
+	Speed float64
+}
+
+// ProgressWeights sets how much of a job's overall percentage each stage accounts for.
+// Only extraction and one per-chunk stage run in a job, so the weights of those two are
+// normalised against each other; the zero value weighs every stage equally.
+type ProgressWeights struct {
+	Extract     float64
+	Postprocess float64
+	Transcribe  float64
+}
+
+// DefaultProgressWeights reflects that whisper usually takes several times longer than ffmpeg.
+var DefaultProgressWeights = ProgressWeights{Extract: 1, Postprocess: 1, Transcribe: 4}
+
+// Percent turns progress within a stage into overall job completion between 0 and 100.
+// chunkStage is the stage that follows extraction in this job (StagePostprocess or
+// StageTranscribe).
+func (w ProgressWeights) Percent(p Progress, chunkStage string) float64 {
+	extract, chunks := w.Extract, w.Postprocess
+	if chunkStage == StageTranscribe {
+		chunks = w.Transcribe
+	}
+	if extract+chunks <= 0 {
+		extract, chunks = 1, 1
+	}
+
+	var fraction float64
+	if p.TotalSeconds > 0 {
+		fraction = min(1, max(0, p.DoneSeconds/p.TotalSeconds))
+	}
+	done := extract * fraction
+	if p.Stage != StageExtract {
+		done = extract + chunks*fraction
+	}
+	return 100 * done / (extract + chunks)
+}
+
+// durationPattern matches the "Duration: 00:01:02.34" line ffmpeg prints for its input.
+var durationPattern = regexp.MustCompile(`Duration:\s*(\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// ProbeDuration asks ffmpeg for the input's duration in seconds without decoding it.
+func (p *Processor) ProbeDuration(ctx context.Context, inputPath string) (float64, error) {
+	ffmpeg := p.FFmpegBin
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	output := runProbe(ctx, ffmpeg, inputPath)
+	seconds, ok := parseDuration(output)
+	if !ok {
+		return 0, errors.New("duration not reported by ffmpeg")
+	}
+	return seconds, nil
+}
+
+// parseDuration extracts the first Duration header from ffmpeg output.
+func parseDuration(output string) (float64, bool) {
+	match := durationPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, false
+	}
+	hours, _ := strconv.Atoi(match[1])
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.ParseFloat(match[3], 64)
+	return float64(hours*3600+minutes*60) + seconds, true
+}
+
+// report invokes the progress callback when one is configured.
+func (o Options) report(stage string, done, total float64) {
+	o.reportSpeed(stage, done, total, 0)
+}
+
+// reportSpeed is report with the speed ffmpeg reported.
+func (o Options) reportSpeed(stage string, done, total, speed float64) {
+	if o.Progress != nil {
+		o.Progress(Progress{Stage: stage, DoneSeconds: done, TotalSeconds: total, Speed: speed})
+	}
+}
+
+// ffmpegProgressInterval spaces out the updates passed on from ffmpeg's progress output,
+// which arrives twice a second; every update is saved with the job.
+const ffmpegProgressInterval = time.Second
+
+// ffmpegProgress reads the key=value blocks ffmpeg writes with -progress, each ending in
+// a progress=continue or progress=end line, and passes the out_time and speed of the
+// latest block on to report.
+type ffmpegProgress struct {
+	report  func(seconds, speed float64)
+	partial []byte
+	seconds float64
+	speed   float64
+	pending bool
+	last    time.Time
+}
+
+func (f *ffmpegProgress) Write(b []byte) (int, error) {
+	f.partial = append(f.partial, b...)
+	for {
+		i := bytes.IndexByte(f.partial, '\n')
+		if i < 0 {
+			break
+		}
+		f.line(strings.TrimSpace(string(f.partial[:i])))
+		f.partial = f.partial[i+1:]
+	}
+	return len(b), nil
+}
+
+func (f *ffmpegProgress) line(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+	switch key {
+	case "out_time_us", "out_time_ms":
+		// Both are in microseconds; out_time_ms is the older name.
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			f.seconds, f.pending = float64(us)/1e6, true
+		}
+	case "speed":
+		if speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "x"), 64); err == nil {
+			f.speed = speed
+		}
+	case "progress":
+		if now := time.Now(); value == "end" || now.Sub(f.last) >= ffmpegProgressInterval {
+			f.last = now
+			f.flush()
+		}
+	}
+}
+
+// flush reports the latest position if it has not been reported yet.
+func (f *ffmpegProgress) flush() {
+	if f.pending && f.report != nil {
+		f.pending = false
+		f.report(f.seconds, f.speed)
+	}
+}
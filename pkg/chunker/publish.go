@@ -0,0 +1,104 @@
+package chunker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// StagingDir is the job subdirectory chunks, Base64 dumps, and transcripts are written
+// to while they are produced. Each file moves to its place in the job only once it is
+// complete, so a run that crashes halfway leaves nothing partial where clients, the
+// chunk glob, or a retry would find it. Process empties it before and after a run.
+const StagingDir = "tmp"
+
+// stagingPath returns where the job file at rel (slash-separated, relative to jobDir) is
+// written before publish moves it into place, creating its directory.
+func stagingPath(jobDir, rel string) (string, error) {
+	path := filepath.Join(jobDir, StagingDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("creating staging directory: %w", err)
+	}
+	return path, nil
+}
+
+// publish moves the finished file staged for rel to jobDir/rel, replacing any earlier
+// one. Within a file system that is a rename, so readers see the old file or the whole
+// new one. Directories linked to a storage class on another file system get a copy
+// beside the target that is then renamed over it.
+func publish(jobDir, rel string) error {
+	src := filepath.Join(jobDir, StagingDir, filepath.FromSlash(rel))
+	dst := filepath.Join(jobDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("publishing %s: %w", rel, err)
+	}
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("publishing %s: %w", rel, err)
+	}
+	if err := copyInto(src, dst); err != nil {
+		return fmt.Errorf("publishing %s: %w", rel, err)
+	}
+	return os.Remove(src)
+}
+
+// copyInto copies src to a temporary file next to dst and renames it over dst.
+func copyInto(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// writeJobFile writes data to the job file at rel through the staging directory.
+func writeJobFile(jobDir, rel string, data []byte) error {
+	path, err := stagingPath(jobDir, rel)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	return publish(jobDir, rel)
+}
+
+// publishChunks moves the finished chunk files staged under tmp/chunks/ to chunks/ and
+// returns their new paths.
+func publishChunks(jobDir string, staged []string) ([]string, error) {
+	published := make([]string, 0, len(staged))
+	for _, path := range staged {
+		rel := "chunks/" + filepath.Base(path)
+		if err := publish(jobDir, rel); err != nil {
+			return nil, err
+		}
+		published = append(published, filepath.Join(jobDir, filepath.FromSlash(rel)))
+	}
+	return published, nil
+}
@@ -0,0 +1,232 @@
+package chunker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactionMode selects the redacted variants written for each transcribed chunk. The
+// empty mode writes none.
+type RedactionMode string
+
+const (
+	RedactNone RedactionMode = ""
+	// RedactText writes a redacted copy of the main transcript.
+	RedactText RedactionMode = "text"
+	// RedactAudio also writes a copy of the chunk audio with the redacted words bleeped.
+	// It needs word timestamps to know where they are spoken.
+	RedactAudio RedactionMode = "audio"
+)
+
+// ParseRedactionMode validates a user-provided mode; "", "none", and "off" mean no redaction.
+func ParseRedactionMode(value string) (RedactionMode, error) {
+	switch mode := RedactionMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "none", "off":
+		return RedactNone, nil
+	case RedactNone, RedactText, RedactAudio:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown redaction option %q (want text or audio)", value)
+}
+
+// DefaultRedactionReplacement stands in for redacted text unless the Redactor overrides it.
+const DefaultRedactionReplacement = "[redacted]"
+
+// Redactor finds the words and patterns to redact. Build it with NewRedactor.
+type Redactor struct {
+	matchers    []*regexp.Regexp
+	replacement string
+	// mute silences redacted spans instead of covering them with a tone.
+	mute bool
+}
+
+// WithRedactor enables Options.Redact, redacting what r matches.
+func WithRedactor(r *Redactor) Option {
+	return func(p *Processor) {
+		p.Redactor = r
+	}
+}
+
+// NewRedactor matches words case-insensitively as whole words and patterns as Go regular
+// expressions. An empty replacement means DefaultRedactionReplacement; mute silences
+// redacted audio rather than bleeping it.
+func NewRedactor(words, patterns []string, replacement string, mute bool) (*Redactor, error) {
+	r := &Redactor{replacement: replacement, mute: mute}
+	if r.replacement == "" {
+		r.replacement = DefaultRedactionReplacement
+	}
+	var quoted []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) > 0 {
+		r.matchers = append(r.matchers, regexp.MustCompile(`(?i)\b(?:`+strings.Join(quoted, "|")+`)\b`))
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern, err)
+		}
+		r.matchers = append(r.matchers, re)
+	}
+	if len(r.matchers) == 0 {
+		return nil, errors.New("redaction needs at least one word or pattern")
+	}
+	return r, nil
+}
+
+// matches returns the byte ranges of text to redact, sorted and merged where they overlap.
+func (r *Redactor) matches(text string) [][2]int {
+	var ranges [][2]int
+	for _, re := range r.matchers {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if m[1] > m[0] {
+				ranges = append(ranges, [2]int{m[0], m[1]})
+			}
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// Redact replaces every match in text and reports how many there were.
+func (r *Redactor) Redact(text string) (string, int) {
+	ranges := r.matches(text)
+	var b strings.Builder
+	pos := 0
+	for _, m := range ranges {
+		b.WriteString(text[pos:m[0]])
+		b.WriteString(r.replacement)
+		pos = m[1]
+	}
+	b.WriteString(text[pos:])
+	return b.String(), len(ranges)
+}
+
+// spokenSpans returns the times, in seconds from the start of the chunk audio, of the
+// words overlapping a match. Each segment's words are joined with spaces and matched as
+// one text, so patterns spanning several words are found too.
+func (r *Redactor) spokenSpans(words WordTranscript) [][2]float64 {
+	var spans [][2]float64
+	for _, seg := range words.Segments {
+		var text strings.Builder
+		offsets := make([][2]int, len(seg.Words))
+		for i, w := range seg.Words {
+			if i > 0 {
+				text.WriteByte(' ')
+			}
+			offsets[i] = [2]int{text.Len(), text.Len() + len(w.Word)}
+			text.WriteString(w.Word)
+		}
+		for _, m := range r.matches(text.String()) {
+			for i, w := range seg.Words {
+				if offsets[i][0] < m[1] && offsets[i][1] > m[0] {
+					spans = append(spans, [2]float64{w.Start - words.ChunkStartSeconds, w.End - words.ChunkStartSeconds})
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func mergeRanges(ranges [][2]int) [][2]int {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:1]
+	for _, m := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if m[0] <= last[1] {
+			last[1] = max(last[1], m[1])
+		} else {
+			merged = append(merged, m)
+		}
+	}
+	return merged
+}
+
+// bleepPaddingSeconds widens every bleeped span, since whisper's word timings are only
+// accurate to a few hundredths of a second.
+const bleepPaddingSeconds = 0.05
+
+// bleepFrequency is the tone covering redacted audio, the classic broadcast 1 kHz.
+const bleepFrequency = 1000
+
+// bleepFilter builds the ffmpeg filter that turns the volume down to zero during spans
+// and, unless mute is set, adds a tone there.
+func bleepFilter(spans [][2]float64, mute bool) string {
+	var during []string
+	for _, s := range spans {
+		during = append(during, fmt.Sprintf("between(t,%.3f,%.3f)", max(s[0]-bleepPaddingSeconds, 0), s[1]+bleepPaddingSeconds))
+	}
+	enable := strings.Join(during, "+")
+	filter := fmt.Sprintf("volume=enable='%s':volume=0", enable)
+	if !mute {
+		filter += fmt.Sprintf(",aeval='val(ch)+0.25*sin(2*PI*%d*t)*gt(%s,0)':c=same", bleepFrequency, enable)
+	}
+	return filter
+}
+
+// redactChunk writes the redacted variants of a transcribed chunk: transcripts/<chunk>.redacted.txt
+// and, for RedactAudio, chunks/redacted/<chunk>.wav with the matched words bleeped. Failures
+// are logged; the chunk keeps its other files.
+func (p *Processor) redactChunk(ctx context.Context, jobDir, chunkPath string, chunk *Chunk, mode RedactionMode) []string {
+	data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+	if err != nil {
+		return []string{fmt.Sprintf("redacting chunk %d failed: %v", chunk.Index, err)}
+	}
+	redacted, count := p.Redactor.Redact(string(data))
+	textFile := strings.TrimSuffix(chunk.TranscriptFile, ".txt") + ".redacted.txt"
+	if err := writeJobFile(jobDir, textFile, []byte(redacted)); err != nil {
+		return []string{fmt.Sprintf("redacting chunk %d failed: %v", chunk.Index, err)}
+	}
+	chunk.RedactedTranscriptFile = textFile
+	chunk.Redactions = count
+	if mode != RedactAudio {
+		return nil
+	}
+
+	if chunk.WordsFile == "" {
+		return []string{fmt.Sprintf("chunk %d has no word timings; its audio was not redacted", chunk.Index)}
+	}
+	raw, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.WordsFile)))
+	var words WordTranscript
+	if err == nil {
+		err = json.Unmarshal(raw, &words)
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("redacting the audio of chunk %d failed: %v", chunk.Index, err)}
+	}
+	audioFile := "chunks/redacted/" + filepath.Base(chunkPath)
+	audioPath, err := stagingPath(jobDir, audioFile)
+	if err != nil {
+		return []string{fmt.Sprintf("redacting the audio of chunk %d failed: %v", chunk.Index, err)}
+	}
+	var logs []string
+	if spans := p.Redactor.spokenSpans(words); len(spans) == 0 {
+		// Nothing to bleep; the redacted variant is the chunk itself.
+		err = linkOrCopy(chunkPath, audioPath)
+	} else {
+		var logEntry string
+		logEntry, err = runCommand(ctx, p.FFmpegBin, "-y", "-i", chunkPath,
+			"-af", bleepFilter(spans, p.Redactor.mute), audioPath)
+		logs = append(logs, logEntry)
+	}
+	if err == nil {
+		err = publish(jobDir, audioFile)
+	}
+	if err != nil {
+		return append(logs, fmt.Sprintf("redacting the audio of chunk %d failed: %v", chunk.Index, err))
+	}
+	chunk.RedactedAudioFile = audioFile
+	return logs
+}
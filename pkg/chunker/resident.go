@@ -0,0 +1,454 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for ResidentWhisper's zero fields.
+const (
+	DefaultResidentAddr           = "127.0.0.1:8178"
+	DefaultResidentStartTimeout   = 2 * time.Minute
+	DefaultResidentHealthInterval = 15 * time.Second
+	// residentFailedChecks is how many health checks in a row may fail before the
+	// server is restarted.
+	residentFailedChecks = 3
+	residentMaxBackoff   = time.Minute
+	residentLogTail      = 4 << 10
+)
+
+// errResidentUnavailable is returned while the server is starting or restarting.
+var errResidentUnavailable = errors.New("resident whisper server is not ready")
+
+// residentParams maps the per-job whisper flags to the inference form fields of
+// whisper.cpp's server. Flags without a value are sent as "true".
+var residentParams = map[string]string{
+	"-l": "language", "--language": "language",
+	"-tr": "translate", "--translate": "translate",
+	"-bs": "beam_size", "--beam-size": "beam_size",
+	"-bo": "best_of", "--best-of": "best_of",
+	"-tp": "temperature", "--temperature": "temperature",
+	"-tpi": "temperature_inc", "--temperature-inc": "temperature_inc",
+	"-nf": "no_fallback", "--no-fallback": "no_fallback",
+	"-mc": "max_context", "--max-context": "max_context",
+	"-et": "entropy_thold", "--entropy-thold": "entropy_thold",
+	"-lpt": "logprob_thold", "--logprob-thold": "logprob_thold",
+	"-sns": "suppress_nst", "--suppress-nst": "suppress_nst",
+}
+
+// ResidentWhisper runs whisper.cpp's HTTP server (whisper-server) as a long-lived child
+// process, so the model is loaded once instead of for every chunk. A supervisor starts
+// it, checks its health, and restarts it with backoff when it exits or stops answering.
+//
+// A Processor with a ResidentWhisper sends a transcription to the server when the
+// server is ready and every argument beyond Processor.WhisperArgs has a server
+// equivalent; otherwise, e.g. for whisper's full JSON output or a transcript set that
+// picks another model, it runs WhisperBin as before.
+type ResidentWhisper struct {
+	// Bin is the whisper-server executable; Args typically load the model ("-m", path).
+	// --host and --port are added from Addr.
+	Bin  string
+	Args []string
+	// Addr is the host:port the server listens on; DefaultResidentAddr when empty.
+	Addr string
+	// HealthPath is requested to check the server; "/" when empty.
+	HealthPath     string
+	StartTimeout   time.Duration
+	HealthInterval time.Duration
+	// Logf, when set, receives lifecycle messages.
+	Logf func(format string, args ...any)
+
+	client *http.Client
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	ready     bool
+	startedAt *time.Time
+	restarts  int
+	lastError string
+}
+
+// ResidentStatus describes a ResidentWhisper for status pages.
+type ResidentStatus struct {
+	Addr      string     `json:"addr"`
+	Ready     bool       `json:"ready"`
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	Restarts  int        `json:"restarts"`
+	LastError string     `json:"lastError,omitempty"`
+}
+
+// Start launches the supervisor and returns at once; transcriptions use the CLI until
+// the server is ready. It fails only if the executable cannot be found.
+func (w *ResidentWhisper) Start() error {
+	if _, err := lookBinary(w.Bin); err != nil {
+		return fmt.Errorf("whisper server binary not found: %w", err)
+	}
+	if w.Addr == "" {
+		w.Addr = DefaultResidentAddr
+	}
+	if w.HealthPath == "" {
+		w.HealthPath = "/"
+	}
+	if w.StartTimeout <= 0 {
+		w.StartTimeout = DefaultResidentStartTimeout
+	}
+	if w.HealthInterval <= 0 {
+		w.HealthInterval = DefaultResidentHealthInterval
+	}
+	w.client = &http.Client{}
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.supervise()
+	return nil
+}
+
+// Close stops the supervisor and the server process.
+func (w *ResidentWhisper) Close() error {
+	if w.stop == nil {
+		return nil
+	}
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+// WaitReady blocks until the server answers, StartTimeout passes, or ctx ends. Short
+// runs call it so their first chunks do not fall back to the CLI while the model loads.
+func (w *ResidentWhisper) WaitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, w.StartTimeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !w.isReady() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", errResidentUnavailable, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Status reports the server's current state.
+func (w *ResidentWhisper) Status() ResidentStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := ResidentStatus{Addr: w.Addr, Ready: w.ready, StartedAt: w.startedAt, Restarts: w.restarts, LastError: w.lastError}
+	if w.cmd != nil && w.cmd.Process != nil {
+		status.PID = w.cmd.Process.Pid
+	}
+	return status
+}
+
+func (w *ResidentWhisper) logf(format string, args ...any) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+	}
+}
+
+// supervise keeps one server process running until Close.
+func (w *ResidentWhisper) supervise() {
+	defer close(w.done)
+	backoff := time.Second
+	for first := true; ; first = false {
+		if !first {
+			w.mu.Lock()
+			w.restarts++
+			w.mu.Unlock()
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, residentMaxBackoff)
+		}
+
+		healthy, err := w.run()
+		if healthy {
+			backoff = time.Second
+		}
+		w.mu.Lock()
+		w.ready = false
+		w.cmd = nil
+		w.startedAt = nil
+		if err != nil {
+			w.lastError = err.Error()
+		}
+		w.mu.Unlock()
+		if err == nil {
+			return
+		}
+		w.logf("whisper server: %v; restarting", err)
+	}
+}
+
+// run starts the server and watches it. It returns a nil error only when Close was
+// called, and reports whether the server ever became ready.
+func (w *ResidentWhisper) run() (bool, error) {
+	// A server left behind by an earlier run would answer our health checks while
+	// ours fails to bind, so refuse to start on a taken address.
+	if conn, err := net.DialTimeout("tcp", w.Addr, time.Second); err == nil {
+		conn.Close()
+		return false, fmt.Errorf("address %s is already in use", w.Addr)
+	}
+	host, port, err := net.SplitHostPort(w.Addr)
+	if err != nil {
+		return false, fmt.Errorf("invalid address %q: %w", w.Addr, err)
+	}
+
+	tail := &tailBuffer{max: residentLogTail}
+	cmd := exec.Command(commandPath(w.Bin), append(append([]string{}, w.Args...), "--host", host, "--port", port)...)
+	cmd.Stdout = tail
+	cmd.Stderr = tail
+	if err := cmd.Start(); err != nil {
+		return false, fmt.Errorf("starting: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	w.mu.Lock()
+	w.cmd = cmd
+	w.mu.Unlock()
+	w.logf("whisper server: started pid %d on %s", cmd.Process.Pid, w.Addr)
+
+	kill := func() {
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+	exitErr := func(err error) error {
+		if out := strings.TrimSpace(tail.String()); out != "" {
+			return fmt.Errorf("exited: %v: %s", err, lastLine(out))
+		}
+		return fmt.Errorf("exited: %v", err)
+	}
+
+	deadline := time.Now().Add(w.StartTimeout)
+	poll := time.NewTicker(250 * time.Millisecond)
+	defer poll.Stop()
+	for !w.healthy() {
+		select {
+		case <-w.stop:
+			kill()
+			return false, nil
+		case err := <-exited:
+			return false, exitErr(err)
+		case <-poll.C:
+		}
+		if time.Now().After(deadline) {
+			kill()
+			return false, fmt.Errorf("not ready after %s", w.StartTimeout)
+		}
+	}
+	now := time.Now().UTC()
+	w.mu.Lock()
+	w.ready = true
+	w.startedAt = &now
+	w.lastError = ""
+	w.mu.Unlock()
+	w.logf("whisper server: ready on %s", w.Addr)
+
+	check := time.NewTicker(w.HealthInterval)
+	defer check.Stop()
+	failed := 0
+	for {
+		select {
+		case <-w.stop:
+			kill()
+			return true, nil
+		case err := <-exited:
+			return true, exitErr(err)
+		case <-check.C:
+			if w.healthy() {
+				failed = 0
+				continue
+			}
+			if failed++; failed >= residentFailedChecks {
+				kill()
+				return true, fmt.Errorf("failed %d health checks", failed)
+			}
+		}
+	}
+}
+
+// healthy requests HealthPath and expects a 2xx answer.
+func (w *ResidentWhisper) healthy() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+w.Addr+w.HealthPath, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+func (w *ResidentWhisper) isReady() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ready
+}
+
+// residentArgs strips the processor's own arguments from a run's, which the server
+// replaces with its Args, and translates the rest into inference form fields. It
+// reports false when an argument has no server equivalent.
+func residentArgs(args, base []string) (map[string]string, bool) {
+	if len(args) < len(base) || !slices.Equal(args[:len(base)], base) {
+		return nil, false
+	}
+	return residentForm(args[len(base):])
+}
+
+// residentForm translates whisper arguments into inference form fields, reporting false
+// when one of them has no server equivalent.
+func residentForm(args []string) (map[string]string, bool) {
+	fields := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		field, ok := residentParams[args[i]]
+		if !ok {
+			return nil, false
+		}
+		if jobWhisperFlags[args[i]] {
+			if i+1 >= len(args) {
+				return nil, false
+			}
+			i++
+			fields[field] = args[i]
+		} else {
+			fields[field] = "true"
+		}
+	}
+	return fields, true
+}
+
+// transcribe posts the chunk to the server and writes the text to transcriptPrefix.txt.
+// The request is recorded in the context's CommandLog like a whisper run.
+func (w *ResidentWhisper) transcribe(ctx context.Context, chunkPath, transcriptPrefix string, fields map[string]string) (string, error) {
+	if !w.isReady() {
+		return "", errResidentUnavailable
+	}
+	url := "http://" + w.Addr + "/inference"
+	started := time.Now()
+	text, err := w.post(ctx, url, chunkPath, fields)
+	if err == nil {
+		err = os.WriteFile(transcriptPrefix+".txt", text, 0o644)
+	}
+
+	run := CommandRun{
+		Args:            []string{"POST", url, "file=" + chunkPath},
+		StartedAt:       started,
+		DurationSeconds: time.Since(started).Seconds(),
+	}
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		run.Args = append(run.Args, name+"="+fields[name])
+	}
+	if err != nil {
+		run.ExitCode = -1
+		run.Error = err.Error()
+	}
+	streamRun(ctx, run)
+	recordCommand(ctx, run)
+	notifyCommandFinished(ctx, run)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("transcribed %s with the resident whisper server on %s", filepath.Base(chunkPath), w.Addr), nil
+}
+
+func (w *ResidentWhisper) post(ctx context.Context, url, chunkPath string, fields map[string]string) ([]byte, error) {
+	audio, err := os.Open(chunkPath)
+	if err != nil {
+		return nil, err
+	}
+	defer audio.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(chunkPath))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return nil, err
+	}
+	if err := form.WriteField("response_format", "text"); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	text, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("whisper server answered %s: %s", resp.Status, lastLine(strings.TrimSpace(string(text))))
+	}
+	return text, nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = t.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
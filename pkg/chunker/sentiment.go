@@ -0,0 +1,320 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Sentiment is the mood of a chunk's transcript.
+type Sentiment struct {
+	// Label is SentimentPositive, SentimentNeutral, or SentimentNegative.
+	Label string `json:"label"`
+	// Score runs from -1, most negative, to 1, most positive.
+	Score float64 `json:"score"`
+	// Emotions scores the emotions found from 0 to 1, keyed by name such as "anger" or
+	// "joy"; the names depend on the analyzer.
+	Emotions map[string]float64 `json:"emotions,omitempty"`
+}
+
+// Sentiment labels.
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// SentimentAnalyzer rates the sentiment of one transcript.
+type SentimentAnalyzer interface {
+	Sentiment(ctx context.Context, text string) (*Sentiment, error)
+}
+
+// WithSentimentAnalyzer replaces the LocalSentiment used for Options.AnalyzeSentiment.
+func WithSentimentAnalyzer(a SentimentAnalyzer) Option {
+	return func(p *Processor) {
+		p.Sentiment = a
+	}
+}
+
+// neutralBand is how far from zero a score must be to count as positive or negative.
+const neutralBand = 0.05
+
+// sentimentLabel labels a score.
+func sentimentLabel(score float64) string {
+	switch {
+	case score >= neutralBand:
+		return SentimentPositive
+	case score <= -neutralBand:
+		return SentimentNegative
+	}
+	return SentimentNeutral
+}
+
+// LocalSentiment rates sentiment without any service, from small English word lists:
+// positive words count up and negative words down, flipped when one of the three words
+// before them is a negation, and the sum is squashed into -1 to 1. Emotions are the
+// share of the emotion words found that belong to anger, fear, joy, and sadness. It
+// misses sarcasm and anything said in other languages.
+type LocalSentiment struct{}
+
+// sentimentSquash sets how quickly the summed word scores approach ±1; the value VADER
+// uses.
+const sentimentSquash = 15
+
+// Sentiment implements SentimentAnalyzer.
+func (LocalSentiment) Sentiment(_ context.Context, text string) (*Sentiment, error) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	sum := 0.0
+	emotions := make(map[string]float64)
+	emotional := 0
+	for i, word := range words {
+		if emotion, ok := emotionWords[word]; ok {
+			emotions[emotion]++
+			emotional++
+		}
+		polarity := 0.0
+		switch {
+		case positiveWords[word]:
+			polarity = 1
+		case negativeWords[word]:
+			polarity = -1
+		default:
+			continue
+		}
+		for j := max(i-3, 0); j < i; j++ {
+			if negations[words[j]] {
+				polarity = -polarity / 2
+				break
+			}
+		}
+		sum += polarity
+	}
+	s := &Sentiment{Score: roundScore(sum / math.Sqrt(sum*sum+sentimentSquash))}
+	s.Label = sentimentLabel(s.Score)
+	if emotional > 0 {
+		s.Emotions = make(map[string]float64, len(emotions))
+		for emotion, n := range emotions {
+			s.Emotions[emotion] = roundScore(n / float64(emotional))
+		}
+	}
+	return s, nil
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+func wordSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+var negations = wordSet(`not no never none nobody nothing neither nor cannot can't don't doesn't
+	didn't isn't wasn't aren't weren't won't wouldn't shouldn't couldn't haven't hasn't hadn't
+	without hardly barely`)
+
+var positiveWords = wordSet(`good great excellent amazing awesome wonderful fantastic perfect
+	happy glad pleased delighted love loved lovely liked enjoy enjoyed nice fine best better
+	helpful thanks thank appreciate appreciated grateful brilliant easy fast quick resolved
+	fixed solved success successful works working recommend satisfied satisfying impressive
+	beautiful clear friendly polite welcome excited exciting agree correct smooth
+	reliable fun positive calm relieved congratulations`)
+
+var negativeWords = wordSet(`bad terrible awful horrible poor worst worse hate hated dislike
+	angry annoyed annoying frustrated frustrating upset unhappy disappointed disappointing
+	sad sorry problem problems issue issues broken broke fail failed failure failing error
+	errors wrong slow difficult hard confusing confused useless waste wasted late delay delayed
+	complaint complain complaining refund cancel cancelled rude unacceptable ridiculous
+	stuck lost missing worried worry afraid scared negative unfortunately painful
+	crash crashed bug bugs expensive overcharged`)
+
+// emotionWords maps the words LocalSentiment takes as a sign of an emotion.
+var emotionWords = func() map[string]string {
+	lists := map[string]string{
+		"anger":   `angry annoyed annoying furious mad outraged irritated frustrated frustrating hate hated ridiculous unacceptable rude livid`,
+		"fear":    `afraid scared worried worry anxious nervous concerned panic terrified fear frightened uneasy`,
+		"joy":     `happy glad pleased delighted love loved lovely enjoy enjoyed excited exciting great wonderful fantastic awesome fun relieved`,
+		"sadness": `sad unhappy disappointed disappointing sorry upset miss missed lonely depressed hurt regret unfortunately`,
+	}
+	words := make(map[string]string)
+	for emotion, list := range lists {
+		for _, w := range strings.Fields(list) {
+			words[w] = emotion
+		}
+	}
+	return words
+}()
+
+// SentimentService rates sentiment through an HTTP endpoint. It posts {"text": "..."} as
+// JSON and accepts either {"label": "...", "score": s, "emotions": {"name": s, ...}},
+// where score runs from -1 to 1 and either field may be left out, or a Hugging Face
+// text-classification answer: a list, or a list of lists, of {"label", "score"}
+// probabilities. In the latter, positive, negative, and neutral labels (or their
+// abbreviations) make up the sentiment and every other label is an emotion.
+type SentimentService struct {
+	URL     string
+	Headers map[string]string
+	Timeout time.Duration
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Sentiment implements SentimentAnalyzer.
+func (s *SentimentService) Sentiment(ctx context.Context, text string) (*Sentiment, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.Headers {
+		req.Header.Set(name, value)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sentiment request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading sentiment response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sentiment service answered %s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 300)])))
+	}
+	sentiment, err := decodeSentiment(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding sentiment response: %w", err)
+	}
+	return sentiment, nil
+}
+
+type labelScore struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+// decodeSentiment reads either answer SentimentService accepts.
+func decodeSentiment(data []byte) (*Sentiment, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("[")) {
+		var answer struct {
+			Label    string             `json:"label"`
+			Score    *float64           `json:"score"`
+			Emotions map[string]float64 `json:"emotions"`
+		}
+		if err := json.Unmarshal(data, &answer); err != nil {
+			return nil, err
+		}
+		s := &Sentiment{Label: polarityLabel(answer.Label), Emotions: answer.Emotions}
+		switch {
+		case answer.Score != nil:
+			s.Score = roundScore(max(-1, min(*answer.Score, 1)))
+		case s.Label == SentimentPositive:
+			s.Score = 1
+		case s.Label == SentimentNegative:
+			s.Score = -1
+		case s.Label == "" && answer.Label != "":
+			return nil, fmt.Errorf("unknown sentiment label %q", answer.Label)
+		}
+		if s.Label == "" {
+			s.Label = sentimentLabel(s.Score)
+		}
+		return s, nil
+	}
+
+	var scores []labelScore
+	if err := json.Unmarshal(data, &scores); err != nil {
+		// Pipelines called with a list of texts answer one list per text.
+		var nested [][]labelScore
+		if json.Unmarshal(data, &nested) != nil || len(nested) == 0 {
+			return nil, err
+		}
+		scores = nested[0]
+	}
+	s := &Sentiment{}
+	var positive, negative float64
+	polar := false
+	for _, ls := range scores {
+		switch polarityLabel(ls.Label) {
+		case SentimentPositive:
+			positive, polar = ls.Score, true
+		case SentimentNegative:
+			negative, polar = ls.Score, true
+		case SentimentNeutral:
+			polar = true
+		default:
+			if s.Emotions == nil {
+				s.Emotions = make(map[string]float64)
+			}
+			s.Emotions[strings.ToLower(ls.Label)] = roundScore(ls.Score)
+		}
+	}
+	if !polar && s.Emotions == nil {
+		return nil, fmt.Errorf("no labels in %s", data[:min(len(data), 100)])
+	}
+	// Classifiers that return only the winning label leave the other at zero, so the
+	// difference still has the winner's sign and confidence.
+	s.Score = roundScore(positive - negative)
+	s.Label = sentimentLabel(s.Score)
+	return s, nil
+}
+
+// polarityLabel maps the labels common sentiment models use to the Sentiment labels,
+// and anything else to "".
+func polarityLabel(label string) string {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "positive", "pos":
+		return SentimentPositive
+	case "negative", "neg":
+		return SentimentNegative
+	case "neutral", "neu":
+		return SentimentNeutral
+	}
+	return ""
+}
+
+// analyzeSentiment fills in chunk.Sentiment from its main transcript. A failing analyzer
+// is logged; the chunk keeps its transcript.
+func (p *Processor) analyzeSentiment(ctx context.Context, jobDir string, chunk *Chunk) []string {
+	data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+	if err != nil {
+		return []string{fmt.Sprintf("sentiment for chunk %d unavailable: %v", chunk.Index, err)}
+	}
+	var analyzer SentimentAnalyzer = LocalSentiment{}
+	if p.Sentiment != nil {
+		analyzer = p.Sentiment
+	}
+	sentiment, err := analyzer.Sentiment(ctx, string(data))
+	if err != nil {
+		return []string{fmt.Sprintf("sentiment for chunk %d unavailable: %v", chunk.Index, err)}
+	}
+	chunk.Sentiment = sentiment
+	return nil
+}
@@ -0,0 +1,228 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// PipelineStage names one step of a job's pipeline. Job.Stages records the state of
+// each, so a failed job shows the step it failed in and a retry can resume after the
+// steps whose output is kept.
+type PipelineStage string
+
+const (
+	// PipelineUpload receives the original, or the chunks of a pushed job.
+	PipelineUpload PipelineStage = "upload"
+	// PipelineProbe reads the input's duration and audio streams.
+	PipelineProbe PipelineStage = "probe"
+	// PipelineChunk cuts the chunk files, or links reused ones, and the merged audio.
+	PipelineChunk PipelineStage = "chunk"
+	// PipelineBase64 writes the Base64 dumps of the chunks.
+	PipelineBase64 PipelineStage = "base64"
+	// PipelineTranscribe runs whisper over the chunks and analyses their transcripts.
+	PipelineTranscribe PipelineStage = "transcribe"
+	// PipelinePostprocess makes the job-wide artefacts: video clips, subtitles, the
+	// summary, and chapters.
+	PipelinePostprocess PipelineStage = "postprocess"
+)
+
+// PipelineStages lists the stages in the order a job passes through them. Base64 and
+// transcription run side by side, chunk by chunk.
+var PipelineStages = []PipelineStage{PipelineUpload, PipelineProbe, PipelineChunk, PipelineBase64, PipelineTranscribe, PipelinePostprocess}
+
+// ValidPipelineStage reports whether name is one of PipelineStages.
+func ValidPipelineStage(name string) bool {
+	return slices.Contains(PipelineStages, PipelineStage(name))
+}
+
+// StageStatus is where a pipeline stage of a job stands.
+type StageStatus string
+
+const (
+	StagePending   StageStatus = "pending"
+	StageRunning   StageStatus = "running"
+	StageCompleted StageStatus = "completed"
+	StageFailed    StageStatus = "failed"
+	// StageSkipped marks a stage the job did not need, e.g. transcription when none
+	// was requested.
+	StageSkipped StageStatus = "skipped"
+	// StageCanceled marks a stage stopped because another one running alongside it
+	// failed.
+	StageCanceled StageStatus = "canceled"
+)
+
+// StageState is the recorded state of one pipeline stage of a job.
+type StageState struct {
+	Name        PipelineStage `json:"name"`
+	Status      StageStatus   `json:"status"`
+	StartedAt   *time.Time    `json:"startedAt,omitempty"`
+	CompletedAt *time.Time    `json:"completedAt,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Duration is how long the stage ran, or 0 unless it both started and ended.
+func (s StageState) Duration() time.Duration {
+	if s.StartedAt == nil || s.CompletedAt == nil {
+		return 0
+	}
+	return s.CompletedAt.Sub(*s.StartedAt)
+}
+
+// Stage returns the state of the named stage. Stages missing from the job, such as all
+// of them for a job saved before they were recorded, are added as pending first, so
+// Stages always lists every stage in order.
+func (j *Job) Stage(name PipelineStage) *StageState {
+	if len(j.Stages) != len(PipelineStages) {
+		stages := make([]StageState, len(PipelineStages))
+		for i, stage := range PipelineStages {
+			stages[i] = StageState{Name: stage, Status: StagePending}
+			for _, recorded := range j.Stages {
+				if recorded.Name == stage {
+					stages[i] = recorded
+				}
+			}
+		}
+		j.Stages = stages
+	}
+	for i := range j.Stages {
+		if j.Stages[i].Name == name {
+			return &j.Stages[i]
+		}
+	}
+	return nil
+}
+
+// ResetStages prepares the stages for processing the job from its original: the upload
+// is recorded as done when the job was created, unless it was recorded otherwise, and
+// every later stage is pending again.
+func (j *Job) ResetStages() {
+	if upload := j.Stage(PipelineUpload); upload.Status != StageCompleted {
+		created := j.CreatedAt
+		*upload = StageState{Name: PipelineUpload, Status: StageCompleted, StartedAt: &created, CompletedAt: &created}
+	}
+	for i := range j.Stages {
+		if j.Stages[i].Name != PipelineUpload {
+			j.Stages[i] = StageState{Name: j.Stages[i].Name, Status: StagePending}
+		}
+	}
+}
+
+// StartStage marks the named stage running from at.
+func (j *Job) StartStage(name PipelineStage, at time.Time) {
+	if s := j.Stage(name); s != nil {
+		*s = StageState{Name: name, Status: StageRunning, StartedAt: &at}
+	}
+}
+
+// EndStage marks the named stage completed at at.
+func (j *Job) EndStage(name PipelineStage, at time.Time) {
+	if s := j.Stage(name); s != nil {
+		if s.StartedAt == nil {
+			s.StartedAt = &at
+		}
+		s.Status = StageCompleted
+		s.CompletedAt = &at
+		s.Error = ""
+	}
+}
+
+// SkipStage marks the named stage skipped.
+func (j *Job) SkipStage(name PipelineStage) {
+	if s := j.Stage(name); s != nil {
+		*s = StageState{Name: name, Status: StageSkipped}
+	}
+}
+
+// EndStages records how processing ended at at. Without err, running stages complete
+// and those never started are skipped. Otherwise err is charged to the stage it
+// happened in: the one it names, else the first stage still running, else the first
+// one that had yet to start. Other running stages are canceled.
+func (j *Job) EndStages(err error, at time.Time) {
+	j.Stage(PipelineUpload)
+	if err == nil {
+		for i := range j.Stages {
+			switch j.Stages[i].Status {
+			case StageRunning:
+				j.EndStage(j.Stages[i].Name, at)
+			case StagePending:
+				j.Stages[i].Status = StageSkipped
+			}
+		}
+		return
+	}
+
+	var failed PipelineStage
+	var stageErr *stageError
+	if errors.As(err, &stageErr) {
+		failed = stageErr.stage
+	} else {
+		for _, status := range []StageStatus{StageRunning, StagePending} {
+			if i := slices.IndexFunc(j.Stages, func(s StageState) bool { return s.Status == status }); i >= 0 {
+				failed = j.Stages[i].Name
+				break
+			}
+		}
+	}
+	for i := range j.Stages {
+		s := &j.Stages[i]
+		switch {
+		case s.Name == failed:
+			if s.StartedAt == nil {
+				s.StartedAt = &at
+			}
+			s.Status = StageFailed
+			s.CompletedAt = &at
+			s.Error = err.Error()
+		case s.Status == StageRunning:
+			s.Status = StageCanceled
+			s.CompletedAt = &at
+		}
+	}
+}
+
+// FailedStage returns the stage the job failed in, if one did.
+func (j *Job) FailedStage() (PipelineStage, bool) {
+	for _, s := range j.Stages {
+		if s.Status == StageFailed {
+			return s.Name, true
+		}
+	}
+	return "", false
+}
+
+// stageError charges an error to the stage it happened in, where more than one stage
+// may be running when it is returned.
+type stageError struct {
+	stage PipelineStage
+	err   error
+}
+
+func (e *stageError) Error() string { return e.err.Error() }
+func (e *stageError) Unwrap() error { return e.err }
+
+// StageRecorder is a StageHook that keeps a job's Stages current while it is processed.
+// Changed, when set, is called after every change, e.g. to save the job.
+type StageRecorder struct {
+	Job     *Job
+	Changed func()
+}
+
+var _ StageHook = StageRecorder{}
+
+func (r StageRecorder) OnStageStart(_ context.Context, stage PipelineStage) {
+	r.Job.StartStage(stage, time.Now())
+	r.changed()
+}
+
+func (r StageRecorder) OnStageEnd(_ context.Context, stage PipelineStage) {
+	r.Job.EndStage(stage, time.Now())
+	r.changed()
+}
+
+func (r StageRecorder) changed() {
+	if r.Changed != nil {
+		r.Changed()
+	}
+}
@@ -0,0 +1,179 @@
+package chunker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AudioStream describes one audio stream ffmpeg found in the input.
+type AudioStream struct {
+	// Index counts audio streams only, matching ffmpeg's 0:a:N specifier.
+	Index    int    `json:"index"`
+	Language string `json:"language,omitempty"`
+	Codec    string `json:"codec"`
+	Details  string `json:"details,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// TrackMode selects which audio streams of a multi-track input are chunked.
+type TrackMode string
+
+const (
+	// TrackSingle chunks the stream at Options.AudioTrack.
+	TrackSingle TrackMode = "single"
+	// TrackMix mixes every audio stream down to one before chunking.
+	TrackMix TrackMode = "mix"
+	// TrackSeparate chunks every audio stream on its own; chunk files are named
+	// chunk_aNN_XXX.wav and carry their stream in Chunk.Track.
+	TrackSeparate TrackMode = "separate"
+)
+
+// ParseTrackSelection turns a user-facing selection into a mode and stream index.
+// Accepted values are "" (first track), a 1-based track number, "mix", or "separate".
+func ParseTrackSelection(value string) (TrackMode, int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "":
+		return TrackSingle, 0, nil
+	case string(TrackMix), string(TrackSeparate):
+		return TrackMode(value), 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("unknown audio track %q (want a track number from 1, mix, or separate)", value)
+	}
+	return TrackSingle, n - 1, nil
+}
+
+// ProbeAudioStreams lists the audio streams in the input as reported by ffmpeg.
+func (p *Processor) ProbeAudioStreams(ctx context.Context, inputPath string) ([]AudioStream, error) {
+	ffmpeg := p.FFmpegBin
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	output := runProbe(ctx, ffmpeg, inputPath)
+	streams := parseAudioStreams(output)
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no audio streams reported by ffmpeg")
+	}
+	return streams, nil
+}
+
+var (
+	streamLinePattern = regexp.MustCompile(`^\s*Stream #\d+:\d+(?:\[[^\]]*\])?(?:\(([^)]*)\))?: (\w+): (.*)$`)
+	titleLinePattern  = regexp.MustCompile(`^\s*(title|handler_name)\s*:\s*(.+)$`)
+)
+
+// parseAudioStreams reads the input section of ffmpeg's banner. A stream's title comes
+// from the metadata lines that follow it, preferring title over handler_name.
+func parseAudioStreams(output string) []AudioStream {
+	var streams []AudioStream
+	var current *AudioStream
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Output #") {
+			break
+		}
+		if m := streamLinePattern.FindStringSubmatch(line); m != nil {
+			current = nil
+			if m[2] != "Audio" {
+				continue
+			}
+			details := strings.TrimSpace(m[3])
+			codec, _, _ := strings.Cut(details, " ")
+			streams = append(streams, AudioStream{
+				Index:    len(streams),
+				Language: m[1],
+				Codec:    strings.TrimSuffix(codec, ","),
+				Details:  details,
+			})
+			current = &streams[len(streams)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := titleLinePattern.FindStringSubmatch(line); m != nil {
+			if m[1] == "title" || current.Title == "" {
+				current.Title = strings.TrimSpace(m[2])
+			}
+		}
+	}
+	return streams
+}
+
+// trackRun is one ffmpeg segmenting pass: which audio to map and where chunks land.
+type trackRun struct {
+	args    []string
+	pattern string
+	glob    string
+}
+
+// trackRuns plans the segmenting passes for opts given the probed stream count, which
+// is zero when probing failed.
+func trackRuns(opts Options, streamCount int) ([]trackRun, error) {
+	single := func(track int, pattern, glob string) trackRun {
+		args := []string{"-map", fmt.Sprintf("0:a:%d", track)}
+		if opts.AudioFilters != "" {
+			args = append(args, "-af", opts.AudioFilters)
+		}
+		return trackRun{args: args, pattern: pattern, glob: glob}
+	}
+
+	switch opts.TrackMode {
+	case "", TrackSingle:
+		if streamCount > 0 && opts.AudioTrack >= streamCount {
+			return nil, fmt.Errorf("audio track %d requested but the input has %d audio stream(s)", opts.AudioTrack+1, streamCount)
+		}
+		return []trackRun{single(opts.AudioTrack, "chunk_%03d.wav", "chunk_*.wav")}, nil
+	case TrackMix:
+		if streamCount == 0 {
+			return nil, fmt.Errorf("mixing tracks needs the audio stream count, but ffmpeg did not report any")
+		}
+		if streamCount == 1 {
+			return []trackRun{single(0, "chunk_%03d.wav", "chunk_*.wav")}, nil
+		}
+		var graph strings.Builder
+		for i := 0; i < streamCount; i++ {
+			fmt.Fprintf(&graph, "[0:a:%d]", i)
+		}
+		fmt.Fprintf(&graph, "amix=inputs=%d:duration=longest", streamCount)
+		if opts.AudioFilters != "" {
+			graph.WriteString("," + opts.AudioFilters)
+		}
+		graph.WriteString("[mix]")
+		return []trackRun{{
+			args:    []string{"-filter_complex", graph.String(), "-map", "[mix]"},
+			pattern: "chunk_%03d.wav",
+			glob:    "chunk_*.wav",
+		}}, nil
+	case TrackSeparate:
+		if streamCount == 0 {
+			return nil, fmt.Errorf("chunking tracks separately needs the audio stream count, but ffmpeg did not report any")
+		}
+		runs := make([]trackRun, 0, streamCount)
+		for i := 0; i < streamCount; i++ {
+			prefix := fmt.Sprintf("chunk_a%02d_", i)
+			runs = append(runs, single(i, prefix+"%03d.wav", prefix+"*.wav"))
+		}
+		return runs, nil
+	}
+	return nil, fmt.Errorf("unknown track mode %q", opts.TrackMode)
+}
+
+var separateChunkPattern = regexp.MustCompile(`^chunk_a(\d+)_\d+\.wav$`)
+
+// chunkTrack recovers the audio stream a chunk file came from in TrackSeparate mode.
+func chunkTrack(name string) (int, bool) {
+	m := separateChunkPattern.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	return n, err == nil
+}
@@ -0,0 +1,181 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SubtitleMode selects the subtitled outputs written once a job is transcribed. The
+// empty mode writes none.
+type SubtitleMode string
+
+const (
+	SubtitlesNone SubtitleMode = ""
+	// SubtitlesSRT writes a sidecar SRT for the original upload.
+	SubtitlesSRT SubtitleMode = "srt"
+	// SubtitlesMP4 writes the sidecar SRT and an MP4 copy of the input carrying it as a
+	// soft subtitle track.
+	SubtitlesMP4 SubtitleMode = "mp4"
+)
+
+// ParseSubtitleMode validates a user-provided mode; "", "none", and "off" mean no subtitles.
+func ParseSubtitleMode(value string) (SubtitleMode, error) {
+	switch mode := SubtitleMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "none", "off":
+		return SubtitlesNone, nil
+	case SubtitlesNone, SubtitlesSRT, SubtitlesMP4:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown subtitles option %q (want srt or mp4)", value)
+}
+
+// maxCueSeconds bounds the cues cut from a chunk transcript without word timings, which
+// would otherwise stay on screen for the whole chunk.
+const maxCueSeconds = 6.0
+
+// Cue is one subtitle: Text is shown from Start to End, in seconds.
+type Cue struct {
+	Start float64
+	End   float64
+	Text  string
+}
+
+// SubtitleCues builds the cues of a job's main transcripts. Chunks with word timings
+// contribute one cue per whisper segment; the others have their transcript spread evenly
+// over the chunk in cues of at most maxCueSeconds. Only the first track is used, since
+// separately chunked tracks overlap in time.
+func SubtitleCues(jobDir string, chunks []Chunk) []Cue {
+	var cues []Cue
+	for _, chunk := range chunks {
+		if chunk.Track != chunks[0].Track {
+			continue
+		}
+		if segments, err := ReadChunkWords(jobDir, chunk); err == nil {
+			for _, seg := range segments {
+				if seg.Text != "" && seg.End > seg.Start {
+					cues = append(cues, Cue{Start: seg.Start, End: seg.End, Text: seg.Text})
+				}
+			}
+			continue
+		}
+		if chunk.TranscriptFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+		if err != nil {
+			continue
+		}
+		cues = append(cues, spreadCues(strings.Fields(string(data)), chunk.StartSeconds, chunk.DurationSeconds)...)
+	}
+	return cues
+}
+
+// spreadCues splits words into equal cues covering duration seconds from start.
+func spreadCues(words []string, start, duration float64) []Cue {
+	if len(words) == 0 || duration <= 0 {
+		return nil
+	}
+	n := min(len(words), int(math.Ceil(duration/maxCueSeconds)))
+	cues := make([]Cue, 0, n)
+	for i := range n {
+		from, to := i*len(words)/n, (i+1)*len(words)/n
+		cues = append(cues, Cue{
+			Start: start + duration*float64(i)/float64(n),
+			End:   start + duration*float64(i+1)/float64(n),
+			Text:  strings.Join(words[from:to], " "),
+		})
+	}
+	return cues
+}
+
+// WriteCues writes cues as SubRip, or as WebVTT when vtt is set.
+func WriteCues(w io.Writer, cues []Cue, vtt bool) error {
+	var b strings.Builder
+	sep := ","
+	if vtt {
+		b.WriteString("WEBVTT\n\n")
+		sep = "."
+	}
+	for i, cue := range cues {
+		if !vtt {
+			fmt.Fprintf(&b, "%d\n", i+1)
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", CueTimestamp(cue.Start, sep), CueTimestamp(cue.End, sep), cue.Text)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CueTimestamp formats seconds as HH:MM:SS plus milliseconds after sep ("," for SRT, "." for WebVTT).
+func CueTimestamp(seconds float64, sep string) string {
+	ms := int64(math.Max(0, seconds)*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// writeSubtitles writes transcripts/subtitles.srt from the chunk transcripts and, for
+// SubtitlesMP4, muxes it into video/subtitled.mp4 next to the input's first video stream
+// and its audio. Cue times come from the chunks as processed, so they line up with the
+// original upload whatever time offset is applied to the job later. It returns the files
+// written, relative to jobDir, and the notes and ffmpeg log.
+func (p *Processor) writeSubtitles(ctx context.Context, ffmpeg, jobDir, inputPath string, chunks []Chunk, mode SubtitleMode) (srtFile, videoFile string, logs []string, err error) {
+	if len(chunks) == 0 {
+		return "", "", nil, nil
+	}
+	cues := SubtitleCues(jobDir, chunks)
+	if len(cues) == 0 {
+		return "", "", []string{"no chunk was transcribed; subtitles skipped"}, nil
+	}
+
+	srtFile = "transcripts/subtitles.srt"
+	srtPath := filepath.Join(jobDir, filepath.FromSlash(srtFile))
+	f, err := os.Create(srtPath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("creating subtitles: %w", err)
+	}
+	err = WriteCues(f, cues, false)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", nil, fmt.Errorf("writing subtitles: %w", err)
+	}
+	logs = append(logs, fmt.Sprintf("wrote %d subtitle cues", len(cues)))
+	if mode != SubtitlesMP4 {
+		return srtFile, "", logs, nil
+	}
+
+	if !hasVideoStream(runProbe(ctx, ffmpeg, inputPath)) {
+		return srtFile, "", append(logs, "the input has no video stream; subtitled video skipped"), nil
+	}
+	videoDir := filepath.Join(jobDir, "video")
+	if err := os.MkdirAll(videoDir, 0o755); err != nil {
+		return srtFile, "", logs, fmt.Errorf("creating video directory: %w", err)
+	}
+	videoFile = "video/subtitled.mp4"
+	// The video is copied; the audio is re-encoded because PCM and some other codecs
+	// common in MOV and MKV are not allowed in MP4.
+	logEntry, err := runCommand(ctx, ffmpeg,
+		"-y",
+		"-i", inputPath,
+		"-i", srtPath,
+		"-map", "0:V:0",
+		"-map", "0:a?",
+		"-map", "1:0",
+		"-c:v", "copy",
+		"-c:a", "aac",
+		"-b:a", "160k",
+		"-c:s", "mov_text",
+		"-movflags", "+faststart",
+		filepath.Join(jobDir, filepath.FromSlash(videoFile)),
+	)
+	logs = append(logs, logEntry)
+	if err != nil {
+		return srtFile, "", logs, fmt.Errorf("muxing subtitled video: %w", err)
+	}
+	return srtFile, videoFile, logs, nil
+}
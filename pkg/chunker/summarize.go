@@ -0,0 +1,270 @@
+package chunker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSummaryPrompt is the system prompt sent with the transcript unless the
+// Summarizer overrides it. The transcript lines start with [HH:MM:SS] offsets, which the
+// model is asked to reuse, in seconds, as chapter starts.
+const DefaultSummaryPrompt = `You summarise transcripts of recordings. Each transcript line starts with its offset into the recording as [HH:MM:SS].
+Answer with a JSON object and nothing else:
+{"summary": "a few paragraphs covering the main points",
+ "chapters": [{"start": <offset in seconds where the chapter begins>, "title": "short title"}],
+ "action_items": ["one task, decision to follow up, or commitment per entry"]}
+Write in the transcript's language. Use an empty list when there are no chapters or action items.`
+
+// defaultSummaryChars bounds the transcript sent to the model when MaxChars is zero.
+const defaultSummaryChars = 100_000
+
+// Summarizer sends a job's merged transcript to an OpenAI-compatible chat completions
+// endpoint and turns the answer into a Summary.
+type Summarizer struct {
+	// URL is the API base URL; the request goes to URL + "/chat/completions", e.g.
+	// https://api.openai.com/v1 or a local server's http://127.0.0.1:11434/v1.
+	URL    string
+	APIKey string
+	Model  string
+	// Prompt replaces DefaultSummaryPrompt. It must still ask for the same JSON object.
+	Prompt string
+	// MaxChars truncates longer transcripts; zero means defaultSummaryChars.
+	MaxChars int
+	// Timeout bounds one request; zero leaves it to the context.
+	Timeout time.Duration
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// WithSummarizer enables Options.Summarize, sending transcripts to s.
+func WithSummarizer(s *Summarizer) Option {
+	return func(p *Processor) {
+		p.Summarizer = s
+	}
+}
+
+// Summary is what the summarization stage stored for a job. A failed attempt keeps only
+// Error, so the job still completes.
+type Summary struct {
+	Model       string           `json:"model,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	Text        string           `json:"text,omitempty"`
+	Chapters    []SummaryChapter `json:"chapters,omitempty"`
+	ActionItems []string         `json:"actionItems,omitempty"`
+	// Truncated is set when only the start of the transcript fitted into MaxChars.
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SummaryChapter is a titled section of the recording starting at StartSeconds, on the
+// job's timeline.
+type SummaryChapter struct {
+	StartSeconds float64 `json:"startSeconds"`
+	Title        string  `json:"title"`
+}
+
+// TimedTranscript joins a job's main transcripts into lines prefixed with their
+// [HH:MM:SS] offset, one per whisper segment when word timings exist and one per chunk
+// otherwise. Like SubtitleCues it only reads the first track.
+func TimedTranscript(jobDir string, chunks []Chunk) string {
+	var b strings.Builder
+	line := func(start float64, text string) {
+		if text = strings.Join(strings.Fields(text), " "); text != "" {
+			clock, _, _ := strings.Cut(CueTimestamp(start, ","), ",")
+			fmt.Fprintf(&b, "[%s] %s\n", clock, text)
+		}
+	}
+	for _, chunk := range chunks {
+		if chunk.Track != chunks[0].Track {
+			continue
+		}
+		if segments, err := ReadChunkWords(jobDir, chunk); err == nil {
+			for _, seg := range segments {
+				line(seg.Start, seg.Text)
+			}
+			continue
+		}
+		if chunk.TranscriptFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.TranscriptFile)))
+		if err == nil {
+			line(chunk.StartSeconds, string(data))
+		}
+	}
+	return b.String()
+}
+
+// Summarize asks the model for a summary, chapters, and action items of transcript.
+func (s *Summarizer) Summarize(ctx context.Context, transcript string) (Summary, error) {
+	summary := Summary{Model: s.Model, CreatedAt: time.Now().UTC()}
+	limit := s.MaxChars
+	if limit <= 0 {
+		limit = defaultSummaryChars
+	}
+	if len(transcript) > limit {
+		cut := strings.LastIndexByte(transcript[:limit], '\n')
+		if cut <= 0 {
+			cut = limit
+		}
+		transcript = transcript[:cut]
+		summary.Truncated = true
+	}
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"model": s.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt},
+			{"role": "user", "content": transcript},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0.2,
+	})
+	if err != nil {
+		return summary, err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.URL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return summary, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return summary, fmt.Errorf("summarization request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return summary, fmt.Errorf("reading summarization response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return summary, fmt.Errorf("summarization endpoint answered %s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 300)])))
+	}
+
+	var completion struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return summary, fmt.Errorf("decoding summarization response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return summary, errors.New("summarization response has no choices")
+	}
+	if completion.Model != "" {
+		summary.Model = completion.Model
+	}
+	if err := summary.parse(completion.Choices[0].Message.Content); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// parse fills s from the model's answer, tolerating a Markdown code fence around the
+// JSON and chapter starts given as numbers or HH:MM:SS strings.
+func (s *Summary) parse(content string) error {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = content[strings.IndexByte(content, '\n')+1:]
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+	var answer struct {
+		Summary  string `json:"summary"`
+		Chapters []struct {
+			Start json.RawMessage `json:"start"`
+			Title string          `json:"title"`
+		} `json:"chapters"`
+		ActionItems []string `json:"action_items"`
+	}
+	if err := json.Unmarshal([]byte(content), &answer); err != nil {
+		return fmt.Errorf("the model did not answer with the expected JSON: %w", err)
+	}
+	s.Text = strings.TrimSpace(answer.Summary)
+	for _, item := range answer.ActionItems {
+		if item = strings.TrimSpace(item); item != "" {
+			s.ActionItems = append(s.ActionItems, item)
+		}
+	}
+	for _, ch := range answer.Chapters {
+		start, ok := parseChapterStart(ch.Start)
+		if title := strings.TrimSpace(ch.Title); ok && title != "" {
+			s.Chapters = append(s.Chapters, SummaryChapter{StartSeconds: start, Title: title})
+		}
+	}
+	sort.SliceStable(s.Chapters, func(i, j int) bool { return s.Chapters[i].StartSeconds < s.Chapters[j].StartSeconds })
+	if s.Text == "" && len(s.Chapters) == 0 && len(s.ActionItems) == 0 {
+		return errors.New("the model's answer was empty")
+	}
+	return nil
+}
+
+func parseChapterStart(raw json.RawMessage) (float64, bool) {
+	var seconds float64
+	if err := json.Unmarshal(raw, &seconds); err == nil {
+		return seconds, seconds >= 0
+	}
+	var clock string
+	if err := json.Unmarshal(raw, &clock); err != nil {
+		return 0, false
+	}
+	var h, m, sec int
+	if n, _ := fmt.Sscanf(strings.Trim(clock, "[]"), "%d:%d:%d", &h, &m, &sec); n == 3 {
+		return float64(h*3600 + m*60 + sec), true
+	}
+	if n, _ := fmt.Sscanf(strings.Trim(clock, "[]"), "%d:%d", &m, &sec); n == 2 {
+		return float64(m*60 + sec), true
+	}
+	return 0, false
+}
+
+// summarize runs the summarization stage over the job's transcripts. Its failures are
+// recorded on the Summary and in the logs rather than failing the job.
+func (p *Processor) summarize(ctx context.Context, jobDir string, chunks []Chunk) (*Summary, []string) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	transcript := TimedTranscript(jobDir, chunks)
+	if transcript == "" {
+		return nil, []string{"no chunk was transcribed; summary skipped"}
+	}
+	summary, err := p.Summarizer.Summarize(ctx, transcript)
+	if err != nil {
+		summary.Error = err.Error()
+		return &summary, []string{"summarization failed: " + err.Error()}
+	}
+	note := fmt.Sprintf("summarized with %s: %d chapters, %d action items", summary.Model, len(summary.Chapters), len(summary.ActionItems))
+	if summary.Truncated {
+		note += " (transcript truncated)"
+	}
+	return &summary, []string{note}
+}
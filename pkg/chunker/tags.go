@@ -0,0 +1,176 @@
+package chunker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on user annotations, kept small because job.json is rewritten on every save.
+const (
+	MaxTags        = 20
+	MaxTagLength   = 40
+	MaxNoteLength  = 4000
+	MaxTitleLength = 200
+
+	MaxMetadataKeys        = 32
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 1024
+)
+
+// NormalizeTags lower-cases, trims, de-duplicates, and sorts tags, dropping empty ones.
+// Tags may contain letters, digits, '-', '_', '.', and ':' so they stay safe to use in
+// URLs and filenames without escaping.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, raw := range tags {
+		tag := strings.ToLower(strings.TrimSpace(raw))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		for _, c := range tag {
+			switch {
+			case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+			default:
+				return nil, fmt.Errorf("tag %q may only contain letters, digits, '-', '_', '.', and ':'", tag)
+			}
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("a job can have at most %d tags", MaxTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ParseTagList splits a comma- or whitespace-separated tag string, as typed into a form
+// field, and normalises the result.
+func ParseTagList(value string) ([]string, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	return NormalizeTags(fields)
+}
+
+// NormalizeNote trims a free-text note and checks its length.
+func NormalizeNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxNoteLength {
+		return "", fmt.Errorf("note is longer than %d characters", MaxNoteLength)
+	}
+	return note, nil
+}
+
+// NormalizeTitle trims a job title, folds runs of whitespace (including newlines) into
+// single spaces, drops other control characters, and checks its length. An empty
+// result clears the title.
+func NormalizeTitle(title string) (string, error) {
+	title = strings.Join(strings.Fields(title), " ")
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return "", fmt.Errorf("title is longer than %d characters", MaxTitleLength)
+	}
+	return title, nil
+}
+
+// NormalizeMetadata trims the keys and values of caller-supplied metadata such as a case
+// or agent ID and checks them. Keys keep their case and may contain letters, digits,
+// '-', '_', and '.'; entries with an empty value are dropped. A nil result means none.
+func NormalizeMetadata(metadata map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(metadata))
+	for rawKey, rawValue := range metadata {
+		key, value := strings.TrimSpace(rawKey), strings.TrimSpace(rawValue)
+		if value == "" {
+			continue
+		}
+		if key == "" {
+			return nil, fmt.Errorf("metadata value %q has no key", value)
+		}
+		if len(key) > MaxMetadataKeyLength {
+			return nil, fmt.Errorf("metadata key %q is longer than %d characters", key, MaxMetadataKeyLength)
+		}
+		for _, c := range key {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			default:
+				return nil, fmt.Errorf("metadata key %q may only contain letters, digits, '-', '_', and '.'", key)
+			}
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return nil, fmt.Errorf("metadata value of %q is longer than %d characters", key, MaxMetadataValueLength)
+		}
+		if strings.ContainsFunc(value, unicode.IsControl) {
+			return nil, fmt.Errorf("metadata value of %q may not contain line breaks or control characters", key)
+		}
+		out[key] = value
+	}
+	if len(out) > MaxMetadataKeys {
+		return nil, fmt.Errorf("a job can have at most %d metadata keys", MaxMetadataKeys)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// ParseMetadataPairs reads "key=value" entries, as typed one per line into a form field
+// or passed as repeated command-line flags, and normalises the result. Blank lines are
+// skipped; a key given twice is an error.
+func ParseMetadataPairs(entries []string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, entry := range entries {
+		for _, line := range strings.Split(entry, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("metadata entry %q is not key=value", line)
+			}
+			key = strings.TrimSpace(key)
+			if _, dup := metadata[key]; dup {
+				return nil, fmt.Errorf("metadata key %q is given twice", key)
+			}
+			metadata[key] = value
+		}
+	}
+	return NormalizeMetadata(metadata)
+}
+
+// HasTag reports whether the job carries tag, compared case-insensitively.
+func (j *Job) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range j.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AdoptAnnotations copies the user-edited title, tags, and note from a fresher copy of
+// the same job, along with its Version, so a long-running writer can save its own
+// changes on top of edits made in the meantime.
+func (j *Job) AdoptAnnotations(latest *Job) {
+	j.Title = latest.Title
+	j.Tags = latest.Tags
+	j.Note = latest.Note
+	j.Version = latest.Version
+	if latest.UpdatedAt.After(j.UpdatedAt) {
+		j.UpdatedAt = latest.UpdatedAt
+	}
+}
@@ -0,0 +1,168 @@
+package chunker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tool names, as used in ToolVersion.Name and for known-bad version lists.
+const (
+	ToolFFmpeg  = "ffmpeg"
+	ToolWhisper = "whisper"
+)
+
+// ToolVersion identifies the build of an external program a job ran, so outputs that
+// differ between machines can be traced to their ffmpeg or whisper. SHA256 starts the
+// digest of the binary, telling apart builds that report the same version.
+type ToolVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+}
+
+// ToolCheck is the outcome of CheckTool: the version found, or why the program is
+// unusable, with warnings such as a known-bad version.
+type ToolCheck struct {
+	ToolVersion
+	Error     string    `json:"error,omitempty"`
+	Warnings  []string  `json:"warnings,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// OK reports whether the program ran.
+func (c ToolCheck) OK() bool {
+	return c.Error == ""
+}
+
+// minFFmpegMajor is the oldest ffmpeg release line the pipeline is used with; older ones
+// run, but with a warning.
+const minFFmpegMajor = 4
+
+// toolCheckTimeout bounds one run of a program's version or help output.
+const toolCheckTimeout = 10 * time.Second
+
+var (
+	ffmpegVersionLine = regexp.MustCompile(`(?m)^ffmpeg version (\S+)`)
+	whisperVersion    = regexp.MustCompile(`(?i)\bversion[: ]+v?(\d+\.\d+(?:\.\d+)?)`)
+)
+
+// CheckTool runs bin, the ffmpeg or whisper binary named by name, to read its version:
+// ffmpeg -version, or whisper --help, which whisper.cpp builds without a version flag
+// also answer. Versions starting with an entry of bad, such as "5.1" for every 5.1.x,
+// are warned about, as is ffmpeg older than 4.0.
+func CheckTool(ctx context.Context, name, bin string, bad []string) ToolCheck {
+	check := ToolCheck{ToolVersion: ToolVersion{Name: name}, CheckedAt: time.Now()}
+	path, err := lookBinary(bin)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.SHA256 = binaryDigest(path)
+
+	ctx, cancel := context.WithTimeout(ctx, toolCheckTimeout)
+	defer cancel()
+	switch name {
+	case ToolFFmpeg:
+		output, err := runCommand(ctx, path, "-version")
+		if err != nil {
+			check.Error = fmt.Sprintf("%s -version: %v", bin, err)
+			return check
+		}
+		m := ffmpegVersionLine.FindStringSubmatch(output)
+		if m == nil {
+			check.Error = fmt.Sprintf("%s -version did not print an ffmpeg version", bin)
+			return check
+		}
+		check.Version = m[1]
+		if major, ok := leadingNumber(strings.TrimPrefix(check.Version, "n")); ok && major < minFFmpegMajor {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("ffmpeg %s is older than %d.0, which audi is not used with", check.Version, minFFmpegMajor))
+		}
+	default:
+		output, err := runCommand(ctx, path, "--help")
+		if err != nil {
+			check.Error = fmt.Sprintf("%s --help: %v", bin, err)
+			return check
+		}
+		if m := whisperVersion.FindStringSubmatch(output); m != nil {
+			check.Version = m[1]
+		}
+	}
+
+	for _, v := range bad {
+		if check.Version != "" && (check.Version == v || strings.HasPrefix(check.Version, strings.TrimSuffix(v, ".")+".")) {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("%s %s is listed as a known-bad version (%s)", name, check.Version, v))
+		}
+	}
+	return check
+}
+
+// CheckTools checks ffmpeg (named "ffmpeg" when empty) and, when configured, whisper,
+// with the known-bad versions in bad keyed by ToolFFmpeg and ToolWhisper.
+func CheckTools(ctx context.Context, ffmpeg, whisper string, bad map[string][]string) []ToolCheck {
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	checks := []ToolCheck{CheckTool(ctx, ToolFFmpeg, ffmpeg, bad[ToolFFmpeg])}
+	if whisper != "" {
+		checks = append(checks, CheckTool(ctx, ToolWhisper, whisper, bad[ToolWhisper]))
+	}
+	return checks
+}
+
+// leadingNumber parses the digits v starts with, as the major version of "6.1.1-3ubuntu5".
+func leadingNumber(v string) (int, bool) {
+	end := strings.IndexFunc(v, func(r rune) bool { return r < '0' || r > '9' })
+	if end < 0 {
+		end = len(v)
+	}
+	n, err := strconv.Atoi(v[:end])
+	return n, err == nil
+}
+
+// binaryDigest returns the first 12 hex digits of the SHA-256 of the file at path, or
+// "" when it cannot be read.
+func binaryDigest(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// WithTools records the tool versions found at startup on every job the processor runs.
+func WithTools(checks ...ToolCheck) Option {
+	return func(p *Processor) {
+		p.Tools = nil
+		for _, c := range checks {
+			if c.OK() {
+				p.Tools = append(p.Tools, c.ToolVersion)
+			}
+		}
+	}
+}
+
+// ToolsFor returns the tool versions of p a run with opts uses: whisper only when it
+// transcribes.
+func (p *Processor) ToolsFor(opts Options) []ToolVersion {
+	var tools []ToolVersion
+	for _, t := range p.Tools {
+		if t.Name == ToolWhisper && !opts.Transcribe {
+			continue
+		}
+		tools = append(tools, t)
+	}
+	return tools
+}
@@ -0,0 +1,132 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// TranscriptSet is an extra transcription pass over every chunk with its own whisper
+// arguments, e.g. a second language, an English translation, or a different model.
+// Args are appended after Processor.WhisperArgs and the job's Options.WhisperArgs, so
+// later flags such as -l or -m win.
+type TranscriptSet struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+// ChunkTranscript is one chunk's output for a TranscriptSet, stored under
+// transcripts/<set>/.
+type ChunkTranscript struct {
+	Set     string `json:"set"`
+	File    string `json:"file,omitempty"`
+	Preview string `json:"preview,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ValidateTranscriptSets checks set names are unique and usable as directory names.
+func ValidateTranscriptSets(sets []TranscriptSet) error {
+	seen := make(map[string]bool, len(sets))
+	for _, set := range sets {
+		if err := validateSetName(set.Name); err != nil {
+			return err
+		}
+		if seen[set.Name] {
+			return fmt.Errorf("transcript set %q is defined twice", set.Name)
+		}
+		seen[set.Name] = true
+	}
+	return nil
+}
+
+func validateSetName(name string) error {
+	if name == "" {
+		return errors.New("transcript set name must not be empty")
+	}
+	if len(name) > 32 {
+		return fmt.Errorf("transcript set name %q is longer than 32 characters", name)
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return fmt.Errorf("transcript set name %q may only contain lower-case letters, digits, '-', and '_'", name)
+		}
+	}
+	return nil
+}
+
+// SelectTranscriptSets picks the named sets from the configured ones, keeping the
+// configured order.
+func SelectTranscriptSets(configured []TranscriptSet, names []string) ([]TranscriptSet, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, set := range configured {
+			found = found || set.Name == name
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown transcript set %q", name)
+		}
+		wanted[name] = true
+	}
+	var selected []TranscriptSet
+	for _, set := range configured {
+		if wanted[set.Name] {
+			selected = append(selected, set)
+		}
+	}
+	return selected, nil
+}
+
+// transcribeSets runs every set over one chunk concurrently, publishing each transcript
+// from the staging directory once written. Results and logs come back in set order; a
+// failed set is recorded on its ChunkTranscript rather than failing the job.
+func (p *Processor) transcribeSets(ctx context.Context, jobDir, chunkPath string, baseArgs []string, sets []TranscriptSet) ([]ChunkTranscript, []string) {
+	results := make([]ChunkTranscript, len(sets))
+	logs := make([]string, len(sets))
+	base := strings.TrimSuffix(filepath.Base(chunkPath), filepath.Ext(chunkPath))
+
+	var wg sync.WaitGroup
+	for i, set := range sets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = ChunkTranscript{Set: set.Name}
+
+			file := "transcripts/" + set.Name + "/" + base + ".txt"
+			path, err := stagingPath(jobDir, file)
+			if err != nil {
+				results[i].Error = fmt.Sprintf("transcription failed: %v", err)
+				return
+			}
+			prefix := strings.TrimSuffix(path, ".txt")
+			args := append(append([]string{}, baseArgs...), set.Args...)
+			logEntry, err := p.transcribeChunkWith(ctx, chunkPath, prefix, args)
+			logs[i] = fmt.Sprintf("[%s] %s", set.Name, logEntry)
+			if err != nil {
+				results[i].Error = fmt.Sprintf("transcription failed: %v", err)
+				return
+			}
+			preview, err := readPreview(path, 400)
+			if err == nil {
+				err = publish(jobDir, file)
+			}
+			if err != nil {
+				results[i].Error = fmt.Sprintf("unable to read transcript: %v", err)
+				return
+			}
+			results[i].Preview = preview
+			results[i].File = file
+		}()
+	}
+	wg.Wait()
+	return results, logs
+}
@@ -0,0 +1,64 @@
+package chunker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// DefaultSilenceThresholdDB is the frame level, in dBFS, below which audio counts as silence.
+const DefaultSilenceThresholdDB = -50.0
+
+// vadFrameMillis is the analysis window; short enough that a single word lifts a frame above the threshold.
+const vadFrameMillis = 30
+
+// chunkIsSilent reports whether every analysis frame of a 16-bit PCM WAV file stays
+// below thresholdDB. It stops reading at the first frame that carries signal.
+func chunkIsSilent(path string, thresholdDB float64) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := readWAVHeader(file)
+	if err != nil {
+		return false, err
+	}
+	if info.bitsPerSample != 16 {
+		return false, errors.New("silence detection needs 16-bit PCM")
+	}
+
+	samplesPerFrame := int(info.sampleRate) * int(info.channels) * vadFrameMillis / 1000
+	if samplesPerFrame == 0 {
+		samplesPerFrame = int(info.channels)
+	}
+	// Compare mean squares against the threshold instead of taking a log per frame.
+	limit := math.Pow(10, thresholdDB/10) * math.MaxInt16 * math.MaxInt16
+
+	reader := bufio.NewReader(io.LimitReader(file, int64(info.dataSize)))
+	buf := make([]byte, samplesPerFrame*2)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n >= 2 {
+			var sum float64
+			samples := n / 2
+			for i := 0; i < samples; i++ {
+				v := float64(int16(binary.LittleEndian.Uint16(buf[i*2:])))
+				sum += v * v
+			}
+			if sum/float64(samples) > limit {
+				return false, nil
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
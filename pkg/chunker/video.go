@@ -0,0 +1,226 @@
+package chunker
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// videoStreamLine matches a video stream in ffmpeg's input header; cover art is
+	// reported as a video stream too, marked "(attached pic)".
+	videoStreamLine = regexp.MustCompile(`(?m)^\s*Stream #\d+:\d+.*?: Video: .*$`)
+	keyframeTime    = regexp.MustCompile(`pts_time:\s*(-?[0-9.]+)`)
+)
+
+// videoClipContainers are the input containers clips keep; anything else is copied into
+// Matroska, which takes almost any codec without re-encoding.
+var videoClipContainers = map[string]bool{".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true}
+
+// keyframeSnapTolerance is how far below a keyframe's time a cut is requested, so float
+// rounding never pushes the segment muxer on to the following keyframe.
+const keyframeSnapTolerance = 0.001
+
+// hasVideoStream reports whether ffmpeg's input header lists a video stream other than
+// cover art.
+func hasVideoStream(probeOutput string) bool {
+	for _, line := range videoStreamLine.FindAllString(probeOutput, -1) {
+		if !strings.Contains(line, "(attached pic)") {
+			return true
+		}
+	}
+	return false
+}
+
+// probeKeyframes lists the times of the first video stream's keyframes in order, or nil
+// when the input has no video. Only keyframes are decoded.
+func (p *Processor) probeKeyframes(ctx context.Context, ffmpeg, inputPath string) ([]float64, error) {
+	if !hasVideoStream(runProbe(ctx, ffmpeg, inputPath)) {
+		return nil, nil
+	}
+	output, err := p.accelerated(ctx, p.Acceleration != nil && p.Acceleration.FFmpegHWAccel != "", func(gpu bool) (string, error) {
+		args := slices.Concat([]string{"-hide_banner", "-nostats", "-skip_frame", "nokey"}, p.hwaccelArgs(gpu))
+		args = append(args,
+			"-i", inputPath,
+			"-map", "0:V:0",
+			"-vf", "showinfo",
+			"-f", "null", "-",
+		)
+		return runCommand(ctx, ffmpeg, args...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding keyframes: %w", err)
+	}
+	var keyframes []float64
+	for _, m := range keyframeTime.FindAllStringSubmatch(output, -1) {
+		if t, err := strconv.ParseFloat(m[1], 64); err == nil {
+			keyframes = append(keyframes, t)
+		}
+	}
+	slices.Sort(keyframes)
+	return slices.Compact(keyframes), nil
+}
+
+// keyframeCuts moves each chunk start after the first to the nearest keyframe, dropping
+// cuts that land on the same keyframe or at the very start. Without keyframes the starts
+// are used as they are and ffmpeg cuts at the next keyframe after each.
+func keyframeCuts(starts, keyframes []float64) []float64 {
+	var cuts []float64
+	for _, start := range starts {
+		cut := nearestKeyframe(start, keyframes)
+		if cut <= keyframeSnapTolerance || (len(cuts) > 0 && cut <= cuts[len(cuts)-1]) {
+			continue
+		}
+		cuts = append(cuts, cut)
+	}
+	return cuts
+}
+
+func nearestKeyframe(t float64, keyframes []float64) float64 {
+	if len(keyframes) == 0 {
+		return t
+	}
+	i, _ := slices.BinarySearch(keyframes, t)
+	switch {
+	case i == 0:
+		return keyframes[0]
+	case i == len(keyframes) || t-keyframes[i-1] <= keyframes[i]-t:
+		return keyframes[i-1]
+	}
+	return keyframes[i]
+}
+
+// videoClip is one entry of the segment muxer's CSV list.
+type videoClip struct {
+	file       string
+	start, end float64
+}
+
+// clipAt returns the clip whose span contains t, the start of a chunk.
+func clipAt(clips []videoClip, t float64) (videoClip, bool) {
+	for i := len(clips) - 1; i >= 0; i-- {
+		if clips[i].start <= t+remainderTolerance {
+			return clips[i], true
+		}
+	}
+	return videoClip{}, false
+}
+
+// trackStarts lists the chunk starts of the first track, which every track shares.
+func trackStarts(chunks []Chunk) []float64 {
+	var starts []float64
+	for _, c := range chunks {
+		if c.Track == chunks[0].Track {
+			starts = append(starts, c.StartSeconds)
+		}
+	}
+	return starts
+}
+
+// writeVideoClips copies the input's video, with its audio, into video/ without
+// re-encoding, cut at the chunk boundaries moved to the nearest keyframes, and points each
+// chunk at the clip containing its start. Inputs without video are skipped with a note.
+func (p *Processor) writeVideoClips(ctx context.Context, ffmpeg, jobDir, inputPath string, chunks []Chunk) ([]string, error) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	keyframes, err := p.probeKeyframes(ctx, ffmpeg, inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if keyframes == nil {
+		return []string{"the input has no video stream; video clips skipped"}, nil
+	}
+
+	videoDir := filepath.Join(jobDir, "video")
+	if err := os.MkdirAll(videoDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating video directory: %w", err)
+	}
+	ext := strings.ToLower(filepath.Ext(inputPath))
+	if !videoClipContainers[ext] {
+		ext = ".mkv"
+	}
+	listPath := filepath.Join(videoDir, "clips.csv")
+	defer os.Remove(listPath)
+
+	args := []string{
+		"-y",
+		"-i", inputPath,
+		"-map", "0:V:0",
+		"-map", "0:a?",
+		"-c", "copy",
+		"-f", "segment",
+	}
+	if cuts := keyframeCuts(trackStarts(chunks), keyframes); len(cuts) > 0 {
+		times := make([]string, len(cuts))
+		for i, cut := range cuts {
+			times[i] = strconv.FormatFloat(math.Max(0, cut-keyframeSnapTolerance), 'f', 3, 64)
+		}
+		args = append(args, "-segment_times", strings.Join(times, ","))
+	} else {
+		// One chunk: keep the whole video in one clip instead of the default 2s segments.
+		args = append(args, "-segment_time", "1000000000")
+	}
+	args = append(args,
+		"-reset_timestamps", "1",
+		"-segment_list", listPath,
+		"-segment_list_type", "csv",
+		filepath.Join(videoDir, "clip_%03d"+ext),
+	)
+	logEntry, err := runCommand(ctx, ffmpeg, args...)
+	logs := []string{fmt.Sprintf("found %d keyframes", len(keyframes)), logEntry}
+	if err != nil {
+		return logs, fmt.Errorf("copying video clips: %w", err)
+	}
+
+	clips, err := readSegmentList(listPath)
+	if err != nil {
+		return logs, fmt.Errorf("reading video clip list: %w", err)
+	}
+	for i := range chunks {
+		clip, ok := clipAt(clips, nearestKeyframe(chunks[i].StartSeconds, keyframes))
+		if !ok {
+			continue
+		}
+		chunks[i].VideoFile = filepath.ToSlash(filepath.Join("video", clip.file))
+		chunks[i].VideoStartSeconds = clip.start
+		chunks[i].VideoDurationSeconds = clip.end - clip.start
+	}
+	return logs, nil
+}
+
+// readSegmentList parses the segment muxer's CSV list: file name, start, and end time.
+func readSegmentList(path string) ([]videoClip, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	clips := make([]videoClip, 0, len(records))
+	for _, record := range records {
+		if len(record) < 3 {
+			return nil, fmt.Errorf("malformed entry %q", strings.Join(record, ","))
+		}
+		start, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed start time %q", record[1])
+		}
+		end, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed end time %q", record[2])
+		}
+		clips = append(clips, videoClip{file: filepath.Base(record[0]), start: start, end: end})
+	}
+	return clips, nil
+}
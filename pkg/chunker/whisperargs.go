@@ -0,0 +1,79 @@
+package chunker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxJobWhisperArgs bounds how many per-job whisper arguments a request may carry.
+const maxJobWhisperArgs = 16
+
+// jobWhisperFlags are the whisper.cpp options end users may set per job, mapped to
+// whether they take a value. They tune decoding and language only: nothing that picks
+// the model or other files, changes the output format the chunker reads, or trims the
+// audio is allowed, since the server runs whisper on the users' behalf.
+var jobWhisperFlags = map[string]bool{
+	"-l": true, "--language": true,
+	"-tr": false, "--translate": false,
+	"-bs": true, "--beam-size": true,
+	"-bo": true, "--best-of": true,
+	"-tp": true, "--temperature": true,
+	"-tpi": true, "--temperature-inc": true,
+	"-nf": false, "--no-fallback": false,
+	"-mc": true, "--max-context": true,
+	"-et": true, "--entropy-thold": true,
+	"-lpt": true, "--logprob-thold": true,
+	"-sns": false, "--suppress-nst": false,
+}
+
+// JobWhisperFlags lists the allowed per-job flags, sorted, for help texts.
+func JobWhisperFlags() []string {
+	flags := make([]string, 0, len(jobWhisperFlags))
+	for flag := range jobWhisperFlags {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// ParseWhisperArgs splits user input on whitespace and validates it with
+// ValidateWhisperArgs.
+func ParseWhisperArgs(value string) ([]string, error) {
+	args := strings.Fields(value)
+	if len(args) == 0 {
+		return nil, nil
+	}
+	return args, ValidateWhisperArgs(args)
+}
+
+// ValidateWhisperArgs checks per-job arguments against the allowlist: known flags only,
+// each followed by a value when it takes one. Values may not look like flags, except
+// for negative numbers such as a log-probability threshold.
+func ValidateWhisperArgs(args []string) error {
+	if len(args) > maxJobWhisperArgs {
+		return fmt.Errorf("at most %d whisper arguments are allowed per job", maxJobWhisperArgs)
+	}
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		takesValue, ok := jobWhisperFlags[flag]
+		if !ok {
+			return fmt.Errorf("whisper argument %q is not allowed per job (allowed: %s)", flag, strings.Join(JobWhisperFlags(), " "))
+		}
+		if !takesValue {
+			continue
+		}
+		if i+1 == len(args) {
+			return fmt.Errorf("whisper argument %s needs a value", flag)
+		}
+		value := args[i+1]
+		if strings.HasPrefix(value, "-") {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("whisper argument %s needs a value, got %q", flag, value)
+			}
+		}
+		i++
+	}
+	return nil
+}
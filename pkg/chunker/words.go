@@ -0,0 +1,140 @@
+package chunker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TranscriptWord is one word of a transcript with its timing in job seconds and the mean
+// probability whisper gave its tokens.
+type TranscriptWord struct {
+	Word       string  `json:"word"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
+}
+
+// TranscriptSegment is one whisper segment (roughly a sentence) and its words.
+type TranscriptSegment struct {
+	Start float64          `json:"start"`
+	End   float64          `json:"end"`
+	Text  string           `json:"text"`
+	Words []TranscriptWord `json:"words"`
+}
+
+// WordTranscript is the content of a chunk's WordsFile. Times are in job seconds as of
+// processing; ChunkStartSeconds records the chunk start they were shifted by, so a later
+// time offset can be applied on read.
+type WordTranscript struct {
+	ChunkStartSeconds float64             `json:"chunkStartSeconds"`
+	Segments          []TranscriptSegment `json:"segments"`
+}
+
+// whisperTimedJSON is the subset of whisper.cpp's --output-json-full output carrying
+// segment and token offsets, in milliseconds from the start of the chunk.
+type whisperTimedJSON struct {
+	Transcription []struct {
+		Offsets whisperOffsets `json:"offsets"`
+		Text    string         `json:"text"`
+		Tokens  []struct {
+			Text    string         `json:"text"`
+			Offsets whisperOffsets `json:"offsets"`
+			P       float64        `json:"p"`
+		} `json:"tokens"`
+	} `json:"transcription"`
+}
+
+type whisperOffsets struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// writeWordTranscript converts a whisper.cpp full JSON file into a WordTranscript at
+// dstPath, shifting every time by chunkStart. Tokens are joined into words at leading
+// spaces, so punctuation stays attached to the word before it.
+func writeWordTranscript(whisperJSON, dstPath string, chunkStart float64) error {
+	data, err := os.ReadFile(whisperJSON)
+	if err != nil {
+		return err
+	}
+	var parsed whisperTimedJSON
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("decoding whisper JSON: %w", err)
+	}
+
+	at := func(ms int64) float64 { return chunkStart + float64(ms)/1000 }
+	out := WordTranscript{ChunkStartSeconds: chunkStart, Segments: []TranscriptSegment{}}
+	for _, seg := range parsed.Transcription {
+		segment := TranscriptSegment{
+			Start: at(seg.Offsets.From),
+			End:   at(seg.Offsets.To),
+			Text:  strings.TrimSpace(seg.Text),
+			Words: []TranscriptWord{},
+		}
+		var probs float64
+		var tokens int
+		flush := func() {
+			if tokens == 0 {
+				return
+			}
+			last := &segment.Words[len(segment.Words)-1]
+			last.Word = strings.TrimSpace(last.Word)
+			last.Confidence = probs / float64(tokens)
+			probs, tokens = 0, 0
+		}
+		for _, token := range seg.Tokens {
+			if strings.HasPrefix(token.Text, "[_") || token.Text == "" {
+				continue
+			}
+			if tokens == 0 || strings.HasPrefix(token.Text, " ") {
+				flush()
+				segment.Words = append(segment.Words, TranscriptWord{Start: at(token.Offsets.From)})
+			}
+			word := &segment.Words[len(segment.Words)-1]
+			word.Word += token.Text
+			word.End = at(token.Offsets.To)
+			probs += token.P
+			tokens++
+		}
+		flush()
+		out.Segments = append(out.Segments, segment)
+	}
+
+	encoded, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dstPath, encoded, 0o644)
+}
+
+// ReadChunkWords loads a chunk's word timings from jobDir, moved along with any time
+// offset applied to the job since processing.
+func ReadChunkWords(jobDir string, chunk Chunk) ([]TranscriptSegment, error) {
+	if chunk.WordsFile == "" {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(filepath.Join(jobDir, filepath.FromSlash(chunk.WordsFile)))
+	if err != nil {
+		return nil, err
+	}
+	var words WordTranscript
+	if err := json.Unmarshal(data, &words); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", chunk.WordsFile, err)
+	}
+	delta := chunk.StartSeconds - words.ChunkStartSeconds
+	if delta != 0 {
+		for i := range words.Segments {
+			seg := &words.Segments[i]
+			seg.Start += delta
+			seg.End += delta
+			for j := range seg.Words {
+				seg.Words[j].Start += delta
+				seg.Words[j].End += delta
+			}
+		}
+	}
+	return words.Segments, nil
+}
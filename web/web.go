@@ -0,0 +1,19 @@
+// Package web bundles the HTML templates so the server binary runs from any directory.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates/*.gohtml
+var embedded embed.FS
+
+// Templates returns the embedded template files rooted at the templates directory.
+func Templates() fs.FS {
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}